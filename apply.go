@@ -0,0 +1,76 @@
+package goose
+
+import (
+	"database/sql"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+var force bool
+
+// SetForce sets whether Apply runs a migration even if the version table
+// says it is already applied, or already rolled back.
+func SetForce(f bool) {
+	force = f
+}
+
+// WithForce runs the migration even if it is already applied, or already
+// rolled back, like SetForce.
+func WithForce() OptionsFunc {
+	return func(o *options) { o.force = true }
+}
+
+// Apply runs the migration with version in isolation, up if direction is
+// true or down otherwise, regardless of the current version, for
+// cherry-picking a hotfix. It fails if the migration is already applied,
+// or already rolled back, unless forced with WithForce. The version table
+// records the run, unless with WithNoVersioning.
+func Apply(db *sql.DB, dir string, version int64, direction bool, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return applyLocked(db, dir, version, direction)
+	})
+}
+
+// applyLocked is Apply for the callers holding optionsMu.
+func applyLocked(db *sql.DB, dir string, version int64, direction bool) error {
+	return invoke("apply-version", db, func() error {
+		return apply(db, dir, version, direction)
+	})
+}
+
+func apply(db *sql.DB, dir string, version int64, direction bool) error {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return err
+	}
+	m, err := migrations.Current(version)
+	if err != nil {
+		return errors.Errorf("no migration %d in %s", version, dir)
+	}
+
+	if !noVersioning {
+		if _, err := EnsureDBVersion(db); err != nil {
+			return errors.Wrap(err, "failed to ensure DB version")
+		}
+		statuses, err := dbMigrationsStatus(db)
+		if err != nil {
+			return errors.Wrap(err, "failed to get status of migrations")
+		}
+		if statuses[version] == direction {
+			state := "applied"
+			if !direction {
+				state = "rolled back"
+			}
+			if !force {
+				return errors.Errorf("%s is already %s, force to run it again", filepath.Base(m.Source), state)
+			}
+			log.Printf("goose: %s is already %s, running it again (forced)\n", filepath.Base(m.Source), state)
+		}
+	}
+
+	if direction {
+		return m.Up(db)
+	}
+	return m.Down(db)
+}
@@ -0,0 +1,51 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	defer SetForce(false)
+
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := Apply(db, dir, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM b"); err != nil {
+		t.Errorf("expected migration 2 to be applied: %v", err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM a"); err == nil {
+		t.Errorf("unexpected migration 1 applied")
+	}
+
+	if err := Apply(db, dir, 2, true); err == nil {
+		t.Errorf("expected an error applying an applied migration")
+	}
+	if err := Apply(db, dir, 1, false); err == nil {
+		t.Errorf("expected an error rolling back a migration not applied")
+	}
+	if err := Apply(db, dir, 4, true); err == nil {
+		t.Errorf("expected an error applying a missing migration")
+	}
+
+	if err := Apply(db, dir, 2, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(db, dir, 2, false, WithForce()); err == nil {
+		t.Errorf("expected an error rolling back twice, as the table is already dropped")
+	}
+	if err := Apply(db, dir, 1, true, WithNoVersioning()); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := dbMigrationsStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[1] {
+		t.Errorf("unexpected migration 1 recorded without versioning")
+	}
+}
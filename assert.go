@@ -0,0 +1,81 @@
+package goose
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// assertAnnotation marks the statements following '-- +goose Assert' in the
+// statements of a SQL migration.
+const assertAnnotation = "-- +goose Assert"
+
+func isAssertion(query string) bool {
+	return strings.HasPrefix(query, assertAnnotation+"\n")
+}
+
+// runAssertions runs the queries of the '-- +goose Assert' annotations of a
+// migration, failing it if one returns no row, or false in the first column
+// of its first row.
+func runAssertions(qe QueryExecer, queries []string) error {
+	for _, query := range queries {
+		verboseInfo("Checking assertion: %s", clearStatement(query))
+		ok, err := assertion(qe, query)
+		if err != nil {
+			return errors.Wrapf(err, "failed to run assertion %q", clearStatement(query))
+		}
+		if !ok {
+			return errors.Errorf("assertion failed: %q", clearStatement(query))
+		}
+	}
+	return nil
+}
+
+func assertion(qe QueryExecer, query string) (bool, error) {
+	rows, err := qe.QueryContext(runCtx, query)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	if err := rows.Scan(values...); err != nil {
+		return false, err
+	}
+	if len(values) == 0 {
+		return true, nil
+	}
+	return truthy(*values[0].(*interface{})), nil
+}
+
+// truthy reports whether a value returned by a driver is true: booleans,
+// non-zero numbers, and strings other than false values like "f" or "0".
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case []byte:
+		return truthy(string(v))
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b
+		}
+	}
+	return true
+}
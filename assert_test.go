@@ -0,0 +1,85 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAssertions(t *testing.T) {
+	sql := `-- +goose Up
+UPDATE users SET email = '' WHERE email IS NULL;
+-- +goose Assert
+SELECT COUNT(*) = 0 FROM users WHERE email IS NULL;
+-- +goose Down
+-- +goose Assert
+SELECT 1;
+`
+	stmts, _, err := parseSQLMigration(strings.NewReader(sql), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 || isAssertion(stmts[0]) || !isAssertion(stmts[1]) {
+		t.Errorf("expected the second statement to be an assertion, got %q", stmts)
+	}
+	stmts, _, err = parseSQLMigration(strings.NewReader(sql), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 1 || !isAssertion(stmts[0]) {
+		t.Errorf("expected a down assertion, got %q", stmts)
+	}
+
+	if _, _, err := parseSQLMigration(strings.NewReader("-- +goose Assert\n-- +goose Up\nSELECT 1;\n"), true); err == nil {
+		t.Error("expected error on assertion before '-- +goose Up'")
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	defer SetLogger(log)
+	SetLogger(&bufferLogger{})
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_users.sql": `-- +goose Up
+-- +goose Assert
+SELECT COUNT(*) = 0 FROM users WHERE email IS NULL;
+ALTER TABLE users ADD COLUMN verified int;
+-- +goose Down
+ALTER TABLE users DROP COLUMN verified;
+`,
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	if _, err := db.Exec("CREATE TABLE users (id int, email text); INSERT INTO users VALUES (1, NULL);"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Up(db, dir); err == nil || !strings.Contains(err.Error(), "assertion failed") {
+		t.Fatalf("expected the assertion to fail the migration, got %v", err)
+	}
+	if _, err := db.Exec("SELECT verified FROM users"); err == nil {
+		t.Error("expected the column to be rolled back with the failed assertion")
+	}
+
+	if _, err := db.Exec("UPDATE users SET email = 'a@example.com';"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT verified FROM users"); err != nil {
+		t.Errorf("expected the column to be added, got %v", err)
+	}
+}
+
+func TestTruthy(t *testing.T) {
+	for _, v := range []interface{}{true, int64(1), []byte("t"), "x"} {
+		if !truthy(v) {
+			t.Errorf("expected %v to be true", v)
+		}
+	}
+	for _, v := range []interface{}{nil, false, int64(0), float64(0), []byte("0"), "false"} {
+		if truthy(v) {
+			t.Errorf("expected %v to be false", v)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var audit = false
+
+// SetAudit enables the audit log. When enabled, every invocation of the
+// commands modifying the database writes a row in the audit table of that
+// database, with the operator, the host, the build revision of the binary,
+// the versions applied or rolled back and the outcome.
+//
+// The audit table is named after the version table, with an "_audit"
+// suffix, and is created when needed.
+func SetAudit(a bool) {
+	audit = a
+}
+
+func auditTableName() string {
+	return TableName() + "_audit"
+}
+
+// writeAudit writes the audit row of an invocation.
+func writeAudit(inv *invocation, runErr error) error {
+	if !audit || inv.db == nil {
+		return nil
+	}
+	if err := ensureAuditTable(inv.db); err != nil {
+		return errors.Wrap(err, "failed to write audit log")
+	}
+
+	host, _ := os.Hostname()
+	versions := make([]string, len(inv.versions))
+	for i, v := range inv.versions {
+		versions[i] = strconv.FormatInt(v, 10)
+	}
+	outcome, message := "success", sql.NullString{}
+	if runErr != nil {
+		outcome, message = "failure", sql.NullString{String: runErr.Error(), Valid: true}
+	}
+
+	d := GetDialect()
+	q := fmt.Sprintf("INSERT INTO %s (started_at, duration_ms, command, operator, host, revision, versions, outcome, error) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)",
+		auditTableName(), d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4), d.placeholder(5), d.placeholder(6), d.placeholder(7), d.placeholder(8), d.placeholder(9))
+	_, err := inv.db.Exec(q,
+		inv.started.Unix(),
+		int64(time.Since(inv.started)/time.Millisecond),
+		inv.command,
+		currentUser(),
+		host,
+		buildRevision(),
+		strings.Join(versions, ","),
+		outcome,
+		message,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to write audit log")
+	}
+	return nil
+}
+
+// ensureAuditTable creates the audit table if it doesn't exist.
+func ensureAuditTable(db *sql.DB) error {
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %s WHERE 1 = 0", auditTableName())); err == nil {
+		return nil
+	}
+	q := fmt.Sprintf(`CREATE TABLE %s (
+                started_at BIGINT NOT NULL,
+                duration_ms BIGINT NOT NULL,
+                command VARCHAR(32) NOT NULL,
+                operator VARCHAR(255),
+                host VARCHAR(255),
+                revision VARCHAR(255),
+                versions %s,
+                outcome VARCHAR(16) NOT NULL,
+                error %s
+            )`, auditTableName(), textType(), textType())
+	if _, err := db.Exec(q); err != nil {
+		return errors.Wrap(err, "failed to create audit table")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	SetAudit(true)
+	defer SetAudit(false)
+
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+
+	if err := UpTo(db, dir, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := Down(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := DownTo(db, dir, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query("SELECT command, versions, outcome, operator FROM " + TableName() + "_audit ORDER BY rowid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	type auditRow struct{ command, versions, outcome string }
+	var got []auditRow
+	for rows.Next() {
+		var r auditRow
+		var operator string
+		if err := rows.Scan(&r.command, &r.versions, &r.outcome, &operator); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+	want := []auditRow{
+		{"up-to", "1,2", "success"},
+		{"down", "2", "success"},
+		{"down-to", "", "success"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected audit rows, got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("unexpected audit row %d, got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
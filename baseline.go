@@ -0,0 +1,123 @@
+package goose
+
+import (
+	"bufio"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var baselineFile string
+
+// SetBaseline sets the baseline schema file initializing new databases.
+// On a database without any migration applied, up runs the schema file,
+// and records the migrations up to its version as applied, instead of
+// replaying them one by one. The file declares its version with a
+// '-- +goose Baseline VERSION' annotation, and is otherwise parsed like the
+// up part of a SQL migration. An empty file disables the baseline.
+func SetBaseline(file string) {
+	baselineFile = file
+}
+
+// baselineVersion returns the version declared by the '+goose Baseline'
+// annotation of a baseline schema file.
+func baselineVersion(file string) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to open baseline %v", filepath.Base(file))
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), scanBufSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "--"))
+		if !strings.HasPrefix(line, "+goose Baseline ") {
+			continue
+		}
+		v := strings.TrimSpace(strings.TrimPrefix(line, "+goose Baseline "))
+		version, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || version <= 0 {
+			return 0, errors.Errorf("%v: invalid version %q in '-- +goose Baseline' annotation", filepath.Base(file), v)
+		}
+		return version, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, errors.Wrapf(err, "failed to read baseline %v", filepath.Base(file))
+	}
+	return 0, errors.Errorf("%v: missing '-- +goose Baseline VERSION' annotation", filepath.Base(file))
+}
+
+// upBaseline initializes db from the baseline schema file, if set, when no
+// migration is applied yet and the baseline version is not above target.
+func upBaseline(db *sql.DB, migrations Migrations, target int64) error {
+	if baselineFile == "" {
+		return nil
+	}
+	version, err := baselineVersion(baselineFile)
+	if err != nil {
+		return err
+	}
+	if version > target {
+		return nil
+	}
+	if _, err := EnsureDBVersion(db); err != nil {
+		return errors.Wrap(err, "failed to ensure DB version")
+	}
+	applied, err := appliedVersions(db)
+	if err != nil || len(applied) > 0 {
+		return err
+	}
+
+	f, err := os.Open(baselineFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open baseline %v", filepath.Base(baselineFile))
+	}
+	defer f.Close()
+	statements, _, err := parseSQLMigration(io.MultiReader(strings.NewReader("-- +goose Up\n"), f), true)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse baseline %v", filepath.Base(baselineFile))
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	if err := execSchema(tx, statements); err != nil {
+		tx.Rollback()
+		return withExitCode(ExitSQLError, errors.Wrapf(err, "failed to run baseline %v", filepath.Base(baselineFile)))
+	}
+	baselined := migrations.Filter(0, version)
+	for _, m := range baselined {
+		checksum, err := m.Checksum()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := insertVersion(tx, m.Version, true, checksum, 0); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "failed to insert new goose version")
+		}
+	}
+	if _, err := migrations.Current(version); err != nil {
+		// Record the baseline version itself as the current version.
+		if err := insertVersion(tx, version, true, "", 0); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "failed to insert new goose version")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	for _, m := range baselined {
+		recordMigration(m.Version)
+	}
+
+	log.Printf("OK    %s (%d migrations)\n", filepath.Base(baselineFile), len(baselined))
+	return nil
+}
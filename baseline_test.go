@@ -0,0 +1,55 @@
+package goose
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	baseline := filepath.Join(dir, "baseline.sql.txt")
+	if err := ioutil.WriteFile(baseline, []byte("-- +goose Baseline 2\nCREATE TABLE a (id int);\nCREATE TABLE b (id int);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer SetBaseline("")
+	SetBaseline(baseline)
+
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := dbMigrationsStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[1] || !statuses[2] || !statuses[3] {
+		t.Errorf("expected every migration to be applied, got %v", statuses)
+	}
+	if err := Check(db, dir); err != nil {
+		t.Errorf("unexpected error checking the database initialized from the baseline: %v", err)
+	}
+
+	// The baseline only initializes new databases.
+	db2, cleanup2 := openTestDB(t)
+	defer cleanup2()
+	SetBaseline("")
+	if err := UpTo(db2, dir, 1); err != nil {
+		t.Fatal(err)
+	}
+	SetBaseline(baseline)
+	if err := Up(db2, dir); err != nil {
+		t.Errorf("unexpected error applying migrations after the first one: %v", err)
+	}
+
+	if err := ioutil.WriteFile(baseline, []byte("CREATE TABLE a (id int);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db3, cleanup3 := openTestDB(t)
+	defer cleanup3()
+	if err := Up(db3, dir); err == nil {
+		t.Errorf("expected an error for a baseline without version")
+	}
+}
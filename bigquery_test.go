@@ -0,0 +1,37 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBigQueryDialect(t *testing.T) {
+	defer SetDialect("postgres")
+	defer SetTableName(TableName())
+	if err := SetDialect("bigquery"); err != nil {
+		t.Fatal(err)
+	}
+	SetTableName("analytics.goose_db_version")
+
+	if transactional() {
+		t.Error("expected BigQuery migrations to run without a transaction")
+	}
+	if q := GetDialect().createVersionTableSQL(); !strings.Contains(q, "CREATE TABLE analytics.goose_db_version (") || strings.Contains(q, " id ") {
+		t.Errorf("expected a version table of the dataset without id, got %q", q)
+	}
+	applied := true
+	q, args := listAppliedQuery(ListFilter{IsApplied: &applied})
+	if !strings.Contains(q, "ARRAY_AGG(is_applied ORDER BY tstamp DESC LIMIT 1)[OFFSET(0)]") || len(args) != 1 {
+		t.Errorf("expected the latest record by timestamp, got %q %v", q, args)
+	}
+	if _, err := compactHistory(nil, 0); err == nil {
+		t.Error("expected compacting to be unsupported")
+	}
+
+	if err := SetDialect("postgres"); err != nil {
+		t.Fatal(err)
+	}
+	if !transactional() {
+		t.Error("expected Postgres migrations to run in a transaction")
+	}
+}
@@ -0,0 +1,145 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/pkg/errors"
+)
+
+// Rebind replaces the ? placeholders of query with the placeholders of the
+// current dialect, so that Go migrations can write their statements once:
+//
+//	goose.Rebind("UPDATE users SET role = ? WHERE id = ?") // UPDATE users SET role = $1 WHERE id = $2 on Postgres
+//
+// Question marks inside quoted strings, with their doubled quotes and
+// backslash escapes, quoted identifiers, and -- and /* */ comments are left
+// untouched.
+func Rebind(query string) string {
+	n := 0
+	query, _ = rewriteSQL(query, func(i int) (string, int, error) {
+		if query[i] != '?' {
+			return "", i, nil
+		}
+		n++
+		return GetDialect().placeholder(n), i + 1, nil
+	})
+	return query
+}
+
+// BindNamed replaces the :name references of query with the placeholders of
+// the current dialect, and returns the values of the sql.Named arguments to
+// bind to them, in order, for the drivers not supporting named arguments:
+//
+//	q, args, err := goose.BindNamed("UPDATE users SET role = :role WHERE role = :old", sql.Named("role", "admin"), sql.Named("old", "root"))
+//
+// A name referenced several times is bound as many times. References inside
+// quoted strings, quoted identifiers and comments, and Postgres :: casts,
+// are left untouched.
+func BindNamed(query string, args ...interface{}) (string, []interface{}, error) {
+	named := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		n, ok := arg.(sql.NamedArg)
+		if !ok {
+			return "", nil, errors.Errorf("unexpected positional argument %v, expected sql.Named arguments", arg)
+		}
+		named[n.Name] = n.Value
+	}
+
+	var bound []interface{}
+	query, err := rewriteSQL(query, func(i int) (string, int, error) {
+		name, j := paramRef(query, i)
+		if name == "" {
+			return "", i, nil
+		}
+		value, ok := named[name]
+		if !ok {
+			return "", 0, errors.Errorf("missing value for named argument %q", name)
+		}
+		bound = append(bound, value)
+		return GetDialect().placeholder(len(bound)), j, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return query, bound, nil
+}
+
+// NewPortableExecer returns a QueryExecer running the statements of qe with
+// the placeholders of the current dialect: the statements given sql.Named
+// arguments are bound with BindNamed, the others are rebound with Rebind.
+// Go migrations can then be written once for several databases:
+//
+//	qe = goose.NewPortableExecer(qe)
+//	_, err := qe.Exec("UPDATE users SET role = ? WHERE id = ?", "admin", 1)
+func NewPortableExecer(qe QueryExecer) QueryExecer {
+	return portableExecer{qe}
+}
+
+type portableExecer struct {
+	qe QueryExecer
+}
+
+func portableQuery(query string, args []interface{}) (string, []interface{}, error) {
+	for _, arg := range args {
+		if _, ok := arg.(sql.NamedArg); ok {
+			return BindNamed(query, args...)
+		}
+	}
+	return Rebind(query), args, nil
+}
+
+// bindError is the argument of the rows of QueryRow failing to bind, so that
+// Scan reports the error.
+type bindError struct {
+	err error
+}
+
+func (e bindError) Value() (driver.Value, error) {
+	return nil, e.err
+}
+
+func (p portableExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return p.ExecContext(context.Background(), query, args...)
+}
+
+func (p portableExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args, err := portableQuery(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return p.qe.ExecContext(ctx, query, args...)
+}
+
+func (p portableExecer) Prepare(query string) (*sql.Stmt, error) {
+	return p.qe.Prepare(Rebind(query))
+}
+
+func (p portableExecer) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.qe.PrepareContext(ctx, Rebind(query))
+}
+
+func (p portableExecer) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return p.QueryContext(context.Background(), query, args...)
+}
+
+func (p portableExecer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	query, args, err := portableQuery(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return p.qe.QueryContext(ctx, query, args...)
+}
+
+func (p portableExecer) QueryRow(query string, args ...interface{}) *sql.Row {
+	return p.QueryRowContext(context.Background(), query, args...)
+}
+
+func (p portableExecer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	bound, boundArgs, err := portableQuery(query, args)
+	if err != nil {
+		return p.qe.QueryRowContext(ctx, query, bindError{err})
+	}
+	return p.qe.QueryRowContext(ctx, bound, boundArgs...)
+}
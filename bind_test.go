@@ -0,0 +1,102 @@
+package goose
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	defer SetDialect("postgres")
+
+	tests := []struct {
+		dialect string
+		want    string
+	}{
+		{"postgres", "UPDATE t SET a = $1, b = '?' WHERE c = $2 -- ?\n"},
+		{"mysql", "UPDATE t SET a = ?, b = '?' WHERE c = ? -- ?\n"},
+		{"mssql", "UPDATE t SET a = @p1, b = '?' WHERE c = @p2 -- ?\n"},
+	}
+	for _, test := range tests {
+		if err := SetDialect(test.dialect); err != nil {
+			t.Fatal(err)
+		}
+		if got := Rebind("UPDATE t SET a = ?, b = '?' WHERE c = ? -- ?\n"); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.dialect, got, test.want)
+		}
+	}
+}
+
+func TestRebindCommentsAndEscapes(t *testing.T) {
+	defer SetDialect("postgres")
+
+	tests := []struct {
+		dialect string
+		query   string
+		want    string
+	}{
+		{"postgres", "SELECT ? /* ? /* nested ? */ ? */, ?", "SELECT $1 /* ? /* nested ? */ ? */, $2"},
+		{"postgres", "SELECT 'C:\\', ?", "SELECT 'C:\\', $1"},
+		{"postgres", "SELECT E'it\\'s ?', ?", "SELECT E'it\\'s ?', $1"},
+		{"postgres", "SELECT name'?', ?", "SELECT name'?', $1"},
+		{"mysql", "SELECT 'it\\'s ?', \"\\\"?\", ?", "SELECT 'it\\'s ?', \"\\\"?\", ?"},
+		{"postgres", "SELECT ? /* unterminated ?", "SELECT $1 /* unterminated ?"},
+	}
+	for _, test := range tests {
+		if err := SetDialect(test.dialect); err != nil {
+			t.Fatal(err)
+		}
+		if got := Rebind(test.query); got != test.want {
+			t.Errorf("%s: Rebind(%q) = %q, want %q", test.dialect, test.query, got, test.want)
+		}
+	}
+}
+
+func TestBindNamed(t *testing.T) {
+	defer SetDialect("postgres")
+	SetDialect("postgres")
+
+	query, args, err := BindNamed("UPDATE t SET a = :a, b = ':a', c = x::text WHERE a <> :a AND d = :d",
+		sql.Named("a", 1), sql.Named("d", "x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "UPDATE t SET a = $1, b = ':a', c = x::text WHERE a <> $2 AND d = $3"; query != want {
+		t.Errorf("got %q, want %q", query, want)
+	}
+	if want := []interface{}{1, 1, "x"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("got args %v, want %v", args, want)
+	}
+
+	if _, _, err := BindNamed("SELECT :missing", sql.Named("a", 1)); err == nil {
+		t.Error("expected error on missing named argument")
+	}
+	if _, _, err := BindNamed("SELECT :a", 1); err == nil {
+		t.Error("expected error on positional argument")
+	}
+}
+
+func TestPortableExecer(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	qe := NewPortableExecer(db)
+
+	if _, err := qe.Exec("CREATE TABLE users (id int, role text)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := qe.Exec("INSERT INTO users VALUES (?, ?)", 1, "root"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := qe.Exec("UPDATE users SET role = :role WHERE role = :old", sql.Named("role", "admin"), sql.Named("old", "root")); err != nil {
+		t.Fatal(err)
+	}
+	var role string
+	if err := qe.QueryRow("SELECT role FROM users WHERE id = :id", sql.Named("id", 1)).Scan(&role); err != nil || role != "admin" {
+		t.Errorf("expected the role to be updated, got %q (%v)", role, err)
+	}
+	err := qe.QueryRow("SELECT role FROM users WHERE id = :missing", sql.Named("id", 1)).Scan(&role)
+	if err == nil || !strings.Contains(err.Error(), `missing value for named argument "missing"`) {
+		t.Errorf("expected the binding error from Scan, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package goose
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const browseHelp = `Commands:
+    list            List the migrations and their status
+    show VERSION    Print the source of a migration
+    up              Apply the next migration, after confirmation
+    down            Roll back the current migration, after confirmation
+    help            Print this help
+    quit            Quit
+`
+
+// Browse runs an interactive browser of the migrations of dir, reading the
+// commands of an operator from in and writing to out: it lists the
+// migrations, prints their source, and applies or rolls them back one at
+// a time, after confirmation.
+func Browse(db *sql.DB, dir string, in io.Reader, out io.Writer) error {
+	b := &browser{db: db, dir: dir, in: bufio.NewScanner(in), out: out}
+	if err := b.list(); err != nil {
+		return err
+	}
+	fmt.Fprint(out, browseHelp)
+	for {
+		fmt.Fprint(out, "goose> ")
+		line, ok := b.readLine()
+		if !ok {
+			fmt.Fprintln(out)
+			return b.in.Err()
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch fields[0] {
+		case "list", "l":
+			err = b.list()
+		case "show", "s":
+			if len(fields) != 2 {
+				err = errors.New("show must be of form: show VERSION")
+				break
+			}
+			err = b.show(fields[1])
+		case "up", "u":
+			err = b.step(true)
+		case "down", "d":
+			err = b.step(false)
+		case "help", "h", "?":
+			fmt.Fprint(out, browseHelp)
+		case "quit", "q", "exit":
+			return nil
+		default:
+			err = errors.Errorf("unknown command %q, see help", fields[0])
+		}
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+}
+
+type browser struct {
+	db  *sql.DB
+	dir string
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func (b *browser) readLine() (string, bool) {
+	if !b.in.Scan() {
+		return "", false
+	}
+	return strings.TrimSpace(b.in.Text()), true
+}
+
+func (b *browser) list() error {
+	return status(b.out, b.db, b.dir, "table")
+}
+
+func (b *browser) show(arg string) error {
+	version, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return errors.Errorf("version must be a number (got %q)", arg)
+	}
+	migrations, err := CollectMigrations(b.dir, minVersion, maxVersion)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.Version != version {
+			continue
+		}
+		source, err := ioutil.ReadFile(m.Source)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read migration %v", filepath.Base(m.Source))
+		}
+		fmt.Fprintf(b.out, "-- %s\n%s", filepath.Base(m.Source), source)
+		if len(source) > 0 && source[len(source)-1] != '\n' {
+			fmt.Fprintln(b.out)
+		}
+		return nil
+	}
+	return errors.Errorf("no migration %d", version)
+}
+
+// step applies the next migration, or rolls back the current one, once the
+// operator confirmed it.
+func (b *browser) step(direction bool) error {
+	migrations, err := CollectMigrations(b.dir, minVersion, maxVersion)
+	if err != nil {
+		return err
+	}
+	current, err := GetDBVersion(b.db)
+	if err != nil {
+		return err
+	}
+
+	var (
+		m      *Migration
+		action = "Apply"
+	)
+	if direction {
+		m, err = ungated(migrations).Next(current)
+		if err == ErrNoNextVersion {
+			return errors.Errorf("no migrations to apply, current version: %d", current)
+		}
+	} else {
+		action = "Roll back"
+		m, err = migrations.Current(current)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b.out, "%s %s? [y/N] ", action, filepath.Base(m.Source))
+	answer, _ := b.readLine()
+	if answer = strings.ToLower(answer); answer != "y" && answer != "yes" {
+		fmt.Fprintln(b.out, "canceled")
+		return nil
+	}
+	if direction {
+		return UpTo(b.db, b.dir, m.Version)
+	}
+	return Down(b.db, b.dir)
+}
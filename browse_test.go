@@ -0,0 +1,35 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBrowse(t *testing.T) {
+	defer SetLogger(log)
+	SetLogger(&bufferLogger{})
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	in := strings.NewReader("show 2\nup\ny\nup\nn\ndown\nyes\nup\nyes\nbogus\nquit\n")
+	var out strings.Builder
+	if err := Browse(db, dir, in, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"-- 00002_create_b.sql\n-- +goose Up\n",
+		"Apply 00001_create_a.sql? [y/N] ",
+		"Apply 00002_create_b.sql? [y/N] canceled",
+		"Roll back 00001_create_a.sql? [y/N] ",
+		`error: unknown command "bogus"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected the output to contain %q, got %s", want, out.String())
+		}
+	}
+	if version, err := GetDBVersion(db); err != nil || version != 1 {
+		t.Errorf("expected version 1, got %d (%v)", version, err)
+	}
+}
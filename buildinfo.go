@@ -0,0 +1,72 @@
+package goose
+
+import (
+	"reflect"
+	"runtime/debug"
+)
+
+// maxBuildInfoLen is the size of the build column of the version table.
+const maxBuildInfoLen = 255
+
+// buildInfo describes the binary applying migrations, as recorded in the
+// version table: its main module, version and VCS revision, for example
+// "example.com/app@v1.4.0 rev 4f1c2e9". It is empty if the binary was built
+// without module support.
+func buildInfo() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	s := info.Main.Path
+	if info.Main.Version != "" {
+		s += "@" + info.Main.Version
+	}
+	if revision := vcsRevision(info); revision != "" {
+		if s != "" {
+			s += " "
+		}
+		s += "rev " + revision
+	}
+	if len(s) > maxBuildInfoLen {
+		s = s[:maxBuildInfoLen]
+	}
+	return s
+}
+
+// buildRevision returns the VCS revision of the running binary, as recorded
+// in the audit log, or its main module version if the revision is not
+// available.
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if revision := vcsRevision(info); revision != "" {
+		return revision
+	}
+	return info.Main.Version
+}
+
+// vcsRevision returns the VCS revision the binary was built from, with a
+// "-dirty" suffix if the working tree had local modifications. The build
+// settings are only recorded from Go 1.18, so they are read by reflection.
+func vcsRevision(info *debug.BuildInfo) string {
+	settings := reflect.ValueOf(info).Elem().FieldByName("Settings")
+	if !settings.IsValid() {
+		return ""
+	}
+	revision, modified := "", false
+	for i := 0; i < settings.Len(); i++ {
+		s := settings.Index(i)
+		switch value := s.FieldByName("Value").String(); s.FieldByName("Key").String() {
+		case "vcs.revision":
+			revision = value
+		case "vcs.modified":
+			modified = value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
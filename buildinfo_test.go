@@ -0,0 +1,18 @@
+// +build go1.18
+
+package goose
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestVCSRevision(t *testing.T) {
+	info := &debug.BuildInfo{Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "4f1c2e9"}, {Key: "vcs.modified", Value: "true"}}}
+	if revision := vcsRevision(info); revision != "4f1c2e9-dirty" {
+		t.Errorf("unexpected revision %q", revision)
+	}
+	if revision := vcsRevision(&debug.BuildInfo{}); revision != "" {
+		t.Errorf("expected no revision without settings, got %q", revision)
+	}
+}
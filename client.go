@@ -0,0 +1,294 @@
+package goose
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ClientVersionStore records the applied migrations of a database without
+// SQL, like DynamoDB, on which client migrations run with RunClient.
+type ClientVersionStore interface {
+	// ListApplied returns the records of the applied migrations, in any
+	// order.
+	ListApplied(ctx context.Context) ([]MigrationRecord, error)
+	// SetApplied records whether the migration version is applied.
+	SetApplied(ctx context.Context, version int64, applied bool) error
+}
+
+// clientMigration is a Go migration running on the client of a database
+// without SQL.
+type clientMigration struct {
+	version  int64
+	source   string
+	up, down func(client interface{}) error
+}
+
+// clientRegistry is the registry of the client migrations, safe for
+// concurrent use.
+type clientRegistry struct {
+	sync.RWMutex
+	migrations map[int64]*clientMigration
+}
+
+var registeredClientMigrations = &clientRegistry{migrations: map[int64]*clientMigration{}}
+
+// add registers m, panicking if a client migration with its version is
+// already registered.
+func (r *clientRegistry) add(m *clientMigration) {
+	r.Lock()
+	defer r.Unlock()
+	if existing, ok := r.migrations[m.version]; ok {
+		panic(fmt.Sprintf("failed to add client migration %q: version conflicts with %q", m.source, existing.source))
+	}
+	r.migrations[m.version] = m
+}
+
+// all returns the registered client migrations, in no particular order.
+func (r *clientRegistry) all() []*clientMigration {
+	r.RLock()
+	defer r.RUnlock()
+	migrations := make([]*clientMigration, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		migrations = append(migrations, m)
+	}
+	return migrations
+}
+
+// AddClientMigration adds a client migration, a Go migration receiving the
+// client passed to RunClient, like a DynamoDB client, instead of a SQL
+// connection. Its version is the numeric prefix of the calling file.
+func AddClientMigration(up, down func(client interface{}) error) {
+	_, filename, _, _ := runtime.Caller(1)
+	AddNamedClientMigration(filename, up, down)
+}
+
+// AddNamedClientMigration adds a named client migration.
+func AddNamedClientMigration(filename string, up, down func(client interface{}) error) {
+	v, _ := NumericComponent(filename)
+	registeredClientMigrations.add(&clientMigration{version: v, source: filename, up: up, down: down})
+}
+
+// RunClient runs a goose command on the client migrations, passing client
+// to them and recording their versions in store. It supports up, up-to,
+// down, down-to, redo, reset, status and version. goose ships no store:
+// implement ClientVersionStore on the database, like a DynamoDB table.
+// Like the other commands, it holds the lock of SetLocker while it runs,
+// and reports to the notifier of SetNotifier; the audit log, version
+// table and connection settings of SQL databases don't apply.
+func RunClient(command string, client interface{}, store ClientVersionStore, args ...string) error {
+	r := newClientRunner(client, store, registeredClientMigrations.all())
+	return r.runCommand(command, args)
+}
+
+// runCommand runs command as an invocation without SQL database.
+func (r *clientRunner) runCommand(command string, args []string) error {
+	return withOptions(nil, func() error {
+		return invoke(command, nil, func() error {
+			return r.dispatch(command, args)
+		})
+	})
+}
+
+func (r *clientRunner) dispatch(command string, args []string) error {
+	switch command {
+	case "up":
+		return r.upTo(maxVersion)
+	case "up-to":
+		version, err := clientVersionArg(command, args)
+		if err != nil {
+			return err
+		}
+		return r.upTo(version)
+	case "down":
+		return r.down(1, -1)
+	case "down-to":
+		version, err := clientVersionArg(command, args)
+		if err != nil {
+			return err
+		}
+		return r.down(-1, version)
+	case "redo":
+		if err := r.down(1, -1); err != nil {
+			return err
+		}
+		return r.upOne()
+	case "reset":
+		return r.down(-1, 0)
+	case "status":
+		return r.status()
+	case "version":
+		applied, err := r.applied()
+		if err != nil {
+			return err
+		}
+		log.Printf("goose: version %v\n", currentClientVersion(applied))
+		return nil
+	default:
+		return fmt.Errorf("%q: no such command for client migrations", command)
+	}
+}
+
+func clientVersionArg(command string, args []string) (int64, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("%s must be of form: %s VERSION", command, command)
+	}
+	version, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("version must be a number (got '%s')", args[0])
+	}
+	return version, nil
+}
+
+// clientRunner runs client migrations, sorted by version.
+type clientRunner struct {
+	ctx        context.Context
+	client     interface{}
+	store      ClientVersionStore
+	migrations []*clientMigration
+}
+
+func newClientRunner(client interface{}, store ClientVersionStore, migrations []*clientMigration) *clientRunner {
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return &clientRunner{ctx: context.Background(), client: client, store: store, migrations: migrations}
+}
+
+// applied returns the applied records of the store, by version.
+func (r *clientRunner) applied() (map[int64]MigrationRecord, error) {
+	records, err := r.store.ListApplied(r.ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list applied migrations")
+	}
+	applied := map[int64]MigrationRecord{}
+	for _, rec := range records {
+		if rec.IsApplied {
+			applied[rec.VersionID] = rec
+		}
+	}
+	return applied, nil
+}
+
+func currentClientVersion(applied map[int64]MigrationRecord) int64 {
+	var current int64
+	for v := range applied {
+		if v > current {
+			current = v
+		}
+	}
+	return current
+}
+
+func (r *clientRunner) run(m *clientMigration, direction bool) error {
+	fn := m.down
+	if direction {
+		fn = m.up
+	}
+	if fn != nil {
+		if err := fn(r.client); err != nil {
+			return errors.Wrapf(err, "ERROR %v: failed to run client migration", filepath.Base(m.source))
+		}
+	}
+	if err := r.store.SetApplied(r.ctx, m.version, direction); err != nil {
+		return errors.Wrapf(err, "ERROR %v: failed to record version", filepath.Base(m.source))
+	}
+	recordMigration(m.version)
+	if fn == nil {
+		log.Println("EMPTY", filepath.Base(m.source))
+	} else {
+		log.Println("OK   ", filepath.Base(m.source))
+	}
+	return nil
+}
+
+// upTo applies the pending migrations up to version, in order.
+func (r *clientRunner) upTo(version int64) error {
+	applied, err := r.applied()
+	if err != nil {
+		return err
+	}
+	current := currentClientVersion(applied)
+	n := 0
+	for _, m := range r.migrations {
+		if m.version > version {
+			break
+		}
+		if _, ok := applied[m.version]; ok || (!allowMissing && m.version < current) {
+			continue
+		}
+		if err := r.run(m, true); err != nil {
+			return err
+		}
+		n++
+	}
+	if n == 0 {
+		log.Printf("goose: no migrations to run. current version: %d\n", current)
+	}
+	return nil
+}
+
+// upOne applies the next pending migration.
+func (r *clientRunner) upOne() error {
+	applied, err := r.applied()
+	if err != nil {
+		return err
+	}
+	current := currentClientVersion(applied)
+	for _, m := range r.migrations {
+		if m.version > current {
+			return r.run(m, true)
+		}
+	}
+	return ErrNoNextVersion
+}
+
+// down rolls back the applied migrations newer than version, latest first,
+// at most limit of them unless limit is negative.
+func (r *clientRunner) down(limit int, version int64) error {
+	applied, err := r.applied()
+	if err != nil {
+		return err
+	}
+	migrations := r.migrations
+	n := 0
+	for i := len(migrations) - 1; i >= 0 && n != limit; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.version]; !ok || m.version <= version {
+			continue
+		}
+		if err := r.run(m, false); err != nil {
+			return err
+		}
+		n++
+	}
+	if n == 0 {
+		if limit == 1 {
+			return ErrNoCurrentVersion
+		}
+		log.Printf("goose: no migrations to run. current version: %d\n", currentClientVersion(applied))
+	}
+	return nil
+}
+
+func (r *clientRunner) status() error {
+	applied, err := r.applied()
+	if err != nil {
+		return err
+	}
+	log.Println("    Applied At                  Migration")
+	log.Println("    =======================================")
+	for _, m := range r.migrations {
+		appliedAt := "Pending"
+		if rec, ok := applied[m.version]; ok {
+			appliedAt = rec.TStamp.Format(time.ANSIC)
+		}
+		log.Printf("    %-24s -- %v\n", appliedAt, filepath.Base(m.source))
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package goose
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mapVersionStore is a ClientVersionStore in memory, standing for a
+// DynamoDB table.
+type mapVersionStore map[int64]time.Time
+
+func (s mapVersionStore) ListApplied(ctx context.Context) ([]MigrationRecord, error) {
+	var records []MigrationRecord
+	for v, t := range s {
+		records = append(records, MigrationRecord{VersionID: v, TStamp: t, IsApplied: true})
+	}
+	return records, nil
+}
+
+func (s mapVersionStore) SetApplied(ctx context.Context, version int64, applied bool) error {
+	if applied {
+		s[version] = time.Now()
+	} else {
+		delete(s, version)
+	}
+	return nil
+}
+
+func TestRunClient(t *testing.T) {
+	saved := registeredClientMigrations
+	registeredClientMigrations = &clientRegistry{migrations: map[int64]*clientMigration{}}
+	defer func() { registeredClientMigrations = saved }()
+
+	var tables []string
+	for _, name := range []string{"00001_users.go", "00002_orders.go", "00003_events.go"} {
+		name := name
+		AddNamedClientMigration(name, func(client interface{}) error {
+			if client != "dynamodb" {
+				t.Errorf("unexpected client %v", client)
+			}
+			tables = append(tables, name)
+			return nil
+		}, func(client interface{}) error {
+			tables = tables[:len(tables)-1]
+			return nil
+		})
+	}
+
+	defer SetLogger(log)
+	logger := &bufferLogger{}
+	SetLogger(logger)
+	store := mapVersionStore{}
+	if err := RunClient("up-to", "dynamodb", store, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if len(store) != 2 || len(tables) != 2 {
+		t.Fatalf("expected 2 applied migrations, got %v", store)
+	}
+	if err := RunClient("up", "dynamodb", store); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunClient("redo", "dynamodb", store); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunClient("down-to", "dynamodb", store, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(store) != 1 || len(tables) != 1 {
+		t.Errorf("expected 1 applied migration, got %v", store)
+	}
+
+	logger.Reset()
+	if err := RunClient("status", "dynamodb", store); err != nil {
+		t.Fatal(err)
+	}
+	if out := logger.String(); !strings.Contains(out, "Pending                  -- 00003_events.go") {
+		t.Errorf("expected 00003 to be pending, got %q", out)
+	}
+
+	if err := RunClient("reset", "dynamodb", store); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunClient("down", "dynamodb", store); err != ErrNoCurrentVersion {
+		t.Errorf("expected ErrNoCurrentVersion, got %v", err)
+	}
+	if err := RunClient("create", "dynamodb", store); err == nil {
+		t.Error("expected an error for an unsupported command")
+	}
+
+	defer SetLocker(nil)
+	defer SetLockTimeout(0)
+	SetLocker(heldLocker{})
+	SetLockTimeout(10 * time.Millisecond)
+	if err := RunClient("up", "dynamodb", store); ExitCode(err) != ExitLockTimeout {
+		t.Errorf("expected the client migrations to wait for the lock, got %v", err)
+	}
+	if len(store) != 0 {
+		t.Errorf("expected no migration to run without the lock, got %v", store)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// completionCommand is a command of the usage, with its description.
+type completionCommand struct {
+	name, description string
+}
+
+// usageCommandList returns the commands listed in the usage, in order.
+func usageCommandList() []completionCommand {
+	var (
+		commands []completionCommand
+		seen     = map[string]bool{}
+		pending  []int // commands whose description is on a later line
+	)
+	lines := strings.Split(usageCommands, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "Commands:" {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			if len(commands) > 0 {
+				break
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "    ") {
+			continue
+		}
+		if strings.HasPrefix(line, "     ") {
+			for _, j := range pending {
+				commands[j].description = strings.TrimSpace(line)
+			}
+			pending = nil
+			continue
+		}
+		name := strings.Fields(line)[0]
+		description := ""
+		// The description of long or repeated commands is on a later line.
+		continued := i+1 < len(lines) && (strings.HasPrefix(lines[i+1], "     ") || strings.HasPrefix(lines[i+1], "    "+name+" "))
+		if len(line) > 25 && !continued {
+			description = strings.TrimSpace(line[25:])
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		commands = append(commands, completionCommand{name, description})
+		if description == "" {
+			pending = append(pending, len(commands)-1)
+		}
+	}
+	return commands
+}
+
+// usageDrivers returns the drivers listed in the usage.
+func usageDrivers() []string {
+	var drivers []string
+	section := false
+	for _, line := range strings.Split(usagePrefix, "\n") {
+		switch {
+		case strings.TrimSpace(line) == "Drivers:":
+			section = true
+		case section && strings.TrimSpace(line) == "":
+			return drivers
+		case section:
+			drivers = append(drivers, strings.TrimSpace(line))
+		}
+	}
+	return drivers
+}
+
+// writeCompletion writes the completion script of shell, completing the
+// flags, drivers and commands of goose.
+func writeCompletion(w io.Writer, shell string) error {
+	var flagNames []string
+	flags.VisitAll(func(f *flag.Flag) { flagNames = append(flagNames, "-"+f.Name) })
+	var names []string
+	for _, c := range usageCommandList() {
+		names = append(names, c.name)
+	}
+	words := strings.Join(append(usageDrivers(), names...), " ")
+
+	switch shell {
+	case "bash", "zsh":
+		if shell == "zsh" {
+			fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+		}
+		fmt.Fprintf(w, `_goose() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+    else
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+    fi
+}
+complete -o default -F _goose goose
+`, strings.Join(flagNames, " "), words)
+	case "fish":
+		flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(w, "complete -c goose -o %s -d %s\n", f.Name, fishQuote(f.Usage))
+		})
+		for _, d := range usageDrivers() {
+			fmt.Fprintf(w, "complete -c goose -f -a %s -d driver\n", d)
+		}
+		for _, c := range usageCommandList() {
+			fmt.Fprintf(w, "complete -c goose -f -a %s -d %s\n", c.name, fishQuote(c.description))
+		}
+	default:
+		return fmt.Errorf("completion must be of form: goose completion bash|zsh|fish (got %q)", shell)
+	}
+	return nil
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/loderunner/goose"
+)
+
+// confirmDataLoss asks on the terminal whether to run the down of m, which
+// would delete the data listed in losses, for -data-loss-guard.
+func confirmDataLoss(m *goose.Migration, losses []goose.DataLoss) bool {
+	fmt.Fprintf(os.Stderr, "%s deletes data:\n", filepath.Base(m.Source))
+	for _, loss := range losses {
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", loss, strings.TrimSpace(loss.Statement))
+	}
+	fmt.Fprintf(os.Stderr, "Roll back %s? [y/N] ", filepath.Base(m.Source))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -19,8 +19,8 @@ import (
 // to assume that DATETIME/DATE/TIMESTAMP can be scanned into the time.Time
 // type.
 func normalizeDBString(driver string, str string, certfile string) string {
-	if driver == "mysql" {
-		var isTLS = certfile != ""
+	if driver == "mysql" || driver == "mariadb" {
+		var isTLS = certfile != "" || *tlsCert != ""
 		if isTLS {
 			if err := registerTLSConfig(certfile); err != nil {
 				log.Fatalf("goose run: %v", err)
@@ -54,16 +54,49 @@ func normalizeMySQLDSN(dsn string, tls bool) (string, error) {
 	return config.FormatDSN(), nil
 }
 
+// registerTLSConfig registers the TLS configuration of the CA certificates
+// of pemfile, if any, and the client certificate of -tls-cert and -tls-key.
 func registerTLSConfig(pemfile string) error {
-	rootCertPool := x509.NewCertPool()
-	pem, err := ioutil.ReadFile(pemfile)
+	config := &tls.Config{ServerName: tlsServerName}
+	if pemfile != "" {
+		rootCertPool := x509.NewCertPool()
+		pem, err := ioutil.ReadFile(pemfile)
+		if err != nil {
+			return err
+		}
+		if ok := rootCertPool.AppendCertsFromPEM(pem); !ok {
+			return fmt.Errorf("failed to append PEM: %q", pemfile)
+		}
+		config.RootCAs = rootCertPool
+	}
+	if *tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return mysql.RegisterTLSConfig(tlsConfigKey, config)
+}
+
+// mysqlAddr returns the address of the server of a MySQL dsn.
+func mysqlAddr(dsn string) (string, error) {
+	config, err := mysql.ParseDSN(dsn)
 	if err != nil {
-		return err
+		return "", err
 	}
-	if ok := rootCertPool.AppendCertsFromPEM(pem); !ok {
-		return fmt.Errorf("failed to append PEM: %q", pemfile)
+	if config.Net != "tcp" {
+		return "", fmt.Errorf("only TCP connections can be tunneled, not %s", config.Net)
 	}
-	return mysql.RegisterTLSConfig(tlsConfigKey, &tls.Config{
-		RootCAs: rootCertPool,
-	})
+	return config.Addr, nil
+}
+
+// withMySQLAddr returns the MySQL dsn, connecting to addr instead.
+func withMySQLAddr(dsn, addr string) (string, error) {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	config.Addr = addr
+	return config.FormatDSN(), nil
 }
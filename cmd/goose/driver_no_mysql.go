@@ -2,6 +2,16 @@
 
 package main
 
+import "errors"
+
 func normalizeDBString(driver string, str string, certfile string) string {
 	return str
 }
+
+func mysqlAddr(dsn string) (string, error) {
+	return "", errors.New("goose was built without MySQL support")
+}
+
+func withMySQLAddr(dsn, addr string) (string, error) {
+	return "", errors.New("goose was built without MySQL support")
+}
@@ -0,0 +1,14 @@
+package main
+
+// goBuildImports are the driver packages imported by the runner of the Go
+// migrations built with -go-build, per driver.
+var goBuildImports = map[string][]string{
+	"postgres":   {"github.com/lib/pq"},
+	"redshift":   {"github.com/lib/pq"},
+	"mysql":      {"github.com/go-sql-driver/mysql"},
+	"mariadb":    {"github.com/go-sql-driver/mysql"},
+	"tidb":       {"github.com/go-sql-driver/mysql"},
+	"sqlite3":    {"github.com/mattn/go-sqlite3"},
+	"mssql":      {"github.com/denisenkom/go-mssqldb"},
+	"clickhouse": {"github.com/ClickHouse/clickhouse-go"},
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// manifestOptions are the options of a generated Kubernetes manifest.
+type manifestOptions struct {
+	Kind      string // job or init-container
+	Name      string
+	Namespace string
+	Image     string
+	Driver    string
+	Secret    string // name of the secret holding the DBSTRING
+	SecretKey string
+	Args      []string
+}
+
+// writeManifest writes a Kubernetes manifest running goose up, with the
+// DBSTRING read from a secret.
+func writeManifest(w io.Writer, o manifestOptions) error {
+	var lines []string
+	switch o.Kind {
+	case "job":
+		lines = []string{
+			"apiVersion: batch/v1",
+			"kind: Job",
+			"metadata:",
+			"  name: " + strconv.Quote(o.Name),
+		}
+		if o.Namespace != "" {
+			lines = append(lines, "  namespace: "+strconv.Quote(o.Namespace))
+		}
+		lines = append(lines,
+			"  annotations:",
+			"    # Run before syncing the application with Argo CD.",
+			"    argocd.argoproj.io/hook: PreSync",
+			"    argocd.argoproj.io/hook-delete-policy: BeforeHookCreation",
+			"spec:",
+			"  # Don't retry failed migrations: the exit code tells what went wrong.",
+			"  backoffLimit: 0",
+			"  template:",
+			"    spec:",
+			"      restartPolicy: Never",
+			"      containers:",
+		)
+		lines = append(lines, indent("      ", containerManifest(o))...)
+	case "init-container":
+		lines = []string{
+			"# Add to the pod spec of the application, so that it starts once its",
+			"# database is migrated.",
+			"initContainers:",
+		}
+		lines = append(lines, containerManifest(o)...)
+	default:
+		return fmt.Errorf("%q: unknown manifest kind, must be job or init-container", o.Kind)
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// containerManifest returns the lines of the goose container of a manifest.
+func containerManifest(o manifestOptions) []string {
+	args := make([]string, len(o.Args))
+	for i, arg := range o.Args {
+		args[i] = strconv.Quote(arg)
+	}
+	return []string{
+		"- name: goose",
+		"  image: " + strconv.Quote(o.Image),
+		`  command: ["goose"]`,
+		"  args: [" + strings.Join(args, ", ") + "]",
+		"  env:",
+		"  - name: " + envGooseDriver,
+		"    value: " + strconv.Quote(o.Driver),
+		"  - name: " + envGooseDBString,
+		"    valueFrom:",
+		"      secretKeyRef:",
+		"        name: " + strconv.Quote(o.Secret),
+		"        key: " + strconv.Quote(o.SecretKey),
+	}
+}
+
+func indent(prefix string, lines []string) []string {
+	indented := make([]string, len(lines))
+	for i, line := range lines {
+		indented[i] = prefix + line
+	}
+	return indented
+}
+
+// manifestArgs returns the goose arguments of the container: the same
+// directory and table, always locked since several pods may run at once.
+func manifestArgs(dir, table string, waitDB time.Duration) []string {
+	if waitDB == 0 {
+		waitDB = 2 * time.Minute
+	}
+	return []string{"-dir", dir, "-table", table, "-lock", "-wait-db", waitDB.String(), "up"}
+}
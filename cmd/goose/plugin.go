@@ -0,0 +1,16 @@
+package main
+
+import (
+	"plugin"
+)
+
+// loadPlugins opens the Go plugins of paths, whose init functions register
+// their Go migrations, like the migrations package of a custom binary.
+func loadPlugins(paths []string) error {
+	for _, path := range paths {
+		if _, err := plugin.Open(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
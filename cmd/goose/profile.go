@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/loderunner/goose"
+)
+
+// startProfile starts the CPU profile of the run into dir, and returns the
+// function stopping it and writing the heap profile, before exiting.
+func startProfile(dir string) func() {
+	if dir == "" {
+		return func() {}
+	}
+	goose.SetProfiling(true)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatalf("goose: failed to create profile directory: %v\n", err)
+	}
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		log.Fatalf("goose: failed to create CPU profile: %v\n", err)
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		log.Fatalf("goose: failed to start CPU profile: %v\n", err)
+	}
+
+	return func() {
+		pprof.StopCPUProfile()
+		cpu.Close()
+		heap, err := os.Create(filepath.Join(dir, "heap.pprof"))
+		if err != nil {
+			log.Printf("goose: failed to create heap profile: %v\n", err)
+			return
+		}
+		defer heap.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			log.Printf("goose: failed to write heap profile: %v\n", err)
+			return
+		}
+		log.Printf("goose: wrote CPU and heap profiles to %s\n", dir)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// postgresTLS adds the -certfile, -tls-cert and -tls-key files to the
+// parameters of a Postgres dbstring, in its URL or key=value form. The
+// server is verified against the CA certificates, and its host name too
+// unless connected through a tunnel.
+func postgresTLS(driver, dbstring string) string {
+	if (driver != "postgres" && driver != "redshift") || (*certfile == "" && *tlsCert == "") {
+		return dbstring
+	}
+
+	params := map[string]string{}
+	if *certfile != "" {
+		params["sslrootcert"] = *certfile
+		params["sslmode"] = "verify-full"
+		if *sshBastion != "" {
+			params["sslmode"] = "verify-ca"
+		}
+	} else {
+		params["sslmode"] = "require"
+	}
+	if *tlsCert != "" {
+		params["sslcert"] = *tlsCert
+		params["sslkey"] = *tlsKey
+	}
+
+	if u, err := url.Parse(dbstring); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		for k, v := range params {
+			// The parameters of the dbstring win over the flags.
+			if q.Get(k) == "" && v != "" {
+				q.Set(k, v)
+			}
+		}
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	for _, k := range []string{"sslmode", "sslrootcert", "sslcert", "sslkey"} {
+		if v := params[k]; v != "" && !strings.Contains(" "+dbstring, " "+k+"=") {
+			dbstring += " " + k + "=" + quotePostgresValue(v)
+		}
+	}
+	return dbstring
+}
+
+// quotePostgresValue quotes a value of a key=value dbstring if needed.
+func quotePostgresValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
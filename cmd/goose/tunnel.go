@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// atExit are run by runAtExit, to stop the SSH tunnel before exiting.
+var atExit []func()
+
+func runAtExit() {
+	for i := len(atExit) - 1; i >= 0; i-- {
+		atExit[i]()
+	}
+	atExit = nil
+}
+
+// exit runs the atExit functions, then exits with code.
+func exit(code int) {
+	runAtExit()
+	os.Exit(code)
+}
+
+// tlsServerName is the host name of the database server verified by TLS,
+// when connecting to it through the tunnel.
+var tlsServerName string
+
+// defaultPorts are the ports of the database servers, when the dbstring
+// has none.
+var defaultPorts = map[string]string{
+	"postgres":   "5432",
+	"redshift":   "5439",
+	"mysql":      "3306",
+	"mariadb":    "3306",
+	"tidb":       "4000",
+	"mssql":      "1433",
+	"clickhouse": "9000",
+}
+
+// tunnelDBString opens an SSH tunnel to the database server of dbstring
+// through the -ssh bastion, with the ssh command, and returns dbstring
+// connecting through the tunnel. The tunnel is closed by runAtExit.
+func tunnelDBString(driver, dbstring string) (string, error) {
+	addr, err := dbAddr(driver, dbstring)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the server of the dbstring: %v", err)
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		tlsServerName = host
+	}
+
+	// Find a free local port for the tunnel.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	local := l.Addr().String()
+	l.Close()
+
+	args := []string{"-N", "-o", "ExitOnForwardFailure=yes", "-o", "BatchMode=yes", "-L", local + ":" + addr}
+	if *sshKey != "" {
+		args = append(args, "-i", *sshKey)
+	}
+	bastion := *sshBastion
+	if host, port, err := net.SplitHostPort(bastion); err == nil {
+		bastion = host
+		args = append(args, "-p", port)
+	}
+	args = append(args, bastion)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("ssh", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start ssh: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	atExit = append(atExit, func() {
+		cmd.Process.Kill()
+		<-exited
+	})
+
+	// Wait for the tunnel to accept connections.
+	deadline := time.After(30 * time.Second)
+	for {
+		if conn, err := net.DialTimeout("tcp", local, time.Second); err == nil {
+			conn.Close()
+			break
+		}
+		select {
+		case err := <-exited:
+			exited <- err
+			return "", fmt.Errorf("ssh tunnel through %s failed: %v: %s", *sshBastion, err, strings.TrimSpace(stderr.String()))
+		case <-deadline:
+			return "", fmt.Errorf("ssh tunnel through %s not ready after 30s: %s", *sshBastion, strings.TrimSpace(stderr.String()))
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return withDBAddr(driver, dbstring, local)
+}
+
+var kvHost = regexp.MustCompile(`(^|\s)(host|port)=('[^']*'|\S*)`)
+
+// dbAddr returns the host:port of the database server of dbstring.
+func dbAddr(driver, dbstring string) (string, error) {
+	switch driver {
+	case "mysql", "mariadb", "tidb":
+		return mysqlAddr(dbstring)
+	}
+	if u, err := url.Parse(dbstring); err == nil && u.Host != "" {
+		if u.Port() == "" {
+			return net.JoinHostPort(u.Hostname(), defaultPorts[driver]), nil
+		}
+		return u.Host, nil
+	}
+	if driver == "postgres" || driver == "redshift" {
+		host, port := "localhost", defaultPorts[driver]
+		for _, m := range kvHost.FindAllStringSubmatch(dbstring, -1) {
+			v := strings.Trim(m[3], "'")
+			if m[2] == "host" {
+				host = v
+			} else {
+				port = v
+			}
+		}
+		return net.JoinHostPort(host, port), nil
+	}
+	return "", fmt.Errorf("tunnels are not supported with %s", driver)
+}
+
+// withDBAddr returns dbstring, connecting to the server at addr instead.
+func withDBAddr(driver, dbstring, addr string) (string, error) {
+	switch driver {
+	case "mysql", "mariadb", "tidb":
+		return withMySQLAddr(dbstring, addr)
+	}
+	if u, err := url.Parse(dbstring); err == nil && u.Host != "" {
+		u.Host = addr
+		return u.String(), nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	dbstring = strings.TrimSpace(kvHost.ReplaceAllString(dbstring, "$1"))
+	return dbstring + " host=" + host + " port=" + port, nil
+}
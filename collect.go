@@ -0,0 +1,75 @@
+package goose
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// WithExclude ignores the migration files of CollectMigrations matching
+// the patterns, matched against their base names like filepath.Match, e.g.
+// "*_seed.sql".
+func WithExclude(patterns ...string) OptionsFunc {
+	return func(o *options) { o.exclude = patterns }
+}
+
+// WithVersionRange restricts the migrations of CollectMigrations to the
+// versions from from to to, inclusive; 0 leaves a bound open. It is applied
+// on top of the current and target versions.
+func WithVersionRange(from, to int64) OptionsFunc {
+	return func(o *options) { o.rangeFrom, o.rangeTo = from, to }
+}
+
+// WithReversibleOnly restricts the migrations of CollectMigrations to the
+// ones that can be rolled back, see HasDown.
+func WithReversibleOnly() OptionsFunc {
+	return func(o *options) { o.reversibleOnly = true }
+}
+
+// HasDown reports whether the migration can be rolled back: whether a SQL
+// migration has statements in its Down section, or a Go migration a down
+// function, and isn't irreversible. External command, WASM and unregistered
+// Go migrations are assumed to.
+func (m *Migration) HasDown() (bool, error) {
+	if m.Irreversible() {
+		return false, nil
+	}
+	switch filepath.Ext(m.Source) {
+	case ".sql":
+		f, err := os.Open(m.Source)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to open SQL migration file %v", filepath.Base(m.Source))
+		}
+		defer f.Close()
+		n := 0
+		if _, err := parseSQLStatements(f, false, func(string) error { n++; return nil }); err != nil {
+			return false, errors.Wrapf(err, "failed to parse SQL migration file %v", filepath.Base(m.Source))
+		}
+		return n > 0, nil
+	case ".go":
+		return !m.Registered || m.DownFn != nil, nil
+	}
+	return true, nil
+}
+
+// collectFilter reports whether the collected migration m is kept by the
+// filters of o, of WithExclude, WithVersionRange and WithReversibleOnly.
+func (o options) collectFilter(m *Migration) (bool, error) {
+	for _, pattern := range o.exclude {
+		matched, err := filepath.Match(pattern, filepath.Base(m.Source))
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid exclude pattern %q", pattern)
+		}
+		if matched {
+			return false, nil
+		}
+	}
+	if (o.rangeFrom > 0 && m.Version < o.rangeFrom) || (o.rangeTo > 0 && m.Version > o.rangeTo) {
+		return false, nil
+	}
+	if o.reversibleOnly {
+		return m.HasDown()
+	}
+	return true, nil
+}
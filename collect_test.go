@@ -0,0 +1,69 @@
+package goose
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCollectFilters(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\n-- +goose Down\nDROP TABLE a;\n",
+		"00002_seed_a.sql":   "-- +goose Up\nINSERT INTO a VALUES (1);\n-- +goose Down\n",
+		"00003_create_b.sql": "-- +goose Up\nCREATE TABLE b (id int);\n",
+		"00004_create_c.sql": "-- +goose Up\nCREATE TABLE c (id int);\n-- +goose Down\nDROP TABLE c;\n",
+	})
+	defer cleanupDir()
+
+	versions := func(opts ...OptionsFunc) []int64 {
+		migrations, err := CollectMigrations(dir, minVersion, maxVersion, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var vs []int64
+		for _, m := range migrations {
+			vs = append(vs, m.Version)
+		}
+		return vs
+	}
+	tests := []struct {
+		opts []OptionsFunc
+		want []int64
+	}{
+		{nil, []int64{1, 2, 3, 4}},
+		{[]OptionsFunc{WithReversibleOnly()}, []int64{1, 4}},
+		{[]OptionsFunc{WithVersionRange(2, 3)}, []int64{2, 3}},
+		{[]OptionsFunc{WithVersionRange(3, 0)}, []int64{3, 4}},
+		{[]OptionsFunc{WithExclude("*_seed_*.sql", "00004_*")}, []int64{1, 3}},
+		{[]OptionsFunc{WithExclude("00001_*"), WithReversibleOnly()}, []int64{4}},
+	}
+	for i, test := range tests {
+		if got := versions(test.opts...); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%d: expected %v, got %v", i, test.want, got)
+		}
+	}
+
+	migrations, err := CollectMigrations(dir, minVersion, maxVersion, WithReversibleOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrations[0].Next != 4 || migrations[1].Previous != 1 {
+		t.Errorf("expected the filtered migrations to be connected, got %+v", migrations)
+	}
+	if _, err := CollectMigrations(dir, minVersion, maxVersion, WithExclude("[")); err == nil {
+		t.Error("expected an invalid pattern to fail")
+	}
+
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	if err := Up(db, dir, WithExclude("*")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 4 {
+		t.Errorf("expected the filters not to apply to Up, got version %d (%v)", v, err)
+	}
+
+	m := &Migration{Version: 5, Source: "00005_go.go", Registered: true, UpFn: func(QueryExecer) error { return nil }}
+	if ok, err := m.HasDown(); err != nil || ok {
+		t.Errorf("expected a Go migration without down function not to have a down, got %v (%v)", ok, err)
+	}
+}
@@ -0,0 +1,34 @@
+package goose
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+var (
+	queryComment bool
+	queryTags    []string
+)
+
+// SetQueryComment sets whether a comment attributing each executed SQL
+// statement to its migration is prepended to it, followed by the tags,
+// like /* goose:00042 deploy:abc123 */, so that the load seen in
+// pg_stat_activity or the slow query logs can be traced back to the
+// migrations.
+func SetQueryComment(enabled bool, tags ...string) {
+	queryComment = enabled
+	queryTags = tags
+}
+
+// commentQuery prepends the comment of the migration of s to its query,
+// innermost in the middleware.
+func commentQuery(next ExecFunc) ExecFunc {
+	return func(ctx context.Context, s *Statement) error {
+		fields := append([]string{fmt.Sprintf("goose:%05d", s.Migration.Version)}, queryTags...)
+		comment := strings.Replace(strings.Join(fields, " "), "*/", "* /", -1)
+		commented := *s
+		commented.Query = fmt.Sprintf("/* %s */ %s", comment, s.Query)
+		return next(ctx, &commented)
+	}
+}
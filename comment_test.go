@@ -0,0 +1,41 @@
+package goose
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryComment(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	var seen, executed []string
+	SetMiddleware(func(next ExecFunc) ExecFunc {
+		return func(ctx context.Context, s *Statement) error {
+			seen = append(seen, s.Query)
+			return next(ctx, s)
+		}
+	})
+	defer SetMiddleware()
+	SetQueryComment(true, "deploy:abc123", "evil:*/")
+	defer SetQueryComment(false)
+	exec := commentQuery(func(ctx context.Context, s *Statement) error {
+		executed = append(executed, s.Query)
+		return nil
+	})
+	if err := exec(context.Background(), &Statement{Migration: &Migration{Version: 42}, Query: "SELECT 1;"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/* goose:00042 deploy:abc123 evil:* / */ SELECT 1;"; len(executed) != 1 || executed[0] != want {
+		t.Errorf("expected %q, got %q", want, executed)
+	}
+
+	if err := UpByOne(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 || seen[0] != "CREATE TABLE a (id int);\n" {
+		t.Errorf("expected the middleware to see the query without comment, got %q", seen)
+	}
+}
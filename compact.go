@@ -0,0 +1,110 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// CompactHistory prunes the redundant records of the version table, except
+// for its keepLast most recent records: the records superseded by a later
+// record of the same migration, and the migrations rolled back, whose
+// absence means the same. The current version and the state and checksum
+// of every migration are unchanged. It returns the number of records
+// pruned.
+func CompactHistory(db *sql.DB, keepLast int) (pruned int64, err error) {
+	err = withOptions(nil, func() error {
+		pruned, err = compactHistoryLocked(db, keepLast)
+		return err
+	})
+	return pruned, err
+}
+
+// compactHistoryLocked is CompactHistory for the callers holding optionsMu.
+func compactHistoryLocked(db *sql.DB, keepLast int) (pruned int64, err error) {
+	err = invoke("compact", db, func() error {
+		pruned, err = compactHistory(db, keepLast)
+		return err
+	})
+	return pruned, err
+}
+
+func compactHistory(db *sql.DB, keepLast int) (int64, error) {
+	switch GetDialect().(type) {
+	case *ClickHouseDialect, *BigQueryDialect, *TrinoDialect, *SpannerDialect:
+		return 0, errors.New("compacting history is not supported by this dialect")
+	case *RedshiftDialect:
+		// The records are pruned in the order of their id, which Redshift
+		// IDENTITY values don't always follow.
+		return 0, errors.New("compacting history is not supported by this dialect")
+	}
+	if keepLast < 0 {
+		return 0, errors.Errorf("the number of records to keep must not be negative (got %d)", keepLast)
+	}
+	if _, err := EnsureDBVersion(db); err != nil {
+		return 0, errors.Wrap(err, "failed to ensure DB version")
+	}
+
+	through, err := prunableRecordsID(db, keepLast)
+	if err != nil || through == 0 {
+		return 0, err
+	}
+
+	d := GetDialect()
+	latest := fmt.Sprintf("SELECT id FROM (SELECT MAX(id) AS id FROM %s GROUP BY version_id) latest", TableName())
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		// Superseded records.
+		{fmt.Sprintf("DELETE FROM %s WHERE id <= %s AND id NOT IN (%s)", TableName(), d.placeholder(1), latest), []interface{}{through}},
+		// Rolled back migrations, but the initial version.
+		{fmt.Sprintf("DELETE FROM %s WHERE id <= %s AND version_id <> 0 AND is_applied = %s AND id IN (%s)", TableName(), d.placeholder(1), d.placeholder(2), latest), []interface{}{through, false}},
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to begin transaction")
+	}
+	var pruned int64
+	for _, stmt := range statements {
+		res, err := tx.Exec(stmt.query, stmt.args...)
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.Wrap(err, "failed to prune version table")
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.Wrap(err, "failed to count pruned records")
+		}
+		pruned += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "failed to commit transaction")
+	}
+	return pruned, nil
+}
+
+// prunableRecordsID returns the id of the most recent record of the
+// version table that may be pruned, the one following the keepLast most
+// recent records. It returns 0 if there are no more records than keepLast.
+func prunableRecordsID(db *sql.DB, keepLast int) (int64, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT id FROM %s ORDER BY id DESC", TableName()))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to query version table")
+	}
+	defer rows.Close()
+
+	var id int64
+	for i := 0; i <= keepLast; i++ {
+		if !rows.Next() {
+			return 0, rows.Err()
+		}
+		if err := rows.Scan(&id); err != nil {
+			return 0, errors.Wrap(err, "failed to scan row")
+		}
+	}
+	return id, nil
+}
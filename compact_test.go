@@ -0,0 +1,70 @@
+package goose
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompactHistory(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	if _, err := EnsureDBVersion(db); err != nil {
+		t.Fatal(err)
+	}
+	history := []MigrationRecord{
+		{VersionID: 1, IsApplied: true},
+		{VersionID: 2, IsApplied: true},
+		{VersionID: 3, IsApplied: true},
+		{VersionID: 3, IsApplied: false},
+		{VersionID: 2, IsApplied: false},
+		{VersionID: 2, IsApplied: true},
+		{VersionID: 4, IsApplied: true},
+		{VersionID: 4, IsApplied: false},
+	}
+	for _, rec := range history {
+		if err := insertVersion(db, rec.VersionID, rec.IsApplied, "", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	countRecords := func() int {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM goose_db_version").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	statuses, err := dbMigrationsStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pruned, err := CompactHistory(db, 100); err != nil || pruned != 0 {
+		t.Errorf("expected nothing to prune, got %d (%v)", pruned, err)
+	}
+	if pruned, err := CompactHistory(db, 1); err != nil || pruned != 5 {
+		t.Errorf("expected 5 records pruned, got %d (%v)", pruned, err)
+	}
+	// The initial version, 1, 2 and the most recent record of 4.
+	if n := countRecords(); n != 4 {
+		t.Errorf("expected 4 records left, got %d", n)
+	}
+	if pruned, err := CompactHistory(db, 0); err != nil || pruned != 1 {
+		t.Errorf("expected 1 record pruned, got %d (%v)", pruned, err)
+	}
+
+	if current, err := GetDBVersion(db); err != nil || current != 2 {
+		t.Errorf("expected the current version to be unchanged, got %d (%v)", current, err)
+	}
+	compacted, err := dbMigrationsStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for v, applied := range statuses {
+		if !applied {
+			delete(statuses, v)
+		}
+	}
+	if !reflect.DeepEqual(compacted, statuses) {
+		t.Errorf("expected the applied migrations to be unchanged, got %v, want %v", compacted, statuses)
+	}
+}
@@ -0,0 +1,117 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// connExecer is a QueryExecer running every statement on a single
+// connection, so that session settings like SET ROLE or SET lock_timeout,
+// and temporary tables, apply to all the statements of a migration run
+// without a transaction.
+type connExecer struct {
+	*sql.Conn
+}
+
+func (c connExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.ExecContext(context.Background(), query, args...)
+}
+
+func (c connExecer) Prepare(query string) (*sql.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c connExecer) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.QueryContext(context.Background(), query, args...)
+}
+
+func (c connExecer) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.QueryRowContext(context.Background(), query, args...)
+}
+
+// withConn runs fn on a connection dedicated to it. The connection is
+// discarded afterwards instead of going back to the pool, so that its
+// session settings don't leak to other statements.
+func withConn(db *sql.DB, fn func(conn connExecer) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get connection")
+	}
+	defer discardConn(conn)
+
+	return fn(connExecer{conn})
+}
+
+// discardConn closes conn, discarding it instead of returning it to the
+// pool, with (*sql.Conn).Raw. Raw is only available from Go 1.13, so it is
+// called by reflection: before, the session of conn is reset instead, as
+// far as the dialect can, switching back from the role and resetting the
+// settings of the session setup on Postgres and Redshift.
+func discardConn(conn *sql.Conn) {
+	defer conn.Close()
+	if raw := reflect.ValueOf(conn).MethodByName("Raw"); raw.IsValid() {
+		raw.Call([]reflect.Value{reflect.ValueOf(func(interface{}) error {
+			return driver.ErrBadConn
+		})})
+		return
+	}
+
+	c := connExecer{conn}
+	resetSession(c)
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		c.Exec("DISCARD ALL")
+	case *RedshiftDialect:
+		c.Exec("RESET ALL")
+	case *HanaDialect:
+		c.Exec("SET TRANSACTION AUTOCOMMIT DDL ON")
+	}
+}
+
+// beginTx begins the transaction of a migration, and sets its session up.
+// When the session is set up, the transaction runs on a connection
+// dedicated to it, discarded by release once the transaction is done.
+//
+// HANA commits DDL statements by default: its transactions turn that off,
+// on their dedicated connection.
+func beginTx(db *sql.DB) (tx *sql.Tx, release func(), err error) {
+	release = func() {}
+	_, hana := GetDialect().(*HanaDialect)
+	if len(sessionSetup) == 0 && role == "" && !hana {
+		tx, err = db.Begin()
+		return tx, release, err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, release, err
+	}
+	release = func() {
+		discardConn(conn)
+	}
+
+	tx, err = conn.BeginTx(ctx, nil)
+	if err != nil {
+		release()
+		return nil, func() {}, err
+	}
+	if hana {
+		if _, err := tx.Exec("SET TRANSACTION AUTOCOMMIT DDL OFF"); err != nil {
+			tx.Rollback()
+			release()
+			return nil, func() {}, errors.Wrap(err, "failed to make DDL statements transactional")
+		}
+	}
+	if err := setupSession(tx); err != nil {
+		tx.Rollback()
+		release()
+		return nil, func() {}, err
+	}
+	return tx, release, nil
+}
@@ -0,0 +1,36 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestNoTxMigrationSingleConnection(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_temp.sql": `-- +goose NO TRANSACTION
+-- +goose Up
+CREATE TEMP TABLE staging (id int);
+INSERT INTO staging VALUES (1), (2);
+CREATE TABLE copied AS SELECT * FROM staging;
+`,
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM copied").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("unexpected row count, got %d, want 2", count)
+	}
+
+	// The connection of the migration and its session are discarded.
+	if _, err := db.Exec("SELECT * FROM staging"); err == nil {
+		t.Errorf("temporary table of the migration leaked to the pool")
+	}
+}
@@ -0,0 +1,168 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// copyEndOfData terminates the inline data of a COPY ... FROM stdin statement.
+const copyEndOfData = `\.`
+
+var matchCopyFromStdin = regexp.MustCompile(`(?i)^\s*COPY\s.+\sFROM\s+STDIN\b.*;\s*$`)
+
+// isCopyFromStdin reports whether the statement is a COPY ... FROM stdin
+// statement followed by its inline data.
+func isCopyFromStdin(statement string) bool {
+	_, _, ok := splitCopyFromStdin(statement)
+	return ok
+}
+
+// splitCopyFromStdin splits a COPY ... FROM stdin statement into the COPY
+// command and its data lines. Comments preceding the command are skipped.
+func splitCopyFromStdin(statement string) (command string, data []string, ok bool) {
+	lines := strings.Split(strings.TrimRight(statement, "\n"), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "--") || matchEmptyLines.MatchString(line) {
+			continue
+		}
+		if !matchCopyFromStdin.MatchString(line) {
+			return "", nil, false
+		}
+		command = strings.TrimSuffix(strings.TrimSpace(line), ";")
+		for _, l := range lines[i+1:] {
+			if l == copyEndOfData {
+				break
+			}
+			data = append(data, l)
+		}
+		return command, data, true
+	}
+	return "", nil, false
+}
+
+// execCopyFromStdin streams the inline data of a COPY ... FROM stdin
+// statement to the database. It relies on drivers that implement COPY
+// through prepared statements, like github.com/lib/pq.
+//
+// Only the default text format is supported: columns are separated by
+// tabs, \N is NULL and backslash escapes are decoded.
+func execCopyFromStdin(tx *sql.Tx, statement string) error {
+	command, data, ok := splitCopyFromStdin(statement)
+	if !ok {
+		return errors.New("not a COPY FROM stdin statement")
+	}
+
+	stmt, err := tx.Prepare(command)
+	if err != nil {
+		return errors.Wrap(err, "failed to prepare COPY statement")
+	}
+
+	for i, line := range data {
+		if _, err := stmt.Exec(decodeCopyRow(line)...); err != nil {
+			stmt.Close()
+			return errors.Wrapf(err, "failed to copy data line %d", i+1)
+		}
+	}
+
+	// Executing the statement without arguments flushes the data.
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return errors.Wrap(err, "failed to flush COPY data")
+	}
+
+	return stmt.Close()
+}
+
+// execCopyFromStdinNoTx runs a COPY ... FROM stdin statement outside of a
+// migration transaction, on the connection of the migration. COPY needs a
+// prepared statement, so it still runs in a transaction of its own.
+func execCopyFromStdinNoTx(conn connExecer, query string) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	if err := execCopyFromStdin(tx, query); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// decodeCopyRow decodes a line of COPY text format data into column values.
+func decodeCopyRow(line string) []interface{} {
+	fields := strings.Split(line, "\t")
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		if field == `\N` {
+			values[i] = nil
+			continue
+		}
+		values[i] = decodeCopyField(field)
+	}
+	return values
+}
+
+func decodeCopyField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' || i == len(field)-1 {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch c = field[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x':
+			// \xHH: one or two hex digits
+			j := i + 1
+			for j < len(field) && j < i+3 && isHexDigit(field[j]) {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte(c)
+				continue
+			}
+			n, _ := strconv.ParseUint(field[i+1:j], 16, 8)
+			b.WriteByte(byte(n))
+			i = j - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// \ooo: one to three octal digits
+			j := i + 1
+			for j < len(field) && j < i+3 && field[j] >= '0' && field[j] <= '7' {
+				j++
+			}
+			n, _ := strconv.ParseUint(field[i:j], 8, 8)
+			b.WriteByte(byte(n))
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
@@ -0,0 +1,52 @@
+package goose
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCopyRow(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		line string
+		want []interface{}
+	}{
+		{line: "1\talice", want: []interface{}{"1", "alice"}},
+		{line: `2	\N`, want: []interface{}{"2", nil}},
+		{line: `3	a\tb\nc\\d`, want: []interface{}{"3", "a\tb\nc\\d"}},
+		{line: `4	\101\x42`, want: []interface{}{"4", "AB"}},
+		{line: "5\t", want: []interface{}{"5", ""}},
+	}
+
+	for _, test := range tt {
+		if got := decodeCopyRow(test.line); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected values for line %q, got %#v, want %#v", test.line, got, test.want)
+		}
+	}
+}
+
+func TestSplitCopyFromStdin(t *testing.T) {
+	t.Parallel()
+
+	stmts, _, err := parseSQLMigration(strings.NewReader(copyFromStdinData), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	command, data, ok := splitCopyFromStdin(stmts[1])
+	if !ok {
+		t.Fatalf("expected COPY FROM stdin statement, got %q", stmts[1])
+	}
+	if want := "COPY users (id, name, bio) FROM stdin"; command != want {
+		t.Errorf("unexpected command, got %q, want %q", command, want)
+	}
+	if want := []string{"1\talice\t-- not a comment", "", "2\tbob\t\\N"}; !reflect.DeepEqual(data, want) {
+		t.Errorf("unexpected data, got %q, want %q", data, want)
+	}
+
+	if isCopyFromStdin(stmts[0]) || isCopyFromStdin(stmts[2]) {
+		t.Errorf("unexpected COPY FROM stdin statement in %q", stmts)
+	}
+}
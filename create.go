@@ -8,18 +8,20 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/loderunner/goose/version"
 	"github.com/pkg/errors"
 )
 
 type tmplVars struct {
 	Version   string
 	CamelName string
+	Filename  string
 }
 
 // CreateWithTemplate writes a new blank migration file.
 func CreateWithTemplate(db *sql.DB, dir string, tmpl *template.Template, name, migrationType string) error {
-	version := time.Now().Format(timestampFormat)
-	filename := fmt.Sprintf("%v_%v.%v", version, snakeCase(name), migrationType)
+	v := version.Format(version.FromTime(time.Now()), version.Timestamp)
+	filename := fmt.Sprintf("%v_%v.%v", v, snakeCase(name), migrationType)
 
 	if tmpl == nil {
 		if migrationType == "go" {
@@ -41,8 +43,9 @@ func CreateWithTemplate(db *sql.DB, dir string, tmpl *template.Template, name, m
 	defer f.Close()
 
 	vars := tmplVars{
-		Version:   version,
+		Version:   v,
 		CamelName: camelCase(name),
+		Filename:  filename,
 	}
 	if err := tmpl.Execute(f, vars); err != nil {
 		return errors.Wrap(err, "failed to execute tmpl")
@@ -75,7 +78,7 @@ import (
 )
 
 func init() {
-	goose.AddMigration(up{{.CamelName}}, down{{.CamelName}})
+	goose.AddNamedMigration("{{.Filename}}", up{{.CamelName}}, down{{.CamelName}})
 }
 
 func up{{.CamelName}}(qe goose.QueryExecer) error {
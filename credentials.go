@@ -0,0 +1,412 @@
+package goose
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Credentials provide the passwords of the connections to a database,
+// typically short-lived authentication tokens of a cloud provider, so that
+// dbstrings don't hold long-lived passwords.
+type Credentials interface {
+	// Password returns the password of user connecting to the server at
+	// addr, of form host:port, and when it expires.
+	Password(ctx context.Context, addr, user string) (password string, expires time.Time, err error)
+}
+
+// AuthCredentials returns the credentials of the authentication method
+// auth, like the -auth flag of the goose command: aws-rds-iam, gcp-iam, or
+// azure-ad with the managed identity of AZURE_CLIENT_ID.
+func AuthCredentials(auth string) (Credentials, error) {
+	switch auth {
+	case "aws-rds-iam":
+		if _, _, err := awsEnvCredentials(); err != nil {
+			return nil, err
+		}
+		c := NewRDSIAMCredentials("")
+		if c.region == "" {
+			return nil, errors.New("no AWS region, set AWS_REGION")
+		}
+		return c, nil
+	case "gcp-iam":
+		return NewCloudSQLIAMCredentials(), nil
+	case "azure-ad":
+		return NewAzureADCredentials(os.Getenv("AZURE_CLIENT_ID")), nil
+	default:
+		return nil, errors.Errorf("unknown authentication %q, expected aws-rds-iam, gcp-iam or azure-ad", auth)
+	}
+}
+
+// OpenDBWithCredentials creates a connection to a database like
+// OpenDBWithDriver, authenticating each new connection with the password
+// of creds. The password is cached until a minute before it expires, then
+// refreshed. Credentials are supported with the Postgres and MySQL drivers,
+// and the user of dbstring. The connections are dialed with the Dialer of
+// SetDialer or WithDialer, if any.
+func OpenDBWithCredentials(driver string, dbstring string, creds Credentials, opts ...OptionsFunc) (*sql.DB, error) {
+	dbstring, err := ResolveDBString(dbstring)
+	if err != nil {
+		return nil, err
+	}
+	addr, user, err := dsnAddrUser(driver, dbstring)
+	if err != nil {
+		return nil, err
+	}
+	o := currentOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.connector != nil {
+		return nil, errors.New("credentials are not supported with a connector")
+	}
+	if err := SetDialect(driver); err != nil {
+		return nil, err
+	}
+	db, err := openDB(driver, dbstring, o)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	db.Close()
+	return sql.OpenDB(&credentialsConnector{
+		drv:      drv,
+		driver:   driver,
+		dbstring: dbstring,
+		addr:     addr,
+		user:     user,
+		creds:    creds,
+		dial:     o.dialer,
+	}), nil
+}
+
+// credentialsConnector opens the connections of a database with the
+// passwords of Credentials.
+type credentialsConnector struct {
+	drv      driver.Driver
+	driver   string
+	dbstring string
+	addr     string
+	user     string
+	creds    Credentials
+	dial     Dialer
+
+	mu       sync.Mutex
+	password string
+	expires  time.Time
+}
+
+func (c *credentialsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	if c.password == "" || time.Until(c.expires) < time.Minute {
+		password, expires, err := c.creds.Password(ctx, c.addr, c.user)
+		if err != nil {
+			c.mu.Unlock()
+			return nil, errors.Wrap(err, "failed to get credentials")
+		}
+		c.password, c.expires = password, expires
+	}
+	password := c.password
+	c.mu.Unlock()
+
+	dsn := dsnWithPassword(c.driver, c.dbstring, password)
+	if c.dial != nil {
+		dc, err := dialConnector(sqlDriver(c.driver), c.dial, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return dc.Connect(ctx)
+	}
+	return c.drv.Open(dsn)
+}
+
+func (c *credentialsConnector) Driver() driver.Driver { return c.drv }
+
+// dsnAddrUser returns the address of the server and the user of dbstring.
+func dsnAddrUser(driver, dbstring string) (addr, user string, err error) {
+	switch driver {
+	case "postgres", "redshift":
+		if u, err := url.Parse(dbstring); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+			port := u.Port()
+			if port == "" {
+				port = "5432"
+			}
+			return u.Hostname() + ":" + port, u.User.Username(), nil
+		}
+		host, port := "localhost", "5432"
+		for _, kv := range strings.Fields(dbstring) {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			switch parts[0] {
+			case "host":
+				host = parts[1]
+			case "port":
+				port = parts[1]
+			case "user":
+				user = parts[1]
+			}
+		}
+		return host + ":" + port, user, nil
+	case "mysql", "mariadb", "tidb":
+		// [user[:password]@][net[(addr)]]/dbname[?params]
+		base := dbstring
+		if i := strings.Index(base, "?"); i >= 0 {
+			base = base[:i]
+		}
+		slash := strings.LastIndex(base, "/")
+		at := strings.LastIndex(base[:slash+1], "@")
+		if slash < 0 || at < 0 {
+			return "", "", errors.Errorf("no user in dbstring %q", dbstring)
+		}
+		user = strings.SplitN(base[:at], ":", 2)[0]
+		addr = "127.0.0.1:3306"
+		if i := strings.Index(base[at:slash], "("); i >= 0 {
+			addr = strings.TrimSuffix(base[at+i+1:slash], ")")
+		}
+		return addr, user, nil
+	default:
+		return "", "", errors.Errorf("credentials are not supported with %s", driver)
+	}
+}
+
+// dsnWithPassword returns dbstring, connecting with password.
+func dsnWithPassword(driver, dbstring, password string) string {
+	switch driver {
+	case "postgres", "redshift":
+		if u, err := url.Parse(dbstring); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+			u.User = url.UserPassword(u.User.Username(), password)
+			return u.String()
+		}
+		quoted := "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(password) + "'"
+		return dbstring + " password=" + quoted
+	default:
+		base := dbstring
+		if i := strings.Index(base, "?"); i >= 0 {
+			base = base[:i]
+		}
+		at := strings.LastIndex(base[:strings.LastIndex(base, "/")+1], "@")
+		user := strings.SplitN(dbstring[:at], ":", 2)[0]
+		return user + ":" + password + dbstring[at:]
+	}
+}
+
+// RDSIAMCredentials are the IAM authentication tokens of Amazon RDS and
+// Aurora, signed with the AWS credentials of the environment variables
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN only: goose
+// doesn't import the AWS SDK, so the shared config files, profiles, SSO and
+// the roles of EC2, ECS or EKS aren't read. The tokens are valid 15 minutes.
+// MySQL requires the allowCleartextPasswords=true parameter and TLS to send
+// them.
+type RDSIAMCredentials struct {
+	region string
+	now    func() time.Time
+}
+
+// NewRDSIAMCredentials creates the RDS IAM credentials of the databases of
+// region, or of the AWS_REGION environment variable if empty.
+func NewRDSIAMCredentials(region string) *RDSIAMCredentials {
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	return &RDSIAMCredentials{region: region, now: time.Now}
+}
+
+// Password returns an authentication token of user, presigned with the
+// Signature Version 4 of AWS.
+func (c *RDSIAMCredentials) Password(ctx context.Context, addr, user string) (string, time.Time, error) {
+	accessKey, secretKey, err := awsEnvCredentials()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if c.region == "" {
+		return "", time.Time{}, errors.New("no AWS region, set AWS_REGION")
+	}
+
+	const expires = 15 * time.Minute
+	now := c.now().UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + c.region + "/rds-db/aws4_request"
+	params := map[string]string{
+		"Action":              "connect",
+		"DBUser":              user,
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    accessKey + "/" + scope,
+		"X-Amz-Date":          now.Format("20060102T150405Z"),
+		"X-Amz-Expires":       strconv.Itoa(int(expires / time.Second)),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		params["X-Amz-Security-Token"] = token
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	query := make([]string, len(keys))
+	for i, k := range keys {
+		query[i] = awsEscape(k) + "=" + awsEscape(params[k])
+	}
+	canonicalQuery := strings.Join(query, "&")
+
+	emptyHash := sha256.Sum256(nil)
+	canonicalRequest := strings.Join([]string{"GET", "/", canonicalQuery, "host:" + addr + "\n", "host", hex.EncodeToString(emptyHash[:])}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", params["X-Amz-Date"], scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(awsSigningKey(secretKey, date, c.region, "rds-db"), stringToSign))
+	return addr + "/?" + canonicalQuery + "&X-Amz-Signature=" + signature, now.Add(expires), nil
+}
+
+// awsEnvCredentials returns the AWS access key and secret key of the
+// environment, the only AWS credentials goose reads.
+func awsEnvCredentials() (accessKey, secretKey string, err error) {
+	accessKey, secretKey = os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return "", "", errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set: the AWS credentials are read from the environment only, export those of a profile or role with `aws configure export-credentials --format env`")
+	}
+	return accessKey, secretKey, nil
+}
+
+// awsSigningKey derives the key of the Signature Version 4 of AWS signing
+// the requests of service in region, on date.
+func awsSigningKey(secretKey, date, region, service string) []byte {
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return key
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscape escapes s as the URI encoding of AWS signatures, escaping all
+// but the unreserved characters of RFC 3986.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// CloudSQLIAMCredentials are the OAuth2 access tokens of the service
+// account of the Google Cloud instance, pod or function, from its metadata
+// server, authenticating with the Cloud SQL IAM database authentication.
+type CloudSQLIAMCredentials struct {
+	url    string
+	client *http.Client
+}
+
+// NewCloudSQLIAMCredentials creates the Cloud SQL IAM credentials of the
+// default service account.
+func NewCloudSQLIAMCredentials() *CloudSQLIAMCredentials {
+	return &CloudSQLIAMCredentials{
+		url:    "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token",
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Password returns an access token of the service account.
+func (c *CloudSQLIAMCredentials) Password(ctx context.Context, addr, user string) (string, time.Time, error) {
+	req, err := http.NewRequest("GET", c.url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := fetchToken(ctx, c.client, req, &token); err != nil {
+		return "", time.Time{}, err
+	}
+	return token.AccessToken, time.Now().Add(time.Duration(token.ExpiresIn) * time.Second), nil
+}
+
+// AzureADCredentials are the Azure Active Directory access tokens of the
+// managed identity of the Azure VM, container or function, from its
+// instance metadata service, authenticating with Azure Database for
+// PostgreSQL and MySQL.
+type AzureADCredentials struct {
+	url    string
+	client *http.Client
+}
+
+// NewAzureADCredentials creates the Azure AD credentials of the managed
+// identity of clientID, or of the system-assigned identity if empty.
+func NewAzureADCredentials(clientID string) *AzureADCredentials {
+	q := url.Values{}
+	q.Set("api-version", "2018-02-01")
+	q.Set("resource", "https://ossrdbms-aad.database.windows.net")
+	if clientID != "" {
+		q.Set("client_id", clientID)
+	}
+	return &AzureADCredentials{
+		url:    "http://169.254.169.254/metadata/identity/oauth2/token?" + q.Encode(),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Password returns an access token of the managed identity.
+func (c *AzureADCredentials) Password(ctx context.Context, addr, user string) (string, time.Time, error) {
+	req, err := http.NewRequest("GET", c.url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Metadata", "true")
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := fetchToken(ctx, c.client, req, &token); err != nil {
+		return "", time.Time{}, err
+	}
+	expiresOn, err := strconv.ParseInt(token.ExpiresOn, 10, 64)
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "invalid expiry %q", token.ExpiresOn)
+	}
+	return token.AccessToken, time.Unix(expiresOn, 0), nil
+}
+
+// fetchToken decodes the JSON token of a metadata server.
+func fetchToken(ctx context.Context, client *http.Client, req *http.Request, token interface{}) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "failed to get token from metadata server")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to get token from metadata server: %s", resp.Status)
+	}
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(token), "failed to decode token")
+}
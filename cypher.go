@@ -0,0 +1,141 @@
+package goose
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CypherSession runs the Cypher statements of migrations, and returns the
+// records of their results as maps of the returned keys to their values.
+// It is implemented on a session of the Neo4j driver.
+type CypherSession interface {
+	Run(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error)
+}
+
+// neo4jStore is a ClientVersionStore recording the applied migrations as
+// GooseMigration nodes.
+type neo4jStore struct {
+	session CypherSession
+}
+
+// NewNeo4jStore returns a ClientVersionStore recording the applied
+// migrations as GooseMigration nodes, with their version and the time they
+// were applied at, in milliseconds.
+func NewNeo4jStore(session CypherSession) ClientVersionStore {
+	return neo4jStore{session: session}
+}
+
+func (s neo4jStore) ListApplied(ctx context.Context) ([]MigrationRecord, error) {
+	rows, err := s.session.Run(ctx, "MATCH (m:GooseMigration) RETURN m.version AS version, m.tstamp AS tstamp", nil)
+	if err != nil {
+		return nil, err
+	}
+	var records []MigrationRecord
+	for _, row := range rows {
+		version, ok := cypherInt(row["version"])
+		if !ok {
+			return nil, errors.Errorf("invalid GooseMigration version %v", row["version"])
+		}
+		ms, _ := cypherInt(row["tstamp"])
+		records = append(records, MigrationRecord{VersionID: version, TStamp: time.Unix(0, ms*int64(time.Millisecond)), IsApplied: true})
+	}
+	return records, nil
+}
+
+func (s neo4jStore) SetApplied(ctx context.Context, version int64, applied bool) error {
+	cypher := "MATCH (m:GooseMigration {version: $version}) DELETE m"
+	if applied {
+		cypher = "MERGE (m:GooseMigration {version: $version}) SET m.tstamp = timestamp()"
+	}
+	_, err := s.session.Run(ctx, cypher, map[string]interface{}{"version": version})
+	return err
+}
+
+// cypherInt returns the integer value of a Cypher result, returned as an
+// int64 by the Neo4j driver.
+func cypherInt(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// RunCypher runs a goose command on the .cypher migrations of dir, on a
+// Neo4j session, recording their versions with NewNeo4jStore. Cypher
+// migrations are annotated like SQL migrations, with '// +goose Up' and
+// '// +goose Down' comments, and their statements end with semicolons. Each
+// statement runs in its own transaction, since Neo4j can't change a schema
+// and write data in the same one. It supports the commands of RunClient.
+func RunCypher(command string, session CypherSession, dir string, args ...string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.cypher"))
+	if err != nil {
+		return err
+	}
+	var migrations []*clientMigration
+	for _, file := range files {
+		v, err := NumericComponent(file)
+		if err != nil {
+			return err
+		}
+		file := file
+		migrations = append(migrations, &clientMigration{
+			version: v,
+			source:  file,
+			up: func(client interface{}) error {
+				return runCypherMigration(session, file, true)
+			},
+			down: func(client interface{}) error {
+				return runCypherMigration(session, file, false)
+			},
+		})
+	}
+	r := newClientRunner(session, NewNeo4jStore(session), migrations)
+	return r.runCommand(command, args)
+}
+
+func runCypherMigration(session CypherSession, file string, direction bool) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %v", filepath.Base(file))
+	}
+	statements, _, err := parseSQLMigration(cypherToSQLComments(data), direction)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %v", filepath.Base(file))
+	}
+	for _, stmt := range statements {
+		stmt = strings.TrimRight(strings.TrimSpace(stmt), ";")
+		verboseInfo("Executing statement: %s", stmt)
+		if _, err := session.Run(runCtx, stmt, nil); err != nil {
+			return errors.Wrapf(err, "failed to execute Cypher statement %q", stmt)
+		}
+	}
+	return nil
+}
+
+// cypherToSQLComments turns the '//' comments of a Cypher migration into the
+// '--' comments of the SQL migration parser.
+func cypherToSQLComments(data []byte) *bytes.Buffer {
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), scanBufSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "//") {
+			line = "--" + strings.TrimPrefix(trimmed, "//")
+		}
+		buf.WriteString(line + "\n")
+	}
+	return &buf
+}
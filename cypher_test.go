@@ -0,0 +1,75 @@
+package goose
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeCypherSession records the statements of migrations, and keeps the
+// GooseMigration nodes in memory.
+type fakeCypherSession struct {
+	statements []string
+	versions   map[int64]bool
+}
+
+func (s *fakeCypherSession) Run(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	switch {
+	case strings.HasPrefix(cypher, "MATCH (m:GooseMigration) RETURN"):
+		var rows []map[string]interface{}
+		for v := range s.versions {
+			rows = append(rows, map[string]interface{}{"version": v, "tstamp": int64(0)})
+		}
+		return rows, nil
+	case strings.HasPrefix(cypher, "MERGE (m:GooseMigration"):
+		s.versions[params["version"].(int64)] = true
+	case strings.HasPrefix(cypher, "MATCH (m:GooseMigration {version"):
+		delete(s.versions, params["version"].(int64))
+	default:
+		s.statements = append(s.statements, cypher)
+	}
+	return nil, nil
+}
+
+func TestRunCypher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"00001_constraints.cypher": "// +goose Up\nCREATE CONSTRAINT user_id IF NOT EXISTS\nFOR (u:User) REQUIRE u.id IS UNIQUE;\n// +goose Down\nDROP CONSTRAINT user_id;\n",
+		"00002_roles.cypher":       "// +goose Up\n// Users are members by default.\nMATCH (u:User) SET u.role = 'member';\n// +goose Down\nMATCH (u:User) REMOVE u.role;\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer SetLogger(log)
+	SetLogger(&bufferLogger{})
+	session := &fakeCypherSession{versions: map[int64]bool{}}
+	if err := RunCypher("up", session, dir); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"CREATE CONSTRAINT user_id IF NOT EXISTS\nFOR (u:User) REQUIRE u.id IS UNIQUE", "MATCH (u:User) SET u.role = 'member'"}
+	if !reflect.DeepEqual(session.statements, want) {
+		t.Errorf("expected %q, got %q", want, session.statements)
+	}
+	if !reflect.DeepEqual(session.versions, map[int64]bool{1: true, 2: true}) {
+		t.Errorf("expected versions 1 and 2 to be applied, got %v", session.versions)
+	}
+
+	session.statements = nil
+	if err := RunCypher("down", session, dir); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"MATCH (u:User) REMOVE u.role"}; !reflect.DeepEqual(session.statements, want) {
+		t.Errorf("expected %q, got %q", want, session.statements)
+	}
+}
@@ -0,0 +1,144 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	dataLossGuard   bool
+	acceptDataLoss  bool
+	confirmDataLoss func(m *Migration, losses []DataLoss) bool
+)
+
+// SetDataLossGuard sets whether goose refuses to roll back a SQL migration
+// whose down drops or truncates existing tables, or drops their columns,
+// protecting against fat-fingered rollbacks. The estimated rows of the
+// tables are reported, since statistics may be stale. The down runs if the
+// data loss is accepted with SetAcceptDataLoss, or confirmed with the
+// function of SetDataLossConfirm.
+func SetDataLossGuard(enabled bool) {
+	dataLossGuard = enabled
+}
+
+// SetAcceptDataLoss sets whether the downs deleting data run despite
+// SetDataLossGuard.
+func SetAcceptDataLoss(accept bool) {
+	acceptDataLoss = accept
+}
+
+// WithAcceptDataLoss runs the downs deleting data despite SetDataLossGuard,
+// like SetAcceptDataLoss.
+func WithAcceptDataLoss() OptionsFunc {
+	return func(o *options) { o.acceptDataLoss = true }
+}
+
+// SetDataLossConfirm sets the function asking whether to run the down of
+// m, which would delete data, when SetDataLossGuard refuses it, like a
+// prompt on a terminal. Without one, the down fails.
+func SetDataLossConfirm(confirm func(m *Migration, losses []DataLoss) bool) {
+	confirmDataLoss = confirm
+}
+
+// DataLoss is the data deleted by a statement of a down.
+type DataLoss struct {
+	Statement string
+	Table     string
+	Column    string // column dropped, empty if the whole table is dropped or truncated
+	Rows      int64  // estimated rows of the table, -1 if unknown
+
+	plain bool // whether the table is an unqualified, unquoted name, which the catalog resolves
+}
+
+func (d DataLoss) String() string {
+	what := "table " + d.Table
+	if d.Column != "" {
+		what = "column " + d.Table + "." + d.Column
+	}
+	if d.Rows < 0 {
+		return what + " (unknown rows)"
+	}
+	return fmt.Sprintf("%s (~%d rows)", what, d.Rows)
+}
+
+var (
+	dropTableStatement   = regexp.MustCompile(`(?is)^\s*(?:DROP\s+TABLE(?:\s+IF\s+EXISTS)?|TRUNCATE(?:\s+TABLE)?(?:\s+ONLY)?)\s+([^;(]+)`)
+	alterTableStatement  = regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE(?:\s+IF\s+EXISTS)?(?:\s+ONLY)?\s+([^\s(;,]+)(.*)`)
+	dropColumnClause     = regexp.MustCompile(`(?is)\bDROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?([^\s,;()]+)`)
+	unquoteIdent         = strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "")
+	plainIdent           = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	notColumnDropClauses = map[string]bool{"CONSTRAINT": true, "INDEX": true, "KEY": true, "PRIMARY": true, "FOREIGN": true, "CHECK": true, "PARTITION": true, "DEFAULT": true, "NOT": true}
+)
+
+// dataLosses returns the tables and columns dropped or truncated by stmt,
+// without their rows.
+func dataLosses(stmt string) []DataLoss {
+	stmt = stripSQLComments(stmt)
+	var losses []DataLoss
+	if match := dropTableStatement.FindStringSubmatch(stmt); match != nil {
+		for _, table := range strings.Split(match[1], ",") {
+			fields := strings.Fields(table)
+			if len(fields) > 0 && !strings.EqualFold(fields[0], "CASCADE") && !strings.EqualFold(fields[0], "RESTRICT") {
+				losses = append(losses, DataLoss{Statement: stmt, Table: unquoteIdent.Replace(fields[0]), plain: plainIdent.MatchString(fields[0])})
+			}
+		}
+		return losses
+	}
+	if match := alterTableStatement.FindStringSubmatch(stmt); match != nil {
+		for _, drop := range dropColumnClause.FindAllStringSubmatch(match[2], -1) {
+			if notColumnDropClauses[strings.ToUpper(drop[1])] {
+				continue
+			}
+			losses = append(losses, DataLoss{Statement: stmt, Table: unquoteIdent.Replace(match[1]), Column: unquoteIdent.Replace(drop[1]), plain: plainIdent.MatchString(match[1])})
+		}
+	}
+	return losses
+}
+
+// checkDataLoss refuses to run the down statements of the SQL migration m
+// if they delete the data of tables that may exist, if SetDataLossGuard is
+// enabled, unless the data loss is accepted or confirmed. Only the tables
+// known not to exist are skipped.
+func checkDataLoss(db *sql.DB, m *Migration, statements []string) error {
+	if !dataLossGuard || acceptDataLoss {
+		return nil
+	}
+	var losses []DataLoss
+	impacts := map[string]*TableImpact{}
+	for _, stmt := range statements {
+		for _, loss := range dataLosses(stmt) {
+			impact, ok := impacts[loss.Table]
+			if !ok {
+				var err error
+				if impact, err = estimateTable(db, loss.Table); err != nil {
+					impact = &TableImpact{Table: loss.Table, Rows: -1} // unknown, without statistics
+				}
+				impacts[loss.Table] = impact
+			}
+			switch {
+			case impact != nil:
+				loss.Rows = impact.Rows
+			case loss.plain:
+				continue // the table doesn't exist
+			default:
+				// Schema-qualified and quoted names may not be resolved by
+				// the lookup of the table: guard them anyway.
+				loss.Rows = -1
+			}
+			losses = append(losses, loss)
+		}
+	}
+	if len(losses) == 0 || (confirmDataLoss != nil && confirmDataLoss(m, losses)) {
+		return nil
+	}
+	described := make([]string, len(losses))
+	for i, loss := range losses {
+		described[i] = loss.String()
+	}
+	return errors.Errorf("ERROR %v: down would delete the data of %s: roll it back with -accept-data-loss", filepath.Base(m.Source), strings.Join(described, ", "))
+}
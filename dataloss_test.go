@@ -0,0 +1,100 @@
+package goose
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDataLosses(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want []string
+	}{
+		{"DROP TABLE IF EXISTS a, \"b\" CASCADE;", []string{"table a", "table b"}},
+		{"TRUNCATE TABLE a RESTART IDENTITY;", []string{"table a"}},
+		{"ALTER TABLE a DROP COLUMN x, DROP y;", []string{"column a.x", "column a.y"}},
+		{"ALTER TABLE a DROP CONSTRAINT a_pkey, ADD COLUMN z int;", nil},
+		{"DROP INDEX a_x;", nil},
+		{"DELETE FROM a;", nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, loss := range dataLosses(test.stmt) {
+			loss.Rows = -1
+			got = append(got, strings.TrimSuffix(loss.String(), " (unknown rows)"))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: expected %v, got %v", test.stmt, test.want, got)
+		}
+	}
+}
+
+func TestDataLossGuard(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\nINSERT INTO a VALUES (1), (2);\n-- +goose Down\nDROP TABLE a;\n",
+		"00002_create_d.sql": "-- +goose Up\nCREATE TABLE d (id int);\nINSERT INTO d VALUES (1), (2), (3);\n-- +goose Down\nDROP TABLE d;\n",
+		"00003_drop_c.sql":   "-- +goose Up\nSELECT 1;\n-- +goose Down\nDROP TABLE IF EXISTS c;\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	SetDataLossGuard(true)
+	defer SetDataLossGuard(false)
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := DownTo(db, dir, 2); err != nil {
+		t.Fatalf("expected the down dropping a table that does not exist to run, got %v", err)
+	}
+
+	err := Down(db, dir)
+	if err == nil || !strings.Contains(err.Error(), "00002_create_d.sql: down would delete the data of table d (~3 rows)") {
+		t.Fatalf("expected the down dropping a table to be refused, got %v", err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 2 {
+		t.Errorf("expected nothing to be rolled back, got version %d (%v)", v, err)
+	}
+	if err := Down(db, dir, WithAcceptDataLoss()); err != nil {
+		t.Fatal(err)
+	}
+
+	var confirmed []DataLoss
+	SetDataLossConfirm(func(m *Migration, losses []DataLoss) bool {
+		confirmed = losses
+		return true
+	})
+	defer SetDataLossConfirm(nil)
+	if err := Down(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(confirmed) != 1 || confirmed[0].String() != "table a (~2 rows)" {
+		t.Errorf("expected the dropped table to be confirmed, got %v", confirmed)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 0 {
+		t.Errorf("expected the confirmed down to run, got version %d (%v)", v, err)
+	}
+}
+
+func TestDataLossGuardQualifiedName(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_users.sql": "-- +goose Up\nCREATE TABLE users (id int);\nINSERT INTO users VALUES (1);\n-- +goose Down\nDROP TABLE main.users;\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	SetDataLossGuard(true)
+	defer SetDataLossGuard(false)
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	err := Down(db, dir)
+	if err == nil || !strings.Contains(err.Error(), "down would delete the data of table main.users (unknown rows)") {
+		t.Fatalf("expected the down dropping a schema-qualified table to be refused, got %v", err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 1 {
+		t.Errorf("expected nothing to be rolled back, got version %d (%v)", v, err)
+	}
+}
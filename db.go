@@ -7,24 +7,62 @@ import (
 
 // OpenDBWithDriver creates a connection a database, and modifies goose
 // internals to be compatible with the supplied driver by calling SetDialect.
-func OpenDBWithDriver(driver string, dbstring string) (*sql.DB, error) {
+// A dbstring referencing a secret is resolved with ResolveDBString. The
+// connections are dialed with the Dialer of SetDialer or WithDialer, or
+// opened with the connector of WithConnector, if any.
+func OpenDBWithDriver(driver string, dbstring string, opts ...OptionsFunc) (*sql.DB, error) {
 	if err := SetDialect(driver); err != nil {
 		return nil, err
 	}
+	dbstring, err := ResolveDBString(dbstring)
+	if err != nil {
+		return nil, err
+	}
 
-	switch driver {
-	case "mssql":
-		driver = "sqlserver"
-	case "redshift":
-		driver = "postgres"
-	case "tidb":
-		driver = "mysql"
+	o := currentOptions()
+	for _, opt := range opts {
+		opt(&o)
 	}
+	return openDB(driver, dbstring, o)
+}
 
-	switch driver {
-	case "postgres", "sqlite3", "mysql", "sqlserver", "clickhouse":
+// openDB opens the database of a resolved dbstring with the dialer or
+// connector of o.
+func openDB(driver, dbstring string, o options) (*sql.DB, error) {
+	switch driver = sqlDriver(driver); driver {
+	case "postgres", "sqlite3", "libsql", "mysql", "sqlserver", "clickhouse", "bigquery", "hdb", "firebirdsql", "trino", "presto", "spanner":
+		if o.connector != nil {
+			return sql.OpenDB(o.connector), nil
+		}
+		if o.dialer != nil {
+			c, err := dialConnector(driver, o.dialer, dbstring)
+			if err != nil {
+				return nil, err
+			}
+			return sql.OpenDB(c), nil
+		}
 		return sql.Open(driver, dbstring)
 	default:
 		return nil, fmt.Errorf("unsupported driver %s", driver)
 	}
 }
+
+// sqlDriver returns the name of the database/sql driver of the goose
+// driver.
+func sqlDriver(driver string) string {
+	switch driver {
+	case "libsql", "turso":
+		return "libsql"
+	case "mssql":
+		return "sqlserver"
+	case "redshift":
+		return "postgres"
+	case "mariadb", "tidb":
+		return "mysql"
+	case "hana":
+		return "hdb"
+	case "firebird", "interbase":
+		return "firebirdsql"
+	}
+	return driver
+}
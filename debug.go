@@ -0,0 +1,143 @@
+package goose
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// DebugState is the state of the migrations run by the process, published
+// by PublishExpvar and DebugHandler.
+type DebugState struct {
+	Version    *int64     `json:"version"`     // current version after the last run, if known
+	Running    *DebugRun  `json:"running"`     // run in progress, if any
+	LastRun    *DebugRun  `json:"last_run"`    // last finished run, if any
+	LockHolder string     `json:"lock_holder"` // holder of the lock, whichever process it is, if known
+	LockedAt   *time.Time `json:"locked_at"`   // when the process acquired the lock
+}
+
+// holderLocker is a Locker whose holder can be queried, the holder of the
+// lock being any process.
+type holderLocker interface {
+	queryHolder(ctx context.Context) (string, error)
+}
+
+// DebugRun is a run of a command modifying the database.
+type DebugRun struct {
+	Command  string        `json:"command"`
+	Versions []int64       `json:"versions"` // versions applied or rolled back, in order
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration_ns,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+var debugStatus struct {
+	sync.Mutex
+	state DebugState
+}
+
+var (
+	publishOnce  sync.Once
+	debugVersion bool // whether the version is read after each run
+)
+
+// PublishExpvar publishes the DebugState of the process as the goose
+// expvar variable, served at /debug/vars by the expvar package, so that
+// the debug tooling of Go programs displays the state of their migrations.
+func PublishExpvar() {
+	debugVersion = true
+	publishOnce.Do(func() {
+		expvar.Publish("goose", expvar.Func(func() interface{} { return GetDebugState() }))
+	})
+}
+
+// DebugHandler returns an HTTP handler serving the DebugState of the
+// process as JSON, for programs not serving expvar.
+func DebugHandler() http.Handler {
+	debugVersion = true
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, GetDebugState())
+	})
+}
+
+// GetDebugState returns the state of the migrations run by the process.
+// The holder of the lock is queried from the database with the Postgres,
+// MySQL and table lockers, so that it is reported whichever process holds
+// it: host:pid for this process, the client address and backend pid on
+// Postgres, the host and connection id on MySQL, the holder of the lock
+// row for a TableLocker.
+func GetDebugState() DebugState {
+	debugStatus.Lock()
+	state := debugStatus.state
+	if state.Running != nil {
+		running := *state.Running
+		running.Versions = append([]int64{}, running.Versions...)
+		state.Running = &running
+	}
+	debugStatus.Unlock()
+
+	if l, ok := locker.(holderLocker); ok && state.LockedAt == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if holder, err := l.queryHolder(ctx); err == nil {
+			state.LockHolder = holder
+		}
+	}
+	return state
+}
+
+// debugStarted records the start of an invocation.
+func debugStarted(inv *invocation) {
+	debugStatus.Lock()
+	defer debugStatus.Unlock()
+	debugStatus.state.Running = &DebugRun{Command: inv.command, Versions: []int64{}, Started: inv.started}
+}
+
+// debugRecorded records a migration applied or rolled back by the running
+// invocation.
+func debugRecorded(v int64) {
+	debugStatus.Lock()
+	defer debugStatus.Unlock()
+	if debugStatus.state.Running != nil {
+		debugStatus.state.Running.Versions = append(debugStatus.state.Running.Versions, v)
+	}
+}
+
+// debugLocked records that the process acquired or released the lock.
+func debugLocked(locked bool) {
+	debugStatus.Lock()
+	defer debugStatus.Unlock()
+	if !locked {
+		debugStatus.state.LockHolder, debugStatus.state.LockedAt = "", nil
+		return
+	}
+	host, _ := os.Hostname()
+	now := time.Now()
+	debugStatus.state.LockHolder, debugStatus.state.LockedAt = fmt.Sprintf("%s:%d", host, os.Getpid()), &now
+}
+
+// debugFinished records the outcome of an invocation, and the current
+// version of its database once the state is published.
+func debugFinished(inv *invocation, err error) {
+	var version *int64
+	if debugVersion && inv.db != nil {
+		if v, err := readDBVersion(inv.db); err == nil {
+			version = &v
+		}
+	}
+
+	debugStatus.Lock()
+	defer debugStatus.Unlock()
+	run := &DebugRun{Command: inv.command, Versions: append([]int64{}, inv.versions...), Started: inv.started, Duration: time.Since(inv.started)}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	debugStatus.state.LastRun, debugStatus.state.Running = run, nil
+	if version != nil {
+		debugStatus.state.Version = version
+	}
+}
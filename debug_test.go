@@ -0,0 +1,88 @@
+package goose
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stateLocker records the debug state while the lock is held.
+type stateLocker struct {
+	held DebugState
+}
+
+func (l *stateLocker) Lock(ctx context.Context) error { return nil }
+
+func (l *stateLocker) Unlock(ctx context.Context) error {
+	l.held = GetDebugState()
+	return nil
+}
+
+func TestDebugState(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	defer func() { debugVersion = false }()
+	handler := DebugHandler()
+	l := &stateLocker{}
+	if err := Up(db, dir, WithLock(l)); err != nil {
+		t.Fatal(err)
+	}
+	if l.held.LockHolder == "" || l.held.LockedAt == nil || l.held.Running == nil || l.held.Running.Command != "up" || len(l.held.Running.Versions) != 3 {
+		t.Errorf("unexpected state while migrating: %+v", l.held)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/debug/goose", nil))
+	var state DebugState
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Version == nil || *state.Version != 3 || state.Running != nil || state.LockHolder != "" {
+		t.Errorf("unexpected state after migrating: %s", w.Body.String())
+	}
+	if state.LastRun == nil || state.LastRun.Command != "up" || len(state.LastRun.Versions) != 3 || state.LastRun.Error != "" {
+		t.Errorf("unexpected last run: %+v", state.LastRun)
+	}
+
+	migrations := map[string]string{"00004_fail.sql": "-- +goose Up\nSELECT * FROM missing;\n"}
+	for name, sql := range testMigrations {
+		migrations[name] = sql
+	}
+	failing, cleanupFailing := writeTestMigrations(t, migrations)
+	defer cleanupFailing()
+	if err := UpTo(db, failing, 4); err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+	PublishExpvar()
+	PublishExpvar()
+	if v := expvar.Get("goose").String(); !strings.Contains(v, `"command":"up-to"`) || !strings.Contains(v, `"error":`) {
+		t.Errorf("expected the failed run to be published, got %s", v)
+	}
+}
+
+func TestDebugStateLockHolder(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	other := NewTableLocker(db)
+	if err := other.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLocker(nil)
+	SetLocker(NewTableLocker(db))
+	if holder := GetDebugState().LockHolder; holder == "" || holder != other.holder {
+		t.Errorf("expected the holder of the lock row, got %q", holder)
+	}
+	if err := other.Unlock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if holder := GetDebugState().LockHolder; holder != "" {
+		t.Errorf("expected no holder once released, got %q", holder)
+	}
+}
@@ -0,0 +1,151 @@
+package goose
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GenerateFromSchema is the declarative schema mode, experimental: the
+// schema is maintained as the desired state in schemaFile, a plain SQL file
+// of CREATE statements, and GenerateFromSchema writes a new SQL migration to
+// dir with the DDL turning the schema produced by the migrations of dir into
+// the desired one. It returns the path of the migration, or "" if the
+// migrations already produce the desired schema.
+//
+// scratch must be an empty database of the same server: the desired schema
+// and the migrations are applied to it to compare their tables and columns.
+// Indexes and constraints are not compared. It supports Postgres, MySQL and
+// SQLite.
+func GenerateFromSchema(scratch *sql.DB, dir, schemaFile, name string) (string, error) {
+	if schemaColumnsQuery() == "" {
+		return "", errors.New("declarative schema is not supported by this dialect")
+	}
+
+	f, err := os.Open(schemaFile)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open schema file")
+	}
+	defer f.Close()
+	// The schema file is parsed like the up part of a SQL migration.
+	statements, _, err := parseSQLMigration(io.MultiReader(strings.NewReader("-- +goose Up\n"), f), true)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse schema file %v", filepath.Base(schemaFile))
+	}
+
+	tables, err := readSchema(scratch)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the schema of the scratch database")
+	}
+	if len(tables) > 0 {
+		return "", errors.New("the scratch database must be empty")
+	}
+
+	desired, err := readDesiredSchema(scratch, statements)
+	if err != nil {
+		return "", err
+	}
+	if err := upTo(scratch, dir, maxVersion); err != nil {
+		return "", errors.Wrap(err, "failed to apply migrations to the scratch database")
+	}
+	migrated, err := readSchema(scratch)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the schema of the scratch database")
+	}
+
+	up, down := diffSchemas(migrated, desired)
+	if len(up) == 0 {
+		log.Printf("goose: the migrations produce the schema of %s\n", schemaFile)
+		return "", nil
+	}
+
+	if name == "" {
+		name = "schema"
+	}
+	header := "-- Generated by goose from " + filepath.Base(schemaFile) + ". Review before applying:\n" +
+		"-- indexes and constraints are not compared.\n"
+	return writeSchemaMigration(dir, name, header, up, down)
+}
+
+// readDesiredSchema applies the statements of the desired schema to the
+// scratch database, reads the schema they produce, and leaves the scratch
+// database empty again: in a transaction rolled back with the databases
+// having transactional DDL, or by dropping the tables otherwise.
+func readDesiredSchema(scratch *sql.DB, statements []string) (map[string]*schemaTable, error) {
+	if !isMySQL() {
+		tx, err := scratch.Begin()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to begin transaction")
+		}
+		defer tx.Rollback()
+		if err := execSchema(tx, statements); err != nil {
+			return nil, err
+		}
+		return readSchema(tx)
+	}
+
+	var desired map[string]*schemaTable
+	err := withConn(scratch, func(conn connExecer) error {
+		if err := execSchema(conn, statements); err != nil {
+			return err
+		}
+		var err error
+		if desired, err = readSchema(conn); err != nil {
+			return err
+		}
+		if _, err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+			return err
+		}
+		for name := range desired {
+			if _, err := conn.Exec(dropTableSQL(name)); err != nil {
+				return errors.Wrapf(err, "failed to drop table %s of the scratch database", name)
+			}
+		}
+		if _, ok := GetDialect().(*MariaDBDialect); ok {
+			return dropSequences(conn)
+		}
+		return nil
+	})
+	return desired, err
+}
+
+func execSchema(qe QueryExecer, statements []string) error {
+	for _, query := range statements {
+		if _, err := qe.Exec(query); err != nil {
+			return errors.Wrapf(err, "failed to execute schema statement %q", clearStatement(query))
+		}
+	}
+	return nil
+}
+
+// dropSequences drops the MariaDB sequences of the scratch database, which
+// are not tables of the schema.
+func dropSequences(conn connExecer) error {
+	rows, err := conn.Query("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'SEQUENCE'")
+	if err != nil {
+		return errors.Wrap(err, "failed to list sequences of the scratch database")
+	}
+	var sequences []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "failed to scan row")
+		}
+		sequences = append(sequences, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "failed to get next row")
+	}
+	for _, name := range sequences {
+		if _, err := conn.Exec("DROP SEQUENCE " + QuoteIdent(name)); err != nil {
+			return errors.Wrapf(err, "failed to drop sequence %s of the scratch database", name)
+		}
+	}
+	return nil
+}
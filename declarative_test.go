@@ -0,0 +1,52 @@
+package goose
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateFromSchema(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	schemaDir, err := ioutil.TempDir("", "goose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(schemaDir)
+	schemaFile := filepath.Join(schemaDir, "schema.sql")
+	schema := "CREATE TABLE a (id int, name text NOT NULL DEFAULT '');\nCREATE TABLE b (id int);\nCREATE TABLE d (id int);\n"
+	if err := ioutil.WriteFile(schemaFile, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scratch, cleanup := openTestDB(t)
+	defer cleanup()
+
+	path, err := GenerateFromSchema(scratch, dir, schemaFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path == "" {
+		t.Fatal("expected a migration to the desired schema")
+	}
+
+	if err := Up(scratch, dir); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := readSchema(scratch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 3 || tables["c"] != nil || tables["d"] == nil || tables["a"].column("name") == nil {
+		t.Errorf("unexpected schema after applying the generated migration: %+v", tables)
+	}
+
+	// The migrations now produce the desired schema.
+	scratch2, cleanup2 := openTestDB(t)
+	defer cleanup2()
+	if path, err := GenerateFromSchema(scratch2, dir, schemaFile, ""); err != nil || path != "" {
+		t.Errorf("unexpected migration %q (%v), the migrations produce the desired schema", path, err)
+	}
+}
@@ -0,0 +1,84 @@
+package goose
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// dialectSet reports whether the dialect was set with SetDialect, or
+// detected from the first database goose ran on.
+var dialectSet = false
+
+// driverDialects maps the packages of well-known drivers to their dialect.
+var driverDialects = []struct {
+	pkg     string
+	dialect string
+}{
+	{"github.com/lib/pq", "postgres"},
+	{"github.com/jackc/pgx/stdlib", "postgres"},
+	{"github.com/go-sql-driver/mysql", "mysql"},
+	{"github.com/ziutek/mymysql/godrv", "mysql"},
+	{"github.com/mattn/go-sqlite3", "sqlite3"},
+	{"modernc.org/sqlite", "sqlite3"},
+	{"github.com/tursodatabase/libsql-client-go/libsql", "libsql"},
+	{"github.com/tursodatabase/go-libsql", "libsql"},
+	{"github.com/denisenkom/go-mssqldb", "mssql"},
+	{"github.com/ClickHouse/clickhouse-go", "clickhouse"},
+	{"github.com/SAP/go-hdb/driver", "hana"},
+	{"github.com/nakagami/firebirdsql", "firebird"},
+	{"github.com/trinodb/trino-go-client/trino", "trino"},
+	{"github.com/prestodb/presto-go-client/presto", "presto"},
+	{"github.com/googleapis/go-sql-spanner", "spanner"},
+}
+
+// DetectDialect detects the dialect of db from the type of its driver. The
+// servers speaking the Postgres or MySQL protocol are told apart with a
+// query of their version, to detect Redshift and TiDB.
+func DetectDialect(db *sql.DB) (string, error) {
+	t := reflect.TypeOf(db.Driver())
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	pkg := t.PkgPath()
+
+	for _, d := range driverDialects {
+		// Match vendored and versioned import paths too.
+		if !strings.HasSuffix(pkg, d.pkg) && !strings.Contains(pkg, d.pkg+"/") {
+			continue
+		}
+		var version string
+		switch d.dialect {
+		case "postgres":
+			if err := db.QueryRow("SELECT version()").Scan(&version); err == nil && strings.Contains(version, "Redshift") {
+				return "redshift", nil
+			}
+		case "mysql":
+			if err := db.QueryRow("SELECT VERSION()").Scan(&version); err == nil && strings.Contains(version, "TiDB") {
+				return "tidb", nil
+			}
+		}
+		return d.dialect, nil
+	}
+
+	return "", errors.Errorf("unknown driver %s.%s", pkg, t.Name())
+}
+
+// ensureDialect detects the dialect of db, unless it was set. The default
+// dialect is kept if the driver is unknown.
+func ensureDialect(db *sql.DB) {
+	if dialectSet {
+		return
+	}
+	dialectSet = true
+
+	d, err := DetectDialect(db)
+	if err != nil {
+		verboseInfo("%v: using the default dialect, set it with SetDialect", err)
+		return
+	}
+	verboseInfo("Detected %s dialect", d)
+	SetDialect(d)
+}
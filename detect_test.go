@@ -0,0 +1,41 @@
+package goose
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestDetectDialect(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	d, err := DetectDialect(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != "sqlite3" {
+		t.Errorf("unexpected dialect, got %q, want \"sqlite3\"", d)
+	}
+}
+
+func TestEnsureDialect(t *testing.T) {
+	defer func(set bool) { dialectSet = set }(dialectSet)
+	defer SetDialect("postgres")
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dialect, dialectSet = &PostgresDialect{}, false
+	if _, err := EnsureDBVersion(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := GetDialect().(*Sqlite3Dialect); !ok {
+		t.Errorf("unexpected dialect %T, want *Sqlite3Dialect", GetDialect())
+	}
+}
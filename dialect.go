@@ -3,15 +3,18 @@ package goose
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 )
 
 // SQLDialect abstracts the details of specific SQL dialects
 // for goose's few SQL specific statements
 type SQLDialect interface {
-	createVersionTableSQL() string // sql string to create the db version table
-	insertVersionSQL() string      // sql string to insert the initial version table row
-	deleteVersionSQL() string      // sql string to delete version
-	migrationSQL() string          // sql string to retrieve migrations
+	createVersionTableSQL() string            // sql string to create the db version table
+	insertVersionSQL() string                 // sql string to insert the initial version table row
+	addVersionColumnSQL(column string) string // sql string to add a column to an older version table
+	deleteVersionSQL() string                 // sql string to delete version
+	migrationSQL() string                     // sql string to retrieve migrations
+	placeholder(n int) string                 // bind parameter placeholder for the nth argument
 	dbVersionQuery(db *sql.DB) (*sql.Rows, error)
 }
 
@@ -22,15 +25,20 @@ func GetDialect() SQLDialect {
 	return dialect
 }
 
-// SetDialect sets the SQLDialect
+// SetDialect sets the SQLDialect. If it is not set, the dialect is
+// detected from the driver of the database, see DetectDialect.
 func SetDialect(d string) error {
 	switch d {
 	case "postgres":
 		dialect = &PostgresDialect{}
 	case "mysql":
 		dialect = &MySQLDialect{}
+	case "mariadb":
+		dialect = &MariaDBDialect{}
 	case "sqlite3":
 		dialect = &Sqlite3Dialect{}
+	case "libsql", "turso":
+		dialect = &LibSQLDialect{}
 	case "mssql":
 		dialect = &SqlServerDialect{}
 	case "redshift":
@@ -39,10 +47,21 @@ func SetDialect(d string) error {
 		dialect = &TiDBDialect{}
 	case "clickhouse":
 		dialect = &ClickHouseDialect{}
+	case "bigquery":
+		dialect = &BigQueryDialect{}
+	case "hana":
+		dialect = &HanaDialect{}
+	case "firebird", "interbase":
+		dialect = &FirebirdDialect{}
+	case "trino", "presto":
+		dialect = &TrinoDialect{}
+	case "spanner":
+		dialect = &SpannerDialect{}
 	default:
 		return fmt.Errorf("%q: unknown dialect", d)
 	}
 
+	dialectSet = true
 	return nil
 }
 
@@ -59,12 +78,20 @@ func (pg PostgresDialect) createVersionTableSQL() string {
                 version_id bigint NOT NULL,
                 is_applied boolean NOT NULL,
                 tstamp timestamp NULL default now(),
+                build varchar(255) NULL,
+                checksum varchar(255) NULL,
+                duration_ms varchar(255) NULL,
+                run_id varchar(255) NULL,
                 PRIMARY KEY(id)
             );`, TableName())
 }
 
 func (pg PostgresDialect) insertVersionSQL() string {
-	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES ($1, $2);", TableName())
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES ($1, $2, $3, $4, $5, $6);", TableName())
+}
+
+func (pg PostgresDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s varchar(255) NULL;", TableName(), column)
 }
 
 func (pg PostgresDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
@@ -84,6 +111,10 @@ func (pg PostgresDialect) deleteVersionSQL() string {
 	return fmt.Sprintf("DELETE FROM %s WHERE version_id=$1;", TableName())
 }
 
+func (pg PostgresDialect) placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
 ////////////////////////////
 // MySQL
 ////////////////////////////
@@ -97,12 +128,28 @@ func (m MySQLDialect) createVersionTableSQL() string {
                 version_id bigint NOT NULL,
                 is_applied boolean NOT NULL,
                 tstamp timestamp NULL default now(),
+                build varchar(255) NULL,
+                checksum varchar(255) NULL,
+                duration_ms varchar(255) NULL,
+                run_id varchar(255) NULL,
                 PRIMARY KEY(id)
             );`, TableName())
 }
 
 func (m MySQLDialect) insertVersionSQL() string {
-	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES (?, ?);", TableName())
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES (?, ?, ?, ?, ?, ?);", TableName())
+}
+
+func (m MySQLDialect) addVersionColumnSQL(column string) string {
+	// Add the column without rebuilding the table, or without locking it
+	// before MySQL 8.0.12.
+	switch {
+	case serverVersion.AtLeast(8, 0, 12):
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s varchar(255) NULL, ALGORITHM=INSTANT;", TableName(), column)
+	case serverVersion.AtLeast(5, 6, 0):
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s varchar(255) NULL, ALGORITHM=INPLACE, LOCK=NONE;", TableName(), column)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s varchar(255) NULL;", TableName(), column)
 }
 
 func (m MySQLDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
@@ -122,6 +169,67 @@ func (m MySQLDialect) deleteVersionSQL() string {
 	return fmt.Sprintf("DELETE FROM %s WHERE version_id=?;", TableName())
 }
 
+func (m MySQLDialect) placeholder(n int) string {
+	return "?"
+}
+
+////////////////////////////
+// MariaDB
+////////////////////////////
+
+// MariaDBDialect struct.
+type MariaDBDialect struct{}
+
+func (m MariaDBDialect) createVersionTableSQL() string {
+	// MariaDB enforces CHECK constraints, which keep the boolean, a
+	// TINYINT(1), to 0 or 1.
+	return fmt.Sprintf(`CREATE TABLE %s (
+                id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+                version_id bigint NOT NULL,
+                is_applied tinyint(1) NOT NULL CHECK (is_applied IN (0, 1)),
+                tstamp timestamp NULL default current_timestamp(),
+                build varchar(255) NULL,
+                checksum varchar(255) NULL,
+                duration_ms varchar(255) NULL,
+                run_id varchar(255) NULL,
+                PRIMARY KEY(id)
+            );`, TableName())
+}
+
+func (m MariaDBDialect) insertVersionSQL() string {
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES (?, ?, ?, ?, ?, ?);", TableName())
+}
+
+func (m MariaDBDialect) addVersionColumnSQL(column string) string {
+	// Add the column without rebuilding the table, or without locking it
+	// before MariaDB 10.3.2.
+	if serverVersion.AtLeast(10, 3, 2) {
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s varchar(255) NULL, ALGORITHM=INSTANT;", TableName(), column)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s varchar(255) NULL, ALGORITHM=INPLACE, LOCK=NONE;", TableName(), column)
+}
+
+func (m MariaDBDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version_id, is_applied from %s ORDER BY id DESC", TableName()))
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, err
+}
+
+func (m MariaDBDialect) migrationSQL() string {
+	return fmt.Sprintf("SELECT tstamp, is_applied FROM %s WHERE version_id=? ORDER BY tstamp DESC LIMIT 1", TableName())
+}
+
+func (m MariaDBDialect) deleteVersionSQL() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version_id=?;", TableName())
+}
+
+func (m MariaDBDialect) placeholder(n int) string {
+	return "?"
+}
+
 ////////////////////////////
 // MSSQL
 ////////////////////////////
@@ -134,12 +242,20 @@ func (m SqlServerDialect) createVersionTableSQL() string {
                 id INT NOT NULL IDENTITY(1,1) PRIMARY KEY,
                 version_id BIGINT NOT NULL,
                 is_applied BIT NOT NULL,
-                tstamp DATETIME NULL DEFAULT CURRENT_TIMESTAMP
+                tstamp DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+                build NVARCHAR(255) NULL,
+                checksum NVARCHAR(255) NULL,
+                duration_ms NVARCHAR(255) NULL,
+                run_id NVARCHAR(255) NULL
             );`, TableName())
 }
 
 func (m SqlServerDialect) insertVersionSQL() string {
-	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES (@p1, @p2);", TableName())
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES (@p1, @p2, @p3, @p4, @p5, @p6);", TableName())
+}
+
+func (m SqlServerDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s NVARCHAR(255) NULL;", TableName(), column)
 }
 
 func (m SqlServerDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
@@ -172,6 +288,10 @@ func (m SqlServerDialect) deleteVersionSQL() string {
 	return fmt.Sprintf("DELETE FROM %s WHERE version_id=@p1;", TableName())
 }
 
+func (m SqlServerDialect) placeholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
+
 ////////////////////////////
 // sqlite3
 ////////////////////////////
@@ -184,12 +304,20 @@ func (m Sqlite3Dialect) createVersionTableSQL() string {
                 id INTEGER PRIMARY KEY AUTOINCREMENT,
                 version_id INTEGER NOT NULL,
                 is_applied INTEGER NOT NULL,
-                tstamp TIMESTAMP DEFAULT (datetime('now'))
+                tstamp TIMESTAMP DEFAULT (datetime('now')),
+                build TEXT,
+                checksum TEXT,
+                duration_ms TEXT,
+                run_id TEXT
             );`, TableName())
 }
 
 func (m Sqlite3Dialect) insertVersionSQL() string {
-	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES (?, ?);", TableName())
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES (?, ?, ?, ?, ?, ?);", TableName())
+}
+
+func (m Sqlite3Dialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT;", TableName(), column)
 }
 
 func (m Sqlite3Dialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
@@ -209,6 +337,20 @@ func (m Sqlite3Dialect) deleteVersionSQL() string {
 	return fmt.Sprintf("DELETE FROM %s WHERE version_id=?;", TableName())
 }
 
+func (m Sqlite3Dialect) placeholder(n int) string {
+	return "?"
+}
+
+////////////////////////////
+// libSQL
+////////////////////////////
+
+// LibSQLDialect struct, for libSQL and Turso, which speak the SQL of
+// SQLite.
+type LibSQLDialect struct {
+	Sqlite3Dialect
+}
+
 ////////////////////////////
 // Redshift
 ////////////////////////////
@@ -222,16 +364,25 @@ func (rs RedshiftDialect) createVersionTableSQL() string {
                 version_id bigint NOT NULL,
                 is_applied boolean NOT NULL,
                 tstamp timestamp NULL default sysdate,
+                build varchar(255) NULL,
+                checksum varchar(255) NULL,
+                duration_ms varchar(255) NULL,
+                run_id varchar(255) NULL,
                 PRIMARY KEY(id)
-            );`, TableName())
+            ) %s;`, TableName(), redshiftTableAttributes)
 }
 
 func (rs RedshiftDialect) insertVersionSQL() string {
-	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES ($1, $2);", TableName())
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES ($1, $2, $3, $4, $5, $6);", TableName())
+}
+
+func (rs RedshiftDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s varchar(255) NULL;", TableName(), column)
 }
 
 func (rs RedshiftDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
-	rows, err := db.Query(fmt.Sprintf("SELECT version_id, is_applied from %s ORDER BY id DESC", TableName()))
+	// IDENTITY values are unique, but not always in the order of insertion.
+	rows, err := db.Query(fmt.Sprintf("SELECT version_id, is_applied from %s ORDER BY tstamp DESC, id DESC", TableName()))
 	if err != nil {
 		return nil, err
 	}
@@ -247,6 +398,10 @@ func (rs RedshiftDialect) deleteVersionSQL() string {
 	return fmt.Sprintf("DELETE FROM %s WHERE version_id=$1;", TableName())
 }
 
+func (rs RedshiftDialect) placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
 ////////////////////////////
 // TiDB
 ////////////////////////////
@@ -260,12 +415,20 @@ func (m TiDBDialect) createVersionTableSQL() string {
                 version_id bigint NOT NULL,
                 is_applied boolean NOT NULL,
                 tstamp timestamp NULL default now(),
+                build varchar(255) NULL,
+                checksum varchar(255) NULL,
+                duration_ms varchar(255) NULL,
+                run_id varchar(255) NULL,
                 PRIMARY KEY(id)
             );`, TableName())
 }
 
 func (m TiDBDialect) insertVersionSQL() string {
-	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES (?, ?);", TableName())
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES (?, ?, ?, ?, ?, ?);", TableName())
+}
+
+func (m TiDBDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s varchar(255) NULL;", TableName(), column)
 }
 
 func (m TiDBDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
@@ -285,6 +448,10 @@ func (m TiDBDialect) deleteVersionSQL() string {
 	return fmt.Sprintf("DELETE FROM %s WHERE version_id=?;", TableName())
 }
 
+func (m TiDBDialect) placeholder(n int) string {
+	return "?"
+}
+
 ////////////////////////////
 // ClickHouse
 ////////////////////////////
@@ -298,7 +465,11 @@ func (m ClickHouseDialect) createVersionTableSQL() string {
       version_id Int64,
       is_applied UInt8,
       date Date default now(),
-      tstamp DateTime default now()
+      tstamp DateTime default now(),
+      build String,
+      checksum String,
+      duration_ms String,
+      run_id String
     ) Engine = MergeTree(date, (date), 8192)
 	`
 }
@@ -312,7 +483,11 @@ func (m ClickHouseDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
 }
 
 func (m ClickHouseDialect) insertVersionSQL() string {
-	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES (?, ?)", TableName())
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES (?, ?, ?, ?, ?, ?)", TableName())
+}
+
+func (m ClickHouseDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s String", TableName(), column)
 }
 
 func (m ClickHouseDialect) migrationSQL() string {
@@ -322,3 +497,288 @@ func (m ClickHouseDialect) migrationSQL() string {
 func (m ClickHouseDialect) deleteVersionSQL() string {
 	return fmt.Sprintf("ALTER TABLE %s DELETE WHERE version_id = ?", TableName())
 }
+
+func (m ClickHouseDialect) placeholder(n int) string {
+	return "?"
+}
+
+////////////////////////////
+// SAP HANA
+////////////////////////////
+
+// HanaDialect struct. Qualify the version table with its schema, like
+// APP.goose_db_version, to keep it out of the default schema of the user.
+type HanaDialect struct{}
+
+func (m HanaDialect) createVersionTableSQL() string {
+	return fmt.Sprintf(`CREATE COLUMN TABLE %s (
+                id BIGINT NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+                version_id BIGINT NOT NULL,
+                is_applied BOOLEAN NOT NULL,
+                tstamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                build NVARCHAR(255),
+                checksum NVARCHAR(255),
+                duration_ms NVARCHAR(255),
+                run_id NVARCHAR(255),
+                PRIMARY KEY (id)
+            )`, TableName())
+}
+
+func (m HanaDialect) insertVersionSQL() string {
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES (?, ?, ?, ?, ?, ?)", TableName())
+}
+
+func (m HanaDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD (%s NVARCHAR(255))", TableName(), column)
+}
+
+func (m HanaDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version_id, is_applied FROM %s ORDER BY id DESC", TableName()))
+	if err != nil {
+		return nil, err
+	}
+	return rows, err
+}
+
+func (m HanaDialect) migrationSQL() string {
+	return fmt.Sprintf("SELECT tstamp, is_applied FROM %s WHERE version_id = ? ORDER BY tstamp DESC LIMIT 1", TableName())
+}
+
+func (m HanaDialect) deleteVersionSQL() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version_id = ?", TableName())
+}
+
+func (m HanaDialect) placeholder(n int) string {
+	return "?"
+}
+
+////////////////////////////
+// Firebird
+////////////////////////////
+
+// FirebirdDialect struct, for Firebird and InterBase. They have no identity
+// columns before Firebird 3: the id of the version table is set by a
+// trigger from a generator, named after the table.
+type FirebirdDialect struct{}
+
+func (m FirebirdDialect) createVersionTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE %s (
+                id BIGINT NOT NULL,
+                version_id BIGINT NOT NULL,
+                is_applied SMALLINT NOT NULL,
+                tstamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                build VARCHAR(255),
+                checksum VARCHAR(255),
+                duration_ms VARCHAR(255),
+                run_id VARCHAR(255),
+                PRIMARY KEY (id)
+            )`, TableName())
+}
+
+// createVersionTableStatements returns the statements creating the version
+// table, its generator and the trigger setting its id.
+func (m FirebirdDialect) createVersionTableStatements() []string {
+	table := TableName()
+	return []string{
+		m.createVersionTableSQL(),
+		fmt.Sprintf("CREATE GENERATOR %s_id", table),
+		fmt.Sprintf(`CREATE TRIGGER %s_bi FOR %s ACTIVE BEFORE INSERT POSITION 0 AS
+            BEGIN
+                IF (NEW.id IS NULL) THEN NEW.id = GEN_ID(%s_id, 1);
+            END`, table, table, table),
+	}
+}
+
+func (m FirebirdDialect) insertVersionSQL() string {
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES (?, ?, ?, ?, ?, ?)", TableName())
+}
+
+func (m FirebirdDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s VARCHAR(255)", TableName(), column)
+}
+
+func (m FirebirdDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version_id, is_applied FROM %s ORDER BY id DESC", TableName()))
+	if err != nil {
+		return nil, err
+	}
+	return rows, err
+}
+
+func (m FirebirdDialect) migrationSQL() string {
+	return fmt.Sprintf("SELECT tstamp, is_applied FROM %s WHERE version_id = ? ORDER BY tstamp DESC ROWS 1", TableName())
+}
+
+func (m FirebirdDialect) deleteVersionSQL() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version_id = ?", TableName())
+}
+
+func (m FirebirdDialect) placeholder(n int) string {
+	return "?"
+}
+
+////////////////////////////
+// BigQuery
+////////////////////////////
+
+// BigQueryDialect struct. It is experimental: BigQuery has no
+// transactions, so migrations run without one, and the version table has
+// no id, the latest record of a version is the one with the latest
+// timestamp. Qualify the version table with its dataset, like
+// analytics.goose_db_version, unless the connection has a default dataset.
+type BigQueryDialect struct{}
+
+func (m BigQueryDialect) createVersionTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE %s (
+                version_id INT64 NOT NULL,
+                is_applied BOOL NOT NULL,
+                tstamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP(),
+                build STRING,
+                checksum STRING,
+                duration_ms STRING,
+                run_id STRING
+            )`, TableName())
+}
+
+func (m BigQueryDialect) insertVersionSQL() string {
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, build, checksum, duration_ms, run_id) VALUES (?, ?, ?, ?, ?, ?)", TableName())
+}
+
+func (m BigQueryDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s STRING", TableName(), column)
+}
+
+func (m BigQueryDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version_id, is_applied FROM %s ORDER BY tstamp DESC", TableName()))
+	if err != nil {
+		return nil, err
+	}
+	return rows, err
+}
+
+func (m BigQueryDialect) migrationSQL() string {
+	return fmt.Sprintf("SELECT tstamp, is_applied FROM %s WHERE version_id = ? ORDER BY tstamp DESC LIMIT 1", TableName())
+}
+
+func (m BigQueryDialect) deleteVersionSQL() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version_id = ?", TableName())
+}
+
+func (m BigQueryDialect) placeholder(n int) string {
+	return "?"
+}
+
+////////////////////////////
+// Trino
+////////////////////////////
+
+// TrinoDialect struct, for Trino and Presto. It is experimental: migrations
+// run without transactions, and the version table, in a catalog of a
+// connector supporting DELETE like Iceberg, has no id, the latest record
+// of a version is the one with the latest timestamp. Qualify the version
+// table with its catalog and schema, like iceberg.analytics.goose_db_version.
+type TrinoDialect struct{}
+
+func (m TrinoDialect) createVersionTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE %s (
+                version_id BIGINT,
+                is_applied BOOLEAN,
+                tstamp TIMESTAMP(6) WITH TIME ZONE,
+                build VARCHAR,
+                checksum VARCHAR,
+                duration_ms VARCHAR,
+                run_id VARCHAR
+            )`, TableName())
+}
+
+func (m TrinoDialect) insertVersionSQL() string {
+	// Trino columns have no defaults.
+	return fmt.Sprintf("INSERT INTO %s (version_id, is_applied, tstamp, build, checksum, duration_ms, run_id) VALUES (?, ?, current_timestamp(6), ?, ?, ?, ?)", TableName())
+}
+
+func (m TrinoDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s VARCHAR", TableName(), column)
+}
+
+func (m TrinoDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version_id, is_applied FROM %s ORDER BY tstamp DESC", TableName()))
+	if err != nil {
+		return nil, err
+	}
+	return rows, err
+}
+
+func (m TrinoDialect) migrationSQL() string {
+	return fmt.Sprintf("SELECT tstamp, is_applied FROM %s WHERE version_id = ? ORDER BY tstamp DESC LIMIT 1", TableName())
+}
+
+func (m TrinoDialect) deleteVersionSQL() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version_id = ?", TableName())
+}
+
+func (m TrinoDialect) placeholder(n int) string {
+	return "?"
+}
+
+////////////////////////////
+// Spanner
+////////////////////////////
+
+// SpannerDialect struct. Spanner DDL statements can't run in transactions:
+// migrations run without one, their consecutive DDL statements submitted
+// as a single schema update. The version table is keyed by version and
+// commit timestamp, without a sequential id, which would make a hotspot of
+// its last split.
+type SpannerDialect struct{}
+
+func (m SpannerDialect) createVersionTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE %s (
+                version_id INT64 NOT NULL,
+                tstamp TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp = true),
+                is_applied BOOL NOT NULL,
+                build STRING(255),
+                checksum STRING(255),
+                duration_ms STRING(255),
+                run_id STRING(255)
+            ) PRIMARY KEY (version_id, tstamp)`, TableName())
+}
+
+func (m SpannerDialect) insertVersionSQL() string {
+	return fmt.Sprintf("INSERT INTO %s (version_id, tstamp, is_applied, build, checksum, duration_ms, run_id) VALUES (@p1, PENDING_COMMIT_TIMESTAMP(), @p2, @p3, @p4, @p5, @p6)", TableName())
+}
+
+func (m SpannerDialect) addVersionColumnSQL(column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s STRING(255)", TableName(), column)
+}
+
+func (m SpannerDialect) dbVersionQuery(db *sql.DB) (*sql.Rows, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version_id, is_applied FROM %s ORDER BY tstamp DESC", TableName()))
+	if err != nil {
+		return nil, err
+	}
+	return rows, err
+}
+
+func (m SpannerDialect) migrationSQL() string {
+	return fmt.Sprintf("SELECT tstamp, is_applied FROM %s WHERE version_id = @p1 ORDER BY tstamp DESC LIMIT 1", TableName())
+}
+
+func (m SpannerDialect) deleteVersionSQL() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version_id = @p1", TableName())
+}
+
+func (m SpannerDialect) placeholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
+
+// transactional reports whether the database of the dialect has
+// transactions. Migrations run without one on the others.
+func transactional() bool {
+	switch GetDialect().(type) {
+	case *BigQueryDialect, *TrinoDialect, *SpannerDialect:
+		return false
+	case *LibSQLDialect:
+		return libsqlTransactions
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package goose
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// Dialer dials the network connections to the database servers, instead of
+// the driver, like the dialer of a Cloud SQL connector authenticating and
+// encrypting the connections itself, without a proxy sidecar.
+type Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// DialConnector creates the connector of the connections to dbstring
+// dialed with dial.
+type DialConnector func(dial Dialer, dbstring string) (driver.Connector, error)
+
+var (
+	dialer         Dialer
+	dialConnectors = map[string]DialConnector{}
+)
+
+// SetDialer sets the dialer of the connections opened by OpenDBWithDriver,
+// nil for the dialer of the driver. The driver must support custom dialers
+// through a DialConnector.
+func SetDialer(d Dialer) {
+	dialer = d
+}
+
+// WithDialer sets the dialer of the connections of one OpenDBWithDriver
+// call, like SetDialer.
+func WithDialer(d Dialer) OptionsFunc {
+	return func(o *options) { o.dialer = d }
+}
+
+// WithConnector makes OpenDBWithDriver open the connections with c,
+// ignoring the dbstring, like a connector of pgx or mysql whose dialer
+// is already configured.
+func WithConnector(c driver.Connector) OptionsFunc {
+	return func(o *options) { o.connector = c }
+}
+
+// RegisterDialConnector sets how the connections of driver, like postgres
+// or mysql, are dialed with a Dialer. goose doesn't import the drivers,
+// so the program importing them registers their connectors.
+func RegisterDialConnector(driver string, c DialConnector) {
+	dialConnectors[driver] = c
+}
+
+// dialConnector returns the connector of dbstring dialed with dial.
+func dialConnector(driver string, dial Dialer, dbstring string) (driver.Connector, error) {
+	c, ok := dialConnectors[driver]
+	if !ok {
+		return nil, errors.Errorf("custom dialers are not supported with %s", driver)
+	}
+	return c(dial, dbstring)
+}
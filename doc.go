@@ -0,0 +1,107 @@
+package goose
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+type docMigration struct {
+	Version     int64
+	Name        string
+	Description string
+	Language    string // sql or go
+	Meta        []docMeta
+	Source      string
+}
+
+type docMeta struct {
+	Key, Value string
+}
+
+// Doc writes the documentation of the migrations of dir, a changelog of the
+// schema in markdown or html format: version, description derived from the
+// file name, metadata and source of every migration, in order.
+func Doc(w io.Writer, dir, format string) error {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return err
+	}
+
+	var docs []docMigration
+	for _, m := range migrations {
+		name := filepath.Base(m.Source)
+		doc := docMigration{
+			Version:     m.Version,
+			Name:        name,
+			Description: migrationDescription(name),
+			Language:    filepath.Ext(name)[1:],
+		}
+		var source []byte
+		if doc.Language != "wasm" {
+			source, err = ioutil.ReadFile(m.Source)
+			if err != nil && !(os.IsNotExist(err) && doc.Language == "go") {
+				return errors.Wrapf(err, "failed to read migration %v", name)
+			}
+		}
+		doc.Source = string(source)
+		if doc.Source != "" && !strings.HasSuffix(doc.Source, "\n") {
+			doc.Source += "\n"
+		}
+		for k, v := range m.Meta {
+			doc.Meta = append(doc.Meta, docMeta{k, v})
+		}
+		sort.Slice(doc.Meta, func(i, j int) bool { return doc.Meta[i].Key < doc.Meta[j].Key })
+		docs = append(docs, doc)
+	}
+
+	switch format {
+	case "", "markdown":
+		return errors.Wrap(markdownDocTemplate.Execute(w, docs), "failed to execute tmpl")
+	case "html":
+		return errors.Wrap(htmlDocTemplate.Execute(w, docs), "failed to execute tmpl")
+	default:
+		return errors.Errorf("%q: unknown documentation format, must be markdown or html", format)
+	}
+}
+
+var markdownDocTemplate = template.Must(template.New("goose.markdown-doc").Parse(`# Migrations
+{{range .}}
+## {{.Version}}: {{.Description}}
+
+` + "`{{.Name}}`" + `
+{{if .Meta}}
+{{range .Meta}}- **{{.Key}}**: {{.Value}}
+{{end}}{{end}}{{if .Source}}
+` + "```{{.Language}}" + `
+{{.Source}}` + "```" + `
+{{end}}{{end}}`))
+
+var htmlDocTemplate = htmltemplate.Must(htmltemplate.New("goose.html-doc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Migrations</title>
+</head>
+<body>
+<h1>Migrations</h1>
+{{range .}}
+<section id="{{.Version}}">
+<h2>{{.Version}}: {{.Description}}</h2>
+<p><code>{{.Name}}</code></p>
+{{if .Meta}}<dl>
+{{range .Meta}}<dt>{{.Key}}</dt><dd>{{.Value}}</dd>
+{{end}}</dl>
+{{end}}{{if .Source}}<pre><code class="language-{{.Language}}">{{.Source}}</code></pre>
+{{end}}</section>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,37 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDoc(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_add_users.sql": "-- +goose Meta owner=payments\n-- +goose Up\nCREATE TABLE users (name text DEFAULT '<none>');\n",
+	})
+	defer cleanupDir()
+
+	var b strings.Builder
+	if err := Doc(&b, dir, "markdown"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## 1: add users", "- **owner**: payments", "```sql\n-- +goose Meta owner=payments\n"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected %q in markdown documentation:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	if err := Doc(&b, dir, "html"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<h2>1: add users</h2>", "<dt>owner</dt><dd>payments</dd>", "&lt;none&gt;"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected %q in html documentation:\n%s", want, b.String())
+		}
+	}
+
+	if err := Doc(&b, dir, "pdf"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
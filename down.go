@@ -6,13 +6,38 @@ import (
 )
 
 // Down rolls back a single migration from the current version.
-func Down(db *sql.DB, dir string) error {
+func Down(db *sql.DB, dir string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return downLocked(db, dir)
+	})
+}
+
+// downLocked is Down for the callers holding optionsMu.
+func downLocked(db *sql.DB, dir string) error {
+	return invoke("down", db, func() error {
+		return down(db, dir)
+	})
+}
+
+func down(db *sql.DB, dir string) error {
+	if noVersioning {
+		migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+		if err != nil {
+			return err
+		}
+		last, err := migrations.Last()
+		if err != nil {
+			return err
+		}
+		return last.Down(db)
+	}
+
 	currentVersion, err := GetDBVersion(db)
 	if err != nil {
 		return err
 	}
 
-	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
 	if err != nil {
 		return err
 	}
@@ -25,32 +50,99 @@ func Down(db *sql.DB, dir string) error {
 	return current.Down(db)
 }
 
-// DownTo rolls back migrations to a specific version.
-func DownTo(db *sql.DB, dir string, version int64) error {
-	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
-	if err != nil {
+// DownTo rolls back migrations to a specific version. The target version
+// itself stays applied, and DownTo(db, dir, 0) rolls back all migrations.
+func DownTo(db *sql.DB, dir string, version int64, opts ...OptionsFunc) error {
+	_, err := DownToVersion(db, dir, version, false, opts...)
+	return err
+}
+
+// downToLocked is DownTo for the callers holding optionsMu.
+func downToLocked(db *sql.DB, dir string, version int64) error {
+	_, err := downToVersionLocked(db, dir, version, false)
+	return err
+}
+
+// DownToVersion rolls back migrations to a specific version, and returns
+// the migrations that were rolled back, in the order they were rolled back.
+//
+// If inclusive is false, the migrations newer than version are rolled back
+// and version stays applied, like DownTo. If inclusive is true, the
+// migration with that version is rolled back too. With a 0 version, all
+// migrations are rolled back either way.
+func DownToVersion(db *sql.DB, dir string, version int64, inclusive bool, opts ...OptionsFunc) (reverted Migrations, err error) {
+	err = withOptions(opts, func() error {
+		reverted, err = downToVersionLocked(db, dir, version, inclusive)
+		return err
+	})
+	return reverted, err
+}
+
+// downToVersionLocked is DownToVersion for the callers holding optionsMu.
+func downToVersionLocked(db *sql.DB, dir string, version int64, inclusive bool) (reverted Migrations, err error) {
+	err = invoke("down-to", db, func() error {
+		reverted, err = downToVersion(db, dir, version, inclusive)
 		return err
+	})
+	return reverted, err
+}
+
+func downToVersion(db *sql.DB, dir string, version int64, inclusive bool) (Migrations, error) {
+	if version < 0 {
+		return nil, fmt.Errorf("version must not be negative (got %d)", version)
+	}
+
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted Migrations
+	if noVersioning {
+		if err := checkIrreversibleDownTo(migrations, maxVersion, version, inclusive); err != nil {
+			return nil, err
+		}
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if m.Version < version || (m.Version == version && !inclusive) {
+				break
+			}
+			if err := m.Down(db); err != nil {
+				return reverted, err
+			}
+			reverted = append(reverted, m)
+		}
+		return reverted, nil
+	}
+
+	currentVersion, err := GetDBVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIrreversibleDownTo(migrations, currentVersion, version, inclusive); err != nil {
+		return nil, err
 	}
 
 	for {
 		currentVersion, err := GetDBVersion(db)
 		if err != nil {
-			return err
+			return reverted, err
 		}
 
 		current, err := migrations.Current(currentVersion)
 		if err != nil {
 			log.Printf("goose: no migrations to run. current version: %d\n", currentVersion)
-			return nil
+			return reverted, nil
 		}
 
-		if current.Version <= version {
+		if current.Version < version || (current.Version == version && !inclusive) {
 			log.Printf("goose: no migrations to run. current version: %d\n", currentVersion)
-			return nil
+			return reverted, nil
 		}
 
 		if err = current.Down(db); err != nil {
-			return err
+			return reverted, err
 		}
+		reverted = append(reverted, current)
 	}
 }
@@ -0,0 +1,72 @@
+package goose
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestMigrations writes the SQL migrations to a new temporary directory.
+func writeTestMigrations(t *testing.T, migrations map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "goose-migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, sql := range migrations {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(sql), 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+var testMigrations = map[string]string{
+	"00001_create_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\n-- +goose Down\nDROP TABLE a;\n",
+	"00002_create_b.sql": "-- +goose Up\nCREATE TABLE b (id int);\n-- +goose Down\nDROP TABLE b;\n",
+	"00003_create_c.sql": "-- +goose Up\nCREATE TABLE c (id int);\n-- +goose Down\nDROP TABLE c;\n",
+}
+
+func TestDownToVersion(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+
+	tt := []struct {
+		version   int64
+		inclusive bool
+		reverted  []int64
+		current   int64
+	}{
+		{version: 2, inclusive: false, reverted: []int64{3}, current: 2},
+		{version: 2, inclusive: true, reverted: []int64{3, 2}, current: 1},
+		{version: 0, inclusive: false, reverted: []int64{3, 2, 1}, current: 0},
+		{version: 0, inclusive: true, reverted: []int64{3, 2, 1}, current: 0},
+		{version: 3, inclusive: false, reverted: nil, current: 3},
+	}
+
+	for i, test := range tt {
+		func() {
+			db, cleanup := openTestDB(t)
+			defer cleanup()
+
+			if err := Up(db, dir); err != nil {
+				t.Fatal(err)
+			}
+
+			reverted, err := DownToVersion(db, dir, test.version, test.inclusive)
+			if err != nil {
+				t.Fatalf("tt[%v] unexpected error: %v", i, err)
+			}
+			validateVersions(t, "DownToVersion()", reverted, test.reverted)
+
+			current, err := GetDBVersion(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current != test.current {
+				t.Errorf("tt[%v] unexpected version, got %v, want %v", i, current, test.current)
+			}
+		}()
+	}
+}
@@ -0,0 +1,139 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DownRun rolls back the migrations applied by the run with the run ID, in
+// reverse order, to revert a deploy as a unit. It fails if migrations
+// newer than the oldest one of the run were applied by other runs, unless
+// forced with SetForce or WithForce, since they may depend on the
+// migrations of the run.
+func DownRun(db *sql.DB, dir, runID string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return downRunLocked(db, dir, runID)
+	})
+}
+
+// downRunLocked is DownRun for the callers holding optionsMu.
+func downRunLocked(db *sql.DB, dir, runID string) error {
+	return invoke("down-run", db, func() error {
+		return downRun(db, dir, runID)
+	})
+}
+
+func downRun(db *sql.DB, dir, runID string) error {
+	if runID == "" {
+		return errors.New("run ID must not be empty")
+	}
+	if noVersioning {
+		return errors.New("down-run needs the version table, it can't run without versioning")
+	}
+	if _, err := EnsureDBVersion(db); err != nil {
+		return err
+	}
+	versions, later, err := runVersions(db, runID)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		return errors.Errorf("no migrations applied by run %s", runID)
+	}
+	if len(later) > 0 && !force {
+		return errors.Errorf("migrations newer than the oldest one of run %s were applied by other runs: %s; roll them back first, or force", runID, joinVersions(later))
+	}
+
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return err
+	}
+	reverted := make(Migrations, 0, len(versions))
+	for _, v := range versions {
+		m, err := migrations.Current(v)
+		if err != nil {
+			return errors.Errorf("no migration %d of run %s in %s", v, runID, dir)
+		}
+		reverted = append(reverted, m)
+	}
+	if err := checkIrreversibleDownTo(reverted, maxVersion, versions[len(versions)-1], true); err != nil {
+		return err
+	}
+	for _, m := range reverted {
+		if err := m.Down(db); err != nil {
+			return err
+		}
+	}
+	log.Printf("goose: rolled back %d migrations of run %s\n", len(reverted), runID)
+	return nil
+}
+
+// runVersions returns the versions applied by the run with runID, newest
+// first, and the versions applied by other runs after the oldest of them.
+func runVersions(db *sql.DB, runID string) (versions, later []int64, err error) {
+	latestFirst := "id DESC"
+	switch GetDialect().(type) {
+	case *ClickHouseDialect, *BigQueryDialect, *TrinoDialect, *SpannerDialect:
+		latestFirst = "tstamp DESC"
+	case *RedshiftDialect:
+		// IDENTITY values are not in insertion order on Redshift.
+		latestFirst = "tstamp DESC, id DESC"
+	}
+	q := fmt.Sprintf("SELECT version_id, is_applied, run_id FROM %s ORDER BY %s", TableName(), latestFirst)
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to query the version table")
+	}
+	defer rows.Close()
+
+	seen := map[int64]bool{}
+	var others []int64
+	for rows.Next() {
+		var (
+			version int64
+			applied bool
+			id      sql.NullString
+		)
+		if err := rows.Scan(&version, &applied, &id); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to scan row")
+		}
+		if seen[version] || version == 0 {
+			continue
+		}
+		seen[version] = true
+		switch {
+		case !applied:
+		case id.String == runID:
+			versions = append(versions, version)
+		default:
+			others = append(others, version)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get next row")
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	if len(versions) > 0 {
+		oldest := versions[len(versions)-1]
+		for _, v := range others {
+			if v > oldest {
+				later = append(later, v)
+			}
+		}
+		sort.Slice(later, func(i, j int) bool { return later[i] < later[j] })
+	}
+	return versions, later, nil
+}
+
+func joinVersions(versions []int64) string {
+	s := make([]string, len(versions))
+	for i, v := range versions {
+		s[i] = fmt.Sprint(v)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,64 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDownRun(t *testing.T) {
+	migrations := map[string]string{"00004_create_d.sql": "-- +goose Up\nCREATE TABLE d (id int);\n-- +goose Down\nDROP TABLE d;\n"}
+	for name, sql := range testMigrations {
+		migrations[name] = sql
+	}
+	dir, cleanupDir := writeTestMigrations(t, migrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	defer SetRunID("")
+
+	SetRunID("deploy-1")
+	if err := UpTo(db, dir, 1); err != nil {
+		t.Fatal(err)
+	}
+	SetRunID("deploy-2")
+	if err := UpTo(db, dir, 3); err != nil {
+		t.Fatal(err)
+	}
+	SetRunID("deploy-3")
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	SetRunID("")
+
+	if err := DownRun(db, dir, "deploy-2"); err == nil || !strings.Contains(err.Error(), "applied by other runs: 4") {
+		t.Errorf("expected the newer migrations of deploy-3 to be reported, got %v", err)
+	}
+	if err := DownRun(db, dir, "deploy-9"); err == nil || !strings.Contains(err.Error(), "no migrations applied by run deploy-9") {
+		t.Errorf("expected an unknown run to fail, got %v", err)
+	}
+	if err := DownRun(db, dir, "deploy-3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := DownRun(db, dir, "deploy-2"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 1 {
+		t.Errorf("expected version 1 after rolling back deploy-2 and deploy-3, got %d (%v)", v, err)
+	}
+
+	// Forced over the migrations of a newer run.
+	SetRunID("deploy-4")
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	SetRunID("")
+	if err := DownRun(db, dir, "deploy-1", WithForce()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT * FROM a"); err == nil {
+		t.Error("expected the migration of deploy-1 to be rolled back")
+	}
+	if _, err := db.Exec("SELECT * FROM d"); err != nil {
+		t.Errorf("expected the migrations of deploy-4 to stay applied: %v", err)
+	}
+}
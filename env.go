@@ -0,0 +1,61 @@
+package goose
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Environment variables read by FromEnv.
+const (
+	EnvTable        = "GOOSE_TABLE"
+	EnvDialect      = "GOOSE_DIALECT"
+	EnvMigrationDir = "GOOSE_MIGRATION_DIR"
+	EnvVerbose      = "GOOSE_VERBOSE"
+	EnvGates        = "GOOSE_GATES"
+	EnvSkip         = "GOOSE_SKIP"
+)
+
+// FromEnv sets the goose defaults from the environment, so that
+// deployments can configure goose without code changes:
+//
+//	GOOSE_TABLE           version table name, see SetTableName
+//	GOOSE_DIALECT         SQL dialect, see SetDialect
+//	GOOSE_VERBOSE         verbose mode, a boolean, see SetVerbose
+//	GOOSE_MIGRATION_DIR   directory of the migrations
+//	GOOSE_GATES           enabled gates, comma-separated, see SetGates
+//	GOOSE_SKIP            exclusion tags, comma-separated, see SetSkipTags
+//
+// Unset variables leave the current settings unchanged. FromEnv returns the
+// migration directory, "." if GOOSE_MIGRATION_DIR is unset.
+func FromEnv() (dir string, err error) {
+	if t := os.Getenv(EnvTable); t != "" {
+		SetTableName(t)
+	}
+	if d := os.Getenv(EnvDialect); d != "" {
+		if err := SetDialect(d); err != nil {
+			return "", errors.Wrap(err, EnvDialect)
+		}
+	}
+	if v := os.Getenv(EnvVerbose); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", errors.Errorf("%s must be a boolean (got %q)", EnvVerbose, v)
+		}
+		SetVerbose(b)
+	}
+	if g := os.Getenv(EnvGates); g != "" {
+		SetGates(strings.Split(g, ",")...)
+	}
+	if t := os.Getenv(EnvSkip); t != "" {
+		SetSkipTags(strings.Split(t, ",")...)
+	}
+
+	dir = os.Getenv(EnvMigrationDir)
+	if dir == "" {
+		dir = "."
+	}
+	return dir, nil
+}
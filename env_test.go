@@ -0,0 +1,47 @@
+package goose
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFromEnv(t *testing.T) {
+	defer SetTableName(TableName())
+	defer SetVerbose(verbose)
+	defer SetDialect("postgres")
+	for _, env := range []string{EnvTable, EnvDialect, EnvMigrationDir, EnvVerbose} {
+		defer os.Unsetenv(env)
+	}
+
+	dir, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != "." {
+		t.Errorf("unexpected default dir, got %q, want \".\"", dir)
+	}
+
+	os.Setenv(EnvTable, "schema_versions")
+	os.Setenv(EnvDialect, "mysql")
+	os.Setenv(EnvMigrationDir, "/migrations")
+	os.Setenv(EnvVerbose, "true")
+	dir, err = FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != "/migrations" || TableName() != "schema_versions" || !verbose {
+		t.Errorf("unexpected settings, got dir %q, table %q, verbose %v", dir, TableName(), verbose)
+	}
+	if _, ok := GetDialect().(*MySQLDialect); !ok {
+		t.Errorf("unexpected dialect %T", GetDialect())
+	}
+
+	os.Setenv(EnvVerbose, "loud")
+	if _, err := FromEnv(); err == nil {
+		t.Errorf("expected an error for %s=loud", EnvVerbose)
+	}
+	os.Setenv(EnvDialect, "oracle")
+	if _, err := FromEnv(); err == nil {
+		t.Errorf("expected an error for %s=oracle", EnvDialect)
+	}
+}
@@ -0,0 +1,110 @@
+package goose
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var execDriver, execDBString string
+
+// SetExecDBString sets the driver and the connection string passed to
+// external command migrations, in the GOOSE_DRIVER and GOOSE_DBSTRING
+// environment variables. They are inherited from the environment of goose
+// when not set.
+func SetExecDBString(driver, dbstring string) {
+	execDriver, execDBString = driver, dbstring
+}
+
+// execCommand returns the command running the external command migration
+// m, with the direction, up or down, as its argument: .sh migrations run
+// with sh, .cmd migrations with cmd, and Go migrations with the runner
+// built for SetGoBuild.
+func execCommand(m *Migration, direction bool) (*exec.Cmd, error) {
+	arg := "down"
+	if direction {
+		arg = "up"
+	}
+	var cmd *exec.Cmd
+	switch filepath.Ext(m.Source) {
+	case ".cmd":
+		cmd = exec.CommandContext(runCtx, "cmd", "/C", m.Source, arg)
+	case ".go":
+		binary, err := goBuildBinary(filepath.Dir(m.Source))
+		if err != nil {
+			return nil, err
+		}
+		cmd = exec.CommandContext(runCtx, binary, arg)
+	default:
+		cmd = exec.CommandContext(runCtx, "sh", m.Source, arg)
+	}
+	cmd.Dir = filepath.Dir(m.Source)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("GOOSE_VERSION=%d", m.Version), "GOOSE_DIRECTION="+arg)
+	if execDriver != "" {
+		cmd.Env = append(cmd.Env, "GOOSE_DRIVER="+execDriver, "GOOSE_DBSTRING="+execDBString)
+	}
+	if filepath.Ext(m.Source) == ".go" {
+		cmd.Env = append(cmd.Env, goBuildEnv(m)...)
+	}
+	return cmd, nil
+}
+
+// runCommand runs the command of the external command migration m, with
+// its output in the error if it fails.
+func runCommand(m *Migration, direction bool) error {
+	cmd, err := execCommand(m, direction)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	verboseInfo("Running command: %s", strings.Join(cmd.Args, " "))
+	err = cmd.Run()
+	if s := strings.TrimSpace(out.String()); s != "" {
+		verboseInfo("%s", s)
+	}
+	if err != nil {
+		// The runner of Go migrations runs them in a transaction, unlike
+		// the external commands.
+		inTx := filepath.Ext(m.Source) == ".go" && !m.NoTx && transactional()
+		return failedMigration(inTx, errors.Errorf("ERROR %v: failed to run command: %v: %s", filepath.Base(m.Source), err, strings.TrimSpace(out.String())))
+	}
+	return nil
+}
+
+// runExecMigration runs the external command migration m, for the steps
+// that can't be expressed in SQL or Go, like invoking pgloader, and records
+// its version once the command succeeded. The command can't run in the
+// transaction of goose.
+func runExecMigration(db *sql.DB, m *Migration, direction bool) error {
+	checksum, err := m.Checksum()
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	if err := runCommand(m, direction); err != nil {
+		return err
+	}
+
+	if noVersioning {
+		return nil
+	}
+	if direction {
+		if err := insertVersion(db, m.Version, direction, checksum, time.Since(started)); err != nil {
+			return errors.Wrap(err, "failed to insert new goose version")
+		}
+		return nil
+	}
+	if err := deleteVersion(db, m.Version); err != nil {
+		return errors.Wrap(err, "failed to delete goose version")
+	}
+	return nil
+}
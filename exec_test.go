@@ -0,0 +1,55 @@
+package goose
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecMigration(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\n-- +goose Down\nDROP TABLE a;\n",
+		"00002_load.sh":      "#!/bin/sh\n# +goose Meta owner=data\necho \"$1 $GOOSE_VERSION $GOOSE_DRIVER $GOOSE_DBSTRING\" >> load.log\n",
+		"00003_fail.sh":      "echo \"pgloader: connection refused\"\nexit 1\n",
+		"seed.sh":            "echo seed\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	defer SetExecDBString("", "")
+	SetExecDBString("sqlite3", "test.db")
+
+	err := Up(db, dir)
+	if err == nil || !strings.Contains(err.Error(), "00003_fail.sh: failed to run command") || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected the failing command to fail the migration, got %v", err)
+	}
+	if version, err := GetDBVersion(db); err != nil || version != 2 {
+		t.Errorf("expected version 2, got %d (%v)", version, err)
+	}
+	if err := Down(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := GetDBVersion(db); err != nil || version != 1 {
+		t.Errorf("expected version 1, got %d (%v)", version, err)
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "load.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "up 2 sqlite3 test.db\ndown 2 sqlite3 test.db\n"; string(out) != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+
+	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("expected the helper script without version to be skipped, got %v", migrations)
+	}
+	if migrations[1].Meta["owner"] != "data" {
+		t.Errorf("expected the metadata of the shell comments, got %v", migrations[1].Meta)
+	}
+}
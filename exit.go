@@ -0,0 +1,94 @@
+package goose
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Exit codes of the goose commands, for wrapper scripts and orchestrators
+// to react differently to different classes of failures. ExitCode returns
+// the exit code of an error returned by goose.
+const (
+	ExitOK               = 0 // success, including when there was nothing to migrate
+	ExitFailure          = 1 // any other failure
+	ExitUsage            = 2 // invalid flags or arguments
+	ExitUnreachable      = 3 // the database could not be reached
+	ExitPending          = 4 // migrations are pending (check)
+	ExitLockTimeout      = 5 // the lock could not be acquired in time
+	ExitChecksumMismatch = 6 // migrations changed since they were applied or planned
+	ExitSQLError         = 7 // a migration failed in a transaction, nothing was applied
+	ExitPartialApply     = 8 // a migration failed after others were applied, or midway without a transaction
+	ExitNotPrimary       = 9 // the database is a read-only replica
+)
+
+// exitError is an error of a class of failures.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Cause() error  { return e.err }
+
+// withExitCode classifies err with an exit code.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// failedMigration classifies the error of a migration failing to run: with
+// ExitSQLError if it ran in a transaction, rolled back, or ExitPartialApply
+// otherwise, as its statements before the failing one stay applied.
+func failedMigration(inTx bool, err error) error {
+	if inTx {
+		return withExitCode(ExitSQLError, err)
+	}
+	return withExitCode(ExitPartialApply, err)
+}
+
+// ExitCode returns the exit code of an error returned by goose: the code
+// of its outermost class, or ExitFailure if it is not classified.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	for err != nil {
+		if e, ok := err.(*exitError); ok {
+			return e.code
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return ExitFailure
+}
+
+// Check returns nil if db is up to date with the migrations of dir. It
+// returns an error with the ExitChecksumMismatch exit code if applied
+// migrations changed since they were applied, or with the ExitPending exit
+// code if migrations are pending, including those older than the current
+// version.
+func Check(db *sql.DB, dir string) error {
+	diff, err := GetDiff(db, dir)
+	if err != nil {
+		return err
+	}
+	if len(diff.Drifted) > 0 {
+		names := make([]string, len(diff.Drifted))
+		for i, m := range diff.Drifted {
+			names[i] = filepath.Base(m.Source)
+		}
+		return withExitCode(ExitChecksumMismatch, errors.Errorf("%d migrations changed since they were applied: %s", len(names), strings.Join(names, ", ")))
+	}
+	if pending := append(diff.OutOfOrder, diff.Pending...); len(pending) > 0 {
+		return withExitCode(ExitPending, errors.Errorf("%d migrations pending, next: %v", len(pending), filepath.Base(pending[0].Source)))
+	}
+	return nil
+}
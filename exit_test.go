@@ -0,0 +1,98 @@
+package goose
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestExitCode(t *testing.T) {
+	tt := []struct {
+		err  error
+		code int
+	}{
+		{nil, ExitOK},
+		{errors.New("boom"), ExitFailure},
+		{withExitCode(ExitSQLError, errors.New("boom")), ExitSQLError},
+		{errors.Wrap(withExitCode(ExitSQLError, errors.New("boom")), "failed"), ExitSQLError},
+		{withExitCode(ExitPartialApply, errors.Wrap(withExitCode(ExitSQLError, errors.New("boom")), "failed")), ExitPartialApply},
+	}
+	for i, test := range tt {
+		if code := ExitCode(test.err); code != test.code {
+			t.Errorf("tt[%v] unexpected exit code, got %v, want %v", i, code, test.code)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	migrations := map[string]string{}
+	for name, sql := range testMigrations {
+		migrations[name] = sql
+	}
+	migrations["00002_create_b.sql"] = "-- +goose Up\nCREATE TABLE b (id int;\n"
+
+	dir, cleanupDir := writeTestMigrations(t, migrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if code := ExitCode(Check(db, dir)); code != ExitPending {
+		t.Errorf("Check(): unexpected exit code, got %v, want %v", code, ExitPending)
+	}
+	if code := ExitCode(UpByOne(db, dir)); code != ExitOK {
+		t.Errorf("UpByOne(): unexpected exit code, got %v, want %v", code, ExitOK)
+	}
+	if code := ExitCode(UpByOne(db, dir)); code != ExitSQLError {
+		t.Errorf("UpByOne(): unexpected exit code, got %v, want %v", code, ExitSQLError)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "00002_create_b.sql"), []byte(testMigrations["00002_create_b.sql"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "00003_create_c.sql"), []byte("-- +goose Up\nCREATE TABLE c (id int;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := ExitCode(Up(db, dir)); code != ExitPartialApply {
+		t.Errorf("Up(): unexpected exit code, got %v, want %v", code, ExitPartialApply)
+	}
+	// Failing midway without a transaction.
+	if err := ioutil.WriteFile(filepath.Join(dir, "00003_create_c.sql"), []byte("-- +goose NO TRANSACTION\n-- +goose Up\nCREATE TABLE c (id int);\nCREATE TABLE d (id int;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := ExitCode(UpByOne(db, dir)); code != ExitPartialApply {
+		t.Errorf("UpByOne(): unexpected exit code, got %v, want %v", code, ExitPartialApply)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "00001_create_a.sql"), []byte("-- +goose Up\nCREATE TABLE a (id bigint);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := ExitCode(Check(db, dir)); code != ExitChecksumMismatch {
+		t.Errorf("Check(): unexpected exit code, got %v, want %v", code, ExitChecksumMismatch)
+	}
+}
+
+// heldLocker is a Locker that is always held by someone else.
+type heldLocker struct{}
+
+func (heldLocker) Lock(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (heldLocker) Unlock(ctx context.Context) error { return nil }
+
+func TestLockTimeout(t *testing.T) {
+	defer SetLocker(nil)
+	defer SetLockTimeout(0)
+	SetLocker(heldLocker{})
+	SetLockTimeout(10 * time.Millisecond)
+
+	err := withLock(func() error { return nil })
+	if code := ExitCode(err); code != ExitLockTimeout {
+		t.Errorf("unexpected exit code, got %v (%v), want %v", code, err, ExitLockTimeout)
+	}
+}
@@ -0,0 +1,228 @@
+package goose
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ExportHistory writes the migrations applied according to the version
+// table to the history table of another migration tool, so that it can
+// take over the database: flyway's flyway_schema_history, golang-migrate's
+// schema_migrations, or Rails' schema_migrations. table overrides the
+// default name of the history table, which is created if it doesn't exist,
+// and must be empty otherwise.
+func ExportHistory(db *sql.DB, dir, tool, table string) error {
+	return withOptions(nil, func() error {
+		return exportHistoryLocked(db, dir, tool, table)
+	})
+}
+
+// exportHistoryLocked is ExportHistory for the callers holding optionsMu.
+func exportHistoryLocked(db *sql.DB, dir, tool, table string) error {
+	t, ok := historyTools[tool]
+	if !ok {
+		return errors.Errorf("%q: unknown migration tool, must be flyway, golang-migrate or rails", tool)
+	}
+	if table == "" {
+		table = t.table
+	}
+	switch GetDialect().(type) {
+	case *ClickHouseDialect, *BigQueryDialect, *TrinoDialect, *SpannerDialect:
+		return errors.New("exporting history is not supported by this dialect")
+	}
+
+	return invoke("export", db, func() error {
+		return exportHistory(db, dir, tool, table)
+	})
+}
+
+// exportHistory writes the applied migrations of dir to the history table
+// of tool.
+func exportHistory(db *sql.DB, dir, tool, table string) error {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return err
+	}
+	if _, err := EnsureDBVersion(db); err != nil {
+		return errors.Wrap(err, "failed to ensure DB version")
+	}
+	versions, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	var count int
+	if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+		if _, err := db.Exec(historyTableSQL(tool, table)); err != nil {
+			return errors.Wrapf(err, "failed to create %s history table %s", tool, table)
+		}
+	} else if count > 0 {
+		return errors.Errorf("%s history table %s is not empty", tool, table)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	switch tool {
+	case "flyway":
+		err = exportFlywayHistory(tx, table, migrations, versions)
+	case "golang-migrate":
+		if len(versions) > 0 {
+			_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (version, dirty) VALUES (%s, %s)", table, GetDialect().placeholder(1), GetDialect().placeholder(2)), versions[len(versions)-1], false)
+		}
+	case "rails":
+		for _, v := range versions {
+			if _, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (version) VALUES (%s)", table, GetDialect().placeholder(1)), fmt.Sprint(v)); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to write %s history to %s", tool, table)
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	log.Printf("goose: exported %d versions to %s history in %s\n", len(versions), tool, table)
+	return nil
+}
+
+// historyTableSQL returns the statement creating the history table of
+// tool, as created by the tool.
+func historyTableSQL(tool, table string) string {
+	boolType, timeType := "BOOLEAN", "TIMESTAMP"
+	if _, ok := GetDialect().(*SqlServerDialect); ok {
+		boolType, timeType = "BIT", "DATETIME"
+	}
+	switch tool {
+	case "flyway":
+		return fmt.Sprintf(`CREATE TABLE %s (
+                installed_rank INT NOT NULL PRIMARY KEY,
+                version VARCHAR(50),
+                description VARCHAR(200) NOT NULL,
+                type VARCHAR(20) NOT NULL,
+                script VARCHAR(1000) NOT NULL,
+                checksum INT,
+                installed_by VARCHAR(100) NOT NULL,
+                installed_on %s NOT NULL,
+                execution_time INT NOT NULL,
+                success %s NOT NULL
+            )`, table, timeType, boolType)
+	case "golang-migrate":
+		return fmt.Sprintf("CREATE TABLE %s (version BIGINT NOT NULL PRIMARY KEY, dirty %s NOT NULL)", table, boolType)
+	default:
+		return fmt.Sprintf("CREATE TABLE %s (version VARCHAR(255) NOT NULL PRIMARY KEY)", table)
+	}
+}
+
+// exportFlywayHistory writes a flyway history row for each version, in
+// order. Go migrations are recorded as code migrations without checksum.
+func exportFlywayHistory(tx *sql.Tx, table string, migrations Migrations, versions []int64) error {
+	d := GetDialect()
+	q := fmt.Sprintf("INSERT INTO %s (installed_rank, version, description, type, script, checksum, installed_by, installed_on, execution_time, success) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)",
+		table, d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4), d.placeholder(5), d.placeholder(6), d.placeholder(7), d.placeholder(8), d.placeholder(9), d.placeholder(10))
+
+	for i, v := range versions {
+		var (
+			script, description string
+			typ                 = "SQL"
+			checksum            sql.NullInt64
+		)
+		if m, err := migrations.Current(v); err == nil {
+			script = filepath.Base(m.Source)
+			description = migrationDescription(script)
+			if filepath.Ext(m.Source) == ".sql" {
+				c, err := flywayChecksum(m.Source)
+				if err != nil {
+					return err
+				}
+				checksum = sql.NullInt64{Int64: int64(c), Valid: true}
+			} else {
+				typ = "JDBC"
+			}
+		}
+		row, err := migrationStatus(tx, v)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(q, i+1, fmt.Sprint(v), description, typ, script, checksum, currentUser(), row.TStamp, 0, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrationDescription returns the description of a migration from its
+// file name, in the style of flyway: "00002_add_users.sql" is "add users".
+func migrationDescription(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if i := strings.Index(name, "_"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.Replace(name, "_", " ", -1)
+}
+
+// flywayChecksum returns the checksum flyway computes for a SQL migration:
+// the CRC-32 of its lines, without line terminators nor byte order mark.
+func flywayChecksum(path string) (int32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to open migration %v", filepath.Base(path))
+	}
+	defer f.Close()
+
+	h := crc32.NewIEEE()
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), scanBufSize)
+	s.Split(scanFlywayLines)
+	first := true
+	for s.Scan() {
+		line := s.Bytes()
+		if first {
+			line = []byte(strings.TrimPrefix(string(line), "\ufeff"))
+			first = false
+		}
+		h.Write(line)
+	}
+	if err := s.Err(); err != nil {
+		return 0, errors.Wrapf(err, "failed to read migration %v", filepath.Base(path))
+	}
+	return int32(h.Sum32()), nil
+}
+
+// scanFlywayLines splits lines terminated by \n, \r or \r\n, like flyway.
+func scanFlywayLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	for i, c := range data {
+		switch c {
+		case '\n':
+			return i + 1, data[:i], nil
+		case '\r':
+			if i+1 < len(data) {
+				if data[i+1] == '\n' {
+					return i + 2, data[:i], nil
+				}
+				return i + 1, data[:i], nil
+			}
+			if atEOF {
+				return i + 1, data[:i], nil
+			}
+			return 0, nil, nil
+		}
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
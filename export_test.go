@@ -0,0 +1,107 @@
+package goose
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportHistory(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ExportHistory(db, dir, "flyway", ""); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM flyway_schema_history WHERE checksum IS NOT NULL AND success").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("unexpected number of flyway history rows, got %d, want 3", count)
+	}
+	var description string
+	if err := db.QueryRow("SELECT description FROM flyway_schema_history WHERE version = '2'").Scan(&description); err != nil {
+		t.Fatal(err)
+	}
+	if description != "create b" {
+		t.Errorf("unexpected flyway description, got %q, want %q", description, "create b")
+	}
+	if err := ExportHistory(db, dir, "flyway", ""); err == nil {
+		t.Errorf("expected an error exporting to a non-empty history table")
+	}
+
+	if err := ExportHistory(db, dir, "golang-migrate", ""); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		version int64
+		dirty   bool
+	)
+	if err := db.QueryRow("SELECT version, dirty FROM schema_migrations").Scan(&version, &dirty); err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 || dirty {
+		t.Errorf("unexpected golang-migrate version, got %d (dirty: %v), want 3", version, dirty)
+	}
+
+	if err := ExportHistory(db, dir, "rails", "ar_schema_migrations"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM ar_schema_migrations").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("unexpected number of rails versions, got %d, want 3", count)
+	}
+}
+
+func TestFlywayChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var checksums []int32
+	for i, content := range []string{
+		"CREATE TABLE a (id int);\nDROP TABLE a;\n",
+		"\ufeffCREATE TABLE a (id int);\r\nDROP TABLE a;",
+	} {
+		path := filepath.Join(dir, filepath.Base(t.Name())+string(rune('a'+i))+".sql")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c, err := flywayChecksum(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checksums = append(checksums, c)
+	}
+	if checksums[0] != checksums[1] {
+		t.Errorf("expected checksums to ignore line terminators and byte order mark, got %d and %d", checksums[0], checksums[1])
+	}
+}
+
+func TestExportHistoryLock(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	defer SetLocker(nil)
+	defer SetLockTimeout(0)
+	SetLocker(heldLocker{})
+	SetLockTimeout(10 * time.Millisecond)
+	if err := ExportHistory(db, dir, "rails", ""); ExitCode(err) != ExitLockTimeout {
+		t.Errorf("expected the export to wait for the lock, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFirebirdDialect(t *testing.T) {
+	defer SetDialect("postgres")
+	if err := SetDialect("interbase"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := GetDialect().(*FirebirdDialect); !ok {
+		t.Fatalf("expected the Firebird dialect, got %T", GetDialect())
+	}
+
+	statements := GetDialect().(*FirebirdDialect).createVersionTableStatements()
+	if len(statements) != 3 || !strings.HasPrefix(statements[1], "CREATE GENERATOR goose_db_version_id") || !strings.Contains(statements[2], "GEN_ID(goose_db_version_id, 1)") {
+		t.Errorf("unexpected version table statements %q", statements)
+	}
+	if q := GetDialect().migrationSQL(); !strings.HasSuffix(q, "ROWS 1") {
+		t.Errorf("expected ROWS instead of LIMIT, got %q", q)
+	}
+	if q, _ := listAppliedQuery(ListFilter{Limit: 10, Offset: 20}); !strings.HasSuffix(q, " ROWS 21 TO 30") {
+		t.Errorf("unexpected pagination, got %q", q)
+	}
+
+	s, err := genAddColumn([]string{"users", "plan", "VARCHAR(20)", "'free'"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(s.header, "+goose NO TRANSACTION") || s.up[0] != "ALTER TABLE users ADD plan VARCHAR(20);" {
+		t.Errorf("expected the column to be added without a transaction, got %q %q", s.header, s.up)
+	}
+	if _, err := genRenameTable([]string{"users", "accounts"}); err == nil {
+		t.Error("expected renaming tables to be unsupported")
+	}
+}
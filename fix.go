@@ -1,46 +1,73 @@
 package goose
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/loderunner/goose/version"
 )
 
 func Fix(dir string) error {
-	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
 	if err != nil {
 		return err
 	}
 
 	// split into timestamped and versioned migrations
-	tsMigrations, err := migrations.timestamped()
+	tsMigrations, err := migrations.Timestamped()
 	if err != nil {
 		return err
 	}
 
-	vMigrations, err := migrations.versioned()
+	vMigrations, err := migrations.Versioned()
 	if err != nil {
 		return err
 	}
-	// Initial version.
-	version := int64(1)
-	if last, err := vMigrations.Last(); err == nil {
-		version = last.Version + 1
+	// The first sequential version after the existing ones.
+	var versions []int64
+	for _, m := range vMigrations {
+		versions = append(versions, m.Version)
 	}
+	next := version.Next(versions, version.Sequential, time.Now())
 
 	// fix filenames by replacing timestamps with sequential versions
 	for _, tsm := range tsMigrations {
 		oldPath := tsm.Source
-		newPath := strings.Replace(oldPath, fmt.Sprintf("%d", tsm.Version), fmt.Sprintf("%05v", version), 1)
+		newPath := strings.Replace(oldPath, fmt.Sprintf("%d", tsm.Version), version.Format(next, version.Sequential), 1)
 
 		if err := os.Rename(oldPath, newPath); err != nil {
 			return err
 		}
+		if filepath.Ext(newPath) == ".go" {
+			if err := renameRegistration(newPath, filepath.Base(oldPath)); err != nil {
+				return err
+			}
+		}
 
 		log.Printf("RENAMED %s => %s", filepath.Base(oldPath), filepath.Base(newPath))
-		version++
+		next++
 	}
 
 	return nil
 }
+
+// renameRegistration rewrites the file name literal registering the Go
+// migration of path with AddNamedMigration, formerly named oldName, so that
+// it registers the version of its new name.
+func renameRegistration(path, oldName string) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	old := []byte(fmt.Sprintf("%q", oldName))
+	if !bytes.Contains(src, old) {
+		return nil
+	}
+	src = bytes.Replace(src, old, []byte(fmt.Sprintf("%q", filepath.Base(path))), -1)
+	return ioutil.WriteFile(path, src, 0644)
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -83,3 +84,22 @@ func TestFix(t *testing.T) {
 		}
 	}
 }
+
+func TestFixNamedMigration(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql":          "-- +goose Up\nCREATE TABLE a (id int);\n",
+		"20261014094734_add_users.go": "package migrations\n\nfunc init() {\n\tgoose.AddNamedMigration(\"20261014094734_add_users.go\", up, down)\n}\n",
+	})
+	defer cleanupDir()
+
+	if err := Fix(dir); err != nil {
+		t.Fatal(err)
+	}
+	src, err := ioutil.ReadFile(filepath.Join(dir, "00002_add_users.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), `goose.AddNamedMigration("00002_add_users.go", up, down)`) {
+		t.Errorf("expected the registration to be renamed, got:\n%s", src)
+	}
+}
@@ -0,0 +1,309 @@
+package goose
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fixture is a fixture file, named after the table it fills.
+type fixture struct {
+	file    string
+	table   string
+	depends []string // fixtures loaded before, declared with '+goose Depends'
+}
+
+// LoadFixtures loads the fixtures of dir into db, typically a test database
+// just migrated. Fixtures are .yml or .yaml files listing the rows of the
+// table they are named after, or .sql files of statements. A fixture
+// filling a table referencing others declares them with a '+goose Depends'
+// comment, so that they are loaded first:
+//
+//	# +goose Depends users
+//	- id: 1
+//	  user_id: 1
+//	  total: 9.99
+//
+// All the fixtures are loaded in a single transaction.
+func LoadFixtures(db *sql.DB, dir string) error {
+	fixtures, err := collectFixtures(dir)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	for _, f := range fixtures {
+		verboseInfo("Loading fixture %v", filepath.Base(f.file))
+		if err := loadFixture(tx, f); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed to load fixture %v", filepath.Base(f.file))
+		}
+	}
+	return tx.Commit()
+}
+
+// TruncateFixtures deletes the rows of the tables of the YAML fixtures of
+// dir, the tables depending on others first, so that tests can start from a
+// clean state. SQL fixtures may write to any table, and are left to the YAML
+// fixtures of their tables, or to the tests, to clean up.
+func TruncateFixtures(db *sql.DB, dir string) error {
+	fixtures, err := collectFixtures(dir)
+	if err != nil {
+		return err
+	}
+	for i := len(fixtures) - 1; i >= 0; i-- {
+		if filepath.Ext(fixtures[i].file) == ".sql" {
+			continue
+		}
+		if _, err := db.Exec("DELETE FROM " + quoteTable(fixtures[i].table)); err != nil {
+			return errors.Wrapf(err, "failed to truncate %s", fixtures[i].table)
+		}
+	}
+	return nil
+}
+
+// ResetFixtures truncates the tables of the fixtures of dir, then loads
+// them again, typically at the start of each test.
+func ResetFixtures(db *sql.DB, dir string) error {
+	if err := TruncateFixtures(db, dir); err != nil {
+		return err
+	}
+	return LoadFixtures(db, dir)
+}
+
+// collectFixtures returns the fixtures of dir, in dependency order, and in
+// the order of their names otherwise.
+func collectFixtures(dir string) ([]fixture, error) {
+	var files []string
+	for _, pattern := range []string{"*.yml", "*.yaml", "*.sql"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	byTable := map[string]*fixture{}
+	var tables []string
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read fixture %v", filepath.Base(file))
+		}
+		name := filepath.Base(file)
+		f := &fixture{file: file, table: strings.TrimSuffix(name, filepath.Ext(name))}
+		if _, ok := byTable[f.table]; ok {
+			return nil, errors.Errorf("duplicate fixtures of table %s", f.table)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			for _, prefix := range []string{"# +goose Depends ", "-- +goose Depends "} {
+				if strings.HasPrefix(line, prefix) {
+					f.depends = append(f.depends, strings.Fields(strings.TrimPrefix(line, prefix))...)
+				}
+			}
+		}
+		byTable[f.table] = f
+		tables = append(tables, f.table)
+	}
+
+	var (
+		ordered []fixture
+		state   = map[string]int{} // 1 while visiting, 2 once ordered
+		visit   func(table string, path []string) error
+	)
+	visit = func(table string, path []string) error {
+		switch state[table] {
+		case 1:
+			return errors.Errorf("fixtures depend on each other: %s", strings.Join(append(path, table), " -> "))
+		case 2:
+			return nil
+		}
+		f, ok := byTable[table]
+		if !ok {
+			return errors.Errorf("fixture of %s depends on %s, which has no fixture", path[len(path)-1], table)
+		}
+		state[table] = 1
+		for _, dep := range f.depends {
+			if err := visit(dep, append(path, table)); err != nil {
+				return err
+			}
+		}
+		state[table] = 2
+		ordered = append(ordered, *f)
+		return nil
+	}
+	for _, table := range tables {
+		if err := visit(table, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func loadFixture(tx *sql.Tx, f fixture) error {
+	data, err := ioutil.ReadFile(f.file)
+	if err != nil {
+		return err
+	}
+
+	if filepath.Ext(f.file) == ".sql" {
+		// SQL fixtures are parsed like the up part of a SQL migration.
+		statements, _, err := parseSQLMigration(strings.NewReader("-- +goose Up\n"+string(data)), true)
+		if err != nil {
+			return err
+		}
+		for _, query := range statements {
+			if _, err := tx.Exec(query); err != nil {
+				return errors.Wrapf(err, "failed to execute SQL query %q", clearStatement(query))
+			}
+		}
+		return nil
+	}
+
+	rows, err := parseFixtureYAML(data)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		columns := make([]string, len(row))
+		placeholders := make([]string, len(row))
+		args := make([]interface{}, len(row))
+		for i, cell := range row {
+			columns[i] = QuoteIdent(cell.column)
+			placeholders[i] = Placeholder(i + 1)
+			args[i] = cell.value
+		}
+		q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteTable(f.table), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+		if _, err := tx.Exec(q, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quoteTable quotes a table name, qualified with its schema as in
+// public.users.
+func quoteTable(table string) string {
+	return QuoteIdent(strings.Split(table, ".")...)
+}
+
+type fixtureCell struct {
+	column string
+	value  interface{}
+}
+
+// parseFixtureYAML parses the rows of a YAML fixture, a list of mappings of
+// columns to scalars, with # comments:
+//
+//	# users.yml
+//	- id: 1
+//	  name: "Ada Lovelace" # the first
+//	  deleted_at: null
+//
+// goose doesn't import a YAML parser, so anything outside of this subset,
+// like nested, flow or block values, anchors and tags, is an error.
+func parseFixtureYAML(data []byte) ([][]fixtureCell, error) {
+	var rows [][]fixtureCell
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "- "):
+			rows = append(rows, nil)
+			line = line[2:]
+		case strings.HasPrefix(line, "  ") && len(rows) > 0:
+			line = line[2:]
+		default:
+			return nil, errors.Errorf("line %d: fixtures must be a list of rows, like '- column: value'", n)
+		}
+		i := strings.Index(line, ":")
+		if i <= 0 || strings.ContainsAny(line[:i], " \t") {
+			return nil, errors.Errorf("line %d: invalid column, expected 'column: value'", n)
+		}
+		value, err := parseFixtureScalar(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], fixtureCell{column: line[:i], value: value})
+	}
+	return rows, scanner.Err()
+}
+
+func parseFixtureScalar(s string) (interface{}, error) {
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+		return parseFixtureQuoted(s)
+	}
+	if strings.HasPrefix(s, "#") {
+		s = ""
+	} else if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	switch {
+	case s == "" || s == "null" || s == "~":
+		return nil, nil
+	case s == "true" || s == "false":
+		return s == "true", nil
+	case strings.ContainsAny(s[:1], "[{|>&*!%@`"):
+		return nil, errors.Errorf("unsupported YAML value %s, fixtures only support scalars", s)
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return s, nil
+}
+
+// parseFixtureQuoted parses the quoted string starting s, followed by
+// nothing but a comment.
+func parseFixtureQuoted(s string) (interface{}, error) {
+	quote, end := s[0], -1
+	for i := 1; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			if quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, errors.Errorf("unterminated string %s", s)
+	}
+	rest := s[end+1:]
+	if comment := strings.TrimLeft(rest, " \t"); rest != "" && (comment == rest || !strings.HasPrefix(comment, "#")) {
+		return nil, errors.Errorf("unexpected %s after string %s", rest, s[:end+1])
+	}
+	if quote == '\'' {
+		return strings.Replace(s[1:end], "''", "'", -1), nil
+	}
+	value, err := strconv.Unquote(s[:end+1])
+	if err != nil {
+		return nil, errors.Errorf("invalid string %s", s[:end+1])
+	}
+	return value, nil
+}
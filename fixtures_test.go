@@ -0,0 +1,111 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	defer SetLogger(log)
+	SetLogger(&bufferLogger{})
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	if _, err := db.Exec("CREATE TABLE users (id int PRIMARY KEY, name text, admin bool); CREATE TABLE orders (id int, user_id int REFERENCES users (id), total real, note text)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatal(err)
+	}
+
+	// orders sorts before users, but depends on it.
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"orders.yml": "# +goose Depends users users2\n- id: 1\n  user_id: 2\n  total: 9.99\n  note: null\n- id: 2\n  user_id: 1\n  total: 10\n  note: 'it''s paid'\n",
+		"users.yaml": "- id: 1\n  name: \"Ada Lovelace\"\n  admin: true\n",
+		"users2.sql": "-- +goose Depends users\nINSERT INTO users (id, name, admin) VALUES (2, 'Alan Turing', 0);\n",
+	})
+	defer cleanupDir()
+	for i := 0; i < 2; i++ {
+		if err := ResetFixtures(db, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	rows, err := db.Query("SELECT u.name || ':' || COALESCE(o.note, '') FROM orders o JOIN users u ON u.id = o.user_id ORDER BY o.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if got := strings.Join(names, ","); got != "Alan Turing:,Ada Lovelace:it's paid" {
+		t.Errorf("unexpected rows %q", got)
+	}
+
+	if err := TruncateFixtures(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil || count != 0 {
+		t.Errorf("expected no users, got %d (%v)", count, err)
+	}
+}
+
+func TestCollectFixturesCycle(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"a.yml": "# +goose Depends b\n- id: 1\n",
+		"b.yml": "# +goose Depends a\n- id: 1\n",
+	})
+	defer cleanupDir()
+	if _, err := collectFixtures(dir); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("expected a dependency cycle error, got %v", err)
+	}
+}
+
+func TestParseFixtureYAML(t *testing.T) {
+	rows, err := parseFixtureYAML([]byte("---\n- id: 1 # the first\n  price: 1.5\n  tag: ~\n  ok: false\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fixtureCell{{"id", int64(1)}, {"price", 1.5}, {"tag", nil}, {"ok", false}}
+	if len(rows) != 1 || len(rows[0]) != len(want) {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+	for i, cell := range rows[0] {
+		if cell != want[i] {
+			t.Errorf("expected %v, got %v", want[i], cell)
+		}
+	}
+	if _, err := parseFixtureYAML([]byte("id: 1\n")); err == nil {
+		t.Error("expected an error for a mapping instead of a list")
+	}
+
+	rows, err = parseFixtureYAML([]byte("- name: \"Ada\" # note\n  quote: 'it''s' # note\n  hash: \"a # b\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []fixtureCell{{"name", "Ada"}, {"quote", "it's"}, {"hash", "a # b"}}
+	for i, cell := range rows[0] {
+		if cell != want[i] {
+			t.Errorf("expected %v, got %v", want[i], cell)
+		}
+	}
+	for _, data := range []string{
+		"- tags: [a, b]\n",
+		"- address: {city: Paris}\n",
+		"- bio: |\n",
+		"- id: &first 1\n",
+		"- name: \"Ada\" Lovelace\n",
+		"- name: \"Ada\n",
+		"- address:\n    city: Paris\n",
+	} {
+		if _, err := parseFixtureYAML([]byte(data)); err == nil {
+			t.Errorf("expected an error for the unsupported YAML %q", data)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package goose
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+var gates = map[string]bool{}
+
+// SetGates sets the enabled gates. A migration gated behind a flag, with
+// a '+goose Gate' annotation, is only applied once its gate is enabled, so
+// that schema changes can ship disabled ahead of the code using them.
+func SetGates(names ...string) {
+	gates = enabledGates(names)
+}
+
+// WithGates enables the gates, like SetGates.
+func WithGates(names ...string) OptionsFunc {
+	return func(o *options) { o.gates = enabledGates(names) }
+}
+
+func enabledGates(names []string) map[string]bool {
+	enabled := map[string]bool{}
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			enabled[name] = true
+		}
+	}
+	return enabled
+}
+
+// Gate returns the name of the flag the migration is gated behind,
+// declared with a '+goose Gate' annotation or the gate metadata, or an
+// empty string if the migration is not gated.
+func (m *Migration) Gate() string {
+	return m.Meta["gate"]
+}
+
+// Gated reports whether the migration is gated behind a disabled flag.
+func (m *Migration) Gated() bool {
+	return m.Gate() != "" && !gates[m.Gate()]
+}
+
+// ungated returns the migrations that are not gated behind a disabled
+// flag, logging the ones skipped.
+func ungated(migrations Migrations) Migrations {
+	var open Migrations
+	for _, m := range migrations {
+		if m.Gated() {
+			log.Printf("goose: skipping %v, gated behind %s\n", filepath.Base(m.Source), m.Gate())
+			continue
+		}
+		open = append(open, m)
+	}
+	return open
+}
+
+// openedGates returns the migrations gated behind an enabled flag.
+func openedGates(migrations Migrations) Migrations {
+	var opened Migrations
+	for _, m := range migrations {
+		if m.Gate() != "" && !m.Gated() {
+			opened = append(opened, m)
+		}
+	}
+	return opened
+}
@@ -0,0 +1,44 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestGates(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\n",
+		"00002_b.sql": "-- +goose Gate new-billing\n-- +goose Up\nCREATE TABLE b (id int);\n",
+		"00003_c.sql": "-- +goose Up\nCREATE TABLE c (id int);\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	defer SetGates()
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := dbMigrationsStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[1] || statuses[2] || !statuses[3] {
+		t.Errorf("expected the gated migration to be skipped, got %v", statuses)
+	}
+	if err := Check(db, dir); err != nil {
+		t.Errorf("expected the gated migration not to be pending, got %v", err)
+	}
+
+	if err := Up(db, dir, WithGates("new-billing")); err != nil {
+		t.Fatal(err)
+	}
+	if statuses, err = dbMigrationsStatus(db); err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[2] {
+		t.Errorf("expected the gated migration to be applied once its gate is enabled")
+	}
+	if gates["new-billing"] {
+		t.Errorf("expected WithGates to only enable the gate for one call")
+	}
+}
@@ -0,0 +1,182 @@
+package goose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// snippet is a migration written by a generator.
+type snippet struct {
+	name     string
+	header   string
+	up, down []string
+}
+
+// errGenUsage is returned by the generators given invalid arguments.
+var errGenUsage = errors.New("invalid generator arguments")
+
+// generators write migrations of common patterns for the current dialect,
+// from their arguments.
+var generators = map[string]struct {
+	usage string
+	gen   func(args []string) (*snippet, error)
+}{
+	"add-column":   {"TABLE COLUMN TYPE BACKFILL", genAddColumn},
+	"create-index": {"TABLE COLUMN...", genCreateIndex},
+	"rename-table": {"TABLE NEW_NAME", genRenameTable},
+}
+
+// Gen writes a new SQL migration to dir with the generator name, for the
+// current dialect:
+//
+//	add-column TABLE COLUMN TYPE BACKFILL   add a NOT NULL column, backfilling the existing rows
+//	create-index TABLE COLUMN...            create an index without blocking writes
+//	rename-table TABLE NEW_NAME             rename a table, with a view of the old name for old code
+//
+// It returns the path of the migration.
+func Gen(dir, name string, args ...string) (string, error) {
+	g, ok := generators[name]
+	if !ok {
+		names := make([]string, 0, len(generators))
+		for name := range generators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", errors.Errorf("%q: unknown generator, must be one of %s", name, strings.Join(names, ", "))
+	}
+	s, err := g.gen(args)
+	if err == errGenUsage {
+		return "", errors.Errorf("%s must be of form: goose [OPTIONS] gen DRIVER %s %s", name, name, g.usage)
+	}
+	if err != nil {
+		return "", err
+	}
+	return writeSchemaMigration(dir, s.name, s.header, s.up, s.down)
+}
+
+func genAddColumn(args []string) (*snippet, error) {
+	if len(args) != 4 {
+		return nil, errGenUsage
+	}
+	table, column, typ, backfill := args[0], args[1], args[2], args[3]
+
+	s := &snippet{
+		name: fmt.Sprintf("add_%s_to_%s", column, table),
+		header: "-- The column is added nullable and backfilled before it is made NOT NULL,\n" +
+			"-- so that adding it doesn't rewrite the table. Backfill very large tables\n" +
+			"-- in batches instead.\n",
+		up: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, typ),
+			fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL;", table, column, backfill, column),
+		},
+		down: []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, column)},
+	}
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		s.up = append(s.up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", table, column))
+	case *RedshiftDialect:
+		// Redshift can't make a column NOT NULL after adding it, it adds
+		// it with its default value instead.
+		s.up = []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s DEFAULT %s NOT NULL;", table, column, typ, backfill)}
+	case *MySQLDialect, *MariaDBDialect, *TiDBDialect:
+		s.up = append(s.up, fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s NOT NULL;", table, column, typ))
+	case *SpannerDialect:
+		s.up = append(s.up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s NOT NULL;", table, column, typ))
+	case *SqlServerDialect:
+		s.up[0] = fmt.Sprintf("ALTER TABLE %s ADD %s %s;", table, column, typ)
+		s.up = append(s.up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s NOT NULL;", table, column, typ))
+	case *Sqlite3Dialect, *LibSQLDialect:
+		s.up = append(s.up, fmt.Sprintf("-- SQLite can't make %s.%s NOT NULL without rebuilding the table.", table, column))
+	case *FirebirdDialect:
+		s.header += "-- Firebird adds the column when its transaction commits, the backfill\n" +
+			"-- runs without one.\n" +
+			"-- +goose NO TRANSACTION\n"
+		s.up[0] = fmt.Sprintf("ALTER TABLE %s ADD %s %s;", table, column, typ)
+		s.up = append(s.up, fmt.Sprintf("ALTER TABLE %s ALTER %s SET NOT NULL;", table, column))
+		s.down = []string{fmt.Sprintf("ALTER TABLE %s DROP %s;", table, column)}
+	case *HanaDialect:
+		s.up[0] = fmt.Sprintf("ALTER TABLE %s ADD (%s %s);", table, column, typ)
+		s.up = append(s.up, fmt.Sprintf("ALTER TABLE %s ALTER (%s %s NOT NULL);", table, column, typ))
+		s.down = []string{fmt.Sprintf("ALTER TABLE %s DROP (%s);", table, column)}
+	case *ClickHouseDialect:
+		// ClickHouse columns are not nullable by default, backfilled with
+		// their default value.
+		s.up = []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s DEFAULT %s;", table, column, typ, backfill)}
+	}
+	return s, nil
+}
+
+func genCreateIndex(args []string) (*snippet, error) {
+	if len(args) < 2 {
+		return nil, errGenUsage
+	}
+	table, columns := args[0], args[1:]
+	index := table + "_" + strings.Join(columns, "_") + "_idx"
+	on := fmt.Sprintf("%s ON %s (%s)", index, table, strings.Join(columns, ", "))
+
+	s := &snippet{name: "create_" + index}
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		s.header = "-- CREATE INDEX CONCURRENTLY doesn't block writes, but can't run in a\n" +
+			"-- transaction. If it fails, it leaves an invalid index: drop it and retry.\n" +
+			"-- +goose NO TRANSACTION\n"
+		s.up = []string{fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s;", on)}
+		s.down = []string{fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s;", index)}
+	case *MySQLDialect, *MariaDBDialect, *TiDBDialect:
+		s.up = []string{fmt.Sprintf("CREATE INDEX %s ALGORITHM=INPLACE LOCK=NONE;", on)}
+		s.down = []string{fmt.Sprintf("DROP INDEX %s ON %s;", index, table)}
+	case *SqlServerDialect:
+		s.header = "-- Online index operations require the Enterprise edition.\n"
+		s.up = []string{fmt.Sprintf("CREATE INDEX %s WITH (ONLINE = ON);", on)}
+		s.down = []string{fmt.Sprintf("DROP INDEX %s ON %s;", index, table)}
+	case *HanaDialect, *FirebirdDialect:
+		// HANA and Firebird have no IF NOT EXISTS.
+		s.up = []string{fmt.Sprintf("CREATE INDEX %s;", on)}
+		s.down = []string{fmt.Sprintf("DROP INDEX %s;", index)}
+	case *RedshiftDialect, *ClickHouseDialect, *TrinoDialect:
+		return nil, errors.Errorf("create-index is not supported by the %T dialect", GetDialect())
+	default:
+		s.up = []string{fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s;", on)}
+		s.down = []string{fmt.Sprintf("DROP INDEX IF EXISTS %s;", index)}
+	}
+	return s, nil
+}
+
+func genRenameTable(args []string) (*snippet, error) {
+	if len(args) != 2 {
+		return nil, errGenUsage
+	}
+	table, newName := args[0], args[1]
+	switch GetDialect().(type) {
+	case *FirebirdDialect:
+		return nil, errors.New("rename-table is not supported by the Firebird dialect, which can't rename tables")
+	case *SpannerDialect:
+		return nil, errors.New("rename-table is not supported by the Spanner dialect, whose views need SQL SECURITY INVOKER")
+	}
+
+	rename := func(from, to string) string {
+		switch GetDialect().(type) {
+		case *MySQLDialect, *MariaDBDialect, *TiDBDialect, *ClickHouseDialect, *HanaDialect:
+			return fmt.Sprintf("RENAME TABLE %s TO %s;", from, to)
+		case *SqlServerDialect:
+			return fmt.Sprintf("EXEC sp_rename '%s', '%s';", from, to)
+		}
+		return fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", from, to)
+	}
+	return &snippet{
+		name: fmt.Sprintf("rename_%s_to_%s", table, newName),
+		header: "-- The view of the old name keeps the code using it working until it is\n" +
+			"-- deployed with the new name. Drop the view in a later migration.\n",
+		up: []string{
+			rename(table, newName),
+			fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM %s;", table, newName),
+		},
+		down: []string{
+			fmt.Sprintf("DROP VIEW %s;", table),
+			rename(newName, table),
+		},
+	}, nil
+}
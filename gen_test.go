@@ -0,0 +1,63 @@
+package goose
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGen(t *testing.T) {
+	tests := []struct {
+		generator string
+		args      []string
+		check     string
+		down      bool // older SQLite versions can't drop columns
+	}{
+		{"add-column", []string{"users", "plan", "text", "'free'"}, "SELECT COUNT(*) FROM users WHERE plan = 'free'", false},
+		{"create-index", []string{"users", "name"}, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'users_name_idx'", true},
+		{"rename-table", []string{"users", "accounts"}, "SELECT COUNT(*) FROM users JOIN accounts USING (id)", true},
+	}
+	for _, test := range tests {
+		db, cleanup := openTestDB(t)
+		defer cleanup()
+		if _, err := db.Exec("CREATE TABLE users (id int, name text); INSERT INTO users VALUES (1, 'alice');"); err != nil {
+			t.Fatal(err)
+		}
+		dir, err := ioutil.TempDir("", "goose")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		path, err := Gen(dir, test.generator, test.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Up(db, dir); err != nil {
+			t.Fatalf("%s: %v", test.generator, err)
+		}
+		var n int
+		if err := db.QueryRow(test.check).Scan(&n); err != nil || n != 1 {
+			t.Errorf("%s: unexpected result of the migration, got %d (%v)", test.generator, n, err)
+		}
+		if test.down {
+			if err := Down(db, dir); err != nil {
+				t.Errorf("%s: unexpected error rolling back: %v", test.generator, err)
+			}
+			if err := Up(db, dir); err != nil {
+				t.Errorf("%s: unexpected error applying again: %v", test.generator, err)
+			}
+		}
+		if !strings.HasSuffix(path, ".sql") {
+			t.Errorf("unexpected migration path %s", path)
+		}
+	}
+
+	if _, err := Gen(".", "add-column", "users"); err == nil || !strings.Contains(err.Error(), "must be of form") {
+		t.Errorf("expected a usage error, got %v", err)
+	}
+	if _, err := Gen(".", "unknown"); err == nil {
+		t.Errorf("expected an error for an unknown generator")
+	}
+}
@@ -0,0 +1,261 @@
+package goose
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	goBuild        bool
+	goBuildImports []string
+	goBuildConn    GoBuildConnection
+	goBuildsMu     sync.Mutex
+	goBuilds       = map[string]string{} // directory of the Go migrations: runner binary
+)
+
+// SetGoBuild sets whether the Go migrations not registered in the running
+// binary, like those of the standalone goose command, are built with the go
+// command into a temporary runner, once per directory, and run by it like
+// external command migrations. The runner blank-imports the packages of
+// imports, like the database driver. The migrations directory must be in a
+// Go module, or GOPATH, providing goose and the imports. Call
+// RemoveGoBuilds to remove the runners.
+func SetGoBuild(enabled bool, imports ...string) {
+	goBuild, goBuildImports = enabled, imports
+}
+
+// GoBuildConnection is how the runners of SetGoBuild connect to the
+// database of SetExecDBString, like the goose command opening it.
+type GoBuildConnection struct {
+	Auth string // authentication method, see AuthCredentials
+}
+
+// SetGoBuildConnection sets how the runners of SetGoBuild connect to the
+// database. The version table, role, session setup and run ID of goose are
+// passed to them.
+func SetGoBuildConnection(c GoBuildConnection) {
+	goBuildConn = c
+}
+
+// RemoveGoBuilds removes the runners built for SetGoBuild.
+func RemoveGoBuilds() {
+	goBuildsMu.Lock()
+	defer goBuildsMu.Unlock()
+	for dir, binary := range goBuilds {
+		os.RemoveAll(filepath.Dir(binary))
+		delete(goBuilds, dir)
+	}
+}
+
+// goBuildBinary returns the runner of the Go migrations of dir, building it
+// the first time.
+func goBuildBinary(dir string) (string, error) {
+	goBuildsMu.Lock()
+	defer goBuildsMu.Unlock()
+	if binary, ok := goBuilds[dir]; ok {
+		return binary, nil
+	}
+	binary, err := buildGoMigrations(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to build the Go migrations of %s", dir)
+	}
+	goBuilds[dir] = binary
+	return binary, nil
+}
+
+var goBuildMainTemplate = template.Must(template.New("goose.go-build-main").Parse(`// Code generated by goose. DO NOT EDIT.
+
+package main
+
+import (
+	"github.com/loderunner/goose"
+{{range .}}	_ {{printf "%q" .}}
+{{end}})
+
+func main() {
+	goose.GoBuildMain()
+}
+`))
+
+// buildGoMigrations builds the Go migrations of dir into a binary, in a
+// temporary directory of dir so that it resolves the packages of their
+// module. The files of their package, including the helpers without
+// version, are copied into a main package, but the one declaring the main
+// function of a custom goose binary.
+func buildGoMigrations(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	// The underscore keeps the temporary package out of ./... patterns.
+	tmp, err := ioutil.TempDir(dir, "_goose_build")
+	if err != nil {
+		return "", err
+	}
+	if tmp, err = filepath.Abs(tmp); err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			os.RemoveAll(tmp)
+			return "", err
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, src, 0)
+		if err != nil {
+			os.RemoveAll(tmp)
+			return "", err
+		}
+		if declaresMain(f) {
+			// The main function of a custom goose binary.
+			continue
+		}
+		start, end := int(f.Name.Pos())-1, int(f.Name.End())-1
+		src = append(append(append([]byte{}, src[:start]...), "main"...), src[end:]...)
+		if err := ioutil.WriteFile(filepath.Join(tmp, filepath.Base(file)), src, 0644); err != nil {
+			os.RemoveAll(tmp)
+			return "", err
+		}
+	}
+
+	var main bytes.Buffer
+	if err := goBuildMainTemplate.Execute(&main, goBuildImports); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "goose_main.go"), main.Bytes(), 0644); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+
+	binary := filepath.Join(tmp, "goose-migrations")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	log.Printf("goose: building the Go migrations of %s\n", dir)
+	cmd := exec.CommandContext(runCtx, "go", "build", "-o", binary, "."+string(filepath.Separator)+filepath.Base(tmp))
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(tmp)
+		return "", errors.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return binary, nil
+}
+
+// declaresMain reports whether f declares a main function.
+func declaresMain(f *ast.File) bool {
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+			return true
+		}
+	}
+	return false
+}
+
+// goBuildEnv returns the environment variables passing the settings of
+// goose to the runner of the Go migration m.
+func goBuildEnv(m *Migration) []string {
+	setup, _ := json.Marshal(sessionSetup)
+	env := []string{
+		"GOOSE_SOURCE=" + m.Source,
+		"GOOSE_TABLE=" + TableName(),
+		"GOOSE_ROLE=" + role,
+		"GOOSE_SESSION_SETUP=" + string(setup),
+		"GOOSE_RUN_ID=" + currentRunID(),
+		"GOOSE_NO_VERSIONING=" + strconv.FormatBool(noVersioning),
+	}
+	if goBuildConn.Auth != "" {
+		env = append(env, "GOOSE_AUTH="+goBuildConn.Auth)
+	}
+	return env
+}
+
+// GoBuildMain is the main function of the runners built for SetGoBuild. It
+// runs the registered Go migration of $GOOSE_VERSION in $GOOSE_DIRECTION on
+// the database of $GOOSE_DRIVER and $GOOSE_DBSTRING, with the settings of
+// the goose command running it, recording its version in the transaction
+// of the migration.
+func GoBuildMain() {
+	if err := runGoBuild(); err != nil {
+		log.Fatalf("goose: %v\n", err)
+	}
+}
+
+func runGoBuild() error {
+	version, err := strconv.ParseInt(os.Getenv("GOOSE_VERSION"), 10, 64)
+	if err != nil {
+		return errors.Wrap(err, "invalid GOOSE_VERSION")
+	}
+	direction := os.Getenv("GOOSE_DIRECTION")
+	if direction != "up" && direction != "down" {
+		return errors.Errorf("GOOSE_DIRECTION must be up or down, got %q", direction)
+	}
+	for _, m := range registeredGoMigrations.all() {
+		if m.Version != version {
+			continue
+		}
+		if source := os.Getenv("GOOSE_SOURCE"); source != "" {
+			// The checksum and metadata of the original file.
+			m.Source = source
+		}
+		if err := setGoBuildSettings(); err != nil {
+			return err
+		}
+		db, err := openGoBuildDB(os.Getenv("GOOSE_DRIVER"), os.Getenv("GOOSE_DBSTRING"))
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return m.run(db, direction == "up")
+	}
+	return errors.Errorf("no Go migration %d", version)
+}
+
+// setGoBuildSettings sets the settings passed to the runner by goBuildEnv.
+func setGoBuildSettings() error {
+	if table := os.Getenv("GOOSE_TABLE"); table != "" {
+		SetTableName(table)
+	}
+	SetRole(os.Getenv("GOOSE_ROLE"))
+	if setup := os.Getenv("GOOSE_SESSION_SETUP"); setup != "" {
+		var statements []string
+		if err := json.Unmarshal([]byte(setup), &statements); err != nil {
+			return errors.Wrap(err, "invalid GOOSE_SESSION_SETUP")
+		}
+		SetSessionSetup(statements...)
+	}
+	SetRunID(os.Getenv("GOOSE_RUN_ID"))
+	SetNoVersioning(os.Getenv("GOOSE_NO_VERSIONING") == "true")
+	return nil
+}
+
+// openGoBuildDB opens the database of the runner like the goose command
+// running it, with $GOOSE_AUTH.
+func openGoBuildDB(driver, dbstring string) (*sql.DB, error) {
+	if auth := os.Getenv("GOOSE_AUTH"); auth != "" {
+		creds, err := AuthCredentials(auth)
+		if err != nil {
+			return nil, err
+		}
+		return OpenDBWithCredentials(driver, dbstring, creds)
+	}
+	return OpenDBWithDriver(driver, dbstring)
+}
@@ -1,18 +1,22 @@
 package goose
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"os/user"
 	"strconv"
+	"strings"
 )
 
 const VERSION = "v2.7.0-rc3"
 
 var (
-	minVersion         = int64(0)
-	maxVersion         = int64((1 << 63) - 1)
-	timestampFormat    = "20060102150405"
-	verbose            = false
+	minVersion = int64(0)
+	maxVersion = int64((1 << 63) - 1)
+	verbose    = false
+	streaming  = false
 )
 
 // SetVerbose set the goose verbosity mode
@@ -20,15 +24,54 @@ func SetVerbose(v bool) {
 	verbose = v
 }
 
+// SetStreaming sets the goose streaming mode. In streaming mode, SQL
+// migration statements are executed as they are read from the file,
+// instead of loading the whole migration in memory first. This keeps
+// memory use bounded for very large data migrations.
+func SetStreaming(s bool) {
+	streaming = s
+}
+
+// currentUser returns the name of the user running goose.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
 // Run runs a goose command.
 func Run(command string, db *sql.DB, dir string, args ...string) error {
+	switch command {
+	case "serve":
+		addr := "localhost:8080"
+		if len(args) > 0 {
+			addr = args[0]
+		}
+		return Serve(db, dir, addr, os.Getenv("GOOSE_ADMIN_TOKEN"))
+	case "watch":
+		return NewWatcher(db, dir).Watch(context.Background())
+	case "browse":
+		return Browse(db, dir, os.Stdin, os.Stdout)
+	}
+
+	// The long-running commands above serialize each command they run,
+	// while the others run at once.
+	return withOptions(nil, func() error {
+		return runLocked(command, db, dir, args...)
+	})
+}
+
+// runLocked is Run for the callers holding optionsMu, for the commands
+// other than serve, watch and browse.
+func runLocked(command string, db *sql.DB, dir string, args ...string) error {
 	switch command {
 	case "up":
-		if err := Up(db, dir); err != nil {
+		if err := upLocked(db, dir); err != nil {
 			return err
 		}
 	case "up-by-one":
-		if err := UpByOne(db, dir); err != nil {
+		if err := upByOneLocked(db, dir); err != nil {
 			return err
 		}
 	case "up-to":
@@ -40,7 +83,7 @@ func Run(command string, db *sql.DB, dir string, args ...string) error {
 		if err != nil {
 			return fmt.Errorf("version must be a number (got '%s')", args[0])
 		}
-		if err := UpTo(db, dir, version); err != nil {
+		if err := upToLocked(db, dir, version); err != nil {
 			return err
 		}
 	case "create":
@@ -56,7 +99,7 @@ func Run(command string, db *sql.DB, dir string, args ...string) error {
 			return err
 		}
 	case "down":
-		if err := Down(db, dir); err != nil {
+		if err := downLocked(db, dir); err != nil {
 			return err
 		}
 	case "down-to":
@@ -68,7 +111,229 @@ func Run(command string, db *sql.DB, dir string, args ...string) error {
 		if err != nil {
 			return fmt.Errorf("version must be a number (got '%s')", args[0])
 		}
-		if err := DownTo(db, dir, version); err != nil {
+		if err := downToLocked(db, dir, version); err != nil {
+			return err
+		}
+	case "down-run":
+		if len(args) == 0 {
+			return fmt.Errorf("down-run must be of form: goose [OPTIONS] DRIVER DBSTRING down-run RUN_ID")
+		}
+		if err := downRunLocked(db, dir, args[0]); err != nil {
+			return err
+		}
+	case "plan":
+		plan, err := CreatePlan(db, dir)
+		if err != nil {
+			return err
+		}
+		migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+		if err != nil {
+			return err
+		}
+		printPlan(plan, migrations)
+	case "approve":
+		if len(args) == 0 {
+			return fmt.Errorf("approve must be of form: goose [OPTIONS] DRIVER DBSTRING approve PLAN [APPROVER]")
+		}
+
+		approver := currentUser()
+		if len(args) > 1 {
+			approver = args[1]
+		}
+		if err := ApprovePlan(db, args[0], approver); err != nil {
+			return err
+		}
+		log.Printf("goose: plan %s approved by %s\n", args[0], approver)
+	case "apply":
+		if len(args) == 0 {
+			return fmt.Errorf("apply must be of form: goose [OPTIONS] DRIVER DBSTRING apply PLAN")
+		}
+		if err := applyPlanLocked(db, dir, args[0]); err != nil {
+			return err
+		}
+	case "gen":
+		if len(args) == 0 {
+			return fmt.Errorf("gen must be of form: goose [OPTIONS] gen DRIVER GENERATOR ARGS...")
+		}
+		if _, err := Gen(dir, args[0], args[1:]...); err != nil {
+			return err
+		}
+	case "script":
+		if len(args) == 0 || (args[0] != "up" && args[0] != "up-to") || (args[0] == "up-to" && len(args) < 2) {
+			return fmt.Errorf("script must be of form: goose [OPTIONS] script DRIVER up [FROM] | up-to VERSION [FROM]")
+		}
+
+		to, from := maxVersion, minVersion
+		rest := args[1:]
+		if args[0] == "up-to" {
+			version, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("version must be a number (got '%s')", args[1])
+			}
+			to, rest = version, args[2:]
+		}
+		if len(rest) > 0 {
+			version, err := strconv.ParseInt(rest[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("version must be a number (got '%s')", rest[0])
+			}
+			from = version
+		}
+		if err := Script(os.Stdout, dir, from, to); err != nil {
+			return err
+		}
+	case "import":
+		if len(args) == 0 {
+			return fmt.Errorf("import must be of form: goose [OPTIONS] DRIVER DBSTRING import flyway|golang-migrate|rails [TABLE]")
+		}
+
+		table := ""
+		if len(args) > 1 {
+			table = args[1]
+		}
+		if err := importHistoryLocked(db, dir, args[0], table); err != nil {
+			return err
+		}
+	case "export":
+		if len(args) == 0 {
+			return fmt.Errorf("export must be of form: goose [OPTIONS] DRIVER DBSTRING export flyway|golang-migrate|rails [TABLE]")
+		}
+
+		table := ""
+		if len(args) > 1 {
+			table = args[1]
+		}
+		if err := exportHistoryLocked(db, dir, args[0], table); err != nil {
+			return err
+		}
+	case "apply-version":
+		if len(args) == 0 {
+			return fmt.Errorf("apply-version must be of form: goose [OPTIONS] DRIVER DBSTRING apply-version VERSION [up|down]")
+		}
+
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("version must be a number (got '%s')", args[0])
+		}
+		direction := true
+		if len(args) > 1 {
+			switch args[1] {
+			case "up":
+			case "down":
+				direction = false
+			default:
+				return fmt.Errorf("direction must be up or down (got '%s')", args[1])
+			}
+		}
+		if err := applyLocked(db, dir, version, direction); err != nil {
+			return err
+		}
+	case "validate":
+		if err := ValidateDown(dir); err != nil {
+			return err
+		}
+	case "doc":
+		format := "markdown"
+		if len(args) > 0 {
+			format = args[0]
+		}
+		if err := Doc(os.Stdout, dir, format); err != nil {
+			return err
+		}
+	case "diff":
+		if len(args) == 0 {
+			return fmt.Errorf("diff must be of form: goose [OPTIONS] DRIVER DBSTRING diff SCRATCH_DBSTRING [NAME]")
+		}
+
+		name := ""
+		if len(args) > 1 {
+			name = args[1]
+		}
+		scratch := openLike(db, args[0])
+		defer scratch.Close()
+		if _, err := ScaffoldSchemaDiff(db, scratch, dir, name); err != nil {
+			return err
+		}
+	case "generate":
+		schemaFile, name := "schema.sql", ""
+		if len(args) > 0 {
+			schemaFile = args[0]
+		}
+		if len(args) > 1 {
+			name = args[1]
+		}
+		if _, err := GenerateFromSchema(db, dir, schemaFile, name); err != nil {
+			return err
+		}
+	case "compact":
+		keep := 100
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("number of records to keep must be a number (got '%s')", args[0])
+			}
+			keep = n
+		}
+		pruned, err := compactHistoryLocked(db, keep)
+		if err != nil {
+			return err
+		}
+		log.Printf("goose: pruned %d records of the version table\n", pruned)
+	case "verify-down":
+		var from int64
+		if len(args) > 0 {
+			v, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("version must be a number (got '%s')", args[0])
+			}
+			from = v
+		}
+		if err := VerifyDown(db, dir, from); err != nil {
+			return err
+		}
+	case "tag":
+		if len(args) == 0 {
+			return fmt.Errorf("tag must be of form: goose [OPTIONS] DRIVER DBSTRING tag NAME [VERSION]")
+		}
+
+		var version int64
+		if len(args) > 1 {
+			v, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("version must be a number (got '%s')", args[1])
+			}
+			version = v
+		} else {
+			v, err := GetDBVersion(db)
+			if err != nil {
+				return err
+			}
+			version = v
+		}
+		if err := TagVersion(db, args[0], version); err != nil {
+			return err
+		}
+		log.Printf("goose: tagged version %d as %s\n", version, args[0])
+	case "tags":
+		tags, err := ListTags(db)
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			log.Printf("    %-24s %d\n", tag.Name, tag.Version)
+		}
+	case "up-to-tag":
+		if len(args) == 0 {
+			return fmt.Errorf("up-to-tag must be of form: goose [OPTIONS] DRIVER DBSTRING up-to-tag NAME")
+		}
+		if err := upToTagLocked(db, dir, args[0]); err != nil {
+			return err
+		}
+	case "down-to-tag":
+		if len(args) == 0 {
+			return fmt.Errorf("down-to-tag must be of form: goose [OPTIONS] DRIVER DBSTRING down-to-tag NAME")
+		}
+		if err := downToTagLocked(db, dir, args[0]); err != nil {
 			return err
 		}
 	case "fix":
@@ -76,15 +341,42 @@ func Run(command string, db *sql.DB, dir string, args ...string) error {
 			return err
 		}
 	case "redo":
-		if err := Redo(db, dir); err != nil {
+		if err := redoLocked(db, dir); err != nil {
 			return err
 		}
 	case "reset":
-		if err := Reset(db, dir); err != nil {
+		if err := resetLocked(db, dir); err != nil {
 			return err
 		}
+	case "preflight":
+		if err := preflightLocked(db); err != nil {
+			return err
+		}
+		log.Println("goose: preflight ok")
+	case "check":
+		if err := Check(db, dir); err != nil {
+			return err
+		}
+		log.Println("goose: up to date")
 	case "status":
-		if err := Status(db, dir); err != nil {
+		format := ""
+		for i := 0; i < len(args); i++ {
+			switch {
+			case args[i] == "-diff":
+				return StatusDiff(db, dir)
+			case args[i] == "-o" && i+1 < len(args):
+				format = args[i+1]
+				i++
+			case strings.HasPrefix(args[i], "-o="):
+				format = strings.TrimPrefix(args[i], "-o=")
+			default:
+				return fmt.Errorf("status must be of form: goose [OPTIONS] DRIVER DBSTRING status [-diff | -o table|wide|json|yaml]")
+			}
+		}
+		if format != "" {
+			return status(os.Stdout, db, dir, format)
+		}
+		if err := statusLocked(db, dir); err != nil {
 			return err
 		}
 	case "version":
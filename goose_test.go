@@ -1,10 +1,13 @@
 package goose
 
 import (
+	"database/sql"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -93,3 +96,125 @@ func TestCustomBinary(t *testing.T) {
 		}
 	}
 }
+
+func TestGoBuildBinary(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "tmptest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)                // clean up
+	defer os.Remove("./bin/gobuild-goose") // clean up
+
+	db := dir + "/go.db"
+	commands := []string{
+		"go build -o ./bin/gobuild-goose ./cmd/goose",
+		"./bin/gobuild-goose -dir=examples/go-migrations -go-build sqlite3 " + db + " up",
+		"./bin/gobuild-goose -dir=examples/go-migrations -go-build sqlite3 " + db + " down",
+	}
+	for _, cmd := range commands {
+		args := strings.Split(cmd, " ")
+		out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s:\n%v\n\n%s", err, cmd, out)
+		}
+		if strings.Contains(cmd, " up") && !strings.Contains(string(out), "OK    00002_rename_root.go") {
+			t.Errorf("expected the Go migration to run, got:\n%s", out)
+		}
+	}
+
+	out, err := exec.Command("./bin/gobuild-goose", "-dir=examples/go-migrations", "sqlite3", db, "up").CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "SetGoBuild") {
+		t.Errorf("expected the Go migration to fail without -go-build, got %v:\n%s", err, out)
+	}
+	if matches, _ := filepath.Glob("examples/go-migrations/_goose_build*"); len(matches) > 0 {
+		t.Errorf("expected the temporary build to be removed, got %v", matches)
+	}
+}
+
+func TestGoBuildSettings(t *testing.T) {
+	t.Parallel()
+
+	// In the module of goose, so that the runner resolves its packages.
+	dir, err := ioutil.TempDir("examples", "_gobuild_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)                         // clean up
+	defer os.Remove("./bin/gobuild-settings-goose") // clean up
+	files := map[string]string{
+		"helpers.go":          "package migrations\n\nfunc tableOf(name string) string { return \"t_\" + name }\n",
+		"00001_create_t_a.go": "package migrations\n\nimport \"github.com/loderunner/goose\"\n\nfunc init() {\n\tgoose.AddMigration(func(qe goose.QueryExecer) error {\n\t\t_, err := qe.Exec(\"CREATE TABLE \" + tableOf(\"a\") + \" (id int)\")\n\t\treturn err\n\t}, nil)\n}\n",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := filepath.Abs(filepath.Join(dir, "go.db")) // the runner runs in dir
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := []string{
+		"go build -o ./bin/gobuild-settings-goose ./cmd/goose",
+		"./bin/gobuild-settings-goose -dir=" + dir + " -go-build -table=schema_history -run-id=deploy-1 sqlite3 " + db + " up",
+	}
+	for _, cmd := range commands {
+		args := strings.Split(cmd, " ")
+		out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s:\n%v\n\n%s", err, cmd, out)
+		}
+	}
+
+	conn, err := sql.Open("sqlite3", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var runID string
+	if err := conn.QueryRow("SELECT run_id FROM schema_history WHERE version_id = 1 AND is_applied").Scan(&runID); err != nil || runID != "deploy-1" {
+		t.Errorf("expected the runner to record the version with the settings of goose, got %q (%v)", runID, err)
+	}
+	var count int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM schema_history WHERE version_id = 1").Scan(&count); err != nil || count != 1 {
+		t.Errorf("expected the version to be recorded once, got %d (%v)", count, err)
+	}
+	if _, err := conn.Exec("SELECT id FROM t_a"); err != nil {
+		t.Errorf("expected the migration using the helper to run: %v", err)
+	}
+}
+
+func TestPluginBinary(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("Go plugins are not supported on " + runtime.GOOS)
+	}
+	dir, err := ioutil.TempDir("", "tmptest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)               // clean up
+	defer os.Remove("./bin/plugin-goose") // clean up
+
+	db := dir + "/go.db"
+	commands := []string{
+		"go build -o ./bin/plugin-goose ./cmd/goose",
+		"go build -buildmode=plugin -o " + dir + "/migrations.so ./examples/go-migrations",
+		"./bin/plugin-goose -dir=examples/go-migrations -plugin=" + dir + "/migrations.so sqlite3 " + db + " up",
+		"./bin/plugin-goose -dir=examples/go-migrations -plugin=" + dir + "/migrations.so sqlite3 " + db + " down",
+	}
+	for _, cmd := range commands {
+		args := strings.Split(cmd, " ")
+		out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s:\n%v\n\n%s", err, cmd, out)
+		}
+		if strings.HasSuffix(cmd, " up") && !strings.Contains(string(out), "OK    00002_rename_root.go") {
+			t.Errorf("expected the Go migration of the plugin to run, got:\n%s", out)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHanaDialect(t *testing.T) {
+	defer SetDialect("postgres")
+	defer SetTableName(TableName())
+	if err := SetDialect("hana"); err != nil {
+		t.Fatal(err)
+	}
+	SetTableName("APP.goose_db_version")
+
+	if q := GetDialect().createVersionTableSQL(); !strings.Contains(q, "CREATE COLUMN TABLE APP.goose_db_version (") || !strings.Contains(q, "GENERATED BY DEFAULT AS IDENTITY") {
+		t.Errorf("unexpected version table DDL %q", q)
+	}
+	if q := GetDialect().addVersionColumnSQL("checksum"); q != "ALTER TABLE APP.goose_db_version ADD (checksum NVARCHAR(255))" {
+		t.Errorf("unexpected ADD COLUMN %q", q)
+	}
+
+	s, err := genCreateIndex([]string{"users", "email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(s.up[0]+s.down[0], "IF NOT EXISTS") || strings.Contains(s.down[0], "IF EXISTS") {
+		t.Errorf("expected no IF [NOT] EXISTS, got %q %q", s.up, s.down)
+	}
+	s, err = genAddColumn([]string{"users", "active", "BOOLEAN", "TRUE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ALTER TABLE users ALTER (active BOOLEAN NOT NULL);"; s.up[len(s.up)-1] != want {
+		t.Errorf("expected %q, got %q", want, s.up)
+	}
+}
@@ -0,0 +1,68 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SchemaVersion is the state of the schema served by VersionHandler.
+type SchemaVersion struct {
+	Version       int64      `json:"version"`
+	Pending       int        `json:"pending"`
+	Next          *int64     `json:"next,omitempty"`
+	LastAppliedAt *time.Time `json:"last_applied_at"`
+	UpToDate      bool       `json:"up_to_date"`
+}
+
+// VersionHandler returns an HTTP handler serving the current version of
+// db, the number of migrations pending among migrations and the time the
+// last one was applied as JSON, for health checks and dashboards:
+//
+//	http.Handle("/internal/schema", goose.VersionHandler(db, goose.RegisteredMigrations()))
+//
+// Like Pending, it never modifies the database. It responds with 503
+// Service Unavailable if the database can't be queried.
+func VersionHandler(db *sql.DB, migrations Migrations) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		v, err := schemaVersion(db, migrations)
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	})
+}
+
+// schemaVersion returns the state of the schema of db.
+func schemaVersion(db *sql.DB, migrations Migrations) (*SchemaVersion, error) {
+	current, err := readDBVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	count, next, err := Pending(db, migrations)
+	if err != nil {
+		return nil, err
+	}
+	v := &SchemaVersion{Version: current, Pending: count, UpToDate: count == 0}
+	if next >= 0 {
+		v.Next = &next
+	}
+	if current > 0 {
+		q := fmt.Sprintf("SELECT tstamp FROM %s WHERE is_applied = %s AND version_id > 0 ORDER BY tstamp DESC", TableName(), GetDialect().placeholder(1))
+		var tstamp time.Time
+		if err := db.QueryRow(q, true).Scan(&tstamp); err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if !tstamp.IsZero() {
+			v.LastAppliedAt = &tstamp
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,58 @@
+package goose
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersionHandler(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	migrations := sortAndConnectMigrations(Migrations{newGoMigration(1), newGoMigration(2)})
+	handler := VersionHandler(db, migrations)
+	get := func() (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/internal/schema", nil))
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+		}
+		return w.Code, body
+	}
+
+	code, body := get()
+	if code != http.StatusOK || body["version"] != 0.0 || body["pending"] != 2.0 || body["next"] != 1.0 || body["last_applied_at"] != nil || body["up_to_date"] != false {
+		t.Errorf("unexpected response on a new database: %d %v", code, body)
+	}
+
+	if _, err := EnsureDBVersion(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations {
+		if err := m.Up(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	code, body = get()
+	if code != http.StatusOK || body["version"] != 2.0 || body["pending"] != 0.0 || body["next"] != nil || body["up_to_date"] != true {
+		t.Errorf("unexpected response on an up to date database: %d %v", code, body)
+	}
+	if at, err := time.Parse(time.RFC3339, body["last_applied_at"].(string)); err != nil || time.Since(at) > time.Hour {
+		t.Errorf("unexpected last applied time %v (%v)", body["last_applied_at"], err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/internal/schema", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected POST to be rejected, got %d", w.Code)
+	}
+
+	db.Close()
+	if code, body := get(); code != http.StatusServiceUnavailable || body["error"] == nil {
+		t.Errorf("expected a closed database to be unavailable, got %d %v", code, body)
+	}
+}
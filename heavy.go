@@ -0,0 +1,108 @@
+package goose
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	allowHeavy        bool
+	maintenanceWindow *window
+)
+
+// SetAllowHeavy sets whether heavy migrations, with a '+goose Heavy'
+// annotation, run outside of their maintenance window.
+func SetAllowHeavy(a bool) {
+	allowHeavy = a
+}
+
+// WithAllowHeavy runs heavy migrations outside of their maintenance
+// window, like SetAllowHeavy.
+func WithAllowHeavy() OptionsFunc {
+	return func(o *options) { o.allowHeavy = true }
+}
+
+// SetMaintenanceWindow sets the daily time window, in local time, in
+// which heavy migrations run, like "01:00-05:00" or "22:00-02:00". Heavy
+// migrations declaring their own window run in that one instead. An empty
+// window clears it: heavy migrations then only run when allowed with
+// SetAllowHeavy.
+func SetMaintenanceWindow(s string) error {
+	if s == "" {
+		maintenanceWindow = nil
+		return nil
+	}
+	w, err := parseWindow(s)
+	if err != nil {
+		return err
+	}
+	maintenanceWindow = w
+	return nil
+}
+
+// window is a daily time window, from start included to end excluded,
+// as durations since midnight. It spans midnight if end is before start.
+type window struct {
+	start, end time.Duration
+}
+
+func parseWindow(s string) (*window, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid maintenance window %q, must be of form HH:MM-HH:MM", s)
+	}
+	var w window
+	for i, bound := range []*time.Duration{&w.start, &w.end} {
+		t, err := time.Parse("15:04", strings.TrimSpace(parts[i]))
+		if err != nil {
+			return nil, errors.Errorf("invalid maintenance window %q, must be of form HH:MM-HH:MM", s)
+		}
+		*bound = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return &w, nil
+}
+
+func (w *window) contains(t time.Time) bool {
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.start <= w.end {
+		return d >= w.start && d < w.end
+	}
+	return d >= w.start || d < w.end
+}
+
+func (w *window) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", int(w.start.Hours()), int(w.start.Minutes())%60, int(w.end.Hours()), int(w.end.Minutes())%60)
+}
+
+// Heavy reports whether the migration is heavy, declared with a
+// '+goose Heavy' annotation or the heavy metadata.
+func (m *Migration) Heavy() bool {
+	return m.Meta["heavy"] != "" && m.Meta["heavy"] != "false"
+}
+
+// checkHeavy returns an error if m is a heavy migration and now is outside
+// of its maintenance window, unless heavy migrations are allowed.
+func checkHeavy(m *Migration, now time.Time) error {
+	if !m.Heavy() || allowHeavy {
+		return nil
+	}
+
+	w := maintenanceWindow
+	if h := m.Meta["heavy"]; h != "true" {
+		var err error
+		if w, err = parseWindow(h); err != nil {
+			return errors.Wrapf(err, "%v", filepath.Base(m.Source))
+		}
+	}
+	if w == nil {
+		return errors.Errorf("%v is a heavy migration: set a maintenance window, or allow it with -allow-heavy", filepath.Base(m.Source))
+	}
+	if !w.contains(now) {
+		return errors.Errorf("%v is a heavy migration, it only runs within the maintenance window %s, or with -allow-heavy", filepath.Base(m.Source), w)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package goose
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2020, 1, 1, hour, min, 0, 0, time.Local)
+	}
+	tests := []struct {
+		window string
+		time   time.Time
+		want   bool
+	}{
+		{"01:00-05:00", at(3, 0), true},
+		{"01:00-05:00", at(5, 0), false},
+		{"01:00-05:00", at(12, 0), false},
+		{"22:00-02:00", at(23, 30), true},
+		{"22:00-02:00", at(1, 59), true},
+		{"22:00-02:00", at(12, 0), false},
+	}
+	for _, test := range tests {
+		w, err := parseWindow(test.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.contains(test.time); got != test.want {
+			t.Errorf("%s contains %s: got %v, want %v", test.window, test.time.Format("15:04"), got, test.want)
+		}
+		if w.String() != test.window {
+			t.Errorf("unexpected window, got %s, want %s", w, test.window)
+		}
+	}
+	if _, err := parseWindow("1am-5am"); err == nil {
+		t.Errorf("expected an error for an invalid window")
+	}
+}
+
+func TestHeavyMigrations(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\n",
+		"00002_b.sql": "-- +goose Heavy\n-- +goose Up\nCREATE TABLE b (id int);\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := Up(db, dir); err == nil {
+		t.Errorf("expected an error applying a heavy migration without maintenance window")
+	}
+	if current, err := GetDBVersion(db); err != nil || current != 1 {
+		t.Errorf("expected goose to stop before the heavy migration, got version %d (%v)", current, err)
+	}
+	if err := Up(db, dir, WithAllowHeavy()); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := GetDBVersion(db); err != nil || current != 2 {
+		t.Errorf("expected the heavy migration to be applied, got version %d (%v)", current, err)
+	}
+
+	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := migrations[1]
+	m.Meta["heavy"] = "01:00-05:00"
+	if err := checkHeavy(m, time.Date(2020, 1, 1, 3, 0, 0, 0, time.Local)); err != nil {
+		t.Errorf("unexpected error within the window of the migration: %v", err)
+	}
+	if err := checkHeavy(m, time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)); err == nil {
+		t.Errorf("expected an error outside of the window of the migration")
+	}
+}
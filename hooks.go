@@ -0,0 +1,78 @@
+package goose
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// SQLHooks are Go functions running along the statements of a SQL
+// migration, in its transaction, so that a data transformation needing both
+// DDL and programmatic logic fits in a single version.
+type SQLHooks struct {
+	BeforeUp   func(QueryExecer) error // runs before the up statements
+	AfterUp    func(QueryExecer) error // runs after the up statements
+	BeforeDown func(QueryExecer) error // runs before the down statements
+	AfterDown  func(QueryExecer) error // runs after the down statements
+}
+
+type sqlHooks struct {
+	SQLHooks
+	source string
+}
+
+var registeredSQLHooks = map[int64]sqlHooks{}
+
+// AddSQLHooks adds hooks to the SQL migration of the version of the calling
+// file, which sits next to it with the same prefix, like
+// 00005_split_names.go adding hooks to 00005_split_names.sql.
+func AddSQLHooks(hooks SQLHooks) {
+	_, filename, _, _ := runtime.Caller(1)
+	AddNamedSQLHooks(filename, hooks)
+}
+
+// AddNamedSQLHooks adds named hooks to a SQL migration.
+func AddNamedSQLHooks(filename string, hooks SQLHooks) {
+	v, _ := NumericComponent(filename)
+	if existing, ok := registeredSQLHooks[v]; ok {
+		panic(fmt.Sprintf("failed to add SQL hooks %q: version conflicts with %q", filename, existing.source))
+	}
+	registeredSQLHooks[v] = sqlHooks{SQLHooks: hooks, source: filename}
+}
+
+// runWithSQLHooks runs the statements of m with exec, between its hooks in
+// direction, run on qe. The assertions of m are checked last, on qe.
+func runWithSQLHooks(qe QueryExecer, m *Migration, direction bool, statements func(exec func(query string) error) error, exec func(query string) error) error {
+	hooks := registeredSQLHooks[m.Version]
+	before, after := hooks.BeforeDown, hooks.AfterDown
+	if direction {
+		before, after = hooks.BeforeUp, hooks.AfterUp
+	}
+
+	if before != nil {
+		verboseInfo("Running before hook of %v", filepath.Base(m.Source))
+		if err := before(qe); err != nil {
+			return errors.Wrapf(err, "failed to run before hook %v", filepath.Base(hooks.source))
+		}
+	}
+	var asserts []string
+	err := statements(func(query string) error {
+		if isAssertion(query) {
+			asserts = append(asserts, query)
+			return nil
+		}
+		return exec(query)
+	})
+	if err != nil {
+		return err
+	}
+	if after != nil {
+		verboseInfo("Running after hook of %v", filepath.Base(m.Source))
+		if err := after(qe); err != nil {
+			return errors.Wrapf(err, "failed to run after hook %v", filepath.Base(hooks.source))
+		}
+	}
+	return runAssertions(qe, asserts)
+}
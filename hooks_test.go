@@ -0,0 +1,82 @@
+package goose
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSQLHooks(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_split_names.sql": "-- +goose Up\nALTER TABLE users ADD COLUMN first_name text;\n-- +goose Down\nUPDATE users SET first_name = NULL;\n",
+		"00001_split_names.go":  "package migrations\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	if _, err := db.Exec("CREATE TABLE users (id int, name text); INSERT INTO users VALUES (1, 'Ada Lovelace');"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { registeredSQLHooks = map[int64]sqlHooks{} }()
+
+	fail := errors.New("unexpected name")
+	AddNamedSQLHooks("00001_split_names.go", SQLHooks{
+		AfterUp: func(qe QueryExecer) error {
+			rows, err := qe.Query("SELECT id, name FROM users")
+			if err != nil {
+				return err
+			}
+			names := map[int]string{}
+			for rows.Next() {
+				var (
+					id   int
+					name string
+				)
+				if err := rows.Scan(&id, &name); err != nil {
+					rows.Close()
+					return err
+				}
+				names[id] = name
+			}
+			rows.Close()
+			for id, name := range names {
+				if !strings.Contains(name, " ") {
+					return fail
+				}
+				if _, err := qe.Exec("UPDATE users SET first_name = ? WHERE id = ?", strings.Fields(name)[0], id); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+
+	if _, err := db.Exec("INSERT INTO users VALUES (2, 'Plato');"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Up(db, dir); err == nil || !strings.Contains(err.Error(), "after hook 00001_split_names.go") {
+		t.Fatalf("expected the hook to fail the migration, got %v", err)
+	}
+	if _, err := db.Exec("SELECT first_name FROM users"); err == nil {
+		t.Error("expected the column to be rolled back with the hook")
+	}
+
+	if _, err := db.Exec("DELETE FROM users WHERE id = 2;"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	var first string
+	if err := db.QueryRow("SELECT first_name FROM users WHERE id = 1").Scan(&first); err != nil || first != "Ada" {
+		t.Errorf("expected the hook to fill first_name, got %q (%v)", first, err)
+	}
+
+	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 {
+		t.Errorf("expected the hooks not to be collected as a migration, got %v", migrations)
+	}
+}
@@ -0,0 +1,166 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	estimateImpact  bool
+	impactThreshold int64
+)
+
+// SetImpactEstimate sets whether goose prints an estimate of the impact of
+// SQL migrations before applying them: the number of rows and size of the
+// tables their ALTER TABLE, UPDATE and DELETE statements touch, from the
+// table statistics of the database.
+func SetImpactEstimate(e bool) {
+	estimateImpact = e
+}
+
+// SetImpactThreshold sets the number of rows above which goose refuses to
+// apply a SQL migration touching a table, unless forced with SetForce. It
+// enables impact estimates. A threshold of 0 disables it.
+func SetImpactThreshold(rows int64) {
+	impactThreshold = rows
+}
+
+// TableImpact is the estimated impact of a migration on a table.
+type TableImpact struct {
+	Table string
+	Rows  int64
+	Bytes int64 // 0 if unknown
+}
+
+func (t TableImpact) String() string {
+	if t.Bytes == 0 {
+		return fmt.Sprintf("%s (~%d rows)", t.Table, t.Rows)
+	}
+	return fmt.Sprintf("%s (~%d rows, %s)", t.Table, t.Rows, formatBytes(t.Bytes))
+}
+
+var impactStatement = regexp.MustCompile(`(?is)^\s*(?:ALTER\s+TABLE(?:\s+IF\s+EXISTS)?(?:\s+ONLY)?|UPDATE(?:\s+ONLY)?|DELETE\s+FROM(?:\s+ONLY)?)\s+([^\s(;,]+)`)
+
+// impactTable returns the table touched by an ALTER TABLE, UPDATE or
+// DELETE statement, unquoted, or an empty string for other statements.
+func impactTable(stmt string) string {
+	match := impactStatement.FindStringSubmatch(stripSQLComments(stmt))
+	if match == nil {
+		return ""
+	}
+	return strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "").Replace(match[1])
+}
+
+// stripSQLComments removes the leading comment lines of a statement.
+func stripSQLComments(stmt string) string {
+	lines := strings.Split(stmt, "\n")
+	for len(lines) > 0 && (strings.HasPrefix(strings.TrimSpace(lines[0]), "--") || strings.TrimSpace(lines[0]) == "") {
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func impactEnabled() bool {
+	return estimateImpact || impactThreshold > 0
+}
+
+// checkImpact estimates the impact of a SQL migration on the tables its
+// statements touch, if enabled, and returns an error if a table has more
+// rows than the impact threshold, unless forced.
+func checkImpact(db *sql.DB, m *Migration, tables []string) error {
+	seen := map[string]bool{}
+	for _, table := range tables {
+		if table == "" || seen[table] {
+			continue
+		}
+		seen[table] = true
+
+		impact, err := estimateTable(db, table)
+		if err != nil {
+			return errors.Wrapf(err, "failed to estimate the impact of %v on %s", filepath.Base(m.Source), table)
+		}
+		if impact == nil {
+			// The table doesn't exist yet, created by the migration.
+			continue
+		}
+		log.Printf("goose: %v touches %s\n", filepath.Base(m.Source), impact)
+		if impactThreshold > 0 && impact.Rows > impactThreshold && !force {
+			return errors.Errorf("%v touches %s, above the threshold of %d rows: apply it with -force", filepath.Base(m.Source), impact, impactThreshold)
+		}
+	}
+	return nil
+}
+
+// estimateTable returns the estimated number of rows and size of table,
+// from the table statistics of the database, or nil if it doesn't exist.
+func estimateTable(db *sql.DB, table string) (*TableImpact, error) {
+	impact := &TableImpact{Table: table}
+	var (
+		q    string
+		rows sql.NullInt64
+		size sql.NullInt64
+	)
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		q = "SELECT reltuples::bigint, pg_total_relation_size(oid) FROM pg_class WHERE oid = to_regclass($1)"
+	case *RedshiftDialect:
+		q = `SELECT tbl_rows::bigint, size::bigint * 1024 * 1024 FROM svv_table_info WHERE "table" = $1`
+	case *MySQLDialect, *MariaDBDialect, *TiDBDialect:
+		q = "SELECT table_rows, data_length + index_length FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	case *SqlServerDialect:
+		q = "SELECT SUM(row_count), SUM(reserved_page_count) * 8192 FROM sys.dm_db_partition_stats WHERE object_id = OBJECT_ID(@p1) AND index_id IN (0, 1) HAVING COUNT(*) > 0"
+	case *ClickHouseDialect:
+		q = "SELECT sum(rows), sum(bytes_on_disk) FROM system.parts WHERE active AND database = currentDatabase() AND table = ? HAVING count() > 0"
+	case *Sqlite3Dialect, *LibSQLDialect:
+		// SQLite has no statistics by default: count the rows.
+		var name string
+		err := db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&name)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + QuoteIdent(name)).Scan(&impact.Rows); err != nil {
+			return nil, err
+		}
+		return impact, nil
+	default:
+		return nil, errors.Errorf("impact estimates are not supported by the %T dialect", GetDialect())
+	}
+
+	err := db.QueryRow(q, table).Scan(&rows, &size)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Valid {
+		return nil, nil
+	}
+	// Postgres estimates -1 rows for tables never analyzed.
+	if rows.Int64 > 0 {
+		impact.Rows = rows.Int64
+	}
+	impact.Bytes = size.Int64
+	return impact, nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,48 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestImpactTable(t *testing.T) {
+	tests := map[string]string{
+		"ALTER TABLE users ADD COLUMN age int;":              "users",
+		"alter table if exists only \"Users\" drop column a": "Users",
+		"-- backfill\nUPDATE `orders` SET paid = 1;":         "orders",
+		"DELETE FROM [sessions] WHERE expired;":              "sessions",
+		"CREATE TABLE users (id int);":                       "",
+		"INSERT INTO users VALUES (1);":                      "",
+	}
+	for stmt, want := range tests {
+		if got := impactTable(stmt); got != want {
+			t.Errorf("%q: got table %q, want %q", stmt, got, want)
+		}
+	}
+	if got := formatBytes(3 * 1024 * 1024); got != "3.0 MiB" {
+		t.Errorf("unexpected formatted size, got %q", got)
+	}
+}
+
+func TestImpactThreshold(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\nINSERT INTO a VALUES (1), (2), (3);\n",
+		"00002_b.sql": "-- +goose Up\nALTER TABLE a ADD COLUMN name text;\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	defer SetImpactThreshold(0)
+
+	SetImpactThreshold(2)
+	if err := Up(db, dir); err == nil {
+		t.Errorf("expected an error applying a migration above the impact threshold")
+	}
+	if current, err := GetDBVersion(db); err != nil || current != 1 {
+		t.Errorf("expected goose to stop before the migration above the threshold, got version %d (%v)", current, err)
+	}
+
+	SetImpactThreshold(3)
+	if err := Up(db, dir); err != nil {
+		t.Errorf("unexpected error applying a migration within the impact threshold: %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// historyReader reads the versions applied by another migration tool from
+// its history table.
+type historyReader func(db *sql.DB, table string, migrations Migrations) ([]int64, error)
+
+// historyTools are the migration tools whose history can be imported, with
+// the default name of their history table.
+var historyTools = map[string]struct {
+	table string
+	read  historyReader
+}{
+	"flyway":         {"flyway_schema_history", readFlywayHistory},
+	"golang-migrate": {"schema_migrations", readGolangMigrateHistory},
+	"rails":          {"schema_migrations", readRailsHistory},
+}
+
+// ImportHistory records in the version table the migrations applied by
+// another migration tool, read from its history table: flyway's
+// flyway_schema_history, golang-migrate's schema_migrations, or Rails'
+// schema_migrations. table overrides the default name of the history table.
+//
+// Versions already applied according to the version table are skipped, so
+// that importing twice is harmless. The migrations of dir must be named
+// after the versions of the other tool, as goose versions are integers.
+func ImportHistory(db *sql.DB, dir, tool, table string) error {
+	return withOptions(nil, func() error {
+		return importHistoryLocked(db, dir, tool, table)
+	})
+}
+
+// importHistoryLocked is ImportHistory for the callers holding optionsMu.
+func importHistoryLocked(db *sql.DB, dir, tool, table string) error {
+	t, ok := historyTools[tool]
+	if !ok {
+		return errors.Errorf("%q: unknown migration tool, must be flyway, golang-migrate or rails", tool)
+	}
+	if table == "" {
+		table = t.table
+	}
+
+	return invoke("import", db, func() error {
+		migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+		if err != nil {
+			return err
+		}
+		versions, err := t.read(db, table, migrations)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s history from %s", tool, table)
+		}
+
+		if _, err := EnsureDBVersion(db); err != nil {
+			return errors.Wrap(err, "failed to ensure DB version")
+		}
+		applied, err := appliedVersions(db)
+		if err != nil {
+			return err
+		}
+		skip := map[int64]bool{0: true}
+		for _, v := range applied {
+			skip[v] = true
+		}
+
+		sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+		tx, err := db.Begin()
+		if err != nil {
+			return errors.Wrap(err, "failed to begin transaction")
+		}
+		imported := 0
+		for _, v := range versions {
+			if skip[v] {
+				continue
+			}
+			skip[v] = true
+
+			checksum := ""
+			if m, err := migrations.Current(v); err == nil {
+				if checksum, err = m.Checksum(); err != nil {
+					tx.Rollback()
+					return err
+				}
+			}
+			if err := insertVersion(tx, v, true, checksum, 0); err != nil {
+				tx.Rollback()
+				return errors.Wrapf(err, "failed to record version %d", v)
+			}
+			imported++
+		}
+		if err := tx.Commit(); err != nil {
+			return errors.Wrap(err, "failed to commit transaction")
+		}
+
+		log.Printf("goose: imported %d versions from %s history in %s\n", imported, tool, table)
+		return nil
+	})
+}
+
+// parseHistoryVersion parses a version of another migration tool.
+func parseHistoryVersion(v string) (int64, error) {
+	version, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("version %q can't be imported: goose versions are integers", v)
+	}
+	return version, nil
+}
+
+// versionsUpTo returns the versions of the migrations up to version.
+func versionsUpTo(migrations Migrations, version int64) []int64 {
+	var versions []int64
+	for _, m := range migrations {
+		if m.Version <= version {
+			versions = append(versions, m.Version)
+		}
+	}
+	return versions
+}
+
+// readFlywayHistory reads the successful versioned migrations of flyway.
+// A baseline marks the migrations up to its version as applied, and an
+// undo migration reverts its version.
+func readFlywayHistory(db *sql.DB, table string, migrations Migrations) ([]int64, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version, type, success FROM %s ORDER BY installed_rank", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var (
+			v       sql.NullString
+			typ     string
+			success bool
+		)
+		if err := rows.Scan(&v, &typ, &success); err != nil {
+			return nil, errors.Wrap(err, "failed to scan row")
+		}
+		if !success || !v.Valid {
+			// Failed or repeatable migration.
+			continue
+		}
+		version, err := parseHistoryVersion(v.String)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case typ == "BASELINE":
+			for _, v := range versionsUpTo(migrations, version) {
+				applied[v] = true
+			}
+			applied[version] = true
+		case strings.HasPrefix(typ, "UNDO_"):
+			delete(applied, version)
+		default:
+			applied[version] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var versions []int64
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// readGolangMigrateHistory reads the current version of golang-migrate: the
+// migrations up to it are applied.
+func readGolangMigrateHistory(db *sql.DB, table string, migrations Migrations) ([]int64, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := db.QueryRow(fmt.Sprintf("SELECT version, dirty FROM %s", table)).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, errors.Errorf("version %d is dirty: fix the database and force the version with golang-migrate first", version)
+	}
+	return append(versionsUpTo(migrations, version), version), nil
+}
+
+// readRailsHistory reads the versions of the migrations applied by Rails.
+func readRailsHistory(db *sql.DB, table string, migrations Migrations) ([]int64, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version FROM %s", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []int64
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, errors.Wrap(err, "failed to scan row")
+		}
+		version, err := parseHistoryVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
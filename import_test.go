@@ -0,0 +1,82 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestImportHistory(t *testing.T) {
+	tests := []struct {
+		tool  string
+		setup []string
+		want  int64
+	}{
+		{"flyway", []string{
+			"CREATE TABLE flyway_schema_history (installed_rank int, version varchar(50), type varchar(20), success boolean)",
+			"INSERT INTO flyway_schema_history VALUES (1, '1', 'BASELINE', 1), (2, NULL, 'SQL', 1), (3, '2', 'SQL', 1), (4, '3', 'SQL', 0)",
+		}, 2},
+		{"golang-migrate", []string{
+			"CREATE TABLE schema_migrations (version bigint, dirty boolean)",
+			"INSERT INTO schema_migrations VALUES (2, 0)",
+		}, 2},
+		{"rails", []string{
+			"CREATE TABLE schema_migrations (version varchar(255))",
+			"INSERT INTO schema_migrations VALUES ('1'), ('2'), ('3')",
+		}, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.tool, func(t *testing.T) {
+			dir, cleanupDir := writeTestMigrations(t, testMigrations)
+			defer cleanupDir()
+			db, cleanup := openTestDB(t)
+			defer cleanup()
+
+			for _, q := range test.setup {
+				if _, err := db.Exec(q); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := ImportHistory(db, dir, test.tool, ""); err != nil {
+				t.Fatal(err)
+			}
+			// Importing again is harmless.
+			if err := ImportHistory(db, dir, test.tool, ""); err != nil {
+				t.Fatal(err)
+			}
+
+			current, err := GetDBVersion(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current != test.want {
+				t.Errorf("unexpected version after import, got %d, want %d", current, test.want)
+			}
+			var count int
+			if err := db.QueryRow("SELECT COUNT(*) FROM goose_db_version WHERE version_id > 0").Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != int(test.want) {
+				t.Errorf("unexpected number of imported versions, got %d, want %d", count, test.want)
+			}
+		})
+	}
+}
+
+func TestImportHistoryDirty(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	for _, q := range []string{
+		"CREATE TABLE schema_migrations (version bigint, dirty boolean)",
+		"INSERT INTO schema_migrations VALUES (2, 1)",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ImportHistory(db, dir, "golang-migrate", ""); err == nil {
+		t.Errorf("expected an error importing a dirty version")
+	}
+}
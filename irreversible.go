@@ -0,0 +1,55 @@
+package goose
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+var allowIrreversible bool
+
+// SetAllowIrreversible sets whether the irreversible migrations are rolled
+// back anyway, see Migration.Irreversible.
+func SetAllowIrreversible(a bool) {
+	allowIrreversible = a
+}
+
+// WithAllowIrreversible rolls back the irreversible migrations anyway, like
+// SetAllowIrreversible.
+func WithAllowIrreversible() OptionsFunc {
+	return func(o *options) { o.allowIrreversible = true }
+}
+
+// Irreversible reports whether the migration is marked irreversible, with a
+// '+goose Irreversible' annotation: it is not meant to be rolled back, so
+// its down only runs when allowed with SetAllowIrreversible.
+func (m *Migration) Irreversible() bool {
+	return m.Meta["irreversible"] == "true"
+}
+
+// checkIrreversible refuses to roll back the migration m if it is
+// irreversible, unless allowed.
+func checkIrreversible(m *Migration) error {
+	if !m.Irreversible() || allowIrreversible {
+		return nil
+	}
+	return errors.Errorf("ERROR %v: migration is irreversible, it can't be rolled back; run its down anyway with -allow-irreversible", filepath.Base(m.Source))
+}
+
+// checkIrreversibleDownTo refuses to roll back from version current to
+// version target, included if inclusive, across an irreversible migration,
+// before rolling back any, unless allowed.
+func checkIrreversibleDownTo(migrations Migrations, current, target int64, inclusive bool) error {
+	if allowIrreversible {
+		return nil
+	}
+	for _, m := range migrations {
+		if m.Version > current || m.Version < target || (m.Version == target && !inclusive) {
+			continue
+		}
+		if m.Irreversible() {
+			return errors.Errorf("can't roll back to version %d across %v, an irreversible migration; run its down anyway with -allow-irreversible", target, filepath.Base(m.Source))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIrreversible(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\n-- +goose Down\nDROP TABLE a;\n",
+		"00002_drop_a.sql":   "-- +goose Irreversible\n-- +goose Up\nDROP TABLE a;\n",
+		"00003_create_b.sql": "-- +goose Up\nCREATE TABLE b (id int);\n-- +goose Down\nDROP TABLE b;\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := ValidateDown(dir); err != nil {
+		t.Errorf("expected irreversible migrations to pass the validation, got %v", err)
+	}
+	SetRunID("deploy-1")
+	err := Up(db, dir, WithMissingDown(MissingDownError))
+	SetRunID("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, down := range map[string]func() error{
+		"down-to":  func() error { return DownTo(db, dir, 1) },
+		"reset":    func() error { return Reset(db, dir) },
+		"down-run": func() error { return DownRun(db, dir, "deploy-1") },
+	} {
+		err := down()
+		if err == nil || !strings.Contains(err.Error(), "across 00002_drop_a.sql, an irreversible migration") {
+			t.Fatalf("%s: expected rolling back across the irreversible migration to fail, got %v", name, err)
+		}
+		if v, err := GetDBVersion(db); err != nil || v != 3 {
+			t.Errorf("%s: expected nothing to be rolled back, got version %d (%v)", name, v, err)
+		}
+	}
+	if err := DownTo(db, dir, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := Down(db, dir); err == nil || !strings.Contains(err.Error(), "00002_drop_a.sql: migration is irreversible") {
+		t.Errorf("expected down to refuse the irreversible migration, got %v", err)
+	}
+	if err := Down(db, dir, WithForce()); err == nil {
+		t.Error("expected forcing not to roll back the irreversible migration")
+	}
+	if err := Down(db, dir, WithAllowIrreversible()); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 1 {
+		t.Errorf("expected the allowed down to roll back the irreversible migration, got version %d (%v)", v, err)
+	}
+	if migrations, err := CollectMigrations(dir, minVersion, maxVersion, WithReversibleOnly()); err != nil || len(migrations) != 2 {
+		t.Errorf("expected the irreversible migration not to be reversible, got %v (%v)", migrations, err)
+	}
+}
@@ -0,0 +1,21 @@
+package goose
+
+import "strings"
+
+var libsqlTransactions = true
+
+// SetLibSQLTransactions sets whether migrations run in transactions on
+// libSQL, which the stateless HTTP mode of its client doesn't support:
+// without them, migrations run as if with '-- +goose NO TRANSACTION'. See
+// LibSQLTransactions for the setting of a database URL.
+func SetLibSQLTransactions(enabled bool) {
+	libsqlTransactions = enabled
+}
+
+// LibSQLTransactions reports whether the libSQL database URL dbstring
+// supports transactions: it doesn't over HTTP, with http:// and https://
+// URLs, unlike with WebSockets or to a local file.
+func LibSQLTransactions(dbstring string) bool {
+	s := strings.ToLower(dbstring)
+	return !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://")
+}
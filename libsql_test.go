@@ -0,0 +1,38 @@
+package goose
+
+import "testing"
+
+func TestLibSQLDialect(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	defer SetLibSQLTransactions(true)
+
+	// The libSQL dialect runs on SQLite, without transactions as over HTTP.
+	if err := SetDialect("turso"); err != nil {
+		t.Fatal(err)
+	}
+	SetLibSQLTransactions(LibSQLTransactions("https://db-org.turso.io?authToken=token"))
+	if transactional() {
+		t.Fatal("expected migrations over HTTP to run without a transaction")
+	}
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	version, err := GetDBVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 {
+		t.Errorf("expected version 3, got %d", version)
+	}
+	var index int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'goose_db_version_version_idx'").Scan(&index); err != nil || index != 1 {
+		t.Errorf("expected the version table to have the index, got %d (%v)", index, err)
+	}
+
+	if !LibSQLTransactions("libsql://db-org.turso.io") || !LibSQLTransactions("file:local.db") {
+		t.Error("expected WebSocket and local databases to support transactions")
+	}
+}
@@ -0,0 +1,340 @@
+package goose
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Locker prevents several goose processes from migrating the same database
+// at the same time.
+type Locker interface {
+	// Lock blocks until the lock is acquired or ctx is done.
+	Lock(ctx context.Context) error
+	// Unlock releases the lock.
+	Unlock(ctx context.Context) error
+}
+
+var locker Locker
+
+// leaseLocker is implemented by the lockers whose lock can be lost while it
+// is held, like the lock row of TableLocker taken over by another process.
+type leaseLocker interface {
+	// lostLock returns a channel closed if the lock held is lost.
+	lostLock() <-chan struct{}
+}
+
+// SetLocker sets the Locker acquired by the commands modifying the
+// database (Up, UpTo, UpByOne, Down, DownTo, Redo and Reset). No lock is
+// acquired by default.
+func SetLocker(l Locker) {
+	locker = l
+}
+
+var lockTimeout time.Duration
+
+// SetLockTimeout sets how long to wait for the lock set with SetLocker.
+// When the lock can't be acquired in time, the command fails with the
+// ExitLockTimeout exit code. By default, commands wait for the lock
+// indefinitely.
+func SetLockTimeout(d time.Duration) {
+	lockTimeout = d
+}
+
+// lockRetryInterval is the interval between two attempts to acquire a
+// lock, for the lockers that can't wait for the lock to be released.
+var lockRetryInterval = time.Second
+
+// withLock runs fn while holding the lock set with SetLocker, if any. If
+// the lock is lost meanwhile, the statements of the migrations are
+// canceled, so that the run stops before the next statement or record of
+// the version table.
+func withLock(fn func() error) (err error) {
+	if locker == nil {
+		return fn()
+	}
+
+	ctx := context.Background()
+	verboseInfo("Acquiring lock")
+	lockCtx, cancel := ctx, func() {}
+	if lockTimeout > 0 {
+		lockCtx, cancel = context.WithTimeout(ctx, lockTimeout)
+	}
+	err = locker.Lock(lockCtx)
+	cancel()
+	if err != nil {
+		if lockCtx.Err() == context.DeadlineExceeded {
+			return withExitCode(ExitLockTimeout, errors.Wrapf(err, "failed to acquire lock within %v", lockTimeout))
+		}
+		return errors.Wrap(err, "failed to acquire lock")
+	}
+	debugLocked(true)
+	defer func() {
+		verboseInfo("Releasing lock")
+		if unlockErr := locker.Unlock(ctx); unlockErr != nil && err == nil {
+			err = errors.Wrap(unlockErr, "failed to release lock")
+		}
+		debugLocked(false)
+	}()
+
+	l, ok := locker.(leaseLocker)
+	if !ok {
+		return fn()
+	}
+	saved := runCtx
+	lockedCtx, cancelRun := context.WithCancel(runCtx)
+	runCtx = lockedCtx
+	released := make(chan struct{})
+	go func() {
+		select {
+		case <-l.lostLock():
+			cancelRun()
+		case <-released:
+		}
+	}()
+	defer func() {
+		close(released)
+		cancelRun()
+		runCtx = saved
+	}()
+
+	err = fn()
+	select {
+	case <-l.lostLock():
+		if err != nil {
+			err = errors.Wrap(err, "lock was taken over by another process, the run was canceled")
+		}
+	default:
+	}
+	return err
+}
+
+// retryLock calls tryLock until it acquires the lock, or ctx is done.
+func retryLock(ctx context.Context, tryLock func() (bool, error)) error {
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		locked, err := tryLock()
+		if err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// lockID identifies the lock of the current version table, so that
+// migrations tracked in different tables don't wait for each other.
+func lockID() string {
+	return "goose:" + TableName()
+}
+
+// lockHolder identifies the current process as the holder of a lock.
+func lockHolder() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+////////////////////////////
+// Postgres
+////////////////////////////
+
+// PostgresLocker is a Locker using a Postgres session-level advisory lock.
+type PostgresLocker struct {
+	db   *sql.DB
+	key  int64
+	conn *sql.Conn
+}
+
+// NewPostgresLocker creates a Locker using a Postgres advisory lock. The key
+// of the lock is derived from the version table name.
+func NewPostgresLocker(db *sql.DB) *PostgresLocker {
+	return &PostgresLocker{db: db, key: int64(crc32.ChecksumIEEE([]byte(lockID())))}
+}
+
+// Lock acquires the advisory lock on a connection dedicated to the lock.
+func (l *PostgresLocker) Lock(ctx context.Context) error {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get connection")
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", l.key); err != nil {
+		conn.Close()
+		return errors.Wrap(err, "failed to acquire advisory lock")
+	}
+	l.conn = conn
+	return nil
+}
+
+// Unlock releases the advisory lock and its connection.
+func (l *PostgresLocker) Unlock(ctx context.Context) error {
+	if l.conn == nil {
+		return errors.New("not locked")
+	}
+	defer func() {
+		l.conn.Close()
+		l.conn = nil
+	}()
+	if _, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		return errors.Wrap(err, "failed to release advisory lock")
+	}
+	return nil
+}
+
+// queryHolder returns the holder of the advisory lock, the client address
+// and backend pid of its session, or "" if it isn't held.
+func (l *PostgresLocker) queryHolder(ctx context.Context) (string, error) {
+	var pid int64
+	var addr string
+	q := `SELECT l.pid, COALESCE(host(a.client_addr), 'local') FROM pg_locks l LEFT JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted AND l.classid = $1 AND l.objid = $2 AND l.objsubid = 1
+		AND l.database = (SELECT oid FROM pg_database WHERE datname = current_database())`
+	err := l.db.QueryRowContext(ctx, q, uint32(l.key>>32), uint32(l.key)).Scan(&pid, &addr)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to query advisory lock")
+	}
+	return fmt.Sprintf("%s:%d", addr, pid), nil
+}
+
+////////////////////////////
+// MySQL
+////////////////////////////
+
+// MySQLLocker is a Locker using a MySQL named lock (GET_LOCK).
+type MySQLLocker struct {
+	db   *sql.DB
+	name string
+	conn *sql.Conn
+}
+
+// NewMySQLLocker creates a Locker using a MySQL named lock. The name of the
+// lock is derived from the version table name.
+func NewMySQLLocker(db *sql.DB) *MySQLLocker {
+	return &MySQLLocker{db: db, name: lockID()}
+}
+
+// Lock acquires the named lock on a connection dedicated to the lock.
+func (l *MySQLLocker) Lock(ctx context.Context) error {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get connection")
+	}
+
+	timeout := int(lockRetryInterval / time.Second)
+	if timeout < 1 {
+		timeout = 1
+	}
+	err = retryLock(ctx, func() (bool, error) {
+		var locked sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", mysqlLockName(l.name), timeout).Scan(&locked); err != nil {
+			return false, errors.Wrap(err, "failed to acquire named lock")
+		}
+		return locked.Valid && locked.Int64 == 1, nil
+	})
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	l.conn = conn
+	return nil
+}
+
+// Unlock releases the named lock and its connection.
+func (l *MySQLLocker) Unlock(ctx context.Context) error {
+	if l.conn == nil {
+		return errors.New("not locked")
+	}
+	defer func() {
+		l.conn.Close()
+		l.conn = nil
+	}()
+	if _, err := l.conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", mysqlLockName(l.name)); err != nil {
+		return errors.Wrap(err, "failed to release named lock")
+	}
+	return nil
+}
+
+// queryHolder returns the holder of the named lock, the host and
+// connection id of its session, or "" if it isn't held. The host is only
+// known with the PROCESS privilege.
+func (l *MySQLLocker) queryHolder(ctx context.Context) (string, error) {
+	var id sql.NullInt64
+	if err := l.db.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?)", mysqlLockName(l.name)).Scan(&id); err != nil {
+		return "", errors.Wrap(err, "failed to query named lock")
+	}
+	if !id.Valid {
+		return "", nil
+	}
+	var host string
+	if err := l.db.QueryRowContext(ctx, "SELECT HOST FROM information_schema.PROCESSLIST WHERE ID = ?", id.Int64).Scan(&host); err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, id.Int64), nil
+}
+
+// mysqlLockName returns the name of the named lock name. From MySQL 5.7.5,
+// names are limited to 64 characters: longer ones are hashed. Before 5.7.5,
+// a session holds a single named lock, which is why each locker has its own
+// connection.
+func mysqlLockName(name string) string {
+	if len(name) <= 64 || !serverVersion.AtLeast(5, 7, 5) {
+		return name
+	}
+	return fmt.Sprintf("goose:%x", sha1.Sum([]byte(name)))
+}
+
+////////////////////////////
+// File
+////////////////////////////
+
+// FileLocker is a Locker using an exclusive lock on a local file. It only
+// prevents concurrent runs on a single host, which is enough for
+// single-host deployments like SQLite.
+type FileLocker struct {
+	path    string
+	release func() error
+}
+
+// NewFileLocker creates a Locker locking the file at path. The file is
+// created if it doesn't exist.
+func NewFileLocker(path string) *FileLocker {
+	return &FileLocker{path: path}
+}
+
+// Lock acquires the file lock.
+func (l *FileLocker) Lock(ctx context.Context) error {
+	return retryLock(ctx, func() (bool, error) {
+		release, locked, err := lockFile(l.path)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to lock %s", l.path)
+		}
+		l.release = release
+		return locked, nil
+	})
+}
+
+// Unlock releases the file lock.
+func (l *FileLocker) Unlock(ctx context.Context) error {
+	if l.release == nil {
+		return errors.New("not locked")
+	}
+	defer func() { l.release = nil }()
+	return l.release()
+}
@@ -0,0 +1,25 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package goose
+
+import (
+	"os"
+)
+
+// lockFile tries to create the file at path exclusively, and removes it on
+// release. Unlike flock, the file is left behind if the process dies, and
+// must then be removed by hand.
+func lockFile(path string) (release func() error, locked bool, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	f.Close()
+
+	return func() error {
+		return os.Remove(path)
+	}, true, nil
+}
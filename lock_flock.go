@@ -0,0 +1,33 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package goose
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile tries to acquire an exclusive flock on the file at path. The
+// lock is released by the kernel if the process dies.
+func lockFile(path string) (release func() error, locked bool, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return func() error {
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}, true, nil
+}
@@ -0,0 +1,241 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TableLocker is a Locker using a row of a lock table, for databases
+// without native locks. While it holds the lock, the locker updates a
+// heartbeat in the lock row. The lock is a lease: if the process holding
+// it crashes, its heartbeat stops, and the lock is taken over by the next
+// locker once the heartbeat is older than the TTL.
+//
+// The lock table is named after the version table, with a "_lock" suffix,
+// and is created when needed.
+type TableLocker struct {
+	db                *sql.DB
+	heartbeatInterval time.Duration
+	ttl               time.Duration
+
+	holder string
+	stop   chan struct{}
+	done   chan struct{}
+	lost   chan struct{} // closed when the lock is taken over
+}
+
+// NewTableLocker creates a Locker using a row of a lock table.
+func NewTableLocker(db *sql.DB) *TableLocker {
+	return &TableLocker{db: db, heartbeatInterval: 10 * time.Second, ttl: time.Minute}
+}
+
+// SetHeartbeat sets the interval between two heartbeats of the lock
+// holder, 10 seconds by default.
+func (l *TableLocker) SetHeartbeat(interval time.Duration) {
+	l.heartbeatInterval = interval
+}
+
+// SetTTL sets the age of the last heartbeat after which a lock is considered
+// stale and is taken over, one minute by default. It must be much longer
+// than the heartbeat interval.
+func (l *TableLocker) SetTTL(ttl time.Duration) {
+	l.ttl = ttl
+}
+
+func (l *TableLocker) tableName() string {
+	return TableName() + "_lock"
+}
+
+// Lock acquires the lock row, waiting for the current holder to release it.
+func (l *TableLocker) Lock(ctx context.Context) error {
+	if err := l.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	d := GetDialect()
+	holder := lockHolder()
+	q := fmt.Sprintf("UPDATE %s SET locked = 1, holder = %s, heartbeat = %s WHERE id = 1 AND locked = 0", l.tableName(), d.placeholder(1), d.placeholder(2))
+
+	err := retryLock(ctx, func() (bool, error) {
+		res, err := l.db.ExecContext(ctx, q, holder, time.Now().Unix())
+		if err != nil {
+			return false, errors.Wrap(err, "failed to update lock row")
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return false, errors.Wrap(err, "failed to update lock row")
+		}
+		if n == 1 {
+			return true, nil
+		}
+		return l.takeOverStale(ctx, holder)
+	})
+	if err != nil {
+		return err
+	}
+
+	l.holder = holder
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	l.lost = make(chan struct{})
+	go l.heartbeat(l.holder, l.stop, l.done, l.lost)
+	return nil
+}
+
+// lostLock returns a channel closed if the lock held was taken over by
+// another process, its heartbeat considered stale.
+func (l *TableLocker) lostLock() <-chan struct{} {
+	return l.lost
+}
+
+// Unlock stops the heartbeat and releases the lock row.
+func (l *TableLocker) Unlock(ctx context.Context) error {
+	if l.holder == "" {
+		return errors.New("not locked")
+	}
+	close(l.stop)
+	<-l.done
+
+	holder := l.holder
+	l.holder = ""
+
+	d := GetDialect()
+	q := fmt.Sprintf("UPDATE %s SET locked = 0, holder = NULL WHERE id = 1 AND holder = %s", l.tableName(), d.placeholder(1))
+	res, err := l.db.ExecContext(ctx, q, holder)
+	if err != nil {
+		return errors.Wrap(err, "failed to update lock row")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("lock was not held anymore")
+	}
+	return nil
+}
+
+// takeOverStale takes the lock over if the heartbeat of its holder is older
+// than the TTL.
+func (l *TableLocker) takeOverStale(ctx context.Context, holder string) (bool, error) {
+	d := GetDialect()
+
+	var (
+		staleHolder sql.NullString
+		heartbeat   sql.NullInt64
+	)
+	q := fmt.Sprintf("SELECT holder, heartbeat FROM %s WHERE id = 1 AND locked = 1", l.tableName())
+	if err := l.db.QueryRowContext(ctx, q).Scan(&staleHolder, &heartbeat); err != nil {
+		if err == sql.ErrNoRows {
+			// Released in the meantime.
+			return false, nil
+		}
+		return false, errors.Wrap(err, "failed to read lock row")
+	}
+
+	age := time.Since(time.Unix(heartbeat.Int64, 0))
+	if heartbeat.Valid && age < l.ttl {
+		return false, nil
+	}
+
+	// Only take over if nobody else did in the meantime.
+	q = fmt.Sprintf("UPDATE %s SET holder = %s, heartbeat = %s WHERE id = 1 AND locked = 1 AND holder = %s AND heartbeat = %s",
+		l.tableName(), d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4))
+	args := []interface{}{holder, time.Now().Unix(), staleHolder.String, heartbeat.Int64}
+	if !heartbeat.Valid {
+		q = fmt.Sprintf("UPDATE %s SET holder = %s, heartbeat = %s WHERE id = 1 AND locked = 1 AND holder = %s AND heartbeat IS NULL",
+			l.tableName(), d.placeholder(1), d.placeholder(2), d.placeholder(3))
+		args = args[:3]
+	}
+	res, err := l.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to take stale lock over")
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+
+	log.Printf("goose: WARNING: took over stale lock from %s, last heartbeat %v ago (TTL %v)\n", staleHolder.String, age.Round(time.Second), l.ttl)
+	return true, nil
+}
+
+func (l *TableLocker) heartbeat(holder string, stop, done, lost chan struct{}) {
+	defer close(done)
+
+	d := GetDialect()
+	q := fmt.Sprintf("UPDATE %s SET heartbeat = %s WHERE id = 1 AND holder = %s", l.tableName(), d.placeholder(1), d.placeholder(2))
+
+	ticker := time.NewTicker(l.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			verboseInfo("Updating lock heartbeat")
+			res, err := l.db.Exec(q, time.Now().Unix(), holder)
+			if err != nil {
+				log.Printf("goose: failed to update lock heartbeat: %v\n", err)
+				continue
+			}
+			if n, err := res.RowsAffected(); err == nil && n == 0 && !l.holds(holder) {
+				log.Printf("goose: WARNING: lock was taken over by another process, canceling the run\n")
+				close(lost)
+				return
+			}
+		}
+	}
+}
+
+// holds reports whether the lock is still held by holder. Some databases
+// report no affected rows when an update doesn't change the row.
+func (l *TableLocker) holds(holder string) bool {
+	var current sql.NullString
+	q := fmt.Sprintf("SELECT holder FROM %s WHERE id = 1", l.tableName())
+	if err := l.db.QueryRow(q).Scan(&current); err != nil {
+		return true
+	}
+	return current.String == holder
+}
+
+// queryHolder returns the holder of the lock row, or "" if it isn't held.
+func (l *TableLocker) queryHolder(ctx context.Context) (string, error) {
+	var holder sql.NullString
+	q := fmt.Sprintf("SELECT holder FROM %s WHERE id = 1 AND locked = 1", l.tableName())
+	err := l.db.QueryRowContext(ctx, q).Scan(&holder)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to query lock row")
+	}
+	return holder.String, nil
+}
+
+// ensureTable creates the lock table and its single row if they don't
+// exist. Several processes may try to create them at the same time.
+func (l *TableLocker) ensureTable(ctx context.Context) error {
+	d := GetDialect()
+	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = 1", l.tableName())
+
+	var count int
+	if err := l.db.QueryRowContext(ctx, countSQL).Scan(&count); err != nil {
+		createSQL := fmt.Sprintf("CREATE TABLE %s (id INTEGER NOT NULL PRIMARY KEY, locked INTEGER NOT NULL, holder VARCHAR(255), heartbeat BIGINT)", l.tableName())
+		if _, createErr := l.db.ExecContext(ctx, createSQL); createErr != nil {
+			if err := l.db.QueryRowContext(ctx, countSQL).Scan(&count); err != nil {
+				return errors.Wrap(createErr, "failed to create lock table")
+			}
+		}
+	}
+	if count > 0 {
+		return nil
+	}
+
+	insertSQL := fmt.Sprintf("INSERT INTO %s (id, locked) VALUES (1, %s)", l.tableName(), d.placeholder(1))
+	if _, insertErr := l.db.ExecContext(ctx, insertSQL, 0); insertErr != nil {
+		if err := l.db.QueryRowContext(ctx, countSQL).Scan(&count); err != nil || count == 0 {
+			return errors.Wrap(insertErr, "failed to insert lock row")
+		}
+	}
+	return nil
+}
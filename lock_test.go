@@ -0,0 +1,156 @@
+package goose
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLocker(t *testing.T, l1, l2 Locker) {
+	if err := l1.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := l2.Lock(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected lock to be held, got %v", err)
+	}
+
+	if err := l1.Unlock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l2.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l2.Unlock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l2.Unlock(context.Background()); err == nil {
+		t.Error("expected error on unlocking a lock that is not held")
+	}
+}
+
+func TestFileLocker(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "goose-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.lock")
+	testLocker(t, NewFileLocker(path), NewFileLocker(path))
+}
+
+func TestTableLocker(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	testLocker(t, NewTableLocker(db), NewTableLocker(db))
+}
+
+func TestWithLock(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	SetLocker(NewTableLocker(db))
+	defer SetLocker(nil)
+
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var locked int
+	if err := db.QueryRow("SELECT locked FROM " + TableName() + "_lock WHERE id = 1").Scan(&locked); err != nil {
+		t.Fatal(err)
+	}
+	if locked != 0 {
+		t.Error("expected lock to be released after Up")
+	}
+}
+
+func TestTableLockerTakenOver(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_a.sql":    "-- +goose Up\nCREATE TABLE a (id int);\n",
+		"00002_slow.sql": "-- +goose Up\nCREATE TABLE slow AS WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000) SELECT x FROM c;\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	// The lock row is in another database, not to wait for the migration.
+	lockDB, cleanupLock := openTestDB(t)
+	defer cleanupLock()
+
+	l := NewTableLocker(lockDB)
+	l.SetHeartbeat(50 * time.Millisecond)
+	SetLocker(l)
+	defer SetLocker(nil)
+
+	done := make(chan error)
+	go func() { done <- Up(db, dir) }()
+	time.Sleep(500 * time.Millisecond)
+	// Another process took the lock over.
+	if _, err := lockDB.Exec("UPDATE " + TableName() + "_lock SET holder = 'other'"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "lock was taken over by another process") {
+			t.Errorf("expected the run to be canceled, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the run to stop once the lock was taken over")
+	}
+	if current, err := GetDBVersion(db); err != nil || current != 1 {
+		t.Errorf("expected the canceled migration not to be recorded, got version %d (%v)", current, err)
+	}
+}
+
+func TestTableLockerStaleTakeOver(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	l1 := NewTableLocker(db)
+	if err := l1.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a crashed holder, whose heartbeat stopped a while ago.
+	close(l1.stop)
+	<-l1.done
+	if _, err := db.Exec("UPDATE "+TableName()+"_lock SET heartbeat = ?", time.Now().Add(-time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	l2 := NewTableLocker(db)
+	l2.SetTTL(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := l2.Lock(ctx); err != nil {
+		t.Fatalf("expected stale lock to be taken over, got %v", err)
+	}
+	if err := l2.Unlock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A lock with a recent heartbeat is not stale.
+	l3 := NewTableLocker(db)
+	if err := l3.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer l3.Unlock(context.Background())
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := l2.Lock(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected lock to be held, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package goose
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseMeta parses the metadata of the '+goose Meta' annotations of a
+// migration source file, in SQL comments or Go comments:
+//
+//	-- +goose Meta owner=payments team=core ticket=PAY-123
+//	// +goose Meta owner=payments reviewers=alice,bob
+//
+// The '+goose Gate NAME', '+goose Heavy [WINDOW]', '+goose Irreversible'
+// and '+goose Requires NAMESPACE:VERSION...' annotations are shorthands for
+// the gate=NAME, heavy=WINDOW, irreversible=true and
+// requires=NAMESPACE:VERSION,... metadata. Requirements
+// accumulate over several annotations.
+//
+// It returns nil for Go migrations built into a binary, when their source
+// file is not available, and for binary WASM migrations.
+func parseMeta(path string) (map[string]string, error) {
+	if filepath.Ext(path) == ".wasm" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && filepath.Ext(path) == ".go" {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to open migration %v", filepath.Base(path))
+	}
+	defer f.Close()
+
+	var meta map[string]string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), scanBufSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "--"):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		case strings.HasPrefix(line, "//"):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "//"))
+		case strings.HasPrefix(line, "#"), strings.HasPrefix(line, "::"):
+			// Comments of external command migrations.
+			line = strings.TrimSpace(strings.TrimLeft(line, "#:"))
+		default:
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+goose Gate "):
+			line = "+goose Meta gate=" + strings.TrimSpace(strings.TrimPrefix(line, "+goose Gate "))
+		case line == "+goose Heavy":
+			line = "+goose Meta heavy=true"
+		case line == "+goose Irreversible":
+			line = "+goose Meta irreversible=true"
+		case strings.HasPrefix(line, "+goose Heavy "):
+			line = "+goose Meta heavy=" + strings.TrimSpace(strings.TrimPrefix(line, "+goose Heavy "))
+		case strings.HasPrefix(line, "+goose Requires "):
+			line = "+goose Meta requires=" + strings.Join(strings.Fields(strings.TrimPrefix(line, "+goose Requires ")), ",")
+		}
+		if !strings.HasPrefix(line, "+goose Meta ") {
+			continue
+		}
+		for _, field := range strings.Fields(strings.TrimPrefix(line, "+goose Meta ")) {
+			i := strings.Index(field, "=")
+			if i <= 0 {
+				return nil, errors.Errorf("%v: invalid metadata %q in '+goose Meta' annotation, must be of form KEY=VALUE", filepath.Base(path), field)
+			}
+			if meta == nil {
+				meta = map[string]string{}
+			}
+			key, value := field[:i], field[i+1:]
+			if key == "requires" && meta[key] != "" {
+				value = meta[key] + "," + value
+			}
+			meta[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read migration %v", filepath.Base(path))
+	}
+	return meta, nil
+}
+
+// formatMeta formats metadata as KEY=VALUE pairs, sorted by key.
+func formatMeta(meta map[string]string) string {
+	pairs := make([]string, 0, len(meta))
+	for k, v := range meta {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// Reviewers returns the required reviewers of the migration, declared
+// with the reviewers metadata: reviewers=alice,bob.
+func (m *Migration) Reviewers() []string {
+	if m.Meta["reviewers"] == "" {
+		return nil
+	}
+	return strings.Split(m.Meta["reviewers"], ",")
+}
+
+// checkReviewers returns an error if approver is not one of the required
+// reviewers of every migration declaring them.
+func checkReviewers(migrations Migrations, approver string) error {
+	for _, m := range migrations {
+		reviewers := m.Reviewers()
+		if len(reviewers) > 0 && !containsString(reviewers, approver) {
+			return errors.Errorf("%v must be approved by one of its reviewers (%s), not %s", filepath.Base(m.Source), strings.Join(reviewers, ", "), approver)
+		}
+	}
+	return nil
+}
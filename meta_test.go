@@ -0,0 +1,63 @@
+package goose
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMeta(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_owned.sql": "-- +goose Meta owner=payments team=core\n-- +goose Meta ticket=PAY-123\n-- +goose Up\nCREATE TABLE a (id int);\n",
+		"00002_plain.sql": "-- +goose Up\nCREATE TABLE b (id int);\n",
+	})
+	defer cleanupDir()
+
+	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"owner": "payments", "team": "core", "ticket": "PAY-123"}
+	if !reflect.DeepEqual(migrations[0].Meta, want) {
+		t.Errorf("unexpected metadata, got %v, want %v", migrations[0].Meta, want)
+	}
+	if migrations[1].Meta != nil {
+		t.Errorf("unexpected metadata, got %v", migrations[1].Meta)
+	}
+	if got := formatMeta(want); got != "owner=payments team=core ticket=PAY-123" {
+		t.Errorf("unexpected formatted metadata, got %q", got)
+	}
+
+	dir, cleanupDir = writeTestMigrations(t, map[string]string{
+		"00001_invalid.sql": "-- +goose Meta owner\n-- +goose Up\nCREATE TABLE a (id int);\n",
+	})
+	defer cleanupDir()
+	if _, err := CollectMigrations(dir, minVersion, maxVersion); err == nil {
+		t.Errorf("expected an error for invalid metadata")
+	}
+}
+
+func TestRequiredReviewers(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_reviewed.sql": "-- +goose Meta reviewers=alice,bob\n-- +goose Up\nCREATE TABLE a (id int);\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	for _, approver := range []string{"mallory", "bob"} {
+		plan, err := CreatePlan(db, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ApprovePlan(db, plan.ID, approver); err != nil {
+			t.Fatal(err)
+		}
+		err = ApplyPlan(db, dir, plan.ID)
+		if approver == "mallory" && err == nil {
+			t.Errorf("expected an error applying a plan approved by someone else than the reviewers")
+		}
+		if approver == "bob" && err != nil {
+			t.Errorf("unexpected error applying a plan approved by a reviewer: %v", err)
+		}
+	}
+}
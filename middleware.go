@@ -0,0 +1,76 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Statement is a SQL statement of a migration, executed by an ExecFunc.
+type Statement struct {
+	Migration *Migration
+	Direction string // up or down
+	Query     string
+	Args      []interface{} // values of the bound parameters, if any
+}
+
+// ExecFunc executes a SQL statement of a migration.
+type ExecFunc func(ctx context.Context, s *Statement) error
+
+// Middleware wraps the execution of the SQL statements of migrations, to
+// log, measure, rewrite or fail them:
+//
+//	goose.SetMiddleware(func(next goose.ExecFunc) goose.ExecFunc {
+//		return func(ctx context.Context, s *goose.Statement) error {
+//			started := time.Now()
+//			err := next(ctx, s)
+//			statementDuration.Observe(time.Since(started).Seconds())
+//			return err
+//		}
+//	})
+//
+// Changes of the middleware to the query and the arguments of the
+// statement are executed.
+type Middleware func(next ExecFunc) ExecFunc
+
+var middleware []Middleware
+
+// SetMiddleware sets the middleware wrapping the execution of the SQL
+// statements of migrations, the first one outermost. COPY FROM stdin
+// statements and the statements of Go migrations are executed without
+// middleware.
+func SetMiddleware(mw ...Middleware) {
+	middleware = mw
+}
+
+// WithMiddleware sets the middleware wrapping the execution of statements,
+// like SetMiddleware.
+func WithMiddleware(mw ...Middleware) OptionsFunc {
+	return func(o *options) { o.middleware = mw }
+}
+
+// execStatement executes s with exec, wrapped by the middleware.
+func execStatement(ctx context.Context, s *Statement, exec ExecFunc) error {
+	if queryComment {
+		exec = commentQuery(exec)
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		exec = middleware[i](exec)
+	}
+	return exec(ctx, s)
+}
+
+// execQuery is the ExecFunc executing statements on qe, innermost in the
+// middleware.
+func execQuery(db *sql.DB, qe QueryExecer) ExecFunc {
+	return func(ctx context.Context, s *Statement) error {
+		stop := watchProgress(db, s.Query)
+		defer stop()
+		defer inFlight(qe)()
+		if e, ok := qe.(contextExecer); ok {
+			_, err := e.ExecContext(ctx, s.Query, s.Args...)
+			return err
+		}
+		_, err := qe.Exec(s.Query, s.Args...)
+		return err
+	}
+}
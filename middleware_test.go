@@ -0,0 +1,67 @@
+package goose
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestMiddleware(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next ExecFunc) ExecFunc {
+			return func(ctx context.Context, s *Statement) error {
+				calls = append(calls, fmt.Sprintf("%s %d %s", name, s.Migration.Version, s.Direction))
+				return next(ctx, s)
+			}
+		}
+	}
+	comment := func(next ExecFunc) ExecFunc {
+		return func(ctx context.Context, s *Statement) error {
+			s.Query = fmt.Sprintf("/* goose:%05d */ %s", s.Migration.Version, s.Query)
+			return next(ctx, s)
+		}
+	}
+	var executed []string
+	last := func(next ExecFunc) ExecFunc {
+		return func(ctx context.Context, s *Statement) error {
+			executed = append(executed, strings.TrimSpace(s.Query))
+			return next(ctx, s)
+		}
+	}
+
+	if err := Up(db, dir, WithMiddleware(record("outer"), comment, last)); err != nil {
+		t.Fatal(err)
+	}
+	if len(middleware) != 0 {
+		t.Error("expected WithMiddleware not to change the global middleware")
+	}
+	if want := []string{"outer 1 up", "outer 2 up", "outer 3 up"}; fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+	if len(executed) != 3 || executed[0] != "/* goose:00001 */ CREATE TABLE a (id int);" {
+		t.Errorf("expected the rewritten queries to be executed, got %q", executed)
+	}
+
+	SetMiddleware(func(next ExecFunc) ExecFunc {
+		return func(ctx context.Context, s *Statement) error {
+			return errors.New("injected failure")
+		}
+	})
+	defer SetMiddleware()
+	err := Down(db, dir)
+	if err == nil || !strings.Contains(err.Error(), "injected failure") {
+		t.Errorf("expected the injected failure, got %v", err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 3 {
+		t.Errorf("expected version 3 after the failed rollback, got %d (%v)", v, err)
+	}
+}
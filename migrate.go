@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"time"
 
+	"github.com/loderunner/goose/version"
 	"github.com/pkg/errors"
 )
 
@@ -19,8 +21,6 @@ var (
 	ErrNoNextVersion = errors.New("no next version found")
 	// MaxVersion is the maximum allowed version.
 	MaxVersion int64 = 9223372036854775807 // max(int64)
-
-	registeredGoMigrations = map[int64]*Migration{}
 )
 
 // Migrations slice.
@@ -78,16 +78,14 @@ func (ms Migrations) Last() (*Migration, error) {
 	return ms[len(ms)-1], nil
 }
 
-// Versioned gets versioned migrations.
-func (ms Migrations) versioned() (Migrations, error) {
+// Versioned gets the versioned migrations, i.e. the ones using sequential
+// version numbers rather than timestamps.
+func (ms Migrations) Versioned() (Migrations, error) {
 	var migrations Migrations
 
 	// assume that the user will never have more than 19700101000000 migrations
 	for _, m := range ms {
-		// parse version as timestmap
-		versionTime, err := time.Parse(timestampFormat, fmt.Sprintf("%d", m.Version))
-
-		if versionTime.Before(time.Unix(0, 0)) || err != nil {
+		if version.SchemeOf(m.Version) == version.Sequential {
 			migrations = append(migrations, m)
 		}
 	}
@@ -96,25 +94,33 @@ func (ms Migrations) versioned() (Migrations, error) {
 }
 
 // Timestamped gets the timestamped migrations.
-func (ms Migrations) timestamped() (Migrations, error) {
+func (ms Migrations) Timestamped() (Migrations, error) {
 	var migrations Migrations
 
 	// assume that the user will never have more than 19700101000000 migrations
 	for _, m := range ms {
-		// parse version as timestmap
-		versionTime, err := time.Parse(timestampFormat, fmt.Sprintf("%d", m.Version))
-		if err != nil {
-			// probably not a timestamp
-			continue
+		if version.SchemeOf(m.Version) == version.Timestamp {
+			migrations = append(migrations, m)
 		}
+	}
+	return migrations, nil
+}
 
-		if versionTime.After(time.Unix(0, 0)) {
+// Filter gets the migrations with a version greater than from and lower
+// than or equal to to, i.e. the migrations to apply to migrate from version
+// from up to version to. The Next and Previous fields of the returned
+// migrations are left untouched.
+func (ms Migrations) Filter(from, to int64) Migrations {
+	var migrations Migrations
+	for _, m := range ms {
+		if m.Version > from && m.Version <= to {
 			migrations = append(migrations, m)
 		}
 	}
-	return migrations, nil
+	return migrations
 }
 
+// String renders the migrations, one source per line.
 func (ms Migrations) String() string {
 	str := ""
 	for _, m := range ms {
@@ -129,16 +135,14 @@ func AddMigration(up func(QueryExecer) error, down func(QueryExecer) error) {
 	AddNamedMigration(filename, up, down)
 }
 
-// AddNamedMigration : Add a named migration.
+// AddNamedMigration adds a migration named after its file, instead of the
+// calling file of AddMigration, for generated code and wrappers registering
+// migrations from other files. A base name is resolved in the migrations
+// directory, for the checksum and the metadata of the migration.
 func AddNamedMigration(filename string, up func(QueryExecer) error, down func(QueryExecer) error) {
 	v, _ := NumericComponent(filename)
 	migration := &Migration{Version: v, Next: -1, Previous: -1, Registered: true, UpFn: up, DownFn: down, Source: filename}
-
-	if existing, ok := registeredGoMigrations[v]; ok {
-		panic(fmt.Sprintf("failed to add migration %q: version conflicts with %q", filename, existing.Source))
-	}
-
-	registeredGoMigrations[v] = migration
+	registeredGoMigrations.add(migration)
 }
 
 // AddMigrationNoTx adds a migration. The migration will not use a transaction.
@@ -147,21 +151,39 @@ func AddMigrationNoTx(up func(QueryExecer) error, down func(QueryExecer) error)
 	AddNamedMigrationNoTx(filename, up, down)
 }
 
-// AddNamedMigrationNoTx adds a named migration. The migration will not use a transaction.
+// AddNamedMigrationNoTx adds a migration named after its file, like
+// AddNamedMigration. The migration will not use a transaction.
 func AddNamedMigrationNoTx(filename string, up func(QueryExecer) error, down func(QueryExecer) error) {
 	v, _ := NumericComponent(filename)
 	migration := &Migration{Version: v, Next: -1, Previous: -1, Registered: true, UpFn: up, DownFn: down, Source: filename, NoTx: true}
+	registeredGoMigrations.add(migration)
+}
 
-	if existing, ok := registeredGoMigrations[v]; ok {
-		panic(fmt.Sprintf("failed to add migration %q: version conflicts with %q", filename, existing.Source))
-	}
-
-	registeredGoMigrations[v] = migration
+// RegisteredMigrations returns the Go migrations registered with
+// goose.AddMigration() and its variants, sorted by version. Unlike
+// CollectMigrations, it doesn't need a migrations directory.
+func RegisteredMigrations() Migrations {
+	return sortAndConnectMigrations(registeredGoMigrations.all())
 }
 
 // CollectMigrations returns all the valid looking migration scripts in the
-// migrations folder and go func registry, and key them by version.
-func CollectMigrations(dirpath string, current, target int64) (Migrations, error) {
+// migrations folder and go func registry, and key them by version. They are
+// filtered with the options of WithExclude, WithVersionRange and
+// WithReversibleOnly, which only apply to CollectMigrations.
+func CollectMigrations(dirpath string, current, target int64, opts ...OptionsFunc) (migrations Migrations, err error) {
+	var filters options
+	for _, opt := range opts {
+		opt(&filters)
+	}
+	err = withOptions(opts, func() error {
+		migrations, err = collectMigrations(dirpath, current, target, filters)
+		return err
+	})
+	return migrations, err
+}
+
+func collectMigrations(dirpath string, current, target int64, filters options) (Migrations, error) {
+	defer enterPhase(phaseParse)()
 	if dirpath != "" {
 		if _, err := os.Stat(dirpath); os.IsNotExist(err) {
 			return nil, fmt.Errorf("%s directory does not exist", dirpath)
@@ -192,12 +214,40 @@ func CollectMigrations(dirpath string, current, target int64) (Migrations, error
 		}
 	}
 
+	// External command and WASM migration files, the latter only with a
+	// runtime to run them.
+	patterns := []string{"/*.sh", "/*.cmd"}
+	if wasmRuntime != nil {
+		patterns = append(patterns, "/*.wasm")
+	}
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(dirpath + pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			v, err := NumericComponent(file)
+			if err != nil {
+				continue // Skip helper scripts that don't have version prefix.
+			}
+			if versionFilter(v, current, target) {
+				migrations = append(migrations, &Migration{Version: v, Next: -1, Previous: -1, Source: file})
+			}
+		}
+	}
+
 	// Go migrations registered via goose.AddMigration().
-	for _, migration := range registeredGoMigrations {
+	for _, migration := range registeredGoMigrations.all() {
 		v, err := NumericComponent(migration.Source)
 		if err != nil {
 			return nil, err
 		}
+		if filepath.Base(migration.Source) == migration.Source {
+			path := filepath.Join(dirpath, migration.Source)
+			if _, err := os.Stat(path); err == nil {
+				migration.Source = path
+			}
+		}
 		if versionFilter(v, current, target) {
 			migrations = append(migrations, migration)
 		}
@@ -215,7 +265,11 @@ func CollectMigrations(dirpath string, current, target int64) (Migrations, error
 		}
 
 		// Skip migrations already existing migrations registered via goose.AddMigration().
-		if _, ok := registeredGoMigrations[v]; ok {
+		if registeredGoMigrations.has(v) {
+			continue
+		}
+		// Skip the hooks of SQL migrations registered via goose.AddSQLHooks().
+		if _, ok := registeredSQLHooks[v]; ok {
 			continue
 		}
 
@@ -225,9 +279,21 @@ func CollectMigrations(dirpath string, current, target int64) (Migrations, error
 		}
 	}
 
-	migrations = sortAndConnectMigrations(migrations)
+	filtered := migrations[:0]
+	for _, m := range migrations {
+		if m.Meta, err = parseMeta(m.Source); err != nil {
+			return nil, err
+		}
+		keep, err := filters.collectFilter(m)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			filtered = append(filtered, m)
+		}
+	}
 
-	return migrations, nil
+	return sortAndConnectMigrations(filtered), nil
 }
 
 func sortAndConnectMigrations(migrations Migrations) Migrations {
@@ -263,55 +329,74 @@ func versionFilter(v, current, target int64) bool {
 // EnsureDBVersion retrieves the current version for this DB.
 // Create and initialize the DB version table if it doesn't exist.
 func EnsureDBVersion(db *sql.DB) (int64, error) {
+	defer enterPhase(phaseBookkeeping)()
+	ensureDialect(db)
+	detectServerVersion(db, false)
 	rows, err := GetDialect().dbVersionQuery(db)
 	if err != nil {
 		return 0, createVersionTable(db)
 	}
-	defer rows.Close()
+	version, err := scanDBVersion(rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
 
+// scanDBVersion finds the current version in the rows of the version
+// table, most recent first.
+func scanDBVersion(rows *sql.Rows) (int64, error) {
 	// The most recent record for each migration specifies
 	// whether it has been applied or rolled back.
-	// The first version we find that has been applied is the current version.
-
-	toSkip := make([]int64, 0)
-
+	// The highest version that has been applied is the current version,
+	// even if older missing migrations were applied after it.
+
+	var (
+		seen    = map[int64]bool{}
+		current int64
+		found   bool
+	)
 	for rows.Next() {
 		var row MigrationRecord
-		if err = rows.Scan(&row.VersionID, &row.IsApplied); err != nil {
+		if err := rows.Scan(&row.VersionID, &row.IsApplied); err != nil {
 			return 0, errors.Wrap(err, "failed to scan row")
 		}
 
-		// have we already marked this version to be skipped?
-		skip := false
-		for _, v := range toSkip {
-			if v == row.VersionID {
-				skip = true
-				break
-			}
-		}
-
-		if skip {
+		if seen[row.VersionID] {
 			continue
 		}
+		seen[row.VersionID] = true
 
-		// if version has been applied we're done
-		if row.IsApplied {
-			return row.VersionID, nil
+		if row.IsApplied && (!found || row.VersionID > current) {
+			current, found = row.VersionID, true
 		}
-
-		// latest version of migration has not been applied.
-		toSkip = append(toSkip, row.VersionID)
 	}
 	if err := rows.Err(); err != nil {
 		return 0, errors.Wrap(err, "failed to get next row")
 	}
 
-	return 0, ErrNoNextVersion
+	if !found {
+		return 0, ErrNoNextVersion
+	}
+	return current, nil
 }
 
 // Create the db version table
 // and insert the initial 0 value into it
 func createVersionTable(db *sql.DB) error {
+	if !transactional() {
+		if _, err := db.Exec(GetDialect().createVersionTableSQL()); err != nil {
+			return err
+		}
+		if q := versionIndexSQL(); q != "" {
+			if _, err := db.Exec(q); err != nil {
+				return err
+			}
+		}
+		return insertVersion(db, 0, true, "", 0)
+	}
+
 	txn, err := db.Begin()
 	if err != nil {
 		return err
@@ -319,14 +404,35 @@ func createVersionTable(db *sql.DB) error {
 
 	d := GetDialect()
 
-	if _, err := txn.Exec(d.createVersionTableSQL()); err != nil {
-		txn.Rollback()
-		return err
+	statements := []string{d.createVersionTableSQL()}
+	fb, firebird := d.(*FirebirdDialect)
+	if firebird {
+		statements = fb.createVersionTableStatements()
+	}
+	if q := versionIndexSQL(); q != "" {
+		statements = append(statements, q)
+	}
+	for _, query := range statements {
+		if _, err := txn.Exec(query); err != nil {
+			txn.Rollback()
+			return err
+		}
+	}
+
+	if firebird {
+		// Firebird doesn't let a transaction use the table it creates: the
+		// table is created when the transaction commits.
+		if err := txn.Commit(); err != nil {
+			return err
+		}
+		if txn, err = db.Begin(); err != nil {
+			return err
+		}
 	}
 
-	version := 0
+	version := int64(0)
 	applied := true
-	if _, err := txn.Exec(d.insertVersionSQL(), version, applied); err != nil {
+	if err := insertVersion(txn, version, applied, "", 0); err != nil {
 		txn.Rollback()
 		return err
 	}
@@ -334,9 +440,35 @@ func createVersionTable(db *sql.DB) error {
 	return txn.Commit()
 }
 
-// GetDBVersion is an alias for EnsureDBVersion, but returns -1 in error.
+// insertVersion records a migration in the version table, along with the
+// build of the binary applying it, the checksum of its source, the
+// duration of its run and the ID of the run.
+func insertVersion(qe QueryExecer, version int64, applied bool, checksum string, duration time.Duration) error {
+	defer enterPhase(phaseBookkeeping)()
+	_, err := qe.ExecContext(runCtx, GetDialect().insertVersionSQL(), version, applied, buildInfo(), checksum, formatDurationMs(duration), currentRunID())
+	return err
+}
+
+// deleteVersion removes a rolled back migration from the version table.
+func deleteVersion(qe QueryExecer, version int64) error {
+	defer enterPhase(phaseBookkeeping)()
+	_, err := qe.ExecContext(runCtx, GetDialect().deleteVersionSQL(), version)
+	return err
+}
+
+// formatDurationMs formats the duration of a migration recorded in the
+// version table, in milliseconds, or "" if it isn't known.
+func formatDurationMs(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
+
+// GetDBVersion is an alias for EnsureDBVersion, but returns -1 in error,
+// and may return a version cached with SetVersionCache.
 func GetDBVersion(db *sql.DB) (int64, error) {
-	version, err := EnsureDBVersion(db)
+	version, err := cachedDBVersion(db, true)
 	if err != nil {
 		return -1, err
 	}
@@ -1,6 +1,8 @@
 package goose
 
 import (
+	"database/sql"
+	"fmt"
 	"testing"
 )
 
@@ -56,3 +58,106 @@ func validateMigrationSort(t *testing.T, ms Migrations, sorted []int64) {
 
 	t.Log(ms)
 }
+
+func TestMigrationsHelpers(t *testing.T) {
+	t.Parallel()
+
+	ms := sortAndConnectMigrations(Migrations{
+		newMigration(1, "00001_a.sql"),
+		newMigration(2, "00002_b.go"),
+		newMigration(20190501120000, "20190501120000_c.sql"),
+		newMigration(3, "00003_d.sql"),
+	})
+
+	if m, err := ms.Current(2); err != nil || m.Version != 2 {
+		t.Errorf("unexpected Current(2): %v, %v", m, err)
+	}
+	if _, err := ms.Current(4); err != ErrNoCurrentVersion {
+		t.Errorf("expected ErrNoCurrentVersion, got %v", err)
+	}
+	if m, err := ms.Next(2); err != nil || m.Version != 3 {
+		t.Errorf("unexpected Next(2): %v, %v", m, err)
+	}
+	if _, err := ms.Next(20190501120000); err != ErrNoNextVersion {
+		t.Errorf("expected ErrNoNextVersion, got %v", err)
+	}
+	if m, err := ms.Previous(3); err != nil || m.Version != 2 {
+		t.Errorf("unexpected Previous(3): %v, %v", m, err)
+	}
+	if _, err := ms.Previous(1); err != ErrNoNextVersion {
+		t.Errorf("expected ErrNoNextVersion, got %v", err)
+	}
+	if m, err := ms.Last(); err != nil || m.Version != 20190501120000 {
+		t.Errorf("unexpected Last(): %v, %v", m, err)
+	}
+	if _, err := (Migrations{}).Last(); err != ErrNoNextVersion {
+		t.Errorf("expected ErrNoNextVersion, got %v", err)
+	}
+
+	versioned, err := ms.Versioned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateVersions(t, "Versioned()", versioned, []int64{1, 2, 3})
+
+	timestamped, err := ms.Timestamped()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateVersions(t, "Timestamped()", timestamped, []int64{20190501120000})
+
+	validateVersions(t, "Filter(1, 3)", ms.Filter(1, 3), []int64{2, 3})
+	validateVersions(t, "Filter(0, MaxVersion)", ms.Filter(0, MaxVersion), []int64{1, 2, 3, 20190501120000})
+	validateVersions(t, "Filter(3, 3)", ms.Filter(3, 3), nil)
+
+	want := "00001_a.sql\n00002_b.go\n00003_d.sql\n20190501120000_c.sql\n"
+	if got := ms.String(); got != want {
+		t.Errorf("unexpected String(), got %q, want %q", got, want)
+	}
+}
+
+func validateVersions(t *testing.T, name string, ms Migrations, versions []int64) {
+	if len(ms) != len(versions) {
+		t.Errorf("%s: unexpected migrations, got %v, want versions %v", name, ms, versions)
+		return
+	}
+	for i, m := range ms {
+		if m.Version != versions[i] {
+			t.Errorf("%s: unexpected migrations, got %v, want versions %v", name, ms, versions)
+			return
+		}
+	}
+}
+
+func TestVersionTableBuild(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	// Version table created by an older version of goose.
+	if _, err := db.Exec(fmt.Sprintf(`CREATE TABLE %s (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                version_id INTEGER NOT NULL,
+                is_applied INTEGER NOT NULL,
+                tstamp TIMESTAMP DEFAULT (datetime('now'))
+            )`, TableName())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES (0, 1)", TableName())); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := EnsureDBVersion(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := newGoMigration(1).Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var build sql.NullString
+	if err := db.QueryRow(fmt.Sprintf("SELECT build FROM %s WHERE version_id = 1", TableName())).Scan(&build); err != nil {
+		t.Fatal(err)
+	}
+	if !build.Valid || build.String != buildInfo() {
+		t.Errorf("unexpected build, got %q, want %q", build.String, buildInfo())
+	}
+}
@@ -2,14 +2,16 @@ package goose
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/loderunner/goose/version"
 	"github.com/pkg/errors"
 )
 
@@ -43,29 +45,88 @@ type Migration struct {
 	UpFn       func(QueryExecer) error // Up go migration function
 	DownFn     func(QueryExecer) error // Down go migration function
 	NoTx       bool
+	Meta       map[string]string // metadata of the '+goose Meta' annotations
 }
 
 func (m *Migration) String() string {
-	return fmt.Sprintf(m.Source)
+	return fmt.Sprint(m.Source)
+}
+
+// Checksum returns the hex-encoded SHA-256 checksum of the migration source
+// file. It returns an empty string for Go migrations built into a binary,
+// when their source file is not available.
+func (m *Migration) Checksum() (string, error) {
+	f, err := os.Open(m.Source)
+	if err != nil {
+		if os.IsNotExist(err) && filepath.Ext(m.Source) == ".go" {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to open migration %v", filepath.Base(m.Source))
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrapf(err, "failed to read migration %v", filepath.Base(m.Source))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // Up runs an up migration.
 func (m *Migration) Up(db *sql.DB) error {
-	if err := m.run(db, true); err != nil {
+	started := time.Now()
+	err := m.run(db, true)
+	recordResult(m, time.Since(started), err)
+	if err != nil {
+		reportFailure(m, true, err)
 		return err
 	}
+	recordMigration(m.Version)
 	return nil
 }
 
 // Down runs a down migration.
 func (m *Migration) Down(db *sql.DB) error {
-	if err := m.run(db, false); err != nil {
+	started := time.Now()
+	err := m.run(db, false)
+	recordResult(m, time.Since(started), err)
+	if err != nil {
+		reportFailure(m, false, err)
 		return err
 	}
+	recordMigration(m.Version)
 	return nil
 }
 
 func (m *Migration) run(db *sql.DB, direction bool) error {
+	defer labelMigration(m)()
+	defer enterPhase(phaseExecute)()
+	if err := runCtx.Err(); err != nil {
+		return err
+	}
+	if activeInvocation == nil && !noVersioning {
+		// Run on its own, outside of the invocation of a command, which
+		// upgrades the version table.
+		if err := upgradeVersionTable(db); err != nil {
+			return err
+		}
+	}
+	if err := checkHeavy(m, time.Now()); err != nil {
+		return err
+	}
+	if direction {
+		if err := checkDown(m); err != nil {
+			return err
+		}
+	} else if err := checkIrreversible(m); err != nil {
+		return err
+	}
+	if direction && !noVersioning {
+		if err := checkRequires(db, m); err != nil {
+			return err
+		}
+	}
+
 	switch filepath.Ext(m.Source) {
 	case ".sql":
 		f, err := os.Open(m.Source)
@@ -74,13 +135,86 @@ func (m *Migration) run(db *sql.DB, direction bool) error {
 		}
 		defer f.Close()
 
-		statements, useTx, err := parseSQLMigration(f, direction)
+		if streaming {
+			// Parse the whole file once without keeping the statements, so
+			// syntax errors are reported before anything is executed.
+			var (
+				check       txCheck
+				tables      []string
+				destructive []string
+			)
+			parsed := enterPhase(phaseParse)
+			a, err := parseSQLStatements(f, direction, func(stmt string) error {
+				check.add(stmt)
+				if impactEnabled() {
+					tables = append(tables, impactTable(stmt))
+				}
+				if !direction && dataLossGuard && len(dataLosses(stmt)) > 0 {
+					destructive = append(destructive, stmt)
+				}
+				return nil
+			})
+			parsed()
+			if err != nil {
+				return errors.Wrapf(err, "ERROR %v: failed to parse SQL migration file", filepath.Base(m.Source))
+			}
+			if err := check.err(m, a); err != nil {
+				return err
+			}
+			if err := checkImpact(db, m, tables); err != nil {
+				return err
+			}
+			if !direction {
+				if err := checkDataLoss(db, m, destructive); err != nil {
+					return err
+				}
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return errors.Wrapf(err, "ERROR %v: failed to rewind SQL migration file", filepath.Base(m.Source))
+			}
+
+			if err := runSQLMigrationStream(db, f, a, m, direction); err != nil {
+				return failedMigration(a.useTx && transactional(), errors.Wrapf(err, "ERROR %v: failed to run SQL migration", filepath.Base(m.Source)))
+			}
+
+			if check.count > 0 {
+				log.Println("OK   ", filepath.Base(m.Source))
+			} else {
+				log.Println("EMPTY", filepath.Base(m.Source))
+			}
+			return nil
+		}
+
+		parsed := enterPhase(phaseParse)
+		statements, a, err := parseSQLMigration(f, direction)
+		parsed()
 		if err != nil {
 			return errors.Wrapf(err, "ERROR %v: failed to parse SQL migration file", filepath.Base(m.Source))
 		}
+		var check txCheck
+		for _, stmt := range statements {
+			check.add(stmt)
+		}
+		if err := check.err(m, a); err != nil {
+			return err
+		}
+		var tables []string
+		if impactEnabled() {
+			for _, stmt := range statements {
+				tables = append(tables, impactTable(stmt))
+			}
+		}
+		if err := checkImpact(db, m, tables); err != nil {
+			return err
+		}
+		if !direction {
+			if err := checkDataLoss(db, m, statements); err != nil {
+				return err
+			}
+		}
 
-		if err := runSQLMigration(db, statements, useTx, m.Version, direction); err != nil {
-			return errors.Wrapf(err, "ERROR %v: failed to run SQL migration", filepath.Base(m.Source))
+		if err := runSQLMigration(db, statements, a, m, direction); err != nil {
+			return failedMigration(a.useTx && transactional(), errors.Wrapf(err, "ERROR %v: failed to run SQL migration", filepath.Base(m.Source)))
 		}
 
 		if len(statements) > 0 {
@@ -89,32 +223,74 @@ func (m *Migration) run(db *sql.DB, direction bool) error {
 			log.Println("EMPTY", filepath.Base(m.Source))
 		}
 
+	case ".sh", ".cmd":
+		if err := runExecMigration(db, m, direction); err != nil {
+			return err
+		}
+		log.Println("OK   ", filepath.Base(m.Source))
+
+	case ".wasm":
+		if err := runWASMMigration(db, m, direction); err != nil {
+			return err
+		}
+		log.Println("OK   ", filepath.Base(m.Source))
+
 	case ".go":
+		if !m.Registered && goBuild {
+			// The runner records the version in the transaction of the
+			// migration.
+			if err := runCommand(m, direction); err != nil {
+				return err
+			}
+			log.Println("OK   ", filepath.Base(m.Source))
+			return nil
+		}
 		if !m.Registered {
-			return errors.Errorf("ERROR %v: failed to run Go migration: Go functions must be registered and built into a custom binary (see https://github.com/pressly/goose/tree/master/examples/go-migrations)", m.Source)
+			return errors.Errorf("ERROR %v: failed to run Go migration: Go functions must be registered and built into a custom binary (see https://github.com/pressly/goose/tree/master/examples/go-migrations), or built by goose with SetGoBuild", m.Source)
+		}
+
+		checksum, err := m.Checksum()
+		if err != nil {
+			return err
 		}
+		started := time.Now()
 
-		if m.NoTx {
+		if m.NoTx || !transactional() {
 			fn := m.UpFn
 			if !direction {
 				fn = m.DownFn
 			}
 
-			if fn != nil {
-				// Run Go migration function.
-				if err := fn(db); err != nil {
-					return errors.Wrapf(err, "ERROR %v: failed to run Go migration function %T", filepath.Base(m.Source), fn)
+			// Run all the statements of the migration on the same connection.
+			err := withConn(db, func(conn connExecer) error {
+				if err := setupSession(conn); err != nil {
+					return err
+				}
+				if fn != nil {
+					// Run Go migration function.
+					if err := fn(conn); err != nil {
+						return withExitCode(ExitPartialApply, errors.Wrapf(err, "ERROR %v: failed to run Go migration function %T", filepath.Base(m.Source), fn))
+					}
 				}
-			}
 
-			if direction {
-				if _, err := db.Exec(GetDialect().insertVersionSQL(), m.Version, direction); err != nil {
-					return errors.Wrap(err, "ERROR failed to execute transaction")
+				if err := resetSession(conn); err != nil {
+					return err
 				}
-			} else {
-				if _, err := db.Exec(GetDialect().deleteVersionSQL(), m.Version); err != nil {
-					return errors.Wrap(err, "ERROR failed to execute transaction")
+				if !noVersioning {
+					if direction {
+						if err := insertVersion(conn, m.Version, direction, checksum, time.Since(started)); err != nil {
+							return errors.Wrap(err, "ERROR failed to execute transaction")
+						}
+					} else {
+						if err := deleteVersion(conn, m.Version); err != nil {
+							return errors.Wrap(err, "ERROR failed to execute transaction")
+						}
+					}
 				}
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 
 			if fn != nil {
@@ -123,10 +299,11 @@ func (m *Migration) run(db *sql.DB, direction bool) error {
 				log.Println("EMPTY", filepath.Base(m.Source))
 			}
 		} else {
-			tx, err := db.Begin()
+			tx, release, err := beginTx(db)
 			if err != nil {
 				return errors.Wrap(err, "ERROR failed to begin transaction")
 			}
+			defer release()
 
 			fn := m.UpFn
 			if !direction {
@@ -137,19 +314,25 @@ func (m *Migration) run(db *sql.DB, direction bool) error {
 				// Run Go migration function.
 				if err := fn(tx); err != nil {
 					tx.Rollback()
-					return errors.Wrapf(err, "ERROR %v: failed to run Go migration function %T", filepath.Base(m.Source), fn)
+					return withExitCode(ExitSQLError, errors.Wrapf(err, "ERROR %v: failed to run Go migration function %T", filepath.Base(m.Source), fn))
 				}
 			}
 
-			if direction {
-				if _, err := tx.Exec(GetDialect().insertVersionSQL(), m.Version, direction); err != nil {
-					tx.Rollback()
-					return errors.Wrap(err, "ERROR failed to execute transaction")
-				}
-			} else {
-				if _, err := tx.Exec(GetDialect().deleteVersionSQL(), m.Version); err != nil {
-					tx.Rollback()
-					return errors.Wrap(err, "ERROR failed to execute transaction")
+			if err := resetSession(tx); err != nil {
+				tx.Rollback()
+				return err
+			}
+			if !noVersioning {
+				if direction {
+					if err := insertVersion(tx, m.Version, direction, checksum, time.Since(started)); err != nil {
+						tx.Rollback()
+						return errors.Wrap(err, "ERROR failed to execute transaction")
+					}
+				} else {
+					if err := deleteVersion(tx, m.Version); err != nil {
+						tx.Rollback()
+						return errors.Wrap(err, "ERROR failed to execute transaction")
+					}
 				}
 			}
 
@@ -176,19 +359,11 @@ func (m *Migration) run(db *sql.DB, direction bool) error {
 func NumericComponent(name string) (int64, error) {
 	base := filepath.Base(name)
 
-	if ext := filepath.Ext(base); ext != ".go" && ext != ".sql" {
+	switch filepath.Ext(base) {
+	case ".go", ".sql", ".sh", ".cmd", ".wasm", ".cypher", ".json", ".ndjson":
+	default:
 		return 0, errors.New("not a recognized migration file type")
 	}
 
-	idx := strings.Index(base, "_")
-	if idx < 0 {
-		return 0, errors.New("no separator found")
-	}
-
-	n, e := strconv.ParseInt(base[:idx], 10, 64)
-	if e == nil && n <= 0 {
-		return 0, errors.New("migration IDs must be greater than zero")
-	}
-
-	return n, e
+	return version.Parse(base)
 }
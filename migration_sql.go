@@ -1,8 +1,12 @@
 package goose
 
 import (
+	"context"
 	"database/sql"
+	"io"
+	"path/filepath"
 	"regexp"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -15,37 +19,93 @@ import (
 //
 // All statements following an Up or Down directive are grouped together
 // until another direction directive is found.
-func runSQLMigration(db *sql.DB, statements []string, useTx bool, v int64, direction bool) error {
-	if useTx {
+//
+// On TiDB, a migration transaction failing on a concurrent schema change is
+// retried.
+func runSQLMigration(db *sql.DB, statements []string, a sqlAnnotations, m *Migration, direction bool) error {
+	for attempt := 1; ; attempt++ {
+		err := runSQLStatements(db, func(exec func(query string) error) error {
+			for _, query := range statements {
+				if err := exec(query); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, a, m, direction)
+		if !a.useTx || attempt > tidbSchemaChangedRetries || !tidbSchemaChanged(err) {
+			return err
+		}
+		log.Printf("goose: %v: schema changed by a concurrent DDL statement, retrying\n", filepath.Base(m.Source))
+	}
+}
+
+// runSQLMigrationStream runs a migration like runSQLMigration, executing
+// statements as soon as they are read from r instead of loading the whole
+// script in memory first.
+func runSQLMigrationStream(db *sql.DB, r io.Reader, a sqlAnnotations, m *Migration, direction bool) error {
+	return runSQLStatements(db, func(exec func(query string) error) error {
+		_, err := parseSQLStatements(r, direction, exec)
+		return err
+	}, a, m, direction)
+}
+
+// runSQLStatements runs the statements fed to exec by statements, then
+// records the new version.
+func runSQLStatements(db *sql.DB, statements func(exec func(query string) error) error, a sqlAnnotations, m *Migration, direction bool) error {
+	checksum, err := m.Checksum()
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+
+	if a.useTx && transactional() {
 		// TRANSACTION.
 
 		verboseInfo("Begin transaction")
 
-		tx, err := db.Begin()
+		tx, release, err := beginTx(db)
 		if err != nil {
 			return errors.Wrap(err, "failed to begin transaction")
 		}
+		defer release()
 
-		for _, query := range statements {
+		err = runWithSQLHooks(tx, m, direction, statements, func(query string) error {
 			verboseInfo("Executing statement: %s\n", clearStatement(query))
-			if _, err = tx.Exec(query); err != nil {
-				verboseInfo("Rollback transaction")
-				tx.Rollback()
-				return errors.Wrapf(err, "failed to execute SQL query %q", clearStatement(query))
+			var err error
+			if isCopyFromStdin(query) {
+				err = execCopyFromStdin(tx, query)
+			} else {
+				err = execSQL(db, tx, m, direction, query, a.params)
 			}
+			if err != nil {
+				return withStatement(err, query)
+			}
+			return nil
+		})
+		if err != nil {
+			verboseInfo("Rollback transaction")
+			tx.Rollback()
+			return err
 		}
 
-		if direction {
-			if _, err := tx.Exec(GetDialect().insertVersionSQL(), v, direction); err != nil {
-				verboseInfo("Rollback transaction")
-				tx.Rollback()
-				return errors.Wrap(err, "failed to insert new goose version")
-			}
-		} else {
-			if _, err := tx.Exec(GetDialect().deleteVersionSQL(), v); err != nil {
-				verboseInfo("Rollback transaction")
-				tx.Rollback()
-				return errors.Wrap(err, "failed to delete goose version")
+		if err := resetSession(tx); err != nil {
+			verboseInfo("Rollback transaction")
+			tx.Rollback()
+			return err
+		}
+		if !noVersioning {
+			if direction {
+				if err := insertVersion(tx, m.Version, direction, checksum, time.Since(started)); err != nil {
+					verboseInfo("Rollback transaction")
+					tx.Rollback()
+					return errors.Wrap(err, "failed to insert new goose version")
+				}
+			} else {
+				if err := deleteVersion(tx, m.Version); err != nil {
+					verboseInfo("Rollback transaction")
+					tx.Rollback()
+					return errors.Wrap(err, "failed to delete goose version")
+				}
 			}
 		}
 
@@ -58,17 +118,67 @@ func runSQLMigration(db *sql.DB, statements []string, useTx bool, v int64, direc
 	}
 
 	// NO TRANSACTION.
-	for _, query := range statements {
-		verboseInfo("Executing statement: %s", clearStatement(query))
-		if _, err := db.Exec(query); err != nil {
-			return errors.Wrapf(err, "failed to execute SQL query %q", clearStatement(query))
+	return withConn(db, func(conn connExecer) error {
+		if err := setupSession(conn); err != nil {
+			return err
 		}
+		batch := &spannerDDLBatch{exec: conn.Exec}
+		err := runWithSQLHooks(conn, m, direction, statements, func(query string) error {
+			if err := batch.add(query); err != nil {
+				return err
+			}
+			verboseInfo("Executing statement: %s", clearStatement(query))
+			if isCopyFromStdin(query) {
+				if err := execCopyFromStdinNoTx(conn, query); err != nil {
+					return withStatement(err, query)
+				}
+				return nil
+			}
+			if err := execSQL(db, conn, m, direction, tidbBatch(trinoStatement(query)), a.params); err != nil {
+				return withStatement(err, query)
+			}
+			return nil
+		})
+		if err == nil {
+			err = batch.run()
+		}
+		if err != nil {
+			batch.abort()
+			return err
+		}
+		if err := resetSession(conn); err != nil {
+			return err
+		}
+		if !noVersioning {
+			if err := insertVersion(conn, m.Version, direction, checksum, time.Since(started)); err != nil {
+				return errors.Wrap(err, "failed to insert new goose version")
+			}
+		}
+		return nil
+	})
+}
+
+// execSQL executes a single statement on qe through the middleware, binding
+// the declared parameters, and reports its progress polling db.
+func execSQL(db *sql.DB, qe QueryExecer, m *Migration, direction bool, query string, params []string) error {
+	query, args, err := bindParams(query, params)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		verboseInfo("Binding parameters: %v", args)
 	}
-	if _, err := db.Exec(GetDialect().insertVersionSQL(), v, direction); err != nil {
-		return errors.Wrap(err, "failed to insert new goose version")
+	s := &Statement{Migration: m, Direction: "down", Query: query, Args: args}
+	if direction {
+		s.Direction = "up"
 	}
+	return execStatement(runCtx, s, execQuery(db, qe))
+}
 
-	return nil
+// contextExecer is implemented by transactions and connections, to run
+// statements canceled with the context of the running Runner.
+type contextExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
 const (
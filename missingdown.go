@@ -0,0 +1,83 @@
+package goose
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// MissingDown is the handling of the migrations that can't be rolled back,
+// with an empty or absent Down section, see Migration.HasDown.
+type MissingDown string
+
+// Handlings of the migrations without Down section.
+const (
+	MissingDownIgnore MissingDown = "ignore"
+	MissingDownWarn   MissingDown = "warn"
+	MissingDownError  MissingDown = "error"
+)
+
+var missingDown = MissingDownIgnore
+
+// ErrMissingDown is the cause of the errors of ValidateDown, when some
+// migrations have no Down section.
+var ErrMissingDown = errors.New("no Down section, they can't be rolled back")
+
+// SetMissingDown sets the handling of the migrations without Down section
+// when they are applied: ignored by default, logged as a warning, or
+// refused as an error before they run, since discovering a missing rollback
+// during an incident is too late.
+func SetMissingDown(mode MissingDown) {
+	missingDown = mode
+}
+
+// WithMissingDown sets the handling of the migrations without Down
+// section, like SetMissingDown.
+func WithMissingDown(mode MissingDown) OptionsFunc {
+	return func(o *options) { o.missingDown = mode }
+}
+
+// checkDown warns about, or refuses, the migration m about to be applied
+// if it can't be rolled back, depending on SetMissingDown.
+func checkDown(m *Migration) error {
+	if (missingDown != MissingDownWarn && missingDown != MissingDownError) || m.Irreversible() {
+		return nil
+	}
+	ok, err := m.HasDown()
+	if err != nil || ok {
+		return err
+	}
+	if missingDown == MissingDownError {
+		return errors.Errorf("ERROR %v: migration has no Down section, it can't be rolled back", filepath.Base(m.Source))
+	}
+	log.Printf("goose: WARNING %v has no Down section, it can't be rolled back\n", filepath.Base(m.Source))
+	return nil
+}
+
+// ValidateDown checks that every migration of dir can be rolled back, but
+// the irreversible ones. It logs the migrations without Down section, and
+// fails with ErrMissingDown if there are any, whatever SetMissingDown.
+func ValidateDown(dir string) error {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return err
+	}
+	var missing []int64
+	for _, m := range migrations {
+		if m.Irreversible() {
+			continue
+		}
+		ok, err := m.HasDown()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Printf("goose: %v has no Down section\n", filepath.Base(m.Source))
+			missing = append(missing, m.Version)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Wrapf(ErrMissingDown, "migrations %s", joinVersions(missing))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestMissingDown(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql":   "-- +goose Up\nCREATE TABLE a (id int);\n-- +goose Down\nDROP TABLE a;\n",
+		"00002_backfill_a.sql": "-- +goose Up\nINSERT INTO a VALUES (1);\n-- +goose Down\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := ValidateDown(dir); errors.Cause(err) != ErrMissingDown || !strings.HasPrefix(err.Error(), "migrations 2: ") {
+		t.Errorf("expected the migration without down to fail the validation, got %v", err)
+	}
+	SetMissingDown(MissingDownWarn)
+	if err := ValidateDown(dir); errors.Cause(err) != ErrMissingDown {
+		t.Errorf("expected the validation to fail whatever SetMissingDown, got %v", err)
+	}
+	SetMissingDown(MissingDownIgnore)
+
+	err := Up(db, dir, WithMissingDown(MissingDownError))
+	if err == nil || !strings.Contains(err.Error(), "00002_backfill_a.sql: migration has no Down section") {
+		t.Fatalf("expected the migration without down to be refused, got %v", err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 1 {
+		t.Errorf("expected version 1, got %d (%v)", v, err)
+	}
+	if err := Up(db, dir, WithMissingDown(MissingDownWarn)); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 2 {
+		t.Errorf("expected version 2, got %d (%v)", v, err)
+	}
+}
@@ -0,0 +1,115 @@
+package goose
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Namespace is a directory of migrations with its own version table, so
+// that the modules of a monolith can keep their migrations next to their
+// code, and version them independently.
+type Namespace struct {
+	Name string // suffix of the version table, or empty for the version table itself
+	Dir  string
+}
+
+// TableName returns the version table of the namespace: the version table
+// name followed by an underscore and the namespace name.
+func (n Namespace) TableName() string {
+	if n.Name == "" {
+		return TableName()
+	}
+	return TableName() + "_" + n.Name
+}
+
+func (n Namespace) String() string {
+	if n.Name == "" {
+		return n.Dir
+	}
+	return n.Name
+}
+
+// ParseNamespaces parses a comma-separated list of namespaces, of form
+// NAME=DIR, or DIR for a namespace named after the base name of its
+// directory:
+//
+//	db/core,analytics=db/analytics,tenant=modules/tenant/migrations
+//
+// A namespace with an empty name, =DIR, uses the version table itself.
+func ParseNamespaces(s string) ([]Namespace, error) {
+	var (
+		namespaces []Namespace
+		seen       = map[string]bool{}
+	)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		n := Namespace{Name: filepath.Base(field), Dir: field}
+		if i := strings.Index(field, "="); i >= 0 {
+			n = Namespace{Name: field[:i], Dir: field[i+1:]}
+		}
+		if n.Dir == "" {
+			return nil, errors.Errorf("namespace %q has no directory", n.Name)
+		}
+		if seen[n.Name] {
+			return nil, errors.Errorf("duplicate namespace %q", n.Name)
+		}
+		seen[n.Name] = true
+		namespaces = append(namespaces, n)
+	}
+	if len(namespaces) == 0 {
+		return nil, errors.New("no migrations directory")
+	}
+	return namespaces, nil
+}
+
+// rollbackCommands run in the reverse order of the namespaces, so that
+// modules roll back before the ones they depend on.
+var rollbackCommands = map[string]bool{
+	"down":        true,
+	"down-to":     true,
+	"down-run":    true,
+	"down-to-tag": true,
+	"redo":        true,
+	"reset":       true,
+}
+
+// RunNamespaces runs a goose command in each namespace, with its version
+// table: in order, or in reverse order for the commands rolling back
+// migrations. It stops at the first namespace failing. Up holds back the
+// migrations requiring migrations of later namespaces until those are
+// applied.
+func RunNamespaces(command string, db *sql.DB, namespaces []Namespace, args ...string) error {
+	switch command {
+	case "up":
+		return withOptions(nil, func() error {
+			return upNamespaces(db, namespaces)
+		})
+	case "serve", "watch", "browse":
+		return errors.Errorf("%s requires a single migrations directory", command)
+	}
+
+	ordered := make([]Namespace, len(namespaces))
+	copy(ordered, namespaces)
+	if rollbackCommands[command] {
+		for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+	}
+
+	for _, n := range ordered {
+		log.Printf("goose: %s: %s\n", n.TableName(), n.Dir)
+		err := withOptions([]OptionsFunc{WithTableName(n.TableName())}, func() error {
+			return runLocked(command, db, n.Dir, args...)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "%s", n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package goose
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNamespaces(t *testing.T) {
+	namespaces, err := ParseNamespaces("db/core, analytics=db/analytics,=legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Namespace{{"core", "db/core"}, {"analytics", "db/analytics"}, {"", "legacy"}}
+	if !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("expected %v, got %v", want, namespaces)
+	}
+	if table := namespaces[1].TableName(); table != "goose_db_version_analytics" {
+		t.Errorf("unexpected version table %q", table)
+	}
+	if table := namespaces[2].TableName(); table != "goose_db_version" {
+		t.Errorf("unexpected version table %q", table)
+	}
+
+	for _, s := range []string{"", "a=db/a,a=db/b", "a="} {
+		if _, err := ParseNamespaces(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestRunNamespaces(t *testing.T) {
+	coreDir, cleanupCore := writeTestMigrations(t, testMigrations)
+	defer cleanupCore()
+	analyticsDir, cleanupAnalytics := writeTestMigrations(t, map[string]string{
+		"00001_create_events.sql": "-- +goose Up\nCREATE TABLE events (id int);\n-- +goose Down\nDROP TABLE events;\n",
+	})
+	defer cleanupAnalytics()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	namespaces := []Namespace{{"core", coreDir}, {"analytics", analyticsDir}}
+	versions := func() []int64 {
+		var versions []int64
+		for _, n := range namespaces {
+			var version int64
+			err := withOptions([]OptionsFunc{WithTableName(n.TableName())}, func() (err error) {
+				version, err = GetDBVersion(db)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			versions = append(versions, version)
+		}
+		return versions
+	}
+
+	defer SetLogger(log)
+	logger := &bufferLogger{}
+	SetLogger(logger)
+	if err := RunNamespaces("up", db, namespaces); err != nil {
+		t.Fatal(err)
+	}
+	if v := versions(); !reflect.DeepEqual(v, []int64{3, 1}) {
+		t.Errorf("expected versions [3 1], got %v", v)
+	}
+	if TableName() != "goose_db_version" {
+		t.Errorf("unexpected table name after the call, got %q", TableName())
+	}
+	if out := logger.String(); strings.Index(out, "00003_create_c.sql") > strings.Index(out, "00001_create_events.sql") {
+		t.Errorf("expected core to be migrated before analytics, got %q", out)
+	}
+
+	logger.Reset()
+	if err := RunNamespaces("reset", db, namespaces); err != nil {
+		t.Fatal(err)
+	}
+	if v := versions(); !reflect.DeepEqual(v, []int64{0, 0}) {
+		t.Errorf("expected versions [0 0], got %v", v)
+	}
+	if out := logger.String(); strings.Index(out, "00001_create_events.sql") > strings.Index(out, "00001_create_a.sql") {
+		t.Errorf("expected analytics to be reset before core, got %q", out)
+	}
+}
@@ -0,0 +1,327 @@
+package goose
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Notification describes the outcome of a command modifying the database.
+type Notification struct {
+	Command    string            // up, down, redo...
+	RunID      string            // run ID recorded with the migrations
+	Versions   []int64           // versions applied or rolled back, in order
+	Migrations []MigrationResult // migrations run, in order, the failed one last
+	Duration   time.Duration     // duration of the command
+	Timings    Timings           // breakdown of the duration by phase
+	Err        error             // nil if the command succeeded
+}
+
+// MigrationResult is the outcome of a migration run by a command.
+type MigrationResult struct {
+	Version  int64
+	Source   string // base name of the migration file
+	Duration time.Duration
+	Err      error // nil if the migration succeeded
+}
+
+// Notifier is notified when a command modifying the database finishes.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+var notifier Notifier
+
+// SetNotifier sets the Notifier notified when the commands modifying the
+// database finish, successfully or not. A failure to notify is logged, and
+// doesn't change the outcome of the command.
+func SetNotifier(n Notifier) {
+	notifier = n
+}
+
+// notify notifies the notifier set with SetNotifier, if any, of the outcome
+// of an invocation.
+func notify(inv *invocation, runErr error) {
+	if notifier == nil {
+		return
+	}
+	n := Notification{
+		Command:    inv.command,
+		RunID:      inv.runID,
+		Versions:   inv.versions,
+		Migrations: inv.results,
+		Duration:   time.Since(inv.started),
+		Timings:    inv.clock.timings,
+		Err:        runErr,
+	}
+	if err := notifier.Notify(n); err != nil {
+		log.Printf("goose: failed to notify: %v\n", err)
+	}
+}
+
+// WebhookFormat is the format of the payloads posted by a WebhookNotifier.
+type WebhookFormat string
+
+const (
+	WebhookJSON  WebhookFormat = "json"  // the payload of goose, with a "text" summary
+	WebhookSlack WebhookFormat = "slack" // a message of Slack incoming webhooks, with blocks
+	WebhookTeams WebhookFormat = "teams" // a message of Microsoft Teams workflows, with an Adaptive Card
+)
+
+// WebhookNotifier is a Notifier posting a JSON payload to webhook URLs. The
+// payload has a "text" field summing up the outcome, so that it can be
+// posted as is to Slack incoming webhooks, unless SetFormat sets a message
+// format listing the migrations run.
+type WebhookNotifier struct {
+	urls     []string
+	database string
+	format   WebhookFormat
+	retries  int
+	timeout  time.Duration
+}
+
+// NewWebhookNotifier creates a Notifier posting to the webhook URLs.
+func NewWebhookNotifier(urls ...string) *WebhookNotifier {
+	return &WebhookNotifier{urls: urls, format: WebhookJSON, retries: 2, timeout: 10 * time.Second}
+}
+
+// SetFormat sets the format of the payloads, WebhookJSON by default.
+func (w *WebhookNotifier) SetFormat(f WebhookFormat) {
+	w.format = f
+}
+
+// SetDatabase sets the name identifying the database in notifications.
+func (w *WebhookNotifier) SetDatabase(name string) {
+	w.database = name
+}
+
+// SetRetries sets the number of retries of a failed post, 2 by default.
+func (w *WebhookNotifier) SetRetries(n int) {
+	w.retries = n
+}
+
+// SetTimeout sets the timeout of each post, 10 seconds by default.
+func (w *WebhookNotifier) SetTimeout(d time.Duration) {
+	w.timeout = d
+}
+
+// webhookPayload is the JSON payload posted to webhooks.
+type webhookPayload struct {
+	Text       string  `json:"text"`
+	Database   string  `json:"database"`
+	Command    string  `json:"command"`
+	Versions   []int64 `json:"versions"`
+	DurationMs int64   `json:"duration_ms"`
+	Success    bool    `json:"success"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Notify posts the notification to every webhook URL, retrying failed posts.
+func (w *WebhookNotifier) Notify(n Notification) error {
+	var payload interface{}
+	switch w.format {
+	case WebhookSlack:
+		payload = slackPayload(w.database, n)
+	case WebhookTeams:
+		payload = teamsPayload(w.database, n)
+	default:
+		payload = w.jsonPayload(n)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode webhook payload")
+	}
+
+	client := &http.Client{Timeout: w.timeout}
+	var failed []string
+	for _, url := range w.urls {
+		if err := w.post(client, url, body); err != nil {
+			log.Printf("goose: %v\n", err)
+			failed = append(failed, url)
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("failed to post to %d webhooks: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// jsonPayload is the payload of the WebhookJSON format.
+func (w *WebhookNotifier) jsonPayload(n Notification) webhookPayload {
+	payload := webhookPayload{
+		Text:       notificationText(w.database, n),
+		Database:   w.database,
+		Command:    n.Command,
+		Versions:   n.Versions,
+		DurationMs: int64(n.Duration / time.Millisecond),
+		Success:    n.Err == nil,
+	}
+	if payload.Versions == nil {
+		payload.Versions = []int64{}
+	}
+	if n.Err != nil {
+		payload.Error = n.Err.Error()
+	}
+	return payload
+}
+
+// post posts the body to a webhook URL. Network errors, server errors and
+// rate limiting are retried, with a delay growing after each attempt.
+func (w *WebhookNotifier) post(client *http.Client, url string, body []byte) error {
+	var err error
+	for attempt := 0; attempt <= w.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(webhookRetryDelay * time.Duration(attempt))
+		}
+
+		var resp *http.Response
+		resp, err = client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			err = errors.Wrapf(err, "failed to post to webhook %s", url)
+			continue
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		err = errors.Errorf("failed to post to webhook %s: %s", url, resp.Status)
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+	}
+	return err
+}
+
+// webhookRetryDelay is the delay before the first retry of a failed post.
+var webhookRetryDelay = time.Second
+
+// notificationTitle sums up the outcome of a notification.
+func notificationTitle(database string, n Notification) string {
+	var b strings.Builder
+	b.WriteString("goose " + n.Command)
+	if database != "" {
+		b.WriteString(" on " + database)
+	}
+	if n.Err != nil {
+		b.WriteString(" failed")
+	} else {
+		b.WriteString(" succeeded")
+	}
+	fmt.Fprintf(&b, " in %v", n.Duration.Round(time.Millisecond))
+	return b.String()
+}
+
+// notificationText sums up a notification in a line of text.
+func notificationText(database string, n Notification) string {
+	var b strings.Builder
+	b.WriteString(notificationTitle(database, n))
+
+	if len(n.Versions) > 0 {
+		versions := make([]string, len(n.Versions))
+		for i, v := range n.Versions {
+			versions[i] = fmt.Sprint(v)
+		}
+		fmt.Fprintf(&b, ", %d migrations: %s", len(n.Versions), strings.Join(versions, ", "))
+	} else {
+		b.WriteString(", no migrations")
+	}
+
+	if n.Err != nil {
+		b.WriteString("\n" + n.Err.Error())
+	}
+	return b.String()
+}
+
+// migrationLines lists the migrations of a notification with their
+// duration, one per line, or the versions of the migrations recorded
+// without running, like the ones of a baseline. code formats the names.
+func migrationLines(n Notification, code func(string) string) []string {
+	var lines []string
+	for _, r := range n.Migrations {
+		if r.Err != nil {
+			lines = append(lines, fmt.Sprintf("%s failed after %v", code(r.Source), r.Duration.Round(time.Millisecond)))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s in %v", code(r.Source), r.Duration.Round(time.Millisecond)))
+		}
+	}
+	if len(n.Migrations) == 0 {
+		for _, v := range n.Versions {
+			lines = append(lines, code(fmt.Sprint(v)))
+		}
+	}
+	return lines
+}
+
+// truncate shortens s to at most n bytes, the limit of the text blocks of
+// messages.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+// slackPayload is the Slack message of a notification, with the text of
+// notificationText as the fallback of the blocks.
+func slackPayload(database string, n Notification) map[string]interface{} {
+	section := func(text string) map[string]interface{} {
+		return map[string]interface{}{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": truncate(text, 3000)}}
+	}
+	icon := ":white_check_mark:"
+	if n.Err != nil {
+		icon = ":x:"
+	}
+	blocks := []interface{}{section(icon + " *" + notificationTitle(database, n) + "*")}
+	lines := migrationLines(n, func(s string) string { return "`" + s + "`" })
+	if len(lines) == 0 {
+		lines = []string{"no migrations"}
+	}
+	blocks = append(blocks, section("• "+strings.Join(lines, "\n• ")))
+	if n.Err != nil {
+		blocks = append(blocks, section("```"+strings.Replace(n.Err.Error(), "```", "'''", -1)+"```"))
+	}
+	return map[string]interface{}{"text": notificationText(database, n), "blocks": blocks}
+}
+
+// teamsPayload is the Microsoft Teams message of a notification, with an
+// Adaptive Card.
+func teamsPayload(database string, n Notification) map[string]interface{} {
+	color := "Good"
+	if n.Err != nil {
+		color = "Attention"
+	}
+	body := []interface{}{
+		map[string]interface{}{"type": "TextBlock", "text": notificationTitle(database, n), "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+	}
+	lines := migrationLines(n, func(s string) string { return s })
+	if len(lines) == 0 {
+		lines = []string{"no migrations"}
+	}
+	for _, line := range lines {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": "- " + line, "spacing": "None", "wrap": true})
+	}
+	if n.Err != nil {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": truncate(n.Err.Error(), 3000), "fontType": "Monospace", "color": "Attention", "wrap": true})
+	}
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{map[string]interface{}{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
@@ -0,0 +1,180 @@
+package goose
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	defer func(d time.Duration) { webhookRetryDelay = d }(webhookRetryDelay)
+	webhookRetryDelay = time.Millisecond
+
+	var (
+		attempts int
+		payload  webhookPayload
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	w := NewWebhookNotifier(server.URL)
+	w.SetDatabase("prod")
+	err := w.Notify(Notification{
+		Command:  "up",
+		Versions: []int64{42, 43},
+		Duration: 1500 * time.Millisecond,
+		Err:      errors.New("boom"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("unexpected number of attempts, got %d, want 2", attempts)
+	}
+	if payload.Database != "prod" || payload.Command != "up" || len(payload.Versions) != 2 || payload.DurationMs != 1500 || payload.Success || payload.Error != "boom" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if want := "goose up on prod failed in 1.5s, 2 migrations: 42, 43\nboom"; payload.Text != want {
+		t.Errorf("unexpected text, got %q, want %q", payload.Text, want)
+	}
+}
+
+func TestWebhookNotifierClientError(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL).Notify(Notification{Command: "down"})
+	if err == nil || !strings.Contains(err.Error(), server.URL) {
+		t.Errorf("expected an error for %s, got %v", server.URL, err)
+	}
+	if attempts != 1 {
+		t.Errorf("client errors should not be retried, got %d attempts", attempts)
+	}
+}
+
+func TestWebhookFormats(t *testing.T) {
+	n := Notification{
+		Command: "up",
+		Migrations: []MigrationResult{
+			{Version: 1, Source: "00001_create_a.sql", Duration: 12 * time.Millisecond},
+			{Version: 2, Source: "00002_fail.sql", Duration: 3 * time.Millisecond, Err: errors.New("syntax error")},
+		},
+		Versions: []int64{1},
+		Duration: 20 * time.Millisecond,
+		Err:      errors.New("syntax error"),
+	}
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		io.Copy(&b, r.Body)
+		bodies = append(bodies, b.String())
+	}))
+	defer server.Close()
+
+	w := NewWebhookNotifier(server.URL)
+	w.SetDatabase("prod")
+	for _, f := range []WebhookFormat{WebhookSlack, WebhookTeams} {
+		w.SetFormat(f)
+		if err := w.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 posts, got %d", len(bodies))
+	}
+
+	var slack struct {
+		Text   string
+		Blocks []struct {
+			Type string
+			Text struct{ Type, Text string }
+		}
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &slack); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(slack.Text, "goose up on prod failed in 20ms") || len(slack.Blocks) != 3 {
+		t.Fatalf("unexpected Slack message %s", bodies[0])
+	}
+	for i, want := range []string{
+		":x: *goose up on prod failed in 20ms*",
+		"• `00001_create_a.sql` in 12ms\n• `00002_fail.sql` failed after 3ms",
+		"```syntax error```",
+	} {
+		if slack.Blocks[i].Text.Text != want {
+			t.Errorf("unexpected block %d, got %q, want %q", i, slack.Blocks[i].Text.Text, want)
+		}
+	}
+
+	var teams struct {
+		Type        string
+		Attachments []struct {
+			ContentType string
+			Content     struct {
+				Type string
+				Body []map[string]interface{}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(bodies[1]), &teams); err != nil {
+		t.Fatal(err)
+	}
+	if teams.Type != "message" || len(teams.Attachments) != 1 || teams.Attachments[0].Content.Type != "AdaptiveCard" {
+		t.Fatalf("unexpected Teams message %s", bodies[1])
+	}
+	var texts []string
+	for _, block := range teams.Attachments[0].Content.Body {
+		texts = append(texts, block["text"].(string))
+	}
+	if want := "goose up on prod failed in 20ms|- 00001_create_a.sql in 12ms|- 00002_fail.sql failed after 3ms|syntax error"; strings.Join(texts, "|") != want {
+		t.Errorf("unexpected Teams card, got %q, want %q", strings.Join(texts, "|"), want)
+	}
+}
+
+func TestNotificationMigrations(t *testing.T) {
+	defer SetNotifier(nil)
+	recorder := &notificationRecorder{}
+	SetNotifier(recorder)
+	migrations := map[string]string{"00004_fail.sql": "-- +goose Up\nSELECT * FROM missing;\n"}
+	for name, sql := range testMigrations {
+		migrations[name] = sql
+	}
+	dir, cleanupDir := writeTestMigrations(t, migrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := Up(db, dir); err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+	n := recorder.notifications[0]
+	if len(n.Migrations) != 4 || len(n.Versions) != 3 {
+		t.Fatalf("expected 4 migrations run and 3 applied, got %+v", n)
+	}
+	for i, r := range n.Migrations {
+		if r.Version != int64(i+1) || (r.Err != nil) != (i == 3) {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+	if n.Migrations[3].Source != "00004_fail.sql" {
+		t.Errorf("unexpected source %q", n.Migrations[3].Source)
+	}
+}
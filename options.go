@@ -0,0 +1,159 @@
+package goose
+
+import (
+	"database/sql/driver"
+	"sync"
+)
+
+var (
+	allowMissing bool
+	noVersioning bool
+)
+
+// SetAllowMissing sets whether up applies the missing migrations: pending
+// migrations older than the current version, typically merged from a
+// branch after newer migrations were applied. They are ignored by default.
+func SetAllowMissing(a bool) {
+	allowMissing = a
+}
+
+// SetNoVersioning sets whether migrations run without the version table:
+// up runs every migration, down the latest one, regardless of what was
+// applied before, to run repeatable scripts like maintenance or seeding
+// with the same parser and execution.
+func SetNoVersioning(n bool) {
+	noVersioning = n
+}
+
+// OptionsFunc is an option of the commands, overriding the global setting
+// of its Set* counterpart for one call, which is serialized with the other
+// commands:
+//
+//	err := goose.Up(db, "migrations", goose.WithTableName("schema_history"), goose.WithAllowMissing())
+type OptionsFunc func(o *options)
+
+// options are the global settings overridable by OptionsFunc.
+type options struct {
+	tableName         string
+	logger            Logger
+	locker            Locker
+	allowMissing      bool
+	noVersioning      bool
+	force             bool
+	gates             map[string]bool
+	skipTags          map[string]bool
+	allowHeavy        bool
+	maxConns          int
+	maxIdleConns      int
+	dialer            Dialer
+	connector         driver.Connector
+	middleware        []Middleware
+	missingDown       MissingDown
+	acceptDataLoss    bool
+	allowIrreversible bool
+
+	// The filters of CollectMigrations, which have no global settings.
+	exclude        []string
+	rangeFrom      int64
+	rangeTo        int64
+	reversibleOnly bool
+}
+
+// WithTableName sets the name of the version table, like SetTableName.
+func WithTableName(name string) OptionsFunc {
+	return func(o *options) { o.tableName = name }
+}
+
+// WithLogger sets the logger of the command output, like SetLogger.
+func WithLogger(l Logger) OptionsFunc {
+	return func(o *options) { o.logger = l }
+}
+
+// WithLock sets the lock held while migrating, like SetLocker.
+func WithLock(l Locker) OptionsFunc {
+	return func(o *options) { o.locker = l }
+}
+
+// WithAllowMissing applies the missing migrations, like SetAllowMissing.
+func WithAllowMissing() OptionsFunc {
+	return func(o *options) { o.allowMissing = true }
+}
+
+// WithNoVersioning runs migrations without the version table, like
+// SetNoVersioning.
+func WithNoVersioning() OptionsFunc {
+	return func(o *options) { o.noVersioning = true }
+}
+
+func currentOptions() options {
+	return options{
+		tableName:         tableName,
+		logger:            log,
+		locker:            locker,
+		allowMissing:      allowMissing,
+		noVersioning:      noVersioning,
+		force:             force,
+		gates:             gates,
+		skipTags:          skipTags,
+		allowHeavy:        allowHeavy,
+		maxConns:          maxConns,
+		maxIdleConns:      maxIdleConns,
+		dialer:            dialer,
+		middleware:        middleware,
+		missingDown:       missingDown,
+		acceptDataLoss:    acceptDataLoss,
+		allowIrreversible: allowIrreversible,
+	}
+}
+
+func setOptions(o options) {
+	tableName = o.tableName
+	log = o.logger
+	locker = o.locker
+	allowMissing = o.allowMissing
+	noVersioning = o.noVersioning
+	force = o.force
+	gates = o.gates
+	skipTags = o.skipTags
+	allowHeavy = o.allowHeavy
+	maxConns = o.maxConns
+	maxIdleConns = o.maxIdleConns
+	dialer = o.dialer
+	middleware = o.middleware
+	missingDown = o.missingDown
+	acceptDataLoss = o.acceptDataLoss
+	allowIrreversible = o.allowIrreversible
+}
+
+// optionsMu serializes the commands, for the settings of their options,
+// and their invocation, to stay those of a single command while it runs.
+var optionsMu sync.Mutex
+
+// withOptions runs fn with the settings of opts in effect, and restores the
+// global settings afterwards. The commands are serialized for the span of
+// the call, so that concurrent calls don't see, or restore, the settings of
+// one another. fn must not call withOptions, or the exported commands
+// calling it: the commands it runs use their variants of suffix Locked.
+func withOptions(opts []OptionsFunc, fn func() error) error {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	return withOptionsLocked(opts, fn)
+}
+
+// withOptionsLocked is withOptions for the callers holding optionsMu
+// already, running fn with the settings of opts in effect.
+func withOptionsLocked(opts []OptionsFunc, fn func() error) error {
+	if len(opts) == 0 {
+		return fn()
+	}
+
+	saved := currentOptions()
+	o := saved
+	for _, opt := range opts {
+		opt(&o)
+	}
+	setOptions(o)
+	defer setOptions(saved)
+
+	return fn()
+}
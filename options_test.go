@@ -0,0 +1,202 @@
+package goose
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type bufferLogger struct {
+	strings.Builder
+}
+
+func (l *bufferLogger) Fatal(v ...interface{})                 { l.Print(v...) }
+func (l *bufferLogger) Fatalf(format string, v ...interface{}) { l.Printf(format, v...) }
+func (l *bufferLogger) Print(v ...interface{})                 { fmt.Fprint(l, v...) }
+func (l *bufferLogger) Println(v ...interface{})               { fmt.Fprintln(l, v...) }
+func (l *bufferLogger) Printf(format string, v ...interface{}) { fmt.Fprintf(l, format, v...) }
+
+func TestOptions(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	logger := &bufferLogger{}
+	if err := Up(db, dir, WithTableName("schema_history"), WithLogger(logger)); err != nil {
+		t.Fatal(err)
+	}
+	if TableName() != "goose_db_version" {
+		t.Errorf("unexpected table name after the call, got %q", TableName())
+	}
+	if !strings.Contains(logger.String(), "00003_create_c.sql") {
+		t.Errorf("expected the output in the logger option, got %q", logger.String())
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_history WHERE version_id > 0").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("unexpected number of versions in schema_history, got %d, want 3", count)
+	}
+}
+
+func TestOptionsConcurrent(t *testing.T) {
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		mismatches []string
+	)
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("schema_history_%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			withOptions([]OptionsFunc{WithTableName(name)}, func() error {
+				time.Sleep(time.Millisecond)
+				// Nested commands hold the lock already.
+				return withOptionsLocked([]OptionsFunc{WithAllowMissing()}, func() error {
+					if TableName() != name {
+						mu.Lock()
+						mismatches = append(mismatches, TableName()+" instead of "+name)
+						mu.Unlock()
+					}
+					return nil
+				})
+			})
+		}()
+	}
+	wg.Wait()
+	if len(mismatches) > 0 {
+		t.Errorf("expected concurrent calls to keep their options, got %v", mismatches)
+	}
+	if TableName() != "goose_db_version" {
+		t.Errorf("unexpected table name after the calls, got %q", TableName())
+	}
+}
+
+func TestOptionsSerializeCommands(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	held, release := make(chan struct{}), make(chan struct{})
+	go withOptions([]OptionsFunc{WithTableName("schema_history")}, func() error {
+		close(held)
+		<-release
+		return nil
+	})
+	<-held
+
+	done := make(chan error)
+	go func() {
+		_, err := CompactHistory(db, 0)
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected CompactHistory to wait for the command in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM goose_db_version").Scan(&count); err != nil {
+		t.Errorf("expected CompactHistory to use its own options, got %v", err)
+	}
+}
+
+func TestAllowMissing(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	// Roll back 2 only, as if it was merged after 3 was applied.
+	if _, err := db.Exec("DROP TABLE b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := insertVersion(db, 2, false, "", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := dbMigrationsStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[2] {
+		t.Errorf("unexpected missing migration applied without WithAllowMissing")
+	}
+
+	if err := Up(db, dir, WithAllowMissing()); err != nil {
+		t.Fatal(err)
+	}
+	if statuses, err = dbMigrationsStatus(db); err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[2] {
+		t.Errorf("expected missing migration to be applied with WithAllowMissing")
+	}
+	current, err := GetDBVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != 3 {
+		t.Errorf("unexpected current version, got %d, want 3", current)
+	}
+}
+
+func TestNoVersioning(t *testing.T) {
+	migrations := map[string]string{
+		"00001_create.sql": "-- +goose Up\nCREATE TABLE IF NOT EXISTS seed (id int);\n-- +goose Down\nDROP TABLE seed;\n",
+		"00002_seed.sql":   "-- +goose Up\nINSERT INTO seed VALUES (1);\n-- +goose Down\nDELETE FROM seed;\n",
+	}
+	dir, cleanupDir := writeTestMigrations(t, migrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	for i := 0; i < 2; i++ {
+		if err := Up(db, dir, WithNoVersioning()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM seed").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("unexpected number of seeded rows, got %d, want 2", count)
+	}
+	if _, err := db.Exec("SELECT 1 FROM goose_db_version"); err == nil {
+		t.Errorf("unexpected version table without versioning")
+	}
+
+	if err := Down(db, dir, WithNoVersioning()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM seed").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("unexpected rows after down, got %d, want 0", count)
+	}
+
+	if err := Reset(db, dir, WithNoVersioning()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM seed"); err == nil {
+		t.Errorf("expected reset to drop the seed table")
+	}
+}
@@ -0,0 +1,174 @@
+package goose
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	params = map[string]interface{}{}
+
+	matchParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// SetParams sets the values of the parameters declared in SQL migrations
+// with the '-- +goose Param' annotation.
+//
+// Parameters are referenced as :name in the statements of the migration,
+// and are executed as bind parameters, never substituted in the SQL text.
+func SetParams(p map[string]interface{}) {
+	params = make(map[string]interface{}, len(p))
+	for name, value := range p {
+		params[name] = value
+	}
+}
+
+// SetParam sets the value of a single SQL migration parameter.
+func SetParam(name string, value interface{}) {
+	params[name] = value
+}
+
+// bindParams replaces the references to the declared parameters in query
+// with placeholders of the current dialect, and returns the values to bind
+// to them. References inside quoted strings, quoted identifiers and
+// comments are left untouched, as are names that are not declared.
+func bindParams(query string, declared []string) (string, []interface{}, error) {
+	if len(declared) == 0 {
+		return query, nil, nil
+	}
+
+	var args []interface{}
+	query, err := rewriteSQL(query, func(i int) (string, int, error) {
+		name, j := paramRef(query, i)
+		if name == "" || !containsString(declared, name) {
+			return "", i, nil
+		}
+		value, ok := params[name]
+		if !ok {
+			return "", 0, errors.Errorf("missing value for parameter %q", name)
+		}
+		args = append(args, value)
+		return GetDialect().placeholder(len(args)), j, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return query, args, nil
+}
+
+// rewriteSQL copies query, replacing the text returned by replace for each
+// byte outside of quoted strings, quoted identifiers, -- comments and
+// nested /* */ comments. replace returns the replacement of query[i:j], or
+// j == i to copy the byte as is. Doubled quotes are escapes, and so are
+// backslashes in the strings of MySQL and the E'' strings of Postgres.
+func rewriteSQL(query string, replace func(i int) (string, int, error)) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			backslash := c != '`' && (isMySQL() || (c == '\'' && isEscapeString(query, i)))
+			j := i + 1
+			for j < len(query) {
+				if backslash && query[j] == '\\' {
+					j += 2
+					continue
+				}
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j < len(query) {
+				j++
+			} else {
+				j = len(query)
+			}
+			b.WriteString(query[i:j])
+			i = j
+
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			b.WriteString(query[i : i+j])
+			i += j
+
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			j, depth := i+2, 1
+			for j < len(query) && depth > 0 {
+				switch {
+				case strings.HasPrefix(query[j:], "/*"):
+					depth++
+					j += 2
+				case strings.HasPrefix(query[j:], "*/"):
+					depth--
+					j += 2
+				default:
+					j++
+				}
+			}
+			b.WriteString(query[i:j])
+			i = j
+
+		default:
+			repl, j, err := replace(i)
+			if err != nil {
+				return "", err
+			}
+			if j <= i {
+				b.WriteByte(c)
+				i++
+				continue
+			}
+			b.WriteString(repl)
+			i = j
+		}
+	}
+	return b.String(), nil
+}
+
+// isEscapeString reports whether the string quoted at query[i] is a
+// Postgres E'' string, with backslash escapes.
+func isEscapeString(query string, i int) bool {
+	if i == 0 || (query[i-1] != 'E' && query[i-1] != 'e') {
+		return false
+	}
+	return i == 1 || !isParamNameChar(query[i-2], false)
+}
+
+// paramRef returns the name of the :name reference at query[i], and the
+// index of its end, or "" if there is none, as in a Postgres :: cast.
+func paramRef(query string, i int) (string, int) {
+	if query[i] != ':' || (i > 0 && query[i-1] == ':') || i+1 >= len(query) || query[i+1] == ':' {
+		return "", i
+	}
+	j := i + 1
+	for j < len(query) && isParamNameChar(query[j], j == i+1) {
+		j++
+	}
+	return query[i+1 : j], j
+}
+
+func isParamNameChar(c byte, first bool) bool {
+	if c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+		return true
+	}
+	return !first && '0' <= c && c <= '9'
+}
+
+func containsString(list []string, s string) bool {
+	for _, str := range list {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
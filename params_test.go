@@ -0,0 +1,85 @@
+package goose
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBindParams(t *testing.T) {
+	SetParams(map[string]interface{}{"tenant_id": 42, "name": "acme"})
+	defer SetParams(nil)
+
+	tt := []struct {
+		query    string
+		declared []string
+		want     string
+		args     []interface{}
+	}{
+		{
+			query:    "INSERT INTO t VALUES (:tenant_id, :name);",
+			declared: []string{"tenant_id", "name"},
+			want:     "INSERT INTO t VALUES ($1, $2);",
+			args:     []interface{}{42, "acme"},
+		},
+		{
+			query:    "SELECT ':tenant_id', \":name\", x::text FROM t WHERE id = :tenant_id -- :name\n;",
+			declared: []string{"tenant_id", "name"},
+			want:     "SELECT ':tenant_id', \":name\", x::text FROM t WHERE id = $1 -- :name\n;",
+			args:     []interface{}{42},
+		},
+		{
+			query:    "SELECT :undeclared, :name;",
+			declared: []string{"name"},
+			want:     "SELECT :undeclared, $1;",
+			args:     []interface{}{"acme"},
+		},
+		{
+			query: "SELECT :name;",
+			want:  "SELECT :name;",
+		},
+	}
+
+	for _, test := range tt {
+		got, args, err := bindParams(test.query, test.declared)
+		if err != nil {
+			t.Errorf("unexpected error for query %q: %v", test.query, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("unexpected query, got %q, want %q", got, test.want)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("unexpected args for query %q, got %v, want %v", test.query, args, test.args)
+		}
+	}
+
+	if _, _, err := bindParams("SELECT :missing;", []string{"missing"}); err == nil {
+		t.Error("expected error on missing parameter value")
+	}
+}
+
+func TestParamAnnotations(t *testing.T) {
+	t.Parallel()
+
+	_, a, err := parseSQLMigration(strings.NewReader(paramSQL), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"tenant_id", "region", "env"}; !reflect.DeepEqual(a.params, want) {
+		t.Errorf("unexpected params, got %v, want %v", a.params, want)
+	}
+
+	if _, _, err := parseSQLMigration(strings.NewReader("-- +goose Param 1nvalid\n-- +goose Up\n"), true); err == nil {
+		t.Error("expected error on invalid parameter name")
+	}
+}
+
+var paramSQL = `-- +goose Param tenant_id
+-- +goose Param region env
+-- +goose Up
+INSERT INTO tenants (id, region, env) VALUES (:tenant_id, :region, :env);
+
+-- +goose Down
+DELETE FROM tenants WHERE id = :tenant_id;
+`
@@ -0,0 +1,69 @@
+package goose
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// Pending returns the number of migrations that Up would apply to the
+// database, and the version of the next one, or -1 if there is none.
+//
+// Pending works from an already collected set of migrations, like the ones
+// returned by CollectMigrations or RegisteredMigrations, and never modifies
+// the database: if the version table doesn't exist yet, all migrations are
+// pending.
+func Pending(db *sql.DB, migrations Migrations) (count int, next int64, err error) {
+	current, err := readDBVersion(db)
+	if err != nil {
+		return 0, -1, err
+	}
+
+	next = -1
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		count++
+		if next < 0 || m.Version < next {
+			next = m.Version
+		}
+	}
+	return count, next, nil
+}
+
+// readDBVersion retrieves the current version for this DB, like
+// EnsureDBVersion, but returns 0 instead of creating the version table if
+// it doesn't exist.
+func readDBVersion(db *sql.DB) (int64, error) {
+	return cachedDBVersion(db, false)
+}
+
+// queryDBVersion reads the current version of readDBVersion from the
+// version table.
+func queryDBVersion(db *sql.DB) (int64, error) {
+	ensureDialect(db)
+	rows, err := GetDialect().dbVersionQuery(db)
+	if err != nil {
+		// Tell a missing version table apart from an unreachable database
+		// and a version table that can't be read.
+		if err := db.Ping(); err != nil {
+			return 0, err
+		}
+		exists, existsErr := versionTableExists(db)
+		if existsErr != nil {
+			return 0, existsErr
+		}
+		if exists {
+			return 0, errors.Wrapf(err, "failed to read version table %s", TableName())
+		}
+		return 0, nil
+	}
+	defer rows.Close()
+
+	version, err := scanDBVersion(rows)
+	if err == ErrNoNextVersion {
+		return 0, nil
+	}
+	return version, err
+}
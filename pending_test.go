@@ -0,0 +1,105 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB opens a new sqlite3 database and sets the sqlite3 dialect
+// until the returned cleanup function is called.
+func openTestDB(t *testing.T) (*sql.DB, func()) {
+	dir, err := ioutil.TempDir("", "goose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	if err := SetDialect("sqlite3"); err != nil {
+		t.Fatal(err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+		SetDialect("postgres")
+	}
+}
+
+func newGoMigration(v int64) *Migration {
+	return &Migration{
+		Version:    v,
+		Next:       -1,
+		Previous:   -1,
+		Source:     filepath.Join("migrations", fmt.Sprintf("%05d_test.go", v)),
+		Registered: true,
+		UpFn:       func(QueryExecer) error { return nil },
+		DownFn:     func(QueryExecer) error { return nil },
+	}
+}
+
+func TestPending(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	migrations := sortAndConnectMigrations(Migrations{newGoMigration(3), newGoMigration(1), newGoMigration(2)})
+
+	count, next, err := Pending(db, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || next != 1 {
+		t.Errorf("unexpected pending migrations on a new database, got (%v, %v), want (3, 1)", count, next)
+	}
+	if _, err := db.Query("SELECT * FROM " + TableName()); err == nil {
+		t.Error("Pending must not create the version table")
+	}
+
+	if _, err := EnsureDBVersion(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrations[0].Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	count, next, err = Pending(db, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || next != 2 {
+		t.Errorf("unexpected pending migrations, got (%v, %v), want (2, 2)", count, next)
+	}
+
+	for _, m := range migrations[1:] {
+		if err := m.Up(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, next, err = Pending(db, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || next != -1 {
+		t.Errorf("unexpected pending migrations on an up to date database, got (%v, %v), want (0, -1)", count, next)
+	}
+}
+
+func TestPendingUnreadableVersionTable(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if _, err := db.Exec("CREATE TABLE " + TableName() + " (version_id INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Pending(db, Migrations{newGoMigration(1)}); err == nil {
+		t.Error("expected an error reading a version table without is_applied column")
+	}
+}
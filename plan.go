@@ -0,0 +1,283 @@
+package goose
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Plan is a recorded run of migrations. A plan is created with CreatePlan,
+// reviewed and approved with ApprovePlan, and only then applied with
+// ApplyPlan, which refuses to run anything but the exact migrations that
+// were approved.
+type Plan struct {
+	ID         string           `json:"id"`
+	Versions   []int64          `json:"versions"`  // versions to apply, in order
+	Checksums  map[int64]string `json:"checksums"` // checksums of the migrations, by version
+	CreatedAt  time.Time        `json:"created_at"`
+	ApprovedAt time.Time        `json:"approved_at"` // zero until the plan is approved
+	ApprovedBy string           `json:"approved_by"`
+	AppliedAt  time.Time        `json:"applied_at"` // zero until the plan is applied
+}
+
+// Approved reports whether the plan was approved.
+func (p *Plan) Approved() bool {
+	return !p.ApprovedAt.IsZero()
+}
+
+// Applied reports whether the plan was applied.
+func (p *Plan) Applied() bool {
+	return !p.AppliedAt.IsZero()
+}
+
+func planTableName() string {
+	return TableName() + "_plan"
+}
+
+// CreatePlan records a plan to apply the pending migrations, the ones Up
+// would apply.
+func CreatePlan(db *sql.DB, dir string) (*Plan, error) {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return nil, err
+	}
+	current, err := GetDBVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Wrap(err, "failed to generate plan ID")
+	}
+	plan := &Plan{
+		ID:        hex.EncodeToString(id),
+		Checksums: map[int64]string{},
+		CreatedAt: time.Now(),
+	}
+	for _, m := range ungated(migrations.Filter(current, maxVersion)) {
+		checksum, err := m.Checksum()
+		if err != nil {
+			return nil, err
+		}
+		plan.Versions = append(plan.Versions, m.Version)
+		plan.Checksums[m.Version] = checksum
+	}
+	if len(plan.Versions) == 0 {
+		return nil, errors.Errorf("no migrations to plan. current version: %d", current)
+	}
+
+	if err := ensurePlanTable(db); err != nil {
+		return nil, err
+	}
+	d := GetDialect()
+	q := fmt.Sprintf("INSERT INTO %s (id, migrations, created_at) VALUES (%s, %s, %s)", planTableName(), d.placeholder(1), d.placeholder(2), d.placeholder(3))
+	if _, err := db.Exec(q, plan.ID, formatPlanMigrations(plan), plan.CreatedAt.Unix()); err != nil {
+		return nil, errors.Wrap(err, "failed to record plan")
+	}
+
+	return plan, nil
+}
+
+// GetPlan retrieves a recorded plan.
+func GetPlan(db *sql.DB, id string) (*Plan, error) {
+	d := GetDialect()
+	q := fmt.Sprintf("SELECT migrations, created_at, approved_at, approved_by, applied_at FROM %s WHERE id = %s", planTableName(), d.placeholder(1))
+
+	var (
+		migrations            string
+		createdAt             int64
+		approvedAt, appliedAt sql.NullInt64
+		approvedBy            sql.NullString
+	)
+	err := db.QueryRow(q, id).Scan(&migrations, &createdAt, &approvedAt, &approvedBy, &appliedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.Errorf("no plan %q", id)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve plan")
+	}
+
+	plan := &Plan{ID: id, CreatedAt: time.Unix(createdAt, 0), ApprovedBy: approvedBy.String}
+	if approvedAt.Valid {
+		plan.ApprovedAt = time.Unix(approvedAt.Int64, 0)
+	}
+	if appliedAt.Valid {
+		plan.AppliedAt = time.Unix(appliedAt.Int64, 0)
+	}
+	if err := parsePlanMigrations(plan, migrations); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ApprovePlan approves a recorded plan, so that it can be applied.
+func ApprovePlan(db *sql.DB, id, approver string) error {
+	plan, err := GetPlan(db, id)
+	if err != nil {
+		return err
+	}
+	if plan.Approved() {
+		return errors.Errorf("plan %s was already approved by %s", id, plan.ApprovedBy)
+	}
+
+	d := GetDialect()
+	q := fmt.Sprintf("UPDATE %s SET approved_at = %s, approved_by = %s WHERE id = %s", planTableName(), d.placeholder(1), d.placeholder(2), d.placeholder(3))
+	if _, err := db.Exec(q, time.Now().Unix(), approver, id); err != nil {
+		return errors.Wrap(err, "failed to approve plan")
+	}
+	return nil
+}
+
+// ApplyPlan applies an approved plan. It fails without applying anything if
+// the pending migrations are not exactly the ones of the plan anymore, or if
+// any of them changed since the plan was created.
+//
+// If a previous attempt to apply the plan failed half-way, ApplyPlan applies
+// the rest of the plan.
+func ApplyPlan(db *sql.DB, dir, id string) error {
+	return withOptions(nil, func() error {
+		return applyPlanLocked(db, dir, id)
+	})
+}
+
+// applyPlanLocked is ApplyPlan for the callers holding optionsMu.
+func applyPlanLocked(db *sql.DB, dir, id string) error {
+	return invoke("apply", db, func() error {
+		return applyPlan(db, dir, id)
+	})
+}
+
+func applyPlan(db *sql.DB, dir, id string) error {
+	plan, err := GetPlan(db, id)
+	if err != nil {
+		return err
+	}
+	if !plan.Approved() {
+		return errors.Errorf("plan %s is not approved", id)
+	}
+	if plan.Applied() {
+		return errors.Errorf("plan %s was already applied on %s", id, plan.AppliedAt.Format(time.ANSIC))
+	}
+
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return err
+	}
+	current, err := GetDBVersion(db)
+	if err != nil {
+		return err
+	}
+
+	var remaining []int64
+	for _, v := range plan.Versions {
+		if v > current {
+			remaining = append(remaining, v)
+		}
+	}
+	pending := ungated(migrations.Filter(current, maxVersion))
+	if err := checkReviewers(pending, plan.ApprovedBy); err != nil {
+		return errors.Wrapf(err, "plan %s can't be applied", id)
+	}
+	if len(pending) != len(remaining) {
+		return withExitCode(ExitChecksumMismatch, errors.Errorf("plan %s has drifted: %d migrations planned, %d pending", id, len(remaining), len(pending)))
+	}
+	for i, m := range pending {
+		if m.Version != remaining[i] {
+			return withExitCode(ExitChecksumMismatch, errors.Errorf("plan %s has drifted: version %d planned, %d pending", id, remaining[i], m.Version))
+		}
+		checksum, err := m.Checksum()
+		if err != nil {
+			return err
+		}
+		if checksum != plan.Checksums[m.Version] {
+			return withExitCode(ExitChecksumMismatch, errors.Errorf("plan %s has drifted: %v changed since it was planned", id, filepath.Base(m.Source)))
+		}
+	}
+
+	for _, m := range pending {
+		if err := m.Up(db); err != nil {
+			return err
+		}
+	}
+
+	d := GetDialect()
+	q := fmt.Sprintf("UPDATE %s SET applied_at = %s WHERE id = %s", planTableName(), d.placeholder(1), d.placeholder(2))
+	if _, err := db.Exec(q, time.Now().Unix(), id); err != nil {
+		return errors.Wrap(err, "failed to record plan as applied")
+	}
+	return nil
+}
+
+// ensurePlanTable creates the plan table if it doesn't exist.
+func ensurePlanTable(db *sql.DB) error {
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %s WHERE 1 = 0", planTableName())); err == nil {
+		return nil
+	}
+	q := fmt.Sprintf(`CREATE TABLE %s (
+                id VARCHAR(32) NOT NULL PRIMARY KEY,
+                migrations %s NOT NULL,
+                created_at BIGINT NOT NULL,
+                approved_at BIGINT,
+                approved_by VARCHAR(255),
+                applied_at BIGINT
+            )`, planTableName(), textType())
+	if _, err := db.Exec(q); err != nil {
+		return errors.Wrap(err, "failed to create plan table")
+	}
+	return nil
+}
+
+// formatPlanMigrations formats the migrations of a plan as a list of
+// version:checksum pairs.
+func formatPlanMigrations(plan *Plan) string {
+	pairs := make([]string, len(plan.Versions))
+	for i, v := range plan.Versions {
+		pairs[i] = fmt.Sprintf("%d:%s", v, plan.Checksums[v])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func parsePlanMigrations(plan *Plan, s string) error {
+	plan.Checksums = map[int64]string{}
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.Index(pair, ":")
+		if i < 0 {
+			return errors.Errorf("invalid plan migration %q", pair)
+		}
+		v, err := strconv.ParseInt(pair[:i], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid plan migration %q", pair)
+		}
+		plan.Versions = append(plan.Versions, v)
+		plan.Checksums[v] = pair[i+1:]
+	}
+	return nil
+}
+
+// printPlan prints the migrations of a plan, and its state.
+func printPlan(plan *Plan, migrations Migrations) {
+	log.Printf("goose: plan %s, created %s\n", plan.ID, plan.CreatedAt.Format(time.ANSIC))
+	for _, v := range plan.Versions {
+		name := strconv.FormatInt(v, 10)
+		if m, err := migrations.Current(v); err == nil {
+			name = filepath.Base(m.Source)
+		}
+		log.Printf("    %s\n", name)
+	}
+	switch {
+	case plan.Applied():
+		log.Printf("goose: applied %s\n", plan.AppliedAt.Format(time.ANSIC))
+	case plan.Approved():
+		log.Printf("goose: approved by %s on %s\n", plan.ApprovedBy, plan.ApprovedAt.Format(time.ANSIC))
+	default:
+		log.Printf("goose: waiting for approval: goose approve %s\n", plan.ID)
+	}
+}
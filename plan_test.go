@@ -0,0 +1,60 @@
+package goose
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyPlan(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+
+	plan, err := CreatePlan(db, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Versions) != 3 {
+		t.Fatalf("unexpected planned versions %v", plan.Versions)
+	}
+
+	if err := ApplyPlan(db, dir, plan.ID); err == nil {
+		t.Error("expected error on applying a plan that is not approved")
+	}
+	if err := ApprovePlan(db, plan.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing a planned migration invalidates the plan.
+	path := filepath.Join(dir, "00002_create_b.sql")
+	if err := ioutil.WriteFile(path, []byte("-- +goose Up\nDROP TABLE a;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyPlan(db, dir, plan.ID); err == nil {
+		t.Error("expected error on applying a plan with a changed migration")
+	}
+	if err := ioutil.WriteFile(path, []byte(testMigrations["00002_create_b.sql"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyPlan(db, dir, plan.ID); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := GetDBVersion(db); err != nil || current != 3 {
+		t.Errorf("unexpected version after applying plan: %v, %v", current, err)
+	}
+
+	plan, err = GetPlan(db, plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Approved() || plan.ApprovedBy != "alice" || !plan.Applied() {
+		t.Errorf("unexpected plan state %+v", plan)
+	}
+	if err := ApplyPlan(db, dir, plan.ID); err == nil {
+		t.Error("expected error on applying a plan twice")
+	}
+}
@@ -0,0 +1,64 @@
+package goose
+
+import "database/sql"
+
+var (
+	maxConns     = 0
+	maxIdleConns = 0
+)
+
+// SetMaxConns caps the connections opened to the database while a command
+// modifying it runs, so that migrations don't exhaust the connections left
+// by the pool of the application, or 1 to run everything on a single
+// connection. The connection holding the lock of NewPostgresLocker or
+// NewMySQLLocker, or the heartbeat of NewTableLocker, comes on top of them.
+// The previous limit is restored afterwards, but database/sql lowers the
+// limit of idle connections to n, see SetMaxIdleConns. 0, the default,
+// leaves the pool as is.
+func SetMaxConns(n int) {
+	maxConns = n
+}
+
+// WithMaxConns caps the connections opened to the database, like
+// SetMaxConns.
+func WithMaxConns(n int) OptionsFunc {
+	return func(o *options) { o.maxConns = n }
+}
+
+// SetMaxIdleConns sets the limit of idle connections of the pool, restored
+// with the limit of open connections once a command capped by SetMaxConns
+// is done: database/sql lowers it along, and can't report it. 0, the
+// default, leaves it lowered.
+func SetMaxIdleConns(n int) {
+	maxIdleConns = n
+}
+
+// WithMaxIdleConns sets the limit of idle connections restored after the
+// command, like SetMaxIdleConns.
+func WithMaxIdleConns(n int) OptionsFunc {
+	return func(o *options) { o.maxIdleConns = n }
+}
+
+// limitConns caps the open connections of db to the limit set with
+// SetMaxConns, and returns the function restoring the previous limit, and
+// the idle limit of SetMaxIdleConns.
+func limitConns(db *sql.DB) func() {
+	if maxConns <= 0 {
+		return func() {}
+	}
+	n := maxConns
+	switch locker.(type) {
+	case *PostgresLocker, *MySQLLocker, *TableLocker:
+		// The lock is held, or its heartbeat updated, on a connection of
+		// its own, so that a stale lock isn't taken over while it runs.
+		n++
+	}
+	previous, idle := db.Stats().MaxOpenConnections, maxIdleConns
+	db.SetMaxOpenConns(n)
+	return func() {
+		db.SetMaxOpenConns(previous)
+		if idle > 0 {
+			db.SetMaxIdleConns(idle)
+		}
+	}
+}
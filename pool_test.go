@@ -0,0 +1,98 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestMaxConns(t *testing.T) {
+	defer SetLogger(log)
+	SetLogger(&bufferLogger{})
+	migrations := map[string]string{
+		"00004_no_tx.sql": "-- +goose NO TRANSACTION\n-- +goose Up\nCREATE TABLE d (id int);\n-- +goose Down\nDROP TABLE d;\n",
+	}
+	for name, sql := range testMigrations {
+		migrations[name] = sql
+	}
+	dir, cleanupDir := writeTestMigrations(t, migrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	db.SetMaxOpenConns(10)
+
+	done := make(chan error)
+	go func() {
+		// A single connection runs everything, without waiting for another.
+		err := Up(db, dir, WithMaxConns(1))
+		if err == nil {
+			err = Redo(db, dir, WithMaxConns(1))
+		}
+		if err == nil {
+			err = DownTo(db, dir, 1, WithMaxConns(1))
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out, the migrations wait for a second connection")
+	}
+	if version, err := GetDBVersion(db); err != nil || version != 1 {
+		t.Errorf("expected version 1, got %d (%v)", version, err)
+	}
+	if n := db.Stats().MaxOpenConnections; n != 10 {
+		t.Errorf("expected the limit of 10 connections to be restored, got %d", n)
+	}
+}
+
+func TestMaxIdleConns(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	defer SetMaxConns(0)
+	SetMaxConns(1)
+	defer SetMaxIdleConns(0)
+	SetMaxIdleConns(5)
+
+	limitConns(db)()
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for i := 0; i < 5; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+	if n := db.Stats().Idle; n != 5 {
+		t.Errorf("expected the limit of 5 idle connections to be restored, got %d idle", n)
+	}
+}
+
+func TestMaxConnsTableLocker(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	db.SetMaxOpenConns(10)
+	defer SetMaxConns(0)
+	SetMaxConns(1)
+	defer SetLocker(nil)
+	SetLocker(NewTableLocker(db))
+
+	restore := limitConns(db)
+	if n := db.Stats().MaxOpenConnections; n != 2 {
+		t.Errorf("expected a connection to be reserved for the heartbeat, got a limit of %d", n)
+	}
+	restore()
+	if n := db.Stats().MaxOpenConnections; n != 10 {
+		t.Errorf("expected the limit of 10 connections to be restored, got %d", n)
+	}
+}
@@ -0,0 +1,233 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PreflightError lists the problems found by Preflight, each with the
+// statement granting the missing privilege when there is one.
+type PreflightError struct {
+	Problems []string
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("preflight failed: %s", strings.Join(e.Problems, "; "))
+}
+
+// Preflight checks, without modifying it, that migrations can run on db
+// before any of them runs, instead of failing midway: that the database is
+// reachable and a writable primary, that the version table can be read and
+// written, or created if missing, and that the migrations can create and
+// alter tables. Privileges are checked on Postgres, Redshift, MySQL and SQL
+// Server, for the role of SetRole if any. The problems are returned in a
+// *PreflightError.
+func Preflight(db *sql.DB, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return preflightLocked(db)
+	})
+}
+
+// preflightLocked is Preflight for the callers holding optionsMu.
+func preflightLocked(db *sql.DB) error {
+	ensureDialect(db)
+	if err := db.Ping(); err != nil {
+		return withExitCode(ExitUnreachable, errors.Wrap(err, "database is unreachable"))
+	}
+	if primaryCheck {
+		if err := CheckPrimary(db); err != nil {
+			return err
+		}
+	}
+
+	var problems []string
+	exists, err := versionTableExists(db)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	privileges, err := missingPrivileges(db, exists)
+	if err != nil {
+		return errors.Wrap(err, "failed to check privileges")
+	}
+	problems = append(problems, privileges...)
+	if len(problems) > 0 {
+		return &PreflightError{Problems: problems}
+	}
+	return nil
+}
+
+// versionTableExists returns whether the version table exists, or an
+// error if it exists but can't be read.
+func versionTableExists(db *sql.DB) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT version_id FROM %s WHERE 1 = 0", TableName()))
+	if err == nil {
+		rows.Close()
+		return true, nil
+	}
+	if _, ok := GetDialect().(*PostgresDialect); ok {
+		var exists bool
+		if db.QueryRow("SELECT to_regclass($1) IS NOT NULL", TableName()).Scan(&exists) == nil && exists {
+			return true, errors.Errorf("version table %s can't be read: %v", TableName(), err)
+		}
+	}
+	return false, nil
+}
+
+// splitTableName returns the schema of a qualified table name, and the
+// table name.
+func splitTableName(name string) (schema, table string) {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// missingPrivileges returns the privileges missing to run migrations and
+// update the version table, in the current dialect.
+func missingPrivileges(db *sql.DB, versionTable bool) ([]string, error) {
+	switch GetDialect().(type) {
+	case *PostgresDialect, *RedshiftDialect:
+		return missingPostgresPrivileges(db, versionTable)
+	case *MySQLDialect, *MariaDBDialect, *TiDBDialect:
+		return missingMySQLPrivileges(db, versionTable)
+	case *SqlServerDialect:
+		return missingSQLServerPrivileges(db, versionTable)
+	default:
+		return nil, nil
+	}
+}
+
+func missingPostgresPrivileges(db *sql.DB, versionTable bool) ([]string, error) {
+	var user, current sql.NullString
+	if err := db.QueryRow("SELECT current_user, current_schema()").Scan(&user, &current); err != nil {
+		return nil, err
+	}
+	migrator := user.String
+	if role != "" {
+		migrator = role
+	}
+	if !current.Valid {
+		return []string{fmt.Sprintf("role %s has no schema: the schemas of its search_path don't exist", migrator)}, nil
+	}
+
+	var problems []string
+	check := func(user, privilege, kind, name, q string, args ...interface{}) error {
+		var ok bool
+		if err := db.QueryRow(q, args...).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("role %s lacks %s on %s %s (GRANT %s ON %s %s TO %s)", user, privilege, kind, name, privilege, strings.ToUpper(kind), name, user))
+		}
+		return nil
+	}
+	if err := check(migrator, "CREATE", "schema", current.String, "SELECT has_schema_privilege($1, $2, 'CREATE')", migrator, current.String); err != nil {
+		return nil, err
+	}
+
+	schema, _ := splitTableName(TableName())
+	if schema == "" {
+		schema = current.String
+	}
+	if !versionTable {
+		if schema != current.String || migrator != user.String {
+			err := check(user.String, "CREATE", "schema", schema, "SELECT has_schema_privilege($1, $2, 'CREATE')", user.String, schema)
+			return problems, err
+		}
+		return problems, nil
+	}
+	for _, privilege := range []string{"INSERT", "DELETE"} {
+		if err := check(user.String, privilege, "table", TableName(), "SELECT has_table_privilege($1, $2, $3)", user.String, TableName(), privilege); err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+func missingMySQLPrivileges(db *sql.DB, versionTable bool) ([]string, error) {
+	var user, database sql.NullString
+	if err := db.QueryRow("SELECT CURRENT_USER(), DATABASE()").Scan(&user, &database); err != nil {
+		return nil, err
+	}
+	if !database.Valid {
+		return []string{"no database selected: add it to the dbstring"}, nil
+	}
+	i := strings.LastIndex(user.String, "@")
+	if i < 0 {
+		return nil, errors.Errorf("unexpected user %q", user.String)
+	}
+	grantee := "'" + user.String[:i] + "'@'" + user.String[i+1:] + "'"
+	_, table := splitTableName(TableName())
+
+	// The privileges of the user, on every database, on the database of
+	// the dbstring, and on the version table.
+	rows, err := db.Query(`SELECT privilege_type, 'database' FROM information_schema.user_privileges WHERE grantee = ?
+		UNION SELECT privilege_type, 'database' FROM information_schema.schema_privileges WHERE grantee = ? AND ? LIKE table_schema
+		UNION SELECT privilege_type, 'table' FROM information_schema.table_privileges WHERE grantee = ? AND table_schema = ? AND table_name = ?`,
+		grantee, grantee, database.String, grantee, database.String, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	granted := map[string]bool{}
+	for rows.Next() {
+		var privilege, level string
+		if err := rows.Scan(&privilege, &level); err != nil {
+			return nil, err
+		}
+		granted[level+" "+privilege] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, privilege := range []string{"CREATE", "ALTER"} {
+		if !granted["database "+privilege] {
+			problems = append(problems, fmt.Sprintf("user %s lacks %s on database %s (GRANT %s ON %s.* TO %s)", grantee, privilege, database.String, privilege, QuoteIdent(database.String), grantee))
+		}
+	}
+	if versionTable {
+		for _, privilege := range []string{"INSERT", "DELETE"} {
+			if !granted["database "+privilege] && !granted["table "+privilege] {
+				problems = append(problems, fmt.Sprintf("user %s lacks %s on table %s (GRANT %s ON %s TO %s)", grantee, privilege, TableName(), privilege, QuoteIdent(database.String, table), grantee))
+			}
+		}
+	}
+	return problems, nil
+}
+
+func missingSQLServerPrivileges(db *sql.DB, versionTable bool) ([]string, error) {
+	var user, database, schema string
+	if err := db.QueryRow("SELECT USER_NAME(), DB_NAME(), SCHEMA_NAME()").Scan(&user, &database, &schema); err != nil {
+		return nil, err
+	}
+	var problems []string
+	check := func(privilege, kind, name, grant, q string, args ...interface{}) error {
+		var ok sql.NullInt64
+		if err := db.QueryRow(q, args...).Scan(&ok); err != nil {
+			return err
+		}
+		if ok.Int64 != 1 {
+			problems = append(problems, fmt.Sprintf("user %s lacks %s on %s %s (GRANT %s TO %s)", user, privilege, kind, name, grant, QuoteIdent(user)))
+		}
+		return nil
+	}
+	if err := check("CREATE TABLE", "database", database, "CREATE TABLE", "SELECT HAS_PERMS_BY_NAME(NULL, 'DATABASE', 'CREATE TABLE')"); err != nil {
+		return nil, err
+	}
+	if err := check("ALTER", "schema", schema, "ALTER ON SCHEMA::"+QuoteIdent(schema), "SELECT HAS_PERMS_BY_NAME(@p1, 'SCHEMA', 'ALTER')", schema); err != nil {
+		return nil, err
+	}
+	if versionTable {
+		for _, privilege := range []string{"INSERT", "DELETE"} {
+			if err := check(privilege, "table", TableName(), privilege+" ON "+TableName(), "SELECT HAS_PERMS_BY_NAME(@p1, 'OBJECT', @p2)", TableName(), privilege); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return problems, nil
+}
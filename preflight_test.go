@@ -0,0 +1,48 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := Preflight(db); err != nil {
+		t.Errorf("expected a missing version table to pass, got %v", err)
+	}
+	if _, err := db.Exec("SELECT * FROM goose_db_version"); err == nil {
+		t.Error("expected preflight not to create the version table")
+	}
+	if _, err := EnsureDBVersion(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := Preflight(db, WithTableName("goose_db_version")); err != nil {
+		t.Errorf("expected an existing version table to pass, got %v", err)
+	}
+
+	db.Close()
+	if err := Preflight(db); ExitCode(err) != ExitUnreachable {
+		t.Errorf("expected a closed database to be unreachable, got %d (%v)", ExitCode(err), err)
+	}
+}
+
+func TestPreflightError(t *testing.T) {
+	err := &PreflightError{Problems: []string{
+		"role app_rw lacks CREATE on schema public (GRANT CREATE ON SCHEMA public TO app_rw)",
+		"role app lacks INSERT on table goose_db_version (GRANT INSERT ON TABLE goose_db_version TO app)",
+	}}
+	if !strings.HasPrefix(err.Error(), "preflight failed: role app_rw lacks CREATE on schema public (") || !strings.Contains(err.Error(), "); role app lacks INSERT") {
+		t.Errorf("unexpected error %q", err)
+	}
+	for name, want := range map[string][2]string{
+		"goose_db_version":         {"", "goose_db_version"},
+		"ops.goose_db_version":     {"ops", "goose_db_version"},
+		"app.ops.goose_db_version": {"app.ops", "goose_db_version"},
+	} {
+		if schema, table := splitTableName(name); schema != want[0] || table != want[1] {
+			t.Errorf("%s: expected %v, got %s and %s", name, want, schema, table)
+		}
+	}
+}
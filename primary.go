@@ -0,0 +1,83 @@
+package goose
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotPrimary is the cause of the error returned by the commands
+// modifying a database connected to a read-only replica.
+var ErrNotPrimary = errors.New("database is read-only, not a writable primary")
+
+var primaryCheck = true
+
+// SetPrimaryCheck sets whether the commands modifying the database first
+// check that it is a writable primary, enabled by default.
+func SetPrimaryCheck(c bool) {
+	primaryCheck = c
+}
+
+// readOnlyQuery returns a query of whether the database is read-only in the
+// current dialect, or "" if the dialect has no read-only replicas.
+func readOnlyQuery() string {
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		return "SELECT pg_is_in_recovery()"
+	case *MySQLDialect:
+		return "SELECT @@global.read_only OR @@global.innodb_read_only"
+	case *MariaDBDialect, *TiDBDialect:
+		return "SELECT @@global.read_only"
+	case *SqlServerDialect:
+		return "SELECT CASE WHEN DATABASEPROPERTYEX(DB_NAME(), 'Updateability') = 'READ_ONLY' THEN 1 ELSE 0 END"
+	default:
+		return ""
+	}
+}
+
+// CheckPrimary returns an error with ErrNotPrimary as cause if db is a
+// read-only replica: pg_is_in_recovery() on Postgres, @@read_only on MySQL
+// and TiDB, or a read-only database on SQL Server.
+func CheckPrimary(db *sql.DB) error {
+	ensureDialect(db)
+	q := readOnlyQuery()
+	if q == "" {
+		return nil
+	}
+	var readOnly bool
+	if err := db.QueryRow(q).Scan(&readOnly); err != nil {
+		return errors.Wrap(err, "failed to check the database is a writable primary")
+	}
+	if readOnly {
+		return withExitCode(ExitNotPrimary, ErrNotPrimary)
+	}
+	return nil
+}
+
+// OpenPrimary opens the first writable primary among the databases of
+// dbstrings, for example the members of a replicated cluster, skipping the
+// unreachable databases and the read-only replicas.
+func OpenPrimary(driver string, dbstrings ...string) (*sql.DB, error) {
+	if len(dbstrings) == 0 {
+		return nil, errors.New("no database to open")
+	}
+	var err error
+	for _, dbstring := range dbstrings {
+		var db *sql.DB
+		db, err = OpenDBWithDriver(driver, dbstring)
+		if err != nil {
+			return nil, err
+		}
+		if err = db.Ping(); err == nil {
+			if err = CheckPrimary(db); err == nil {
+				return db, nil
+			}
+		}
+		verboseInfo("Skipping database: %v", err)
+		db.Close()
+	}
+	if errors.Cause(err) == ErrNotPrimary {
+		return nil, withExitCode(ExitNotPrimary, errors.Errorf("no writable primary among %d databases", len(dbstrings)))
+	}
+	return nil, withExitCode(ExitUnreachable, errors.Wrapf(err, "no writable primary among %d databases", len(dbstrings)))
+}
@@ -0,0 +1,51 @@
+package goose
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadOnlyQuery(t *testing.T) {
+	defer SetDialect("postgres")
+
+	for _, d := range []string{"postgres", "mysql", "tidb", "mssql"} {
+		SetDialect(d)
+		if readOnlyQuery() == "" {
+			t.Errorf("%s: expected a read-only query", d)
+		}
+	}
+	for _, d := range []string{"sqlite3", "redshift", "clickhouse"} {
+		SetDialect(d)
+		if q := readOnlyQuery(); q != "" {
+			t.Errorf("%s: unexpected read-only query %q", d, q)
+		}
+	}
+}
+
+func TestOpenPrimary(t *testing.T) {
+	defer SetDialect("postgres")
+
+	dir, err := ioutil.TempDir("", "goose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	unreachable := filepath.Join(dir, "missing", "test.db")
+	primary := filepath.Join(dir, "test.db")
+
+	db, err := OpenPrimary("sqlite3", unreachable, primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := os.Stat(primary); err != nil {
+		t.Errorf("expected the reachable database to be opened: %v", err)
+	}
+
+	if _, err := OpenPrimary("sqlite3", unreachable); ExitCode(err) != ExitUnreachable {
+		t.Errorf("unexpected exit code without a reachable database, got %d (%v)", ExitCode(err), err)
+	}
+}
@@ -0,0 +1,138 @@
+package goose
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime/pprof"
+	"time"
+)
+
+var profiling = false
+
+// SetProfiling sets whether the commands modifying the database label
+// their goroutine with pprof labels, goose_command, goose_migration and
+// goose_phase, so that CPU profiles of a run can be broken down by
+// migration, and log the time spent in each phase once done.
+func SetProfiling(p bool) {
+	profiling = p
+}
+
+// Timings break down the duration of a command modifying the database.
+type Timings struct {
+	Parse       time.Duration // collecting and parsing the migrations
+	LockWait    time.Duration // waiting for the lock set with SetLocker
+	Execute     time.Duration // running the migrations
+	Bookkeeping time.Duration // reading and writing the version table
+}
+
+func (t Timings) String() string {
+	return fmt.Sprintf("parse %v, lock wait %v, execute %v, bookkeeping %v",
+		t.Parse.Round(time.Millisecond), t.LockWait.Round(time.Millisecond),
+		t.Execute.Round(time.Millisecond), t.Bookkeeping.Round(time.Millisecond))
+}
+
+type phase int
+
+const (
+	phaseParse phase = iota
+	phaseLockWait
+	phaseExecute
+	phaseBookkeeping
+)
+
+var phaseNames = []string{"parse", "lock wait", "execute", "bookkeeping"}
+
+// phaseClock measures the time spent in each phase of an invocation. The
+// phases nest, an execution recording the versions it applies for
+// instance, and the time of a nested phase only counts for it.
+type phaseClock struct {
+	stack   []phase
+	since   time.Time
+	timings Timings
+	labels  []context.Context // pprof labels of the goroutine, innermost last
+}
+
+func (c *phaseClock) add(p phase, d time.Duration) {
+	switch p {
+	case phaseParse:
+		c.timings.Parse += d
+	case phaseLockWait:
+		c.timings.LockWait += d
+	case phaseExecute:
+		c.timings.Execute += d
+	case phaseBookkeeping:
+		c.timings.Bookkeeping += d
+	}
+}
+
+// enterPhase starts measuring phase p of the active invocation, until the
+// returned function is called:
+//
+//	defer enterPhase(phaseParse)()
+func enterPhase(p phase) func() {
+	inv := activeInvocation
+	if inv == nil {
+		return func() {}
+	}
+	c := &inv.clock
+	now := time.Now()
+	if len(c.stack) > 0 {
+		c.add(c.stack[len(c.stack)-1], now.Sub(c.since))
+	}
+	c.stack = append(c.stack, p)
+	c.since = now
+	popLabels := pushLabels(inv, "goose_phase", phaseNames[p])
+
+	return func() {
+		popLabels()
+		now := time.Now()
+		c.add(c.stack[len(c.stack)-1], now.Sub(c.since))
+		c.stack = c.stack[:len(c.stack)-1]
+		c.since = now
+	}
+}
+
+// labelMigration labels the goroutine with the migration m until the
+// returned function is called, when profiling.
+func labelMigration(m *Migration) func() {
+	if activeInvocation == nil {
+		return func() {}
+	}
+	return pushLabels(activeInvocation, "goose_migration", filepath.Base(m.Source))
+}
+
+// pushLabels adds the pprof label key of the goroutine of the invocation,
+// until the returned function is called, when profiling.
+func pushLabels(inv *invocation, key, value string) func() {
+	if !profiling {
+		return func() {}
+	}
+	c := &inv.clock
+	ctx := context.Background()
+	if len(c.labels) > 0 {
+		ctx = c.labels[len(c.labels)-1]
+	} else {
+		ctx = pprof.WithLabels(ctx, pprof.Labels("goose_command", inv.command))
+	}
+	ctx = pprof.WithLabels(ctx, pprof.Labels(key, value))
+	c.labels = append(c.labels, ctx)
+	pprof.SetGoroutineLabels(ctx)
+
+	return func() {
+		c.labels = c.labels[:len(c.labels)-1]
+		if len(c.labels) > 0 {
+			pprof.SetGoroutineLabels(c.labels[len(c.labels)-1])
+		} else {
+			pprof.SetGoroutineLabels(context.Background())
+		}
+	}
+}
+
+// logTimings logs the time spent in each phase of the invocation, when
+// profiling.
+func logTimings(inv *invocation) {
+	if profiling {
+		log.Printf("goose: %s took %v: %v\n", inv.command, time.Since(inv.started).Round(time.Millisecond), inv.clock.timings)
+	}
+}
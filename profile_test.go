@@ -0,0 +1,67 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// notificationRecorder records the notifications of the commands.
+type notificationRecorder struct {
+	notifications []Notification
+}
+
+func (r *notificationRecorder) Notify(n Notification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func TestTimings(t *testing.T) {
+	defer SetLogger(log)
+	logger := &bufferLogger{}
+	SetLogger(logger)
+	defer SetProfiling(false)
+	SetProfiling(true)
+	defer SetNotifier(nil)
+	recorder := &notificationRecorder{}
+	SetNotifier(recorder)
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.notifications) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(recorder.notifications))
+	}
+	n := recorder.notifications[0]
+	if n.Timings.Parse <= 0 || n.Timings.Execute <= 0 || n.Timings.Bookkeeping <= 0 {
+		t.Errorf("expected the time of every phase, got %v", n.Timings)
+	}
+	if total := n.Timings.Parse + n.Timings.LockWait + n.Timings.Execute + n.Timings.Bookkeeping; total > n.Duration {
+		t.Errorf("expected the phases to take at most %v, got %v", n.Duration, total)
+	}
+	if !strings.Contains(logger.String(), "goose: up took ") {
+		t.Errorf("expected the timings to be logged, got %q", logger.String())
+	}
+}
+
+func TestEnterPhaseNested(t *testing.T) {
+	defer func() { activeInvocation = nil }()
+	inv := &invocation{command: "up", started: time.Now()}
+	activeInvocation = inv
+
+	leaveExecute := enterPhase(phaseExecute)
+	time.Sleep(10 * time.Millisecond)
+	leaveBookkeeping := enterPhase(phaseBookkeeping)
+	time.Sleep(50 * time.Millisecond)
+	leaveBookkeeping()
+	leaveExecute()
+
+	// The time of the version table doesn't count for the execution.
+	if got := inv.clock.timings; got.Execute < 10*time.Millisecond || got.Execute >= 50*time.Millisecond || got.Bookkeeping < 50*time.Millisecond {
+		t.Errorf("unexpected timings %v", got)
+	}
+}
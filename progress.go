@@ -0,0 +1,110 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var progressInterval = 30 * time.Second
+
+// SetProgressInterval sets the interval at which goose logs the elapsed
+// time of a long running statement, and on Postgres its progress from the
+// pg_stat_progress_* views. An interval of 0 disables progress reporting.
+func SetProgressInterval(d time.Duration) {
+	progressInterval = d
+}
+
+// pgProgressQueries query the progress of the statement of a backend,
+// as a phase and a count of work done out of a total, in the progress
+// views of Postgres. Views missing from older versions are ignored.
+var pgProgressQueries = []string{
+	"SELECT phase, blocks_done, blocks_total FROM pg_stat_progress_create_index WHERE pid = $1",
+	"SELECT phase, heap_blks_scanned, heap_blks_total FROM pg_stat_progress_cluster WHERE pid = $1",
+	"SELECT phase, heap_blks_scanned, heap_blks_total FROM pg_stat_progress_vacuum WHERE pid = $1",
+	"SELECT phase, sample_blks_scanned, sample_blks_total FROM pg_stat_progress_analyze WHERE pid = $1",
+	"SELECT type, bytes_processed, bytes_total FROM pg_stat_progress_copy WHERE pid = $1",
+}
+
+// watchProgress logs the progress of query every progress interval, until
+// stop is called once the query is done. The progress views are queried on
+// another connection of db than the one running query.
+func watchProgress(db *sql.DB, query string) (stop func()) {
+	if progressInterval <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	start := time.Now()
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				msg := fmt.Sprintf("goose: still running after %v: %s", time.Since(start).Round(time.Second), summarizeStatement(query))
+				if p := statementProgress(ctx, db, query); p != "" {
+					msg += " (" + p + ")"
+				}
+				log.Println(msg)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// statementProgress returns the progress of query on Postgres, like
+// "building index: 42%", or an empty string if it is unknown.
+func statementProgress(ctx context.Context, db *sql.DB, query string) string {
+	if _, ok := GetDialect().(*PostgresDialect); !ok {
+		return ""
+	}
+	// Polling must not outlive the interval, e.g. on a pool of a single
+	// connection busy running the query.
+	ctx, cancel := context.WithTimeout(ctx, progressInterval)
+	defer cancel()
+
+	var pid int64
+	if err := db.QueryRowContext(ctx, "SELECT pid FROM pg_stat_activity WHERE query = $1 AND state = 'active' AND pid <> pg_backend_pid() LIMIT 1", query).Scan(&pid); err != nil {
+		return ""
+	}
+	for _, q := range pgProgressQueries {
+		var (
+			phase       string
+			done, total sql.NullInt64
+		)
+		if err := db.QueryRowContext(ctx, q, pid).Scan(&phase, &done, &total); err != nil {
+			continue
+		}
+		if total.Int64 > 0 {
+			return fmt.Sprintf("%s: %d%%", phase, done.Int64*100/total.Int64)
+		}
+		return phase
+	}
+	return ""
+}
+
+// summarizeStatement returns the first line of a statement, without its
+// comments, shortened for logging.
+func summarizeStatement(query string) string {
+	s := strings.TrimSpace(clearStatement(query))
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i] + " ..."
+	}
+	if len(s) > 80 {
+		s = s[:77] + "..."
+	}
+	return s
+}
@@ -0,0 +1,34 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchProgress(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	defer SetLogger(log)
+	defer SetProgressInterval(progressInterval)
+
+	logger := &bufferLogger{}
+	SetLogger(logger)
+	SetProgressInterval(10 * time.Millisecond)
+
+	stop := watchProgress(db, "-- rebuild\nUPDATE a\nSET b = 1;")
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	if !strings.Contains(logger.String(), "goose: still running after") || !strings.Contains(logger.String(), "UPDATE a ...") {
+		t.Errorf("expected progress of the statement to be logged, got %q", logger.String())
+	}
+
+	logger.Reset()
+	SetProgressInterval(0)
+	stop = watchProgress(db, "UPDATE a SET b = 1;")
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	if logger.String() != "" {
+		t.Errorf("expected no progress with progress reporting disabled, got %q", logger.String())
+	}
+}
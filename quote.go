@@ -0,0 +1,39 @@
+package goose
+
+import (
+	"strings"
+)
+
+// QuoteIdent quotes an identifier for the current dialect, escaping the
+// quotes it contains, so that Go migrations can build statements running
+// on several databases. Several parts are joined with dots, as in a
+// qualified name:
+//
+//	goose.QuoteIdent("public", "users") // "public"."users" on Postgres, `public`.`users` on MySQL
+func QuoteIdent(parts ...string) string {
+	open, close := `"`, `"`
+	switch GetDialect().(type) {
+	case *MySQLDialect, *MariaDBDialect, *TiDBDialect, *ClickHouseDialect, *BigQueryDialect, *SpannerDialect:
+		open, close = "`", "`"
+	case *SqlServerDialect:
+		open, close = "[", "]"
+	}
+	escaped := close + close
+	switch GetDialect().(type) {
+	case *BigQueryDialect, *SpannerDialect:
+		escaped = `\` + close
+	}
+
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = open + strings.Replace(part, close, escaped, -1) + close
+	}
+	return strings.Join(quoted, ".")
+}
+
+// Placeholder returns the bind parameter placeholder of the nth argument
+// of a statement, starting at 1, for the current dialect: $1 on Postgres,
+// ? on MySQL, @p1 on SQL Server.
+func Placeholder(n int) string {
+	return GetDialect().placeholder(n)
+}
@@ -0,0 +1,42 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	defer SetDialect("postgres")
+
+	tests := []struct {
+		dialect     string
+		ident       string
+		placeholder string
+	}{
+		{"postgres", `"public"."user""s"`, "$2"},
+		{"mysql", "`public`.`user\"s`", "?"},
+		{"mariadb", "`public`.`user\"s`", "?"},
+		{"mssql", `[public].[user"s]`, "@p2"},
+		{"sqlite3", `"public"."user""s"`, "?"},
+		{"bigquery", "`public`.`user\"s`", "?"},
+	}
+	for _, test := range tests {
+		if err := SetDialect(test.dialect); err != nil {
+			t.Fatal(err)
+		}
+		if got := QuoteIdent("public", `user"s`); got != test.ident {
+			t.Errorf("%s: got %s, want %s", test.dialect, got, test.ident)
+		}
+		if got := Placeholder(2); got != test.placeholder {
+			t.Errorf("%s: got placeholder %s, want %s", test.dialect, got, test.placeholder)
+		}
+	}
+
+	SetDialect("mssql")
+	if got := QuoteIdent("a]b"); got != "[a]]b]" {
+		t.Errorf("unexpected escaping, got %s", got)
+	}
+	SetDialect("bigquery")
+	if got := QuoteIdent("a`b"); got != "`a\\`b`" {
+		t.Errorf("unexpected escaping, got %s", got)
+	}
+}
@@ -5,18 +5,35 @@ import (
 )
 
 // Redo rolls back the most recently applied migration, then runs it again.
-func Redo(db *sql.DB, dir string) error {
-	currentVersion, err := GetDBVersion(db)
-	if err != nil {
-		return err
-	}
+func Redo(db *sql.DB, dir string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return redoLocked(db, dir)
+	})
+}
 
-	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+// redoLocked is Redo for the callers holding optionsMu.
+func redoLocked(db *sql.DB, dir string) error {
+	return invoke("redo", db, func() error {
+		return redo(db, dir)
+	})
+}
+
+func redo(db *sql.DB, dir string) error {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
 	if err != nil {
 		return err
 	}
 
-	current, err := migrations.Current(currentVersion)
+	var current *Migration
+	if noVersioning {
+		current, err = migrations.Last()
+	} else {
+		var currentVersion int64
+		if currentVersion, err = GetDBVersion(db); err != nil {
+			return err
+		}
+		current, err = migrations.Current(currentVersion)
+	}
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,28 @@
+package goose
+
+import "regexp"
+
+var redshiftTableAttributes string
+
+// redshiftNoTxStatement matches the Redshift statements that can't run in a
+// transaction block.
+var redshiftNoTxStatement = regexp.MustCompile(`(?is)^\s*(?:VACUUM|(?:CREATE|DROP)\s+DATABASE|(?:CREATE|ALTER|DROP)\s+EXTERNAL\s+(?:TABLE|SCHEMA|VIEW)|ALTER\s+TABLE\s.*\s(?:APPEND\s+FROM|ALTER\s+COLUMN\s+\S+\s+TYPE)\s)`)
+
+// SetRedshiftTableAttributes sets the table attributes of the version table
+// created on Redshift, like its distribution style and sort key:
+//
+//	goose.SetRedshiftTableAttributes("DISTSTYLE ALL SORTKEY (version_id)")
+//
+// An empty string, the default, leaves them to Redshift.
+func SetRedshiftTableAttributes(attributes string) {
+	redshiftTableAttributes = attributes
+}
+
+// textType returns the type of the unbounded text columns of the goose
+// tables: TEXT is a VARCHAR(256) on Redshift.
+func textType() string {
+	if _, ok := GetDialect().(*RedshiftDialect); ok {
+		return "VARCHAR(65535)"
+	}
+	return "TEXT"
+}
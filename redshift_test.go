@@ -0,0 +1,31 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedshiftTableAttributes(t *testing.T) {
+	defer SetDialect("postgres")
+	defer SetRedshiftTableAttributes("")
+	if err := SetDialect("redshift"); err != nil {
+		t.Fatal(err)
+	}
+
+	SetRedshiftTableAttributes("DISTSTYLE ALL SORTKEY (version_id)")
+	if q := GetDialect().createVersionTableSQL(); !strings.HasSuffix(q, ") DISTSTYLE ALL SORTKEY (version_id);") {
+		t.Errorf("expected the table attributes in the version table DDL, got %q", q)
+	}
+	if typ := textType(); typ != "VARCHAR(65535)" {
+		t.Errorf("expected a bounded VARCHAR for text columns, got %q", typ)
+	}
+	if s, err := genAddColumn([]string{"users", "active", "boolean", "true"}); err != nil || len(s.up) != 1 || !strings.Contains(s.up[0], "DEFAULT true NOT NULL") {
+		t.Errorf("expected add-column to add the column with its default, got %v (%v)", s, err)
+	}
+	if q, _ := listAppliedQuery(ListFilter{}); !strings.Contains(q, "ORDER BY tstamp DESC, id DESC") || strings.Contains(q, "MAX(id)") {
+		t.Errorf("expected the latest record by timestamp, got %q", q)
+	}
+	if _, err := compactHistory(nil, 0); err == nil {
+		t.Error("expected compacting to be unsupported")
+	}
+}
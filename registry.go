@@ -0,0 +1,114 @@
+package goose
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// DuplicateMigrationError is the panic of the registration of a Go
+// migration with the version of an already registered one, typically
+// through a package of migrations imported twice, or two migration files
+// with the same version.
+type DuplicateMigrationError struct {
+	Version        int64
+	Source         string   // file of the migration registered last
+	ExistingSource string   // file of the migration registered first
+	Stack          []string // functions and lines registering the migration registered last
+	ExistingStack  []string // functions and lines registering the migration registered first
+}
+
+func (e *DuplicateMigrationError) Error() string {
+	return fmt.Sprintf("failed to add migration %q: version %d conflicts with %q\n\nregistered by:\n\t%s\n\n%q registered by:\n\t%s",
+		e.Source, e.Version, e.ExistingSource, strings.Join(e.Stack, "\n\t"), e.ExistingSource, strings.Join(e.ExistingStack, "\n\t"))
+}
+
+// goRegistry is the registry of the Go migrations, safe for concurrent use.
+type goRegistry struct {
+	sync.RWMutex
+	migrations map[int64]*Migration
+	stacks     map[int64][]string
+}
+
+var registeredGoMigrations = &goRegistry{migrations: map[int64]*Migration{}, stacks: map[int64][]string{}}
+
+// add registers m, panicking with a DuplicateMigrationError if a migration
+// with its version is already registered.
+func (r *goRegistry) add(m *Migration) {
+	stack := registrationStack()
+	r.Lock()
+	defer r.Unlock()
+	if existing, ok := r.migrations[m.Version]; ok {
+		panic(&DuplicateMigrationError{
+			Version:        m.Version,
+			Source:         m.Source,
+			ExistingSource: existing.Source,
+			Stack:          stack,
+			ExistingStack:  r.stacks[m.Version],
+		})
+	}
+	r.migrations[m.Version] = m
+	r.stacks[m.Version] = stack
+}
+
+// has reports whether a migration with version v is registered.
+func (r *goRegistry) has(v int64) bool {
+	r.RLock()
+	defer r.RUnlock()
+	_, ok := r.migrations[v]
+	return ok
+}
+
+// all returns copies of the registered migrations, in no particular order,
+// that the caller can connect.
+func (r *goRegistry) all() Migrations {
+	r.RLock()
+	defer r.RUnlock()
+	migrations := make(Migrations, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		migration := *m
+		migrations = append(migrations, &migration)
+	}
+	return migrations
+}
+
+// swap replaces the registered migrations with an empty registry, and
+// returns a function restoring them.
+func (r *goRegistry) swap() (restore func()) {
+	r.Lock()
+	defer r.Unlock()
+	saved, savedStacks := r.migrations, r.stacks
+	r.migrations, r.stacks = map[int64]*Migration{}, map[int64][]string{}
+	return func() {
+		r.Lock()
+		defer r.Unlock()
+		r.migrations, r.stacks = saved, savedStacks
+	}
+}
+
+// registrationStack returns the functions and lines calling into goose to
+// register a migration, outermost last: the init functions of the packages
+// importing it.
+func registrationStack() []string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	self, _ := frames.Next()
+	// The registering functions of goose, like AddMigration, are skipped.
+	add := self.Function[:strings.LastIndex(self.Function, ".")+1] + "Add"
+	var stack []string
+	for {
+		frame, more := frames.Next()
+		switch {
+		case strings.HasPrefix(frame.Function, add):
+			stack = nil
+		case frame.Function != "" && !strings.HasPrefix(frame.Function, "runtime."):
+			stack = append(stack, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
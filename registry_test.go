@@ -0,0 +1,99 @@
+package goose
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func registerTestMigration(filename string) {
+	AddNamedMigration(filename, func(QueryExecer) error { return nil }, nil)
+}
+
+func TestDuplicateMigration(t *testing.T) {
+	defer registeredGoMigrations.swap()()
+
+	registerTestMigration("a/00001_first.go")
+	defer func() {
+		e, ok := recover().(*DuplicateMigrationError)
+		if !ok {
+			t.Fatalf("expected a DuplicateMigrationError, got %v", e)
+		}
+		if e.Version != 1 || e.Source != "b/00001_second.go" || e.ExistingSource != "a/00001_first.go" {
+			t.Errorf("unexpected error %+v", e)
+		}
+		if len(e.Stack) == 0 || !strings.Contains(e.Stack[0], "registerTestMigration") || len(e.ExistingStack) == 0 || !strings.Contains(e.ExistingStack[0], "registerTestMigration") {
+			t.Errorf("expected the stacks to start at the registering function, got %q and %q", e.Stack, e.ExistingStack)
+		}
+		if msg := e.Error(); !strings.Contains(msg, "b/00001_second.go") || !strings.Contains(msg, "registry_test.go:") {
+			t.Errorf("expected the files and the stacks in the message, got %q", msg)
+		}
+	}()
+	registerTestMigration("b/00001_second.go")
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	defer registeredGoMigrations.swap()()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(2)
+		go func(v int) {
+			defer wg.Done()
+			registerTestMigration(fmt.Sprintf("%05d_concurrent.go", v))
+		}(i)
+		go func() {
+			defer wg.Done()
+			RegisteredMigrations()
+		}()
+	}
+	wg.Wait()
+	migrations := RegisteredMigrations()
+	if len(migrations) != 20 || migrations[0].Version != 1 || migrations[19].Next != -1 {
+		t.Errorf("expected 20 connected migrations, got %d", len(migrations))
+	}
+	if registeredGoMigrations.migrations[20].Previous != -1 {
+		t.Error("expected the registered migrations not to be connected in place")
+	}
+}
+
+func TestAddNamedMigration(t *testing.T) {
+	defer registeredGoMigrations.swap()()
+	dir, err := ioutil.TempDir("", "goose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := Create(nil, dir, "backfill users", "go"); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected 1 created migration, got %v (%v)", files, err)
+	}
+	name := filepath.Base(files[0])
+	source, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("goose.AddNamedMigration(%q, upBackfillUsers, downBackfillUsers)", name); !strings.Contains(string(source), want) {
+		t.Errorf("expected the generated migration to register with %s, got:\n%s", want, source)
+	}
+
+	registerTestMigration(name)
+	migrations, err := CollectMigrations(dir, 0, MaxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 || migrations[0].Source != files[0] || !migrations[0].Registered {
+		t.Fatalf("expected the registered migration, resolved in the directory, got %v", migrations)
+	}
+	if sum, err := migrations[0].Checksum(); err != nil || sum == "" {
+		t.Errorf("expected the checksum of the migration file, got %q (%v)", sum, err)
+	}
+}
@@ -0,0 +1,273 @@
+package goose
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MigrationFailure describes a failed migration, reported to the
+// ErrorReporter.
+type MigrationFailure struct {
+	Command   string // up, down, redo..., empty if the migration was run directly
+	Version   int64
+	Source    string // base name of the migration file
+	Direction string // up or down
+	Statement string // SQL statement that failed, if any
+	SQLState  string // SQLSTATE code of the error, if the driver reports it
+	Err       error
+}
+
+// ErrorReporter is reported the failed migrations, to send them to an error
+// tracker.
+type ErrorReporter interface {
+	ReportError(f MigrationFailure) error
+}
+
+var errorReporter ErrorReporter
+
+// SetErrorReporter sets the ErrorReporter reported the migrations failing.
+// A failure to report is logged, and doesn't change the outcome of the
+// migration.
+func SetErrorReporter(r ErrorReporter) {
+	errorReporter = r
+}
+
+// statementError is the error of a SQL statement of a migration.
+type statementError struct {
+	query string
+	err   error
+}
+
+func (e *statementError) Error() string {
+	return fmt.Sprintf("failed to execute SQL query %q: %v", clearStatement(e.query), e.err)
+}
+
+func (e *statementError) Cause() error { return e.err }
+
+// withStatement wraps the error of a SQL statement.
+func withStatement(err error, query string) error {
+	return &statementError{query: query, err: err}
+}
+
+// reportFailure reports a failed migration to the reporter set with
+// SetErrorReporter, if any.
+func reportFailure(m *Migration, direction bool, err error) {
+	if errorReporter == nil {
+		return
+	}
+	f := MigrationFailure{Version: m.Version, Source: filepath.Base(m.Source), Direction: "down", Err: err}
+	if direction {
+		f.Direction = "up"
+	}
+	if activeInvocation != nil {
+		f.Command = activeInvocation.command
+	}
+	for e := err; e != nil; {
+		if s, ok := e.(*statementError); ok && f.Statement == "" {
+			f.Statement = strings.TrimSpace(clearStatement(s.query))
+		}
+		if state := sqlState(e); state != "" {
+			f.SQLState = state
+		}
+		cause, ok := e.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		e = cause.Cause()
+	}
+	if reportErr := errorReporter.ReportError(f); reportErr != nil {
+		log.Printf("goose: failed to report error: %v\n", reportErr)
+	}
+}
+
+// sqlState returns the SQLSTATE code of a driver error: the result of its
+// SQLState method (pgx), or its five characters Code or SQLState field
+// (lib/pq, go-sql-driver/mysql).
+func sqlState(err error) string {
+	if e, ok := err.(interface{ SQLState() string }); ok {
+		return e.SQLState()
+	}
+	v := reflect.Indirect(reflect.ValueOf(err))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	for _, name := range []string{"Code", "SQLState"} {
+		f := v.FieldByName(name)
+		switch {
+		case !f.IsValid():
+		case f.Kind() == reflect.String && f.Len() == 5:
+			return f.String()
+		case f.Kind() == reflect.Array && f.Len() == 5 && f.Type().Elem().Kind() == reflect.Uint8:
+			b := make([]byte, 5)
+			reflect.Copy(reflect.ValueOf(b), f)
+			if b[0] != 0 {
+				return string(b)
+			}
+		}
+	}
+	return ""
+}
+
+// SentryReporter is an ErrorReporter sending the failed migrations to
+// Sentry as events, grouped by migration and SQLSTATE.
+type SentryReporter struct {
+	endpoint    string
+	key         string
+	environment string
+	database    string
+	timeout     time.Duration
+}
+
+// NewSentryReporter creates an ErrorReporter sending events to the Sentry
+// project of the DSN, https://KEY@HOST/PROJECT.
+func NewSentryReporter(dsn string) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Sentry DSN")
+	}
+	project := strings.Trim(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || u.Host == "" || project == "" {
+		return nil, errors.Errorf("invalid Sentry DSN %q: must be of form https://KEY@HOST/PROJECT", dsn)
+	}
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	return &SentryReporter{
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, project),
+		key:      u.User.Username(),
+		timeout:  10 * time.Second,
+	}, nil
+}
+
+// SetEnvironment sets the environment of the events, production, staging...
+func (s *SentryReporter) SetEnvironment(env string) {
+	s.environment = env
+}
+
+// SetDatabase sets the name identifying the database in events.
+func (s *SentryReporter) SetDatabase(name string) {
+	s.database = name
+}
+
+// SetTimeout sets the timeout of each event sent, 10 seconds by default.
+func (s *SentryReporter) SetTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// sentryEvent is an event of the Sentry envelope API.
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release"`
+	Message     string            `json:"message"`
+	Exception   sentryExceptions  `json:"exception"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]string `json:"extra"`
+	Fingerprint []string          `json:"fingerprint"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// event returns the Sentry event of a failed migration.
+func (s *SentryReporter) event(f MigrationFailure) (sentryEvent, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return sentryEvent{}, errors.Wrap(err, "failed to generate event ID")
+	}
+	host, _ := os.Hostname()
+	title := fmt.Sprintf("migration %s failed", f.Source)
+	if f.SQLState != "" {
+		title = fmt.Sprintf("migration %s failed with SQLSTATE %s", f.Source, f.SQLState)
+	}
+	e := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       "error",
+		Platform:    "go",
+		Logger:      "goose",
+		ServerName:  host,
+		Environment: s.environment,
+		Release:     "goose@" + VERSION,
+		Message:     title,
+		Exception:   sentryExceptions{Values: []sentryException{{Type: title, Value: f.Err.Error()}}},
+		Tags: map[string]string{
+			"migration": f.Source,
+			"version":   fmt.Sprint(f.Version),
+			"direction": f.Direction,
+		},
+		Extra:       map[string]string{},
+		Fingerprint: []string{"goose", f.Source, f.Direction, f.SQLState},
+	}
+	for k, v := range map[string]string{"command": f.Command, "sqlstate": f.SQLState, "database": s.database} {
+		if v != "" {
+			e.Tags[k] = v
+		}
+	}
+	if f.Statement != "" {
+		e.Extra["statement"] = f.Statement
+	}
+	return e, nil
+}
+
+// ReportError sends the failed migration to Sentry.
+func (s *SentryReporter) ReportError(f MigrationFailure) error {
+	e, err := s.event(f)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, item := range []interface{}{
+		map[string]string{"event_id": e.EventID, "sent_at": e.Timestamp},
+		map[string]string{"type": "event"},
+		e,
+	} {
+		if err := enc.Encode(item); err != nil {
+			return errors.Wrap(err, "failed to encode Sentry event")
+		}
+	}
+
+	req, err := http.NewRequest("POST", s.endpoint, &body)
+	if err != nil {
+		return errors.Wrap(err, "failed to send Sentry event")
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=goose/%s, sentry_key=%s", VERSION, s.key))
+	resp, err := (&http.Client{Timeout: s.timeout}).Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send Sentry event")
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("failed to send Sentry event: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package goose
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type failureRecorder struct {
+	failures []MigrationFailure
+}
+
+func (r *failureRecorder) ReportError(f MigrationFailure) error {
+	r.failures = append(r.failures, f)
+	return nil
+}
+
+// pqError and mysqlError mimic the errors of lib/pq and go-sql-driver/mysql.
+type pqError struct{ Code string }
+
+func (e *pqError) Error() string { return "pq: relation does not exist" }
+
+type mysqlError struct {
+	Number   uint16
+	SQLState [5]byte
+}
+
+func (e *mysqlError) Error() string { return "Error 1146: table doesn't exist" }
+
+func TestSQLState(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{&pqError{Code: "42P01"}, "42P01"},
+		{&mysqlError{Number: 1146, SQLState: [5]byte{'4', '2', 'S', '0', '2'}}, "42S02"},
+		{&mysqlError{Number: 1146}, ""},
+		{errors.New("failed"), ""},
+	} {
+		if got := sqlState(tc.err); got != tc.want {
+			t.Errorf("%v: expected SQLSTATE %q, got %q", tc.err, tc.want, got)
+		}
+	}
+
+	err := withExitCode(ExitSQLError, errors.Wrap(withStatement(&pqError{Code: "42P01"}, "SELECT * FROM missing;\n"), "ERROR 00001_failing.sql"))
+	r := &failureRecorder{}
+	SetErrorReporter(r)
+	defer SetErrorReporter(nil)
+	reportFailure(&Migration{Version: 1, Source: "migrations/00001_failing.sql"}, true, err)
+	if len(r.failures) != 1 {
+		t.Fatalf("expected 1 failure, got %d", len(r.failures))
+	}
+	f := r.failures[0]
+	if f.Version != 1 || f.Source != "00001_failing.sql" || f.Direction != "up" || f.Statement != "SELECT * FROM missing;" || f.SQLState != "42P01" || f.Err != err {
+		t.Errorf("unexpected failure %+v", f)
+	}
+}
+
+func TestErrorReporter(t *testing.T) {
+	migrations := map[string]string{"00004_fail.sql": "-- +goose Up\nSELECT * FROM missing;\n"}
+	for name, sql := range testMigrations {
+		migrations[name] = sql
+	}
+	dir, cleanupDir := writeTestMigrations(t, migrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	r := &failureRecorder{}
+	SetErrorReporter(r)
+	defer SetErrorReporter(nil)
+	if err := Up(db, dir); err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+	if len(r.failures) != 1 {
+		t.Fatalf("expected 1 failure, got %d", len(r.failures))
+	}
+	if f := r.failures[0]; f.Command != "up" || f.Version != 4 || f.Source != "00004_fail.sql" || f.Statement != "SELECT * FROM missing;" || f.Err == nil {
+		t.Errorf("unexpected failure %+v", f)
+	}
+}
+
+func TestSentryReporter(t *testing.T) {
+	var auth, contentType string
+	var lines []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/envelope/" {
+			http.NotFound(w, r)
+			return
+		}
+		auth, contentType = r.Header.Get("X-Sentry-Auth"), r.Header.Get("Content-Type")
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+	}))
+	defer server.Close()
+
+	if _, err := NewSentryReporter("https://sentry.example.com/42"); err == nil {
+		t.Error("expected a DSN without key to be rejected")
+	}
+	s, err := NewSentryReporter(strings.Replace(server.URL, "://", "://abc123@", 1) + "/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetEnvironment("staging")
+	s.SetDatabase("billing")
+	err = s.ReportError(MigrationFailure{
+		Command:   "up",
+		Version:   4,
+		Source:    "00004_fail.sql",
+		Direction: "up",
+		Statement: "SELECT * FROM missing;",
+		SQLState:  "42P01",
+		Err:       errors.New("pq: relation \"missing\" does not exist"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(auth, "sentry_key=abc123") || contentType != "application/x-sentry-envelope" {
+		t.Errorf("unexpected headers %q %q", auth, contentType)
+	}
+	if len(lines) != 3 || lines[1] != `{"type":"event"}` {
+		t.Fatalf("unexpected envelope %q", lines)
+	}
+	var e sentryEvent
+	if err := json.Unmarshal([]byte(lines[2]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Message != "migration 00004_fail.sql failed with SQLSTATE 42P01" || e.Environment != "staging" || e.Tags["database"] != "billing" || e.Tags["sqlstate"] != "42P01" || e.Extra["statement"] != "SELECT * FROM missing;" || len(e.EventID) != 32 {
+		t.Errorf("unexpected event %s", lines[2])
+	}
+
+	bad, _ := NewSentryReporter(strings.Replace(server.URL, "://", "://abc123@", 1) + "/7")
+	if err := bad.ReportError(MigrationFailure{Source: "00004_fail.sql", Err: errors.New("failed")}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected the rejected event to fail, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Requirement is a migration of another namespace, or migration set,
+// required by a migration.
+type Requirement struct {
+	Namespace string
+	Version   int64
+}
+
+func (r Requirement) String() string {
+	return fmt.Sprintf("%s:%d", r.Namespace, r.Version)
+}
+
+// Requires returns the migrations required by the migration, declared with
+// '+goose Requires NAMESPACE:VERSION...' annotations:
+//
+//	-- +goose Requires auth:00012 billing:00003
+func (m *Migration) Requires() ([]Requirement, error) {
+	if m.Meta["requires"] == "" {
+		return nil, nil
+	}
+	var requirements []Requirement
+	for _, field := range strings.Split(m.Meta["requires"], ",") {
+		i := strings.LastIndex(field, ":")
+		if i <= 0 {
+			return nil, errors.Errorf("%v: invalid requirement %q, must be of form NAMESPACE:VERSION", filepath.Base(m.Source), field)
+		}
+		version, err := strconv.ParseInt(field[i+1:], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, errors.Errorf("%v: invalid version in requirement %q", filepath.Base(m.Source), field)
+		}
+		requirements = append(requirements, Requirement{Namespace: field[:i], Version: version})
+	}
+	return requirements, nil
+}
+
+// namespaceTables maps the namespaces migrated together by upNamespaces to
+// their version table.
+var namespaceTables map[string]string
+
+// requirementApplied reports whether the migration r is applied, in the
+// version table of its namespace.
+func requirementApplied(db *sql.DB, r Requirement) (bool, error) {
+	table := Namespace{Name: r.Namespace}.TableName()
+	if namespaceTables != nil {
+		var ok bool
+		if table, ok = namespaceTables[r.Namespace]; !ok {
+			return false, errors.Errorf("requirement %s: unknown namespace %q", r, r.Namespace)
+		}
+	}
+
+	var applied bool
+	err := withOptionsLocked([]OptionsFunc{WithTableName(table)}, func() error {
+		if _, err := EnsureDBVersion(db); err != nil {
+			return errors.Wrap(err, "failed to ensure DB version")
+		}
+		records, err := NewStore(db).ListApplied(context.Background(), ListFilter{FromVersion: r.Version, ToVersion: r.Version})
+		if err != nil {
+			return err
+		}
+		applied = len(records) > 0 && records[0].IsApplied
+		return nil
+	})
+	return applied, err
+}
+
+// unmetRequirement returns the first requirement of m that is not applied,
+// or nil.
+func unmetRequirement(db *sql.DB, m *Migration) (*Requirement, error) {
+	requirements, err := m.Requires()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range requirements {
+		applied, err := requirementApplied(db, r)
+		if err != nil {
+			return nil, err
+		}
+		if !applied {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkRequires returns an error if a migration required by m is not
+// applied.
+func checkRequires(db *sql.DB, m *Migration) error {
+	r, err := unmetRequirement(db, m)
+	if err != nil {
+		return err
+	}
+	if r != nil {
+		return errors.Errorf("ERROR %v: requires %s, which is not applied", filepath.Base(m.Source), r)
+	}
+	return nil
+}
+
+// upNamespaces applies the migrations of namespaces, in order, holding back
+// the migrations requiring migrations of later namespaces until those are
+// applied. It returns an error if requirements can't be met, when they are
+// circular or required migrations don't exist. The caller holds optionsMu.
+func upNamespaces(db *sql.DB, namespaces []Namespace) error {
+	namespaceTables = map[string]string{}
+	for _, n := range namespaces {
+		namespaceTables[n.Name] = n.TableName()
+	}
+	defer func() { namespaceTables = nil }()
+
+	done := make([]bool, len(namespaces))
+	for {
+		var (
+			progress bool
+			blocked  string
+		)
+		for i, n := range namespaces {
+			if done[i] {
+				continue
+			}
+			err := withOptionsLocked([]OptionsFunc{WithTableName(n.TableName())}, func() error {
+				pending, err := pendingMigrations(db, n.Dir)
+				if err != nil {
+					return err
+				}
+				for k, m := range pending {
+					r, err := unmetRequirement(db, m)
+					if err != nil {
+						return err
+					}
+					if r == nil {
+						continue
+					}
+					if blocked == "" {
+						blocked = fmt.Sprintf("%v requires %s", filepath.Base(m.Source), r)
+					}
+					if k == 0 {
+						return nil
+					}
+					progress = true
+					return upToLocked(db, n.Dir, pending[k-1].Version)
+				}
+				done[i] = true
+				progress = progress || len(pending) > 0
+				return upLocked(db, n.Dir)
+			})
+			if err != nil {
+				return errors.Wrapf(err, "%s", n)
+			}
+		}
+
+		if !progress {
+			for _, d := range done {
+				if !d {
+					return errors.Errorf("unmet requirements: %s, which can't be applied first", blocked)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// pendingMigrations returns the migrations of dir that up would apply, in
+// order.
+func pendingMigrations(db *sql.DB, dir string) (Migrations, error) {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return nil, err
+	}
+	current, err := EnsureDBVersion(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to ensure DB version")
+	}
+	versions, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int64]bool{}
+	for _, v := range versions {
+		applied[v] = true
+	}
+
+	var pending Migrations
+	for _, m := range migrations {
+		if !m.Gated() && !applied[m.Version] && (allowMissing || m.Version > current) {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
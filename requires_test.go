@@ -0,0 +1,85 @@
+package goose
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequires(t *testing.T) {
+	m := &Migration{Source: "00002_add_owner.sql", Meta: map[string]string{"requires": "auth:00012,billing:3"}}
+	requirements, err := m.Requires()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Requirement{{"auth", 12}, {"billing", 3}}
+	if !reflect.DeepEqual(requirements, want) {
+		t.Errorf("expected %v, got %v", want, requirements)
+	}
+
+	for _, requires := range []string{"auth", ":12", "auth:x", "auth:0"} {
+		m.Meta["requires"] = requires
+		if _, err := m.Requires(); err == nil {
+			t.Errorf("%q: expected an error", requires)
+		}
+	}
+}
+
+func TestUpNamespacesRequires(t *testing.T) {
+	core := map[string]string{}
+	for name, sql := range testMigrations {
+		core[name] = sql
+	}
+	core["00002_create_b.sql"] = "-- +goose Requires analytics:00001\n" + core["00002_create_b.sql"]
+	coreDir, cleanupCore := writeTestMigrations(t, core)
+	defer cleanupCore()
+	analyticsDir, cleanupAnalytics := writeTestMigrations(t, map[string]string{
+		"00001_create_events.sql": "-- +goose Up\nCREATE TABLE events (id int);\n-- +goose Down\nDROP TABLE events;\n",
+		"00002_create_views.sql":  "-- +goose Requires core:00003\n-- +goose Up\nCREATE TABLE views (id int);\n-- +goose Down\nDROP TABLE views;\n",
+	})
+	defer cleanupAnalytics()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	// Applying core alone stops at the migration requiring analytics.
+	err := Up(db, coreDir, WithTableName("goose_db_version_core"))
+	if err == nil || !strings.Contains(err.Error(), "requires analytics:1, which is not applied") {
+		t.Errorf("expected an unmet requirement error, got %v", err)
+	}
+
+	defer SetLogger(log)
+	logger := &bufferLogger{}
+	SetLogger(logger)
+	namespaces := []Namespace{{"core", coreDir}, {"analytics", analyticsDir}}
+	if err := RunNamespaces("up", db, namespaces); err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, line := range strings.Split(logger.String(), "\n") {
+		if strings.HasPrefix(line, "OK") {
+			order = append(order, strings.Fields(line)[1])
+		}
+	}
+	want := []string{"00001_create_events.sql", "00002_create_b.sql", "00003_create_c.sql", "00002_create_views.sql"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("expected migrations applied in order %v, got %v", want, order)
+	}
+}
+
+func TestUpNamespacesCircularRequires(t *testing.T) {
+	aDir, cleanupA := writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql": "-- +goose Requires b:00001\n-- +goose Up\nCREATE TABLE a (id int);\n",
+	})
+	defer cleanupA()
+	bDir, cleanupB := writeTestMigrations(t, map[string]string{
+		"00001_create_b.sql": "-- +goose Requires a:00001\n-- +goose Up\nCREATE TABLE b (id int);\n",
+	})
+	defer cleanupB()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	err := RunNamespaces("up", db, []Namespace{{"a", aDir}, {"b", bDir}})
+	if err == nil || !strings.Contains(err.Error(), "unmet requirements") {
+		t.Errorf("expected an unmet requirements error, got %v", err)
+	}
+}
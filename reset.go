@@ -1,6 +1,7 @@
 package goose
 
 import (
+	"context"
 	"database/sql"
 	"sort"
 
@@ -8,21 +9,43 @@ import (
 )
 
 // Reset rolls back all migrations
-func Reset(db *sql.DB, dir string) error {
-	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+func Reset(db *sql.DB, dir string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return resetLocked(db, dir)
+	})
+}
+
+// resetLocked is Reset for the callers holding optionsMu.
+func resetLocked(db *sql.DB, dir string) error {
+	return invoke("reset", db, func() error {
+		return reset(db, dir)
+	})
+}
+
+func reset(db *sql.DB, dir string) error {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
 	if err != nil {
 		return errors.Wrap(err, "failed to collect migrations")
 	}
-	statuses, err := dbMigrationsStatus(db)
-	if err != nil {
-		return errors.Wrap(err, "failed to get status of migrations")
+	statuses := map[int64]bool{}
+	if !noVersioning {
+		if statuses, err = dbMigrationsStatus(db); err != nil {
+			return errors.Wrap(err, "failed to get status of migrations")
+		}
 	}
 	sort.Sort(sort.Reverse(migrations))
 
+	applied := make(Migrations, 0, len(migrations))
 	for _, migration := range migrations {
-		if !statuses[migration.Version] {
-			continue
+		if noVersioning || statuses[migration.Version] {
+			applied = append(applied, migration)
 		}
+	}
+	if err := checkIrreversibleDownTo(applied, maxVersion, 0, true); err != nil {
+		return err
+	}
+
+	for _, migration := range applied {
 		if err = migration.Down(db); err != nil {
 			return errors.Wrap(err, "failed to db-down")
 		}
@@ -32,29 +55,16 @@ func Reset(db *sql.DB, dir string) error {
 }
 
 func dbMigrationsStatus(db *sql.DB) (map[int64]bool, error) {
-	rows, err := GetDialect().dbVersionQuery(db)
+	// The most recent record for each migration specifies
+	// whether it has been applied or rolled back.
+	records, err := NewStore(db).ListApplied(context.Background(), ListFilter{})
 	if err != nil {
 		return map[int64]bool{}, nil
 	}
-	defer rows.Close()
-
-	// The most recent record for each migration specifies
-	// whether it has been applied or rolled back.
-
-	result := make(map[int64]bool)
 
-	for rows.Next() {
-		var row MigrationRecord
-		if err = rows.Scan(&row.VersionID, &row.IsApplied); err != nil {
-			return nil, errors.Wrap(err, "failed to scan row")
-		}
-
-		if _, ok := result[row.VersionID]; ok {
-			continue
-		}
-
-		result[row.VersionID] = row.IsApplied
+	result := make(map[int64]bool, len(records))
+	for _, rec := range records {
+		result[rec.VersionID] = rec.IsApplied
 	}
-
 	return result, nil
 }
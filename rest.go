@@ -0,0 +1,47 @@
+package goose
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// doREST sends a request to the REST API at baseURL, and returns the body of
+// its response. It returns an error for the responses with another status
+// than 2xx. The credentials of baseURL are sent with basic authentication.
+func doREST(ctx context.Context, client *http.Client, baseURL, method, path, contentType string, body []byte) ([]byte, int, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(path, "/"))
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "invalid URL")
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req = req.WithContext(ctx)
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, errors.Wrap(err, "failed to read response")
+	}
+	if resp.StatusCode/100 != 2 {
+		return data, resp.StatusCode, errors.Errorf("%s %s: %s: %s", method, u.Path, resp.Status, bytes.TrimSpace(data))
+	}
+	return data, resp.StatusCode, nil
+}
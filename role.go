@@ -0,0 +1,46 @@
+package goose
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var role string
+
+// SetRole sets the database role migrations run as, separate from the user
+// goose connects with: SET ROLE on Postgres and Redshift, EXECUTE AS USER on
+// SQL Server. The role is switched back before the version table is
+// updated, so that the connecting user keeps owning it.
+func SetRole(r string) {
+	role = r
+}
+
+// roleSQL returns the statements switching to and back from role, in the
+// current dialect.
+func roleSQL(role string) (set, reset string, err error) {
+	switch GetDialect().(type) {
+	case *PostgresDialect, *RedshiftDialect:
+		return `SET ROLE "` + strings.Replace(role, `"`, `""`, -1) + `"`, "RESET ROLE", nil
+	case *SqlServerDialect:
+		return "EXECUTE AS USER = '" + strings.Replace(role, "'", "''", -1) + "'", "REVERT", nil
+	default:
+		return "", "", errors.Errorf("running migrations as role %q is not supported by this dialect", role)
+	}
+}
+
+// resetSession switches back from the role set by setupSession.
+func resetSession(qe QueryExecer) error {
+	if role == "" {
+		return nil
+	}
+	_, reset, err := roleSQL(role)
+	if err != nil {
+		return err
+	}
+	verboseInfo("Resetting role: %s", reset)
+	if _, err := qe.Exec(reset); err != nil {
+		return errors.Wrapf(err, "failed to reset role %q", role)
+	}
+	return nil
+}
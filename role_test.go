@@ -0,0 +1,53 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestRoleSQL(t *testing.T) {
+	defer SetDialect("postgres")
+
+	tests := []struct {
+		dialect    string
+		set, reset string
+	}{
+		{"postgres", `SET ROLE "migrator"`, "RESET ROLE"},
+		{"redshift", `SET ROLE "migrator"`, "RESET ROLE"},
+		{"mssql", "EXECUTE AS USER = 'migrator'", "REVERT"},
+	}
+	for _, test := range tests {
+		if err := SetDialect(test.dialect); err != nil {
+			t.Fatal(err)
+		}
+		set, reset, err := roleSQL("migrator")
+		if err != nil {
+			t.Fatalf("%s: %v", test.dialect, err)
+		}
+		if set != test.set || reset != test.reset {
+			t.Errorf("%s: unexpected role statements, got %q and %q, want %q and %q", test.dialect, set, reset, test.set, test.reset)
+		}
+	}
+
+	SetDialect("postgres")
+	if set, _, _ := roleSQL(`a"b`); set != `SET ROLE "a""b"` {
+		t.Errorf("unexpected quoting of role, got %q", set)
+	}
+	SetDialect("mssql")
+	if set, _, _ := roleSQL("a'b"); set != "EXECUTE AS USER = 'a''b'" {
+		t.Errorf("unexpected quoting of role, got %q", set)
+	}
+}
+
+func TestRoleUnsupported(t *testing.T) {
+	defer SetRole("")
+
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	SetRole("migrator")
+	if err := Up(db, dir); err == nil {
+		t.Errorf("expected an error running migrations as a role on sqlite3")
+	}
+}
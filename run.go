@@ -0,0 +1,104 @@
+package goose
+
+import (
+	"database/sql"
+	"path/filepath"
+	"time"
+)
+
+// invocation is a run of one of the commands modifying the database.
+type invocation struct {
+	db       *sql.DB
+	command  string
+	runID    string // recorded with the migrations applied or rolled back
+	started  time.Time
+	versions []int64 // versions applied or rolled back, in order
+	results  []MigrationResult
+	clock    phaseClock
+}
+
+// activeInvocation is the invocation in progress, if any. It is set holding
+// optionsMu, so that it is only ever the one of the caller.
+var activeInvocation *invocation
+
+// invoke runs fn as the invocation of command, holding the lock set with
+// SetLocker, within the connections of SetMaxConns, and recording the
+// migrations it applies or rolls back. The
+// database must be a writable primary, unless disabled with SetPrimaryCheck.
+// The caller holds optionsMu.
+func invoke(command string, db *sql.DB, fn func() error) error {
+	if activeInvocation != nil {
+		// Nested in another invocation.
+		return fn()
+	}
+
+	// Client migrations run without SQL database.
+	if db != nil {
+		defer limitConns(db)()
+	}
+	detectServerVersion(db, true)
+	if primaryCheck && db != nil {
+		if err := CheckPrimary(db); err != nil {
+			return err
+		}
+	}
+	inv := &invocation{db: db, command: command, runID: runID, started: time.Now()}
+	if inv.runID == "" {
+		inv.runID = NewRunID()
+	}
+	activeInvocation = inv
+	defer func() { activeInvocation = nil }()
+	debugStarted(inv)
+	waited := enterPhase(phaseLockWait)
+	err := withLock(func() error {
+		waited()
+		if !noVersioning && db != nil {
+			if err := upgradeVersionTable(db); err != nil {
+				return err
+			}
+		}
+		versionCacheRunning(true)
+		defer versionCacheRunning(false)
+		err := fn()
+		activeInvocation = nil
+		if err != nil && len(inv.versions) > 0 {
+			err = withExitCode(ExitPartialApply, err)
+		}
+		return inv.finish(err)
+	})
+	debugFinished(inv, err)
+	return err
+}
+
+// recordMigration records that the migration with version v was applied or
+// rolled back by the active invocation.
+func recordMigration(v int64) {
+	InvalidateVersionCache()
+	if activeInvocation != nil {
+		activeInvocation.versions = append(activeInvocation.versions, v)
+		debugRecorded(v)
+	}
+}
+
+// recordResult records the outcome of a migration run by the active
+// invocation.
+func recordResult(m *Migration, d time.Duration, err error) {
+	if activeInvocation != nil {
+		activeInvocation.results = append(activeInvocation.results, MigrationResult{Version: m.Version, Source: filepath.Base(m.Source), Duration: d, Err: err})
+	}
+}
+
+// finish reports the outcome of the invocation, and returns the error of
+// the invocation, or the error of the reporting if the invocation succeeded.
+func (inv *invocation) finish(err error) error {
+	logTimings(inv)
+	notify(inv, err)
+	if reportErr := writeAudit(inv, err); reportErr != nil {
+		if err != nil {
+			log.Printf("goose: %v\n", reportErr)
+			return err
+		}
+		return reportErr
+	}
+	return err
+}
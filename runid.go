@@ -0,0 +1,48 @@
+package goose
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+)
+
+var runID string
+
+// SetRunID sets the run ID recorded in the version table with the
+// migrations applied or rolled back, like the ID of a deployment, so that
+// they can be rolled back as a unit. By default, each command modifying the
+// database records a new ID, from NewRunID.
+func SetRunID(id string) {
+	runID = id
+}
+
+// NewRunID returns a new ULID: 26 characters, sorted by creation time.
+func NewRunID() string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(time.Now().UnixNano()/int64(time.Millisecond))<<16)
+	rand.Read(id[6:])
+	return encodeULID(id)
+}
+
+// currentRunID returns the run ID of the active invocation, or the one set
+// with SetRunID outside of commands.
+func currentRunID() string {
+	if activeInvocation != nil {
+		return activeInvocation.runID
+	}
+	return runID
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// encodeULID encodes the 128 bits of a ULID in Crockford's base32.
+func encodeULID(id [16]byte) string {
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	b := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		b[i] = crockfordBase32[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(b)
+}
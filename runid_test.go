@@ -0,0 +1,73 @@
+package goose
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNewRunID(t *testing.T) {
+	first := NewRunID()
+	time.Sleep(2 * time.Millisecond)
+	second := NewRunID()
+	for _, id := range []string{first, second} {
+		if !regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`).MatchString(id) {
+			t.Errorf("invalid ULID %q", id)
+		}
+	}
+	if first >= second {
+		t.Errorf("expected %s to sort before %s", first, second)
+	}
+	if got := encodeULID([16]byte{0x01, 0x8d, 0xf0, 0x00, 0x00, 0x00, 15: 0x1f}); got != "01HQR00000000000000000000Z" {
+		t.Errorf("unexpected encoding %s", got)
+	}
+}
+
+func TestRunID(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	runIDs := func() map[int64]string {
+		rows, err := db.Query("SELECT version_id, run_id FROM goose_db_version WHERE version_id > 0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		ids := map[int64]string{}
+		for rows.Next() {
+			var v int64
+			var id string
+			if err := rows.Scan(&v, &id); err != nil {
+				t.Fatal(err)
+			}
+			ids[v] = id
+		}
+		return ids
+	}
+
+	if err := UpTo(db, dir, 2); err != nil {
+		t.Fatal(err)
+	}
+	ids := runIDs()
+	if len(ids[1]) != 26 || ids[1] != ids[2] {
+		t.Errorf("expected the migrations of a run to share a run ID, got %v", ids)
+	}
+
+	SetRunID("deploy-42")
+	defer SetRunID("")
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if ids := runIDs(); ids[3] != "deploy-42" || ids[2] == "deploy-42" {
+		t.Errorf("expected the run ID set with SetRunID, got %v", ids)
+	}
+	rows, err := migrationStatuses(db, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[2].RunID != "deploy-42" || rows[0].RunID != ids[1] {
+		t.Errorf("expected the status to show the run IDs, got %+v", rows)
+	}
+}
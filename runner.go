@@ -0,0 +1,218 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAborted marks the errors of the commands aborted with Runner.Abort,
+// see IsAborted.
+var ErrAborted = errors.New("goose: aborted")
+
+// abortedError is the error of an aborted command, marked with ErrAborted
+// and caused by the error the abort produced.
+type abortedError struct {
+	err error
+}
+
+func (e *abortedError) Error() string { return ErrAborted.Error() + ": " + e.err.Error() }
+
+func (e *abortedError) Cause() error { return e.err }
+
+func (e *abortedError) Unwrap() error { return e.err }
+
+// Is reports whether target is ErrAborted, for errors.Is.
+func (e *abortedError) Is(target error) bool { return target == ErrAborted }
+
+// IsAborted reports whether err is the error of a command aborted with
+// Runner.Abort. The error keeps its cause, like the canceled statement,
+// and its exit code.
+func IsAborted(err error) bool {
+	for err != nil {
+		if _, ok := err.(*abortedError); ok || err == ErrAborted {
+			return true
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
+
+// runCtx and running are set holding optionsMu, for the span of the
+// command of a Runner.
+var (
+	// runCtx is the context of the statements of migrations, canceled
+	// when the running Runner is aborted.
+	runCtx = context.Background()
+	// running is the Runner of the command in progress, if any.
+	running *Runner
+)
+
+// Runner runs the goose commands on a database, and can abort them while
+// they run, for admin tooling to stop a mistakenly started migration.
+// Commands of a Runner run one at a time, like the goose commands.
+type Runner struct {
+	db  *sql.DB
+	dir string
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	backend int64 // connection of the statement in flight, 0 if none
+	aborted bool
+}
+
+// NewRunner returns a Runner of the migrations of dir on db.
+func NewRunner(db *sql.DB, dir string) *Runner {
+	return &Runner{db: db, dir: dir}
+}
+
+// Up applies all available migrations, like Up.
+func (r *Runner) Up(opts ...OptionsFunc) error {
+	return r.run(opts, func() error { return upLocked(r.db, r.dir) })
+}
+
+// UpByOne migrates up by a single version, like UpByOne.
+func (r *Runner) UpByOne(opts ...OptionsFunc) error {
+	return r.run(opts, func() error { return upByOneLocked(r.db, r.dir) })
+}
+
+// UpTo migrates up to version, like UpTo.
+func (r *Runner) UpTo(version int64, opts ...OptionsFunc) error {
+	return r.run(opts, func() error { return upToLocked(r.db, r.dir, version) })
+}
+
+// Down rolls back a single migration, like Down.
+func (r *Runner) Down(opts ...OptionsFunc) error {
+	return r.run(opts, func() error { return downLocked(r.db, r.dir) })
+}
+
+// DownTo rolls back migrations to version, like DownTo.
+func (r *Runner) DownTo(version int64, opts ...OptionsFunc) error {
+	return r.run(opts, func() error { return downToLocked(r.db, r.dir, version) })
+}
+
+// Abort aborts the command in progress, if any: it cancels the statement
+// in flight, with pg_cancel_backend on Postgres and KILL QUERY on MySQL and
+// TiDB on the connection running it, and the command stops before the next
+// statement. The migration in
+// progress is rolled back if it runs in a transaction, and isn't recorded
+// as applied. The command returns an error marked with ErrAborted, see
+// IsAborted.
+func (r *Runner) Abort() error {
+	r.mu.Lock()
+	cancel, backend := r.cancel, r.backend
+	if cancel != nil {
+		r.aborted = true
+	}
+	r.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+
+	var err error
+	if backend != 0 {
+		err = cancelBackend(r.db, backend)
+	}
+	cancel()
+	return err
+}
+
+// run runs fn, a command of the variants holding optionsMu, with the
+// settings of opts in effect.
+func (r *Runner) run(opts []OptionsFunc, fn func() error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.mu.Unlock()
+		cancel()
+		return errors.New("a command of the runner is already in progress")
+	}
+	r.cancel, r.aborted = cancel, false
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.cancel, r.backend = nil, 0
+		r.mu.Unlock()
+		cancel()
+	}()
+
+	err := withOptions(opts, func() error {
+		runCtx, running = ctx, r
+		defer func() { runCtx, running = context.Background(), nil }()
+		return fn()
+	})
+	r.mu.Lock()
+	aborted := r.aborted
+	r.mu.Unlock()
+	if err != nil && aborted {
+		return &abortedError{err: err}
+	}
+	return err
+}
+
+// inFlight records the connection of qe as the one running the statement
+// in flight of the running Runner, until done is called.
+func inFlight(qe QueryExecer) (done func()) {
+	r := running
+	if r == nil {
+		return func() {}
+	}
+	backend, err := backendID(qe)
+	if err != nil {
+		verboseInfo("Failed to get the connection of the statement: %v", err)
+	}
+	r.mu.Lock()
+	r.backend = backend
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		r.backend = 0
+		r.mu.Unlock()
+	}
+}
+
+// backendID returns the ID of the connection of qe, a transaction or a
+// dedicated connection, on the databases whose statements cancelBackend
+// cancels. It returns 0 on others.
+func backendID(qe QueryExecer) (int64, error) {
+	var query string
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		query = "SELECT pg_backend_pid()"
+	case *MySQLDialect, *MariaDBDialect, *TiDBDialect:
+		query = "SELECT CONNECTION_ID()"
+	default:
+		return 0, nil
+	}
+	var id int64
+	err := qe.QueryRow(query).Scan(&id)
+	return id, err
+}
+
+// cancelBackend cancels the statement running on the connection with ID
+// backend, from another connection of db, on the databases supporting it.
+// On others, canceling the context of the statement is left to the driver.
+func cancelBackend(db *sql.DB, backend int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var err error
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		_, err = db.ExecContext(ctx, "SELECT pg_cancel_backend($1)", backend)
+	case *MySQLDialect, *MariaDBDialect:
+		_, err = db.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", backend))
+	case *TiDBDialect:
+		_, err = db.ExecContext(ctx, fmt.Sprintf("KILL TIDB QUERY %d", backend))
+	}
+	return errors.Wrap(err, "failed to cancel statement")
+}
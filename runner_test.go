@@ -0,0 +1,48 @@
+package goose
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRunnerAbort(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_a.sql":    "-- +goose Up\nCREATE TABLE a (id int);\n",
+		"00002_slow.sql": "-- +goose Up\nCREATE TABLE slow AS WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000) SELECT x FROM c;\n",
+		"00003_c.sql":    "-- +goose Up\nCREATE TABLE c (id int);\n",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	r := NewRunner(db, dir)
+	if err := r.Abort(); err != nil {
+		t.Errorf("unexpected error aborting an idle runner: %v", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- r.Up() }()
+	time.Sleep(500 * time.Millisecond)
+	if err := r.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if !IsAborted(err) || errors.Cause(err) == ErrAborted {
+			t.Errorf("expected the command to be aborted, keeping its cause, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the command to stop once aborted")
+	}
+
+	if current, err := GetDBVersion(db); err != nil || current != 1 {
+		t.Errorf("expected the aborted migration not to be recorded, got version %d (%v)", current, err)
+	}
+
+	// The runner is reusable once aborted.
+	if err := r.UpTo(1); err != nil {
+		t.Errorf("unexpected error running a command after an abort: %v", err)
+	}
+}
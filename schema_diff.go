@@ -0,0 +1,304 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/loderunner/goose/version"
+	"github.com/pkg/errors"
+)
+
+// schemaColumn is a column of a table, as read from the database.
+type schemaColumn struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Default sql.NullString
+}
+
+// schemaTable is a table, with its columns in order.
+type schemaTable struct {
+	Name    string
+	Columns []schemaColumn
+}
+
+func (t *schemaTable) column(name string) *schemaColumn {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// ScaffoldSchemaDiff compares the tables and columns of db with the ones
+// produced by applying all the migrations of dir to scratch, an empty
+// database of the same server, and writes a new SQL migration to dir with
+// the DDL reconciling the drift: applied after the other migrations, it
+// reproduces the schema of db. It returns the path of the migration, or ""
+// if there is no drift. Indexes and constraints are not compared.
+//
+// It supports Postgres, MySQL and SQLite.
+func ScaffoldSchemaDiff(db, scratch *sql.DB, dir, name string) (string, error) {
+	if schemaColumnsQuery() == "" {
+		return "", errors.New("schema diff is not supported by this dialect")
+	}
+
+	tables, err := readSchema(scratch)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the schema of the scratch database")
+	}
+	if len(tables) > 0 {
+		return "", errors.New("the scratch database must be empty")
+	}
+	// Bypass invoke: the lock, audit and notifications are the live
+	// database's.
+	if err := upTo(scratch, dir, maxVersion); err != nil {
+		return "", errors.Wrap(err, "failed to apply migrations to the scratch database")
+	}
+
+	migrated, err := readSchema(scratch)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the schema of the scratch database")
+	}
+	live, err := readSchema(db)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the schema of the database")
+	}
+
+	up, down := diffSchemas(migrated, live)
+	if len(up) == 0 {
+		log.Println("goose: no drift from the migrations")
+		return "", nil
+	}
+
+	if name == "" {
+		name = "reconcile_drift"
+	}
+	header := "-- Scaffolded by goose diff: the drift of the database from the schema\n" +
+		"-- produced by the migrations. Review before applying: indexes and\n" +
+		"-- constraints are not compared.\n"
+	return writeSchemaMigration(dir, name, header, up, down)
+}
+
+// writeSchemaMigration writes a new SQL migration to dir, with the up and
+// down statements of a schema difference.
+func writeSchemaMigration(dir, name, header string, up, down []string) (string, error) {
+	path := filepath.Join(dir, fmt.Sprintf("%v_%v.sql", version.Format(version.FromTime(time.Now()), version.Timestamp), snakeCase(name)))
+	content := header +
+		"-- +goose Up\n" + strings.Join(up, "\n") + "\n\n" +
+		"-- +goose Down\n" + strings.Join(down, "\n") + "\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", errors.Wrap(err, "failed to create migration file")
+	}
+
+	log.Printf("Created new file: %s\n", path)
+	return path, nil
+}
+
+// schemaColumnsQuery returns a query of the table, name, type, NOT NULL
+// and default of the columns of the tables of the current schema, in the
+// current dialect, or "" if not supported.
+func schemaColumnsQuery() string {
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		return `SELECT table_name, column_name,
+                CASE WHEN data_type = 'USER-DEFINED' OR data_type = 'ARRAY' THEN udt_name
+                    WHEN character_maximum_length IS NOT NULL THEN data_type || '(' || character_maximum_length || ')'
+                    ELSE data_type END,
+                is_nullable = 'NO', column_default
+            FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name IN (
+                SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE')
+            ORDER BY table_name, ordinal_position`
+	case *MySQLDialect:
+		return `SELECT table_name, column_name, column_type, is_nullable = 'NO', column_default
+            FROM information_schema.columns
+            WHERE table_schema = DATABASE() AND table_name IN (
+                SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE')
+            ORDER BY table_name, ordinal_position`
+	case *MariaDBDialect:
+		// MariaDB reports the absence of default as the NULL literal, and
+		// sequences as tables of type SEQUENCE.
+		return `SELECT table_name, column_name, column_type, is_nullable = 'NO', NULLIF(column_default, 'NULL')
+            FROM information_schema.columns
+            WHERE table_schema = DATABASE() AND table_name IN (
+                SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE')
+            ORDER BY table_name, ordinal_position`
+	case *Sqlite3Dialect, *LibSQLDialect:
+		return `SELECT m.name, p.name, p.type, p."notnull", p.dflt_value
+            FROM sqlite_master m, pragma_table_info(m.name) p
+            WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+            ORDER BY m.name, p.cid`
+	default:
+		return ""
+	}
+}
+
+// readSchema reads the tables of db, except the tables of goose.
+func readSchema(db QueryExecer) (map[string]*schemaTable, error) {
+	rows, err := db.Query(schemaColumnsQuery())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := map[string]*schemaTable{}
+	for rows.Next() {
+		var (
+			table string
+			c     schemaColumn
+		)
+		if err := rows.Scan(&table, &c.Name, &c.Type, &c.NotNull, &c.Default); err != nil {
+			return nil, errors.Wrap(err, "failed to scan row")
+		}
+		if table == TableName() || strings.HasPrefix(table, TableName()+"_") {
+			continue
+		}
+		t, ok := tables[table]
+		if !ok {
+			t = &schemaTable{Name: table}
+			tables[table] = t
+		}
+		t.Columns = append(t.Columns, c)
+	}
+	return tables, rows.Err()
+}
+
+// diffSchemas returns the statements turning the from schema into the to
+// schema, and back.
+func diffSchemas(from, to map[string]*schemaTable) (up, down []string) {
+	for _, name := range sortedTableNames(to) {
+		t := to[name]
+		f, ok := from[name]
+		if !ok {
+			up = append(up, createTableSQL(t))
+			down = append(down, dropTableSQL(name))
+			continue
+		}
+		for _, c := range t.Columns {
+			fc := f.column(c.Name)
+			if fc == nil {
+				up = append(up, addColumnSQL(name, c))
+				down = append(down, dropColumnSQL(name, c.Name))
+			} else if *fc != c {
+				up = append(up, alterColumnSQL(name, *fc, c)...)
+				down = append(down, alterColumnSQL(name, c, *fc)...)
+			}
+		}
+		for _, fc := range f.Columns {
+			if t.column(fc.Name) == nil {
+				up = append(up, dropColumnSQL(name, fc.Name))
+				down = append(down, addColumnSQL(name, fc))
+			}
+		}
+	}
+	for _, name := range sortedTableNames(from) {
+		if _, ok := to[name]; !ok {
+			up = append(up, dropTableSQL(name))
+			down = append(down, createTableSQL(from[name]))
+		}
+	}
+
+	// Undo in reverse order.
+	for i, j := 0, len(down)-1; i < j; i, j = i+1, j-1 {
+		down[i], down[j] = down[j], down[i]
+	}
+	return up, down
+}
+
+func sortedTableNames(tables map[string]*schemaTable) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func columnDefinition(c schemaColumn) string {
+	def := c.Name + " " + c.Type
+	if c.NotNull {
+		def += " NOT NULL"
+	}
+	if c.Default.Valid {
+		def += " DEFAULT " + c.Default.String
+	}
+	return def
+}
+
+func createTableSQL(t *schemaTable) string {
+	columns := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		columns[i] = "    " + columnDefinition(c)
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n);", t.Name, strings.Join(columns, ",\n"))
+}
+
+func dropTableSQL(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", table)
+}
+
+func addColumnSQL(table string, c schemaColumn) string {
+	if _, ok := GetDialect().(*PostgresDialect); ok {
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s;", table, columnDefinition(c))
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, columnDefinition(c))
+}
+
+func dropColumnSQL(table, column string) string {
+	if _, ok := GetDialect().(*PostgresDialect); ok {
+		return fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s;", table, column)
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, column)
+}
+
+// alterColumnSQL returns the statements changing column from to column to.
+func alterColumnSQL(table string, from, to schemaColumn) []string {
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		var stmts []string
+		if from.Type != to.Type {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s;", table, to.Name, to.Type))
+		}
+		if from.NotNull != to.NotNull {
+			action := "DROP NOT NULL"
+			if to.NotNull {
+				action = "SET NOT NULL"
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table, to.Name, action))
+		}
+		if from.Default != to.Default {
+			action := "DROP DEFAULT"
+			if to.Default.Valid {
+				action = "SET DEFAULT " + to.Default.String
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table, to.Name, action))
+		}
+		return stmts
+	case *MySQLDialect, *MariaDBDialect:
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", table, columnDefinition(to))}
+	default:
+		return []string{fmt.Sprintf("-- %s.%s changed from %q to %q: rebuild the table, the column can't be altered.", table, to.Name, columnDefinition(from), columnDefinition(to))}
+	}
+}
+
+// openLike opens dbstring with the driver of db.
+func openLike(db *sql.DB, dbstring string) *sql.DB {
+	return sql.OpenDB(dsnConnector{dsn: dbstring, drv: db.Driver()})
+}
+
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
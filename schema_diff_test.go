@@ -0,0 +1,93 @@
+package goose
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScaffoldSchemaDiff(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	scratchDir, err := ioutil.TempDir("", "goose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(scratchDir)
+	scratch := openLike(db, filepath.Join(scratchDir, "scratch.db"))
+	defer scratch.Close()
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"ALTER TABLE a ADD COLUMN name text NOT NULL DEFAULT ''",
+		"CREATE TABLE d (id int NOT NULL)",
+		"DROP TABLE c",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, err := ScaffoldSchemaDiff(db, scratch, dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, "_reconcile_drift.sql") {
+		t.Fatalf("unexpected migration path %q", path)
+	}
+
+	// Applied to the scratch database, the new migration reproduces the
+	// schema of the database.
+	if err := Up(scratch, dir); err != nil {
+		t.Fatal(err)
+	}
+	want, err := readSchema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readSchema(scratch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected schema after applying the scaffolded migration, got %+v, want %+v", got, want)
+	}
+
+	if _, err := ScaffoldSchemaDiff(db, scratch, dir, ""); err == nil {
+		t.Errorf("expected an error with a scratch database that isn't empty")
+	}
+}
+
+func TestScaffoldSchemaDiffNoDrift(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	scratch, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer scratch.Close()
+	scratch.SetMaxOpenConns(1)
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	path, err := ScaffoldSchemaDiff(db, scratch, dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "" {
+		t.Errorf("unexpected migration %q without drift", path)
+	}
+}
@@ -0,0 +1,54 @@
+package goose
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.EscapedPath()+" "+string(body)))
+		if r.URL.Path == "/subjects/invalid-value/versions" {
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+	defer server.Close()
+	registry := &SchemaRegistry{URL: server.URL}
+
+	up := schemaMigrationFunc(registry, []SchemaChange{{
+		Subject:       "orders/created-value",
+		SchemaType:    "PROTOBUF",
+		Schema:        `syntax = "proto3"; message OrderCreated { string id = 1; }`,
+		Compatibility: "BACKWARD",
+	}})
+	if err := up(nil); err != nil {
+		t.Fatal(err)
+	}
+	down := schemaMigrationFunc(registry, []SchemaChange{{Subject: "orders/created-value", DeleteLatest: true}})
+	if err := down(nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`PUT /config/orders%2Fcreated-value {"compatibility":"BACKWARD"}`,
+		`POST /subjects/orders%2Fcreated-value/versions {"schema":"syntax = \"proto3\"; message OrderCreated { string id = 1; }","schemaType":"PROTOBUF"}`,
+		`DELETE /subjects/orders%2Fcreated-value/versions/latest`,
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("expected %q, got %q", want, requests)
+	}
+
+	err := registry.Apply(context.Background(), []SchemaChange{{Subject: "invalid-value", Schema: `"string"`}})
+	if err == nil || !strings.Contains(err.Error(), "409 Conflict") {
+		t.Errorf("expected the incompatible schema to be rejected, got %v", err)
+	}
+	if schemaMigrationFunc(registry, nil) != nil {
+		t.Error("expected a migration without changes to be empty")
+	}
+}
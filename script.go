@@ -0,0 +1,143 @@
+package goose
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Script writes a SQL script applying the migrations of dir after version
+// from, up to version to, in the current dialect, without connecting to
+// the database: for environments where changes are reviewed and applied by
+// hand. Each migration is followed by the statement recording it in the
+// version table, in the same transaction unless the migration is annotated
+// with '-- +goose NO TRANSACTION'. When from is 0, the script starts by
+// creating the version table.
+//
+// Go migrations and migrations declaring parameters can't be written as a
+// script.
+func Script(w io.Writer, dir string, from, to int64) error {
+	migrations, err := collectMigrations(dir, from, to, options{})
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- goose script: migrations %d to %d of %s\n", from, to, dir)
+	if from == 0 {
+		b.WriteString("\n-- Create the version table.\n")
+		b.WriteString(strings.TrimSpace(GetDialect().createVersionTableSQL()) + ";\n")
+		if q := versionIndexSQL(); q != "" {
+			b.WriteString(q + ";\n")
+		}
+		b.WriteString(insertVersionScript(0, "") + "\n")
+	}
+
+	for _, m := range migrations {
+		if err := writeMigrationScript(&b, m); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func writeMigrationScript(b *strings.Builder, m *Migration) error {
+	if filepath.Ext(m.Source) != ".sql" {
+		return errors.Errorf("%v: Go migrations can't be written as a SQL script", filepath.Base(m.Source))
+	}
+	f, err := os.Open(m.Source)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open SQL migration file %v", filepath.Base(m.Source))
+	}
+	defer f.Close()
+
+	statements, a, err := parseSQLMigration(f, true)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse SQL migration file %v", filepath.Base(m.Source))
+	}
+	if len(a.params) > 0 {
+		return errors.Errorf("%v: migrations declaring parameters can't be written as a SQL script", filepath.Base(m.Source))
+	}
+	checksum, err := m.Checksum()
+	if err != nil {
+		return err
+	}
+
+	begin, commit := transactionScript()
+	useTx := a.useTx && begin != ""
+
+	fmt.Fprintf(b, "\n-- %s\n", filepath.Base(m.Source))
+	if useTx {
+		b.WriteString(begin + "\n")
+	}
+	for _, query := range statements {
+		if isAssertion(query) {
+			return errors.Errorf("%v: migrations with assertions can't be written as a SQL script", filepath.Base(m.Source))
+		}
+		b.WriteString(strings.TrimSpace(query) + "\n")
+	}
+	b.WriteString(insertVersionScript(m.Version, checksum) + "\n")
+	if useTx {
+		b.WriteString(commit + "\n")
+	}
+	return nil
+}
+
+// transactionScript returns the statements beginning and committing a
+// transaction in the current dialect, or "" if it has no transactions.
+func transactionScript() (begin, commit string) {
+	switch GetDialect().(type) {
+	case *SqlServerDialect:
+		return "BEGIN TRANSACTION;", "COMMIT;"
+	case *ClickHouseDialect:
+		return "", ""
+	default:
+		return "BEGIN;", "COMMIT;"
+	}
+}
+
+// insertVersionScript returns the statement recording version in the
+// version table, with its values as literals.
+func insertVersionScript(version int64, checksum string) string {
+	query := GetDialect().insertVersionSQL()
+	values := []string{fmt.Sprint(version), boolLiteral(true), stringLiteral(buildInfo()), stringLiteral(checksum), stringLiteral(""), stringLiteral(currentRunID())}
+	// Values are substituted in order, after the previous one, since ? is
+	// the placeholder of every argument in some dialects.
+	start := 0
+	for i, v := range values {
+		p := GetDialect().placeholder(i + 1)
+		j := strings.Index(query[start:], p)
+		if j < 0 {
+			break
+		}
+		j += start
+		query = query[:j] + v + query[j+len(p):]
+		start = j + len(v)
+	}
+	return strings.TrimSuffix(query, ";") + ";"
+}
+
+func boolLiteral(b bool) string {
+	switch GetDialect().(type) {
+	case *PostgresDialect, *RedshiftDialect:
+		if b {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		if b {
+			return "1"
+		}
+		return "0"
+	}
+}
+
+func stringLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
@@ -0,0 +1,61 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	var b strings.Builder
+	if err := Script(&b, dir, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	script := b.String()
+	if strings.Contains(script, "CREATE TABLE c") {
+		t.Errorf("unexpected migration after the target version in script:\n%s", script)
+	}
+
+	if _, err := db.Exec(script); err != nil {
+		t.Fatalf("failed to run script: %v\n%s", err, script)
+	}
+	current, err := GetDBVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != 2 {
+		t.Errorf("unexpected version after running script, got %d, want 2", current)
+	}
+
+	b.Reset()
+	if err := Script(&b, dir, 2, maxVersion); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "CREATE TABLE "+TableName()) {
+		t.Errorf("unexpected version table creation in script from version 2:\n%s", b.String())
+	}
+	if _, err := db.Exec(b.String()); err != nil {
+		t.Fatalf("failed to run script: %v\n%s", err, b.String())
+	}
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if diff, err := GetDiff(db, dir); err != nil || len(diff.Pending) > 0 || len(diff.Drifted) > 0 {
+		t.Errorf("unexpected difference after running scripts: %+v, %v", diff, err)
+	}
+}
+
+func TestScriptParams(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_param.sql": "-- +goose Param name\n-- +goose Up\nINSERT INTO t VALUES (:name);\n",
+	})
+	defer cleanupDir()
+
+	if err := Script(&strings.Builder{}, dir, 0, maxVersion); err == nil {
+		t.Errorf("expected an error for a migration declaring parameters")
+	}
+}
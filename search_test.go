@@ -0,0 +1,99 @@
+package goose
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunSearch(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+		docs     = map[string]json.RawMessage{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/goose_db_version/_search":
+			var hits []map[string]json.RawMessage
+			for _, doc := range docs {
+				hits = append(hits, map[string]json.RawMessage{"_source": doc})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"hits": map[string]interface{}{"hits": hits}})
+		case strings.HasPrefix(r.URL.Path, "/goose_db_version/_doc/"):
+			if r.Method == "DELETE" {
+				delete(docs, r.URL.Path)
+			} else {
+				docs[r.URL.Path] = body
+			}
+		default:
+			if r.URL.Path == "/_bulk" && r.Header.Get("Content-Type") != "application/x-ndjson" {
+				t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+			}
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+strings.TrimSpace(string(body)))
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "goose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"00001_users.json": `{
+			"up": [{"method": "PUT", "path": "/users_v1", "body": {"mappings": {"properties": {"name": {"type": "keyword"}}}}}],
+			"down": [{"method": "DELETE", "path": "/users_v1"}]
+		}`,
+		"00002_seed.ndjson": `{"method": "POST", "path": "/_bulk", "body": "{\"index\":{\"_index\":\"users_v1\"}}\n{\"name\":\"alice\"}\n"}
+{"method": "POST", "path": "/users_v1/_delete_by_query", "body": {"query": {"match_all": {}}}, "down": true}
+`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer SetLogger(log)
+	SetLogger(&bufferLogger{})
+	client := &SearchClient{URL: server.URL}
+	if err := RunSearch("up", client, dir); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`PUT /users_v1 {"mappings": {"properties": {"name": {"type": "keyword"}}}}`,
+		"POST /_bulk {\"index\":{\"_index\":\"users_v1\"}}\n{\"name\":\"alice\"}",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("expected %q, got %q", want, requests)
+	}
+	if len(docs) != 2 {
+		t.Errorf("expected 2 version documents, got %v", docs)
+	}
+
+	requests = nil
+	if err := RunSearch("reset", client, dir); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		`POST /users_v1/_delete_by_query {"query": {"match_all": {}}}`,
+		"DELETE /users_v1 ",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("expected %q, got %q", want, requests)
+	}
+	if len(docs) != 0 {
+		t.Errorf("expected no version documents, got %v", docs)
+	}
+}
@@ -0,0 +1,303 @@
+package goose
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SecretResolver resolves the references of dbstrings to the secrets of a
+// secrets manager, like aws-sm://prod/db, so that the dbstrings of CI
+// variables or config files hold no credentials.
+type SecretResolver interface {
+	// Resolve returns the secret ref, the dbstring without its scheme nor
+	// its parameters.
+	Resolve(ctx context.Context, ref string, params url.Values) (string, error)
+}
+
+// secretResolvers are the resolvers of the dbstrings, by scheme.
+var secretResolvers = map[string]SecretResolver{
+	"aws-sm": NewAWSSecretsManager(),
+	"vault":  NewVaultSecrets(),
+	"gcp-sm": NewGCPSecretManager(),
+}
+
+// RegisterSecretResolver sets the resolver of the dbstrings of scheme,
+// replacing the built-in resolvers of aws-sm, vault and gcp-sm if needed.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretResolvers[scheme] = r
+}
+
+// ResolveDBString returns the secret referenced by dbstring, if its scheme
+// is the one of a SecretResolver, or dbstring otherwise. The key parameter
+// of a reference picks a field of a JSON secret:
+//
+//	aws-sm://prod/db?region=eu-west-1&key=dsn
+//	vault://secret/prod/db?key=dsn
+//	gcp-sm://projects/acme/secrets/prod-db/versions/latest
+func ResolveDBString(dbstring string) (string, error) {
+	i := strings.Index(dbstring, "://")
+	if i < 0 {
+		return dbstring, nil
+	}
+	r, ok := secretResolvers[dbstring[:i]]
+	if !ok {
+		return dbstring, nil
+	}
+
+	ref, query := dbstring[i+3:], ""
+	if j := strings.Index(ref, "?"); j >= 0 {
+		ref, query = ref[:j], ref[j+1:]
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid parameters of secret %s", dbstring[:i+3]+ref)
+	}
+	secret, err := r.Resolve(context.Background(), ref, params)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve secret %s", dbstring[:i+3]+ref)
+	}
+	if key := params.Get("key"); key != "" {
+		return secretField(secret, key)
+	}
+	return strings.TrimSpace(secret), nil
+}
+
+// secretField returns the field key of a JSON secret.
+func secretField(secret, key string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", errors.Wrap(err, "secret is not a JSON object")
+	}
+	v, ok := fields[key]
+	if !ok {
+		return "", errors.Errorf("secret has no %s field", key)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.Errorf("field %s of secret is not a string", key)
+	}
+	return s, nil
+}
+
+// AWSSecretsManager resolves the aws-sm:// references to the secrets of
+// AWS Secrets Manager, by name or ARN, with the AWS credentials of the
+// environment variables AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN. The region is the region parameter of the reference,
+// or AWS_REGION.
+type AWSSecretsManager struct {
+	endpoint string // overrides https://secretsmanager.REGION.amazonaws.com
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewAWSSecretsManager creates the resolver of AWS Secrets Manager.
+func NewAWSSecretsManager() *AWSSecretsManager {
+	return &AWSSecretsManager{client: &http.Client{Timeout: 30 * time.Second}, now: time.Now}
+}
+
+// Resolve returns the SecretString of the current version of secret ref.
+func (s *AWSSecretsManager) Resolve(ctx context.Context, ref string, params url.Values) (string, error) {
+	region := params.Get("region")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return "", errors.New("no AWS region, set the region parameter or AWS_REGION")
+	}
+	endpoint := s.endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": ref})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if err := signAWSRequest(req, body, region, "secretsmanager", s.now()); err != nil {
+		return "", err
+	}
+	var value struct {
+		SecretString string
+	}
+	if err := fetchSecret(ctx, s.client, req, &value); err != nil {
+		return "", err
+	}
+	return value.SecretString, nil
+}
+
+// signAWSRequest signs req, with a body, with the Signature Version 4 of
+// AWS in its Authorization header.
+func signAWSRequest(req *http.Request, body []byte, region, service string, now time.Time) error {
+	accessKey, secretKey, err := awsEnvCredentials()
+	if err != nil {
+		return err
+	}
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	// The signed headers, in alphabetical order.
+	var headers []string
+	canonicalHeaders := ""
+	for _, h := range []string{"Content-Type", "Host", "X-Amz-Date", "X-Amz-Security-Token", "X-Amz-Target"} {
+		v := req.Header.Get(h)
+		if h == "Host" {
+			v = req.URL.Host
+		}
+		if v != "" {
+			headers = append(headers, strings.ToLower(h))
+			canonicalHeaders += strings.ToLower(h) + ":" + strings.TrimSpace(v) + "\n"
+		}
+	}
+	signedHeaders := strings.Join(headers, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	bodyHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{req.Method, path, req.URL.RawQuery, canonicalHeaders, signedHeaders, hex.EncodeToString(bodyHash[:])}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", req.Header.Get("X-Amz-Date"), scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := awsSigningKey(secretKey, date, region, service)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(hmacSHA256(key, stringToSign)))
+	return nil
+}
+
+// VaultSecrets resolves the vault:// references to the secrets of a KV
+// secrets engine of HashiCorp Vault, as in vault://MOUNT/PATH, at the
+// address of VAULT_ADDR with the token of VAULT_TOKEN. The secrets of
+// version 2 engines are read from MOUNT/data/PATH, and of version 1 ones
+// with the kv=1 parameter.
+type VaultSecrets struct {
+	client *http.Client
+}
+
+// NewVaultSecrets creates the resolver of Vault.
+func NewVaultSecrets() *VaultSecrets {
+	return &VaultSecrets{client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Resolve returns the data of the secret ref, as a JSON object.
+func (s *VaultSecrets) Resolve(ctx context.Context, ref string, params url.Values) (string, error) {
+	addr, token := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", errors.New("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	path := ref
+	if params.Get("kv") != "1" {
+		parts := strings.SplitN(ref, "/", 2)
+		if len(parts) != 2 {
+			return "", errors.Errorf("secret %q must be of form MOUNT/PATH", ref)
+		}
+		path = parts[0] + "/data/" + parts[1]
+	}
+	req, err := http.NewRequest("GET", addr+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	var secret struct {
+		Data map[string]json.RawMessage
+	}
+	if err := fetchSecret(ctx, s.client, req, &secret); err != nil {
+		return "", err
+	}
+	data := secret.Data
+	if params.Get("kv") != "1" {
+		data = nil
+		if err := json.Unmarshal(secret.Data["data"], &data); err != nil {
+			return "", errors.Wrap(err, "failed to decode secret")
+		}
+	}
+	b, err := json.Marshal(data)
+	return string(b), err
+}
+
+// GCPSecretManager resolves the gcp-sm:// references to the versions of
+// the secrets of Google Cloud Secret Manager, as in
+// gcp-sm://projects/PROJECT/secrets/SECRET/versions/VERSION, with the
+// access token of the service account from the metadata server.
+type GCPSecretManager struct {
+	endpoint string
+	token    *CloudSQLIAMCredentials
+	client   *http.Client
+}
+
+// NewGCPSecretManager creates the resolver of Google Cloud Secret Manager.
+func NewGCPSecretManager() *GCPSecretManager {
+	return &GCPSecretManager{
+		endpoint: "https://secretmanager.googleapis.com",
+		token:    NewCloudSQLIAMCredentials(),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Resolve returns the payload of the secret version ref.
+func (s *GCPSecretManager) Resolve(ctx context.Context, ref string, params url.Values) (string, error) {
+	if !strings.Contains(ref, "/versions/") {
+		ref += "/versions/latest"
+	}
+	token, _, err := s.token.Password(ctx, "", "")
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("GET", s.endpoint+"/v1/"+ref+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var version struct {
+		Payload struct {
+			Data string
+		}
+	}
+	if err := fetchSecret(ctx, s.client, req, &version); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(version.Payload.Data)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode secret")
+	}
+	return string(data), nil
+}
+
+// fetchSecret decodes the JSON response of a secrets manager.
+func fetchSecret(ctx context.Context, client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(v), "failed to decode secret")
+}
@@ -0,0 +1,238 @@
+package goose
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Serve serves the admin API of NewAdminHandler on addr.
+func Serve(db *sql.DB, dir, addr, token string) error {
+	if token == "" {
+		return errors.New("an admin token is required to serve the admin API: set GOOSE_ADMIN_TOKEN")
+	}
+	log.Printf("goose: serving admin API on %s\n", addr)
+	return http.ListenAndServe(addr, NewAdminHandler(db, dir, token))
+}
+
+// NewAdminHandler returns an HTTP handler exposing a small admin API, so
+// that deployment tools can observe and trigger migrations of db:
+//
+//	GET  /status               status of every migration
+//	GET  /history              records of the version table, most recent first
+//	POST /plan                 record a plan of the pending migrations
+//	POST /up-to?version=N      migrate up to version N
+//	POST /down-to?version=N    roll back to version N
+//
+// Requests must be authenticated with an "Authorization: Bearer token"
+// header. Responses are JSON. Requests are served one at a time.
+func NewAdminHandler(db *sql.DB, dir, token string) http.Handler {
+	s := &adminServer{db: db, dir: dir, token: token}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handle("GET", s.status))
+	mux.HandleFunc("/history", s.handle("GET", s.history))
+	mux.HandleFunc("/plan", s.handle("POST", s.plan))
+	mux.HandleFunc("/up-to", s.handle("POST", s.upTo))
+	mux.HandleFunc("/down-to", s.handle("POST", s.downTo))
+	return mux
+}
+
+type adminServer struct {
+	db    *sql.DB
+	dir   string
+	token string
+
+	// mu serializes requests: goose commands rely on global state.
+	mu sync.Mutex
+}
+
+// errBadRequest marks the errors caused by an invalid request.
+type errBadRequest struct {
+	error
+}
+
+// handle authenticates and serializes requests to h, and writes its
+// response or error as JSON.
+func (s *adminServer) handle(method string, h func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		s.mu.Lock()
+		v, err := h(r)
+		s.mu.Unlock()
+
+		if err != nil {
+			status := http.StatusInternalServerError
+			if _, ok := err.(errBadRequest); ok {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+func (s *adminServer) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(s.token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("goose: failed to write admin API response: %v\n", err)
+	}
+}
+
+type adminMigrationStatus struct {
+	Version   int64             `json:"version"`
+	Source    string            `json:"source"`
+	Meta      map[string]string `json:"meta,omitempty"`
+	Applied   bool              `json:"applied"`
+	AppliedAt *time.Time        `json:"applied_at,omitempty"`
+}
+
+type adminStatus struct {
+	Version    int64                  `json:"version"`
+	Migrations []adminMigrationStatus `json:"migrations"`
+}
+
+func (s *adminServer) status(r *http.Request) (interface{}, error) {
+	migrations, err := CollectMigrations(s.dir, minVersion, maxVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to collect migrations")
+	}
+	current, err := GetDBVersion(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	status := adminStatus{Version: current, Migrations: []adminMigrationStatus{}}
+	for _, m := range migrations {
+		row, err := migrationStatus(s.db, m.Version)
+		if err != nil {
+			return nil, err
+		}
+		ms := adminMigrationStatus{Version: m.Version, Source: filepath.Base(m.Source), Meta: m.Meta, Applied: row.IsApplied}
+		if row.IsApplied {
+			ms.AppliedAt = &row.TStamp
+		}
+		status.Migrations = append(status.Migrations, ms)
+	}
+	return status, nil
+}
+
+type adminHistoryRecord struct {
+	Version   int64             `json:"version"`
+	Source    string            `json:"source,omitempty"`
+	Meta      map[string]string `json:"meta,omitempty"`
+	Applied   bool              `json:"applied"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// history returns the records of the version table, most recent first, up
+// to the limit query parameter, 100 by default.
+func (s *adminServer) history(r *http.Request) (interface{}, error) {
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			return nil, errBadRequest{errors.Errorf("limit must be a positive number (got %q)", l)}
+		}
+		limit = n
+	}
+
+	migrations, err := CollectMigrations(s.dir, minVersion, maxVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to collect migrations")
+	}
+	if _, err := EnsureDBVersion(s.db); err != nil {
+		return nil, err
+	}
+	q := fmt.Sprintf("SELECT version_id, is_applied, tstamp FROM %s ORDER BY tstamp DESC", TableName()) + limitClause(limit, 0)
+	rows, err := s.db.Query(q)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query version table")
+	}
+	defer rows.Close()
+
+	history := []adminHistoryRecord{}
+	for rows.Next() {
+		var rec adminHistoryRecord
+		if err := rows.Scan(&rec.Version, &rec.Applied, &rec.Timestamp); err != nil {
+			return nil, errors.Wrap(err, "failed to scan row")
+		}
+		if m, err := migrations.Current(rec.Version); err == nil {
+			rec.Source, rec.Meta = filepath.Base(m.Source), m.Meta
+		}
+		history = append(history, rec)
+	}
+	return history, rows.Err()
+}
+
+func (s *adminServer) plan(r *http.Request) (interface{}, error) {
+	return CreatePlan(s.db, s.dir)
+}
+
+func (s *adminServer) upTo(r *http.Request) (interface{}, error) {
+	version, err := versionParam(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := UpTo(s.db, s.dir, version); err != nil {
+		return nil, err
+	}
+	return s.currentVersion()
+}
+
+func (s *adminServer) downTo(r *http.Request) (interface{}, error) {
+	version, err := versionParam(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := DownTo(s.db, s.dir, version); err != nil {
+		return nil, err
+	}
+	return s.currentVersion()
+}
+
+func (s *adminServer) currentVersion() (interface{}, error) {
+	current, err := GetDBVersion(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int64{"version": current}, nil
+}
+
+func versionParam(r *http.Request) (int64, error) {
+	v := r.URL.Query().Get("version")
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, errBadRequest{errors.Errorf("version must be a number (got %q)", v)}
+	}
+	return version, nil
+}
@@ -0,0 +1,71 @@
+package goose
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	h := NewAdminHandler(db, dir, "secret")
+	do := func(method, target, token string, v interface{}) int {
+		r := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if v != nil && w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+				t.Fatalf("%s %s: %v", method, target, err)
+			}
+		}
+		return w.Code
+	}
+
+	tt := []struct {
+		method, target, token string
+		code                  int
+	}{
+		{"GET", "/status", "", http.StatusUnauthorized},
+		{"GET", "/status", "wrong", http.StatusUnauthorized},
+		{"GET", "/up-to?version=2", "secret", http.StatusMethodNotAllowed},
+		{"POST", "/up-to?version=two", "secret", http.StatusBadRequest},
+		{"POST", "/up-to?version=2", "secret", http.StatusOK},
+	}
+	for i, test := range tt {
+		if code := do(test.method, test.target, test.token, nil); code != test.code {
+			t.Errorf("tt[%v] %s %s: unexpected status, got %v, want %v", i, test.method, test.target, code, test.code)
+		}
+	}
+
+	var status adminStatus
+	if code := do("GET", "/status", "secret", &status); code != http.StatusOK {
+		t.Fatalf("GET /status: unexpected status %v", code)
+	}
+	if status.Version != 2 || len(status.Migrations) != 3 || !status.Migrations[1].Applied || status.Migrations[2].Applied {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	var current map[string]int64
+	if code := do("POST", "/down-to?version=1", "secret", &current); code != http.StatusOK || current["version"] != 1 {
+		t.Errorf("POST /down-to: unexpected response %v %v", code, current)
+	}
+
+	var history []adminHistoryRecord
+	if code := do("GET", "/history", "secret", &history); code != http.StatusOK {
+		t.Fatalf("GET /history: unexpected status %v", code)
+	}
+	if len(history) != 2 {
+		t.Errorf("unexpected history %+v", history)
+	}
+	if code := do("GET", "/history?limit=1", "secret", &history); code != http.StatusOK || len(history) != 1 {
+		t.Errorf("GET /history?limit=1: unexpected response %v %+v", code, history)
+	}
+}
@@ -0,0 +1,102 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// ServerVersion is the version of a MySQL server.
+type ServerVersion struct {
+	Major, Minor, Patch int
+}
+
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether the version is major.minor.patch or later. The
+// zero version, of an unknown server, is older than any release.
+func (v ServerVersion) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+var (
+	serverVersion   ServerVersion
+	serverVersionDB *sql.DB // database serverVersion was queried on
+
+	serverVersionRegexp = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+)
+
+// GetServerVersion returns the version of the MySQL or MariaDB server goose
+// runs on, queried at the start of each command, so that Go migrations can
+// use the features of recent servers, like instant ADD COLUMN from MySQL
+// 8.0.12:
+//
+//	if goose.GetServerVersion().AtLeast(8, 0, 12) {
+//		_, err = qe.Exec("ALTER TABLE users ADD COLUMN nickname varchar(255), ALGORITHM=INSTANT")
+//	}
+//
+// It returns the zero version for the other dialects, or when the version
+// couldn't be queried.
+func GetServerVersion() ServerVersion {
+	return serverVersion
+}
+
+// parseServerVersion parses the output of SELECT VERSION(), like
+// 8.0.36-0ubuntu0.22.04.1, 5.7.44-log or 10.11.6-MariaDB.
+func parseServerVersion(s string) (ServerVersion, error) {
+	m := serverVersionRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return ServerVersion{}, errors.Errorf("invalid server version %q", s)
+	}
+	var v ServerVersion
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, nil
+}
+
+// detectServerVersion queries the version of the MySQL or MariaDB server of
+// db, if refresh is set or it wasn't queried on db yet.
+func detectServerVersion(db *sql.DB, refresh bool) {
+	if db == nil || (!refresh && db == serverVersionDB) {
+		return
+	}
+	ensureDialect(db)
+	serverVersion, serverVersionDB = ServerVersion{}, db
+	if !isMySQL() {
+		return
+	}
+
+	var s string
+	if err := db.QueryRow("SELECT VERSION()").Scan(&s); err != nil {
+		verboseInfo("Failed to query the server version: %v", err)
+		return
+	}
+	v, err := parseServerVersion(s)
+	if err != nil {
+		verboseInfo("%v", err)
+		return
+	}
+	serverVersion = v
+	verboseInfo("Detected server version %s", v)
+}
+
+// isMySQL reports whether the dialect is MySQL or MariaDB.
+func isMySQL() bool {
+	switch GetDialect().(type) {
+	case *MySQLDialect, *MariaDBDialect:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	tt := []struct {
+		version string
+		want    ServerVersion
+	}{
+		{"8.0.36-0ubuntu0.22.04.1", ServerVersion{8, 0, 36}},
+		{"5.7.44-log", ServerVersion{5, 7, 44}},
+		{"8.0.12", ServerVersion{8, 0, 12}},
+		{"10.11.6-MariaDB-1:10.11.6+maria~ubu2204", ServerVersion{10, 11, 6}},
+	}
+	for _, tc := range tt {
+		v, err := parseServerVersion(tc.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.want {
+			t.Errorf("%q: expected %v, got %v", tc.version, tc.want, v)
+		}
+	}
+	if _, err := parseServerVersion("unknown"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestServerVersionAtLeast(t *testing.T) {
+	v := ServerVersion{5, 7, 44}
+	for _, tc := range []struct {
+		major, minor, patch int
+		want                bool
+	}{
+		{5, 7, 44, true},
+		{5, 7, 5, true},
+		{5, 6, 99, true},
+		{5, 7, 45, false},
+		{8, 0, 0, false},
+	} {
+		if got := v.AtLeast(tc.major, tc.minor, tc.patch); got != tc.want {
+			t.Errorf("%v.AtLeast(%d, %d, %d): expected %v", v, tc.major, tc.minor, tc.patch, tc.want)
+		}
+	}
+	if (ServerVersion{}).AtLeast(5, 0, 0) {
+		t.Error("expected the zero version to be older than any release")
+	}
+}
+
+func TestMySQLServerVersionFeatures(t *testing.T) {
+	defer func(v ServerVersion) { serverVersion = v }(serverVersion)
+	d := MySQLDialect{}
+
+	serverVersion = ServerVersion{8, 0, 36}
+	if q := d.addVersionColumnSQL("checksum"); !strings.Contains(q, "ALGORITHM=INSTANT") {
+		t.Errorf("expected an instant ADD COLUMN on 8.0, got %q", q)
+	}
+	serverVersion = ServerVersion{5, 7, 44}
+	if q := d.addVersionColumnSQL("checksum"); !strings.Contains(q, "ALGORITHM=INPLACE") {
+		t.Errorf("expected an in-place ADD COLUMN on 5.7, got %q", q)
+	}
+
+	name := "goose:" + strings.Repeat("x", 64)
+	if hashed := mysqlLockName(name); len(hashed) > 64 {
+		t.Errorf("expected a lock name of at most 64 characters on 5.7, got %q", hashed)
+	}
+	serverVersion = ServerVersion{5, 6, 51}
+	if got := mysqlLockName(name); got != name {
+		t.Errorf("expected the lock name unchanged before 5.7.5, got %q", got)
+	}
+}
+
+func TestMariaDBVersionTable(t *testing.T) {
+	defer func(v ServerVersion) { serverVersion = v }(serverVersion)
+	d := MariaDBDialect{}
+
+	if q := d.createVersionTableSQL(); !strings.Contains(q, "CHECK (is_applied IN (0, 1))") {
+		t.Errorf("expected a check constraint on is_applied, got %q", q)
+	}
+	serverVersion = ServerVersion{10, 6, 12}
+	if q := d.addVersionColumnSQL("checksum"); !strings.Contains(q, "ALGORITHM=INSTANT") {
+		t.Errorf("expected an instant ADD COLUMN on 10.6, got %q", q)
+	}
+	serverVersion = ServerVersion{10, 2, 44}
+	if q := d.addVersionColumnSQL("checksum"); !strings.Contains(q, "ALGORITHM=INPLACE") {
+		t.Errorf("expected an in-place ADD COLUMN on 10.2, got %q", q)
+	}
+}
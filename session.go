@@ -0,0 +1,42 @@
+package goose
+
+import (
+	"github.com/pkg/errors"
+)
+
+var sessionSetup []string
+
+// SetSessionSetup sets statements executed at the start of the transaction
+// of every migration, or of its connection for migrations without
+// transaction, before the statements of the migration. For example, on
+// Postgres:
+//
+//	goose.SetSessionSetup("SET lock_timeout = '5s'", "SET statement_timeout = '10min'")
+//
+// Settings set in a transaction may outlive it on the connection, use SET
+// LOCAL on Postgres to limit them to the migration.
+func SetSessionSetup(statements ...string) {
+	sessionSetup = statements
+}
+
+// setupSession switches to the role set by SetRole, if any, and executes
+// the session setup statements.
+func setupSession(qe QueryExecer) error {
+	if role != "" {
+		set, _, err := roleSQL(role)
+		if err != nil {
+			return err
+		}
+		verboseInfo("Setting role: %s", set)
+		if _, err := qe.Exec(set); err != nil {
+			return errors.Wrapf(err, "failed to set role %q", role)
+		}
+	}
+	for _, query := range sessionSetup {
+		verboseInfo("Executing session setup statement: %s", query)
+		if _, err := qe.Exec(query); err != nil {
+			return errors.Wrapf(err, "failed to execute session setup statement %q", query)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestSessionSetup(t *testing.T) {
+	defer SetSessionSetup()
+
+	migrations := map[string]string{
+		"00001_tx.sql":   "-- +goose Up\nINSERT INTO setup_log VALUES (1);\n",
+		"00002_notx.sql": "-- +goose NO TRANSACTION\n-- +goose Up\nINSERT INTO setup_log VALUES (2);\n",
+	}
+	dir, cleanupDir := writeTestMigrations(t, migrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if _, err := db.Exec("CREATE TABLE setup_log (id int)"); err != nil {
+		t.Fatal(err)
+	}
+	SetSessionSetup("INSERT INTO setup_log VALUES (0)")
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM setup_log WHERE id = 0").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("unexpected number of session setups, got %d, want 2", count)
+	}
+
+	SetSessionSetup("NOT SQL")
+	if err := Down(db, dir); err == nil {
+		t.Errorf("expected an error for an invalid session setup statement")
+	}
+}
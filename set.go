@@ -0,0 +1,96 @@
+// +build go1.16
+
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// migrationSet is a set of SQL migrations shipped by a Go library.
+type migrationSet struct {
+	name string
+	fsys fs.FS
+}
+
+var registeredSets []migrationSet
+
+// RegisterMigrationSet registers the SQL migrations at the root of fsys as
+// the migration set name, so that a Go library can ship the migrations of
+// its tables, applied by the host application with UpAllSets. It is
+// typically called from the init function of the library, with an embedded
+// directory:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	func init() {
+//		fsys, _ := fs.Sub(migrations, "migrations")
+//		goose.RegisterMigrationSet("auth", fsys)
+//	}
+//
+// Each set is versioned independently, in its own version table: the version
+// table name followed by an underscore and the set name.
+func RegisterMigrationSet(name string, fsys fs.FS) {
+	for _, s := range registeredSets {
+		if s.name == name {
+			panic(fmt.Sprintf("failed to register migration set %q: already registered", name))
+		}
+	}
+	registeredSets = append(registeredSets, migrationSet{name: name, fsys: fsys})
+}
+
+// UpAllSets applies the migrations of every migration set, in the order they
+// were registered, holding back the migrations requiring migrations of later
+// sets until those are applied. Sets only hold SQL migrations: the Go
+// migrations registered with goose.AddMigration() belong to the host
+// application, and are not applied with them.
+func UpAllSets(db *sql.DB, opts ...OptionsFunc) error {
+	var namespaces []Namespace
+	for _, s := range registeredSets {
+		dir, err := extractSet(s)
+		if err != nil {
+			return errors.Wrapf(err, "migration set %s", s.name)
+		}
+		defer os.RemoveAll(dir)
+		namespaces = append(namespaces, Namespace{Name: s.name, Dir: dir})
+	}
+
+	defer registeredGoMigrations.swap()()
+
+	return withOptions(opts, func() error {
+		return upNamespaces(db, namespaces)
+	})
+}
+
+// extractSet writes the SQL migrations of s to a temporary directory, so
+// that they are collected, parsed and checksummed like the files of a
+// migrations directory.
+func extractSet(s migrationSet) (string, error) {
+	files, err := fs.Glob(s.fsys, "*.sql")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list migrations")
+	}
+	dir, err := ioutil.TempDir("", "goose-"+s.name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create temporary directory")
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(s.fsys, file)
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", errors.Wrapf(err, "failed to read %v", file)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, file), data, 0644); err != nil {
+			os.RemoveAll(dir)
+			return "", errors.Wrapf(err, "failed to write %v", file)
+		}
+	}
+	return dir, nil
+}
@@ -0,0 +1,55 @@
+// +build go1.16
+
+package goose
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestUpAllSets(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	saved := registeredSets
+	registeredSets = nil
+	defer func() { registeredSets = saved }()
+
+	RegisterMigrationSet("auth", fstest.MapFS{
+		"00001_create_users.sql":    {Data: []byte("-- +goose Up\nCREATE TABLE users (id int);\n-- +goose Down\nDROP TABLE users;\n")},
+		"00002_create_sessions.sql": {Data: []byte("-- +goose Up\nCREATE TABLE sessions (id int);\n-- +goose Down\nDROP TABLE sessions;\n")},
+		"README.md":                 {Data: []byte("not a migration")},
+	})
+	RegisterMigrationSet("billing", fstest.MapFS{
+		"00001_create_invoices.sql": {Data: []byte("-- +goose Up\nCREATE TABLE invoices (id int);\n-- +goose Down\nDROP TABLE invoices;\n")},
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := UpAllSets(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var versions []int64
+	for _, table := range []string{"goose_db_version_auth", "goose_db_version_billing"} {
+		var version int64
+		if err := db.QueryRow("SELECT MAX(version_id) FROM " + table).Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, version)
+	}
+	if !reflect.DeepEqual(versions, []int64{2, 1}) {
+		t.Errorf("expected versions [2 1], got %v", versions)
+	}
+	for _, table := range []string{"users", "sessions", "invoices"} {
+		if _, err := db.Exec("SELECT * FROM " + table); err != nil {
+			t.Errorf("expected table %s to be created: %v", table, err)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a set twice to panic")
+		}
+	}()
+	RegisterMigrationSet("auth", fstest.MapFS{})
+}
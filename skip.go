@@ -0,0 +1,26 @@
+package goose
+
+var skipTags = map[string]bool{}
+
+// SetSkipTags sets the exclusion tags of the environment. The statements of
+// SQL migrations annotated with '-- +goose Skip' and one of these tags are
+// not executed, like the GRANT statements of roles missing on local
+// databases.
+func SetSkipTags(tags ...string) {
+	skipTags = enabledGates(tags)
+}
+
+// WithSkipTags sets the exclusion tags, like SetSkipTags.
+func WithSkipTags(tags ...string) OptionsFunc {
+	return func(o *options) { o.skipTags = enabledGates(tags) }
+}
+
+// skipped reports whether a statement annotated with tags is skipped.
+func skipped(tags []string) bool {
+	for _, tag := range tags {
+		if skipTags[tag] {
+			return true
+		}
+	}
+	return false
+}
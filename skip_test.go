@@ -0,0 +1,58 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSkipTags(t *testing.T) {
+	defer SetSkipTags()
+	sql := `-- +goose Up
+CREATE TABLE reports (id int);
+-- +goose Skip local ci
+GRANT SELECT ON reports TO analytics;
+INSERT INTO reports VALUES (1);
+-- +goose Down
+-- +goose Skip local
+REVOKE SELECT ON reports FROM analytics;
+DROP TABLE reports;
+`
+	stmts, _, err := parseSQLMigration(strings.NewReader(sql), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 3 {
+		t.Errorf("expected no statement to be skipped without exclusion tags, got %q", stmts)
+	}
+
+	SetSkipTags("ci")
+	stmts, _, err = parseSQLMigration(strings.NewReader(sql), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 || strings.Contains(stmts[1], "GRANT") {
+		t.Errorf("expected the GRANT statement to be skipped, got %q", stmts)
+	}
+	stmts, _, err = parseSQLMigration(strings.NewReader(sql), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 {
+		t.Errorf("expected the REVOKE statement not to be skipped, got %q", stmts)
+	}
+
+	err = withOptions([]OptionsFunc{WithSkipTags("local")}, func() error {
+		stmts, _, err = parseSQLMigration(strings.NewReader(sql), false)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 1 || !strings.Contains(stmts[0], "DROP") {
+		t.Errorf("expected the REVOKE statement to be skipped, got %q", stmts)
+	}
+
+	if _, _, err := parseSQLMigration(strings.NewReader("-- +goose Up\n-- +goose Skip\nSELECT 1;\n"), true); err == nil {
+		t.Error("expected error on skip annotation without tag")
+	}
+}
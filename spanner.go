@@ -0,0 +1,62 @@
+package goose
+
+import (
+	"database/sql"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// spannerDDL matches the Spanner DDL statements, applied as schema updates.
+var spannerDDL = regexp.MustCompile(`(?is)^\s*(?:CREATE|ALTER|DROP|RENAME|GRANT|REVOKE|ANALYZE)\b`)
+
+// spannerDDLBatch submits the consecutive DDL statements of a migration run
+// on Spanner as a single schema update, with the START BATCH DDL and RUN
+// BATCH statements of the go-sql-spanner driver, which waits for the
+// long-running operation of the update. Applying a schema update takes
+// minutes on large databases: one per statement would slow migrations down.
+type spannerDDLBatch struct {
+	exec func(query string, args ...interface{}) (sql.Result, error)
+	open bool
+}
+
+// add starts a batch before the first of consecutive DDL statements, and
+// runs it before a statement that isn't, on Spanner.
+func (b *spannerDDLBatch) add(query string) error {
+	if _, ok := GetDialect().(*SpannerDialect); !ok {
+		return nil
+	}
+	if !spannerDDL.MatchString(stripSQLComments(query)) {
+		return b.run()
+	}
+	if b.open {
+		return nil
+	}
+	verboseInfo("Starting DDL batch")
+	if _, err := b.exec("START BATCH DDL"); err != nil {
+		return errors.Wrap(err, "failed to start DDL batch")
+	}
+	b.open = true
+	return nil
+}
+
+// run applies the statements of the batch, if one was started.
+func (b *spannerDDLBatch) run() error {
+	if !b.open {
+		return nil
+	}
+	b.open = false
+	verboseInfo("Running DDL batch")
+	if _, err := b.exec("RUN BATCH"); err != nil {
+		return errors.Wrap(err, "failed to run DDL batch")
+	}
+	return nil
+}
+
+// abort discards the statements of the batch, after a statement failed.
+func (b *spannerDDLBatch) abort() {
+	if b.open {
+		b.open = false
+		b.exec("ABORT BATCH")
+	}
+}
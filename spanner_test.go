@@ -0,0 +1,47 @@
+package goose
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSpannerDDLBatch(t *testing.T) {
+	defer SetDialect("postgres")
+	if err := SetDialect("spanner"); err != nil {
+		t.Fatal(err)
+	}
+
+	var executed []string
+	batch := &spannerDDLBatch{exec: func(query string, args ...interface{}) (sql.Result, error) {
+		executed = append(executed, query)
+		return nil, nil
+	}}
+	for _, query := range []string{
+		"CREATE TABLE users (id INT64) PRIMARY KEY (id);",
+		"-- index\nCREATE INDEX users_id_idx ON users (id);",
+		"INSERT INTO users (id) VALUES (1);",
+		"ALTER TABLE users ADD COLUMN name STRING(MAX);",
+	} {
+		if err := batch.add(query); err != nil {
+			t.Fatal(err)
+		}
+		executed = append(executed, strings.Fields(stripSQLComments(query))[0])
+	}
+	if err := batch.run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"START BATCH DDL", "CREATE", "CREATE", "RUN BATCH", "INSERT", "START BATCH DDL", "ALTER", "RUN BATCH"}
+	if !reflect.DeepEqual(executed, want) {
+		t.Errorf("expected %q, got %q", want, executed)
+	}
+
+	applied := true
+	if q, _ := listAppliedQuery(ListFilter{IsApplied: &applied}); !strings.Contains(q, "ANY_VALUE(is_applied HAVING MAX tstamp) = @p1") {
+		t.Errorf("expected the latest record by timestamp, got %q", q)
+	}
+	if transactional() {
+		t.Error("expected Spanner migrations to run without a transaction")
+	}
+}
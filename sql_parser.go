@@ -23,6 +23,12 @@ const (
 	gooseStatementEndDown                      // 6
 )
 
+// sqlAnnotations holds the file-level annotations of a SQL migration.
+type sqlAnnotations struct {
+	useTx  bool     // false if annotated with '-- +goose NO TRANSACTION'
+	params []string // parameters declared with '-- +goose Param'
+}
+
 type stateMachine parserState
 
 func (s *stateMachine) Get() parserState {
@@ -35,6 +41,10 @@ func (s *stateMachine) Set(new parserState) {
 
 const scanBufSize = 4 * 1024 * 1024
 
+// copyChunkSize is the size above which inline COPY data is split into
+// several COPY statements.
+const copyChunkSize = 1024 * 1024
+
 var matchEmptyLines = regexp.MustCompile(`^\s*$`)
 
 var bufferPool = sync.Pool{
@@ -53,7 +63,25 @@ var bufferPool = sync.Pool{
 // within a statement. For these cases, we provide the explicit annotations
 // 'StatementBegin' and 'StatementEnd' to allow the script to
 // tell us to ignore semicolons.
-func parseSQLMigration(r io.Reader, direction bool) (stmts []string, useTx bool, err error) {
+func parseSQLMigration(r io.Reader, direction bool) (stmts []string, a sqlAnnotations, err error) {
+	a, err = parseSQLStatements(r, direction, func(stmt string) error {
+		stmts = append(stmts, stmt)
+		return nil
+	})
+	if err != nil {
+		return nil, sqlAnnotations{}, err
+	}
+	return stmts, a, nil
+}
+
+// parseSQLStatements splits the SQL script like parseSQLMigration, but hands
+// each statement to handle as soon as it is read instead of collecting
+// them, so memory use is bounded by the size of the largest statement.
+// COPY ... FROM stdin data is split into several COPY statements of at
+// most copyChunkSize bytes.
+//
+// Errors returned by handle are returned as is.
+func parseSQLStatements(r io.Reader, direction bool, handle func(stmt string) error) (a sqlAnnotations, err error) {
 	var buf bytes.Buffer
 	scanBuf := bufferPool.Get().([]byte)
 	defer bufferPool.Put(scanBuf)
@@ -62,7 +90,25 @@ func parseSQLMigration(r io.Reader, direction bool) (stmts []string, useTx bool,
 	scanner.Buffer(scanBuf, scanBufSize)
 
 	stateMachine := stateMachine(start)
-	useTx = true
+	a.useTx = true
+	copyData := false
+	copyCommand := ""
+	assertNext := false
+	var skipTags []string
+	store := func(stmt string) error {
+		tags := skipTags
+		skipTags = nil
+		if skipped(tags) {
+			assertNext = false
+			verboseInfo("StateMachine: skip statement tagged %s", strings.Join(tags, " "))
+			return nil
+		}
+		if assertNext {
+			assertNext = false
+			stmt = assertAnnotation + "\n" + stmt
+		}
+		return handle(stmt)
+	}
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -70,16 +116,70 @@ func parseSQLMigration(r io.Reader, direction bool) (stmts []string, useTx bool,
 			log.Println(line)
 		}
 
+		// Inline COPY data is kept verbatim until the end-of-data marker.
+		if copyData {
+			if _, err := buf.WriteString(line + "\n"); err != nil {
+				return sqlAnnotations{}, errors.Wrap(err, "failed to write to buf")
+			}
+			switch {
+			case line == copyEndOfData:
+				copyData = false
+			case buf.Len() >= copyChunkSize:
+				if _, err := buf.WriteString(copyEndOfData + "\n"); err != nil {
+					return sqlAnnotations{}, errors.Wrap(err, "failed to write to buf")
+				}
+			default:
+				continue
+			}
+			if (stateMachine.Get() == gooseUp) == direction {
+				if err := handle(buf.String()); err != nil {
+					return sqlAnnotations{}, err
+				}
+				verboseInfo("StateMachine: store COPY FROM stdin statement")
+			}
+			buf.Reset()
+			if copyData {
+				if _, err := buf.WriteString(copyCommand + "\n"); err != nil {
+					return sqlAnnotations{}, errors.Wrap(err, "failed to write to buf")
+				}
+			}
+			continue
+		}
+
 		if strings.HasPrefix(line, "--") {
 			cmd := strings.TrimSpace(strings.TrimPrefix(line, "--"))
 
+			if strings.HasPrefix(cmd, "+goose Param ") {
+				for _, name := range strings.Fields(strings.TrimPrefix(cmd, "+goose Param ")) {
+					if !matchParamName.MatchString(name) {
+						return sqlAnnotations{}, errors.Errorf("invalid parameter name %q in '-- +goose Param' annotation", name)
+					}
+					a.params = append(a.params, name)
+				}
+				continue
+			}
+
+			if cmd == "+goose Skip" || strings.HasPrefix(cmd, "+goose Skip ") {
+				tags := strings.Fields(strings.TrimPrefix(cmd, "+goose Skip"))
+				if len(tags) == 0 {
+					return sqlAnnotations{}, errors.New("missing tag in '-- +goose Skip' annotation")
+				}
+				switch stateMachine.Get() {
+				case gooseUp, gooseStatementEndUp, gooseDown, gooseStatementEndDown:
+					skipTags = append(skipTags, tags...)
+				default:
+					return sqlAnnotations{}, errors.New("'-- +goose Skip' must be defined after '-- +goose Up' or '-- +goose Down' annotation, outside of a statement")
+				}
+				continue
+			}
+
 			switch cmd {
 			case "+goose Up":
 				switch stateMachine.Get() {
 				case start:
 					stateMachine.Set(gooseUp)
 				default:
-					return nil, false, errors.Errorf("duplicate '-- +goose Up' annotations; stateMachine=%v, see https://github.com/pressly/goose#sql-migrations", stateMachine)
+					return sqlAnnotations{}, errors.Errorf("duplicate '-- +goose Up' annotations; stateMachine=%v, see https://github.com/pressly/goose#sql-migrations", stateMachine)
 				}
 				continue
 
@@ -87,8 +187,10 @@ func parseSQLMigration(r io.Reader, direction bool) (stmts []string, useTx bool,
 				switch stateMachine.Get() {
 				case gooseUp, gooseStatementEndUp:
 					stateMachine.Set(gooseDown)
+					assertNext = false
+					skipTags = nil
 				default:
-					return nil, false, errors.Errorf("must start with '-- +goose Up' annotation, stateMachine=%v, see https://github.com/pressly/goose#sql-migrations", stateMachine)
+					return sqlAnnotations{}, errors.Errorf("must start with '-- +goose Up' annotation, stateMachine=%v, see https://github.com/pressly/goose#sql-migrations", stateMachine)
 				}
 				continue
 
@@ -99,7 +201,7 @@ func parseSQLMigration(r io.Reader, direction bool) (stmts []string, useTx bool,
 				case gooseDown, gooseStatementEndDown:
 					stateMachine.Set(gooseStatementBeginDown)
 				default:
-					return nil, false, errors.Errorf("'-- +goose StatementBegin' must be defined after '-- +goose Up' or '-- +goose Down' annotation, stateMachine=%v, see https://github.com/pressly/goose#sql-migrations", stateMachine)
+					return sqlAnnotations{}, errors.Errorf("'-- +goose StatementBegin' must be defined after '-- +goose Up' or '-- +goose Down' annotation, stateMachine=%v, see https://github.com/pressly/goose#sql-migrations", stateMachine)
 				}
 				continue
 
@@ -110,11 +212,20 @@ func parseSQLMigration(r io.Reader, direction bool) (stmts []string, useTx bool,
 				case gooseStatementBeginDown:
 					stateMachine.Set(gooseStatementEndDown)
 				default:
-					return nil, false, errors.New("'-- +goose StatementEnd' must be defined after '-- +goose StatementBegin', see https://github.com/pressly/goose#sql-migrations")
+					return sqlAnnotations{}, errors.New("'-- +goose StatementEnd' must be defined after '-- +goose StatementBegin', see https://github.com/pressly/goose#sql-migrations")
 				}
 
 			case "+goose NO TRANSACTION":
-				useTx = false
+				a.useTx = false
+				continue
+
+			case "+goose Assert":
+				switch stateMachine.Get() {
+				case gooseUp, gooseStatementEndUp, gooseDown, gooseStatementEndDown:
+					assertNext = true
+				default:
+					return sqlAnnotations{}, errors.New("'-- +goose Assert' must be defined after '-- +goose Up' or '-- +goose Down' annotation, outside of a statement")
+				}
 				continue
 
 			default:
@@ -132,7 +243,19 @@ func parseSQLMigration(r io.Reader, direction bool) (stmts []string, useTx bool,
 
 		// Write SQL line to a buffer.
 		if _, err := buf.WriteString(line + "\n"); err != nil {
-			return nil, false, errors.Wrap(err, "failed to write to buf")
+			return sqlAnnotations{}, errors.Wrap(err, "failed to write to buf")
+		}
+
+		// COPY ... FROM stdin; is followed by data lines, read them as part
+		// of the same statement.
+		switch stateMachine.Get() {
+		case gooseUp, gooseDown:
+			if matchCopyFromStdin.MatchString(line) {
+				copyData = true
+				copyCommand = line
+				verboseInfo("StateMachine: begin COPY FROM stdin data")
+				continue
+			}
 		}
 
 		// Read SQL body one by line, if we're in the right direction.
@@ -154,51 +277,62 @@ func parseSQLMigration(r io.Reader, direction bool) (stmts []string, useTx bool,
 				continue
 			}
 		default:
-			return nil, false, errors.Errorf("failed to parse migration: unexpected state %q on line %q, see https://github.com/pressly/goose#sql-migrations", stateMachine, line)
+			return sqlAnnotations{}, errors.Errorf("failed to parse migration: unexpected state %v on line %q, see https://github.com/pressly/goose#sql-migrations", stateMachine, line)
 		}
 
 		switch stateMachine.Get() {
 		case gooseUp:
 			if endsWithSemicolon(line) {
-				stmts = append(stmts, buf.String())
+				if err := store(buf.String()); err != nil {
+					return sqlAnnotations{}, err
+				}
 				buf.Reset()
 				verboseInfo("StateMachine: store simple Up query")
 			}
 		case gooseDown:
 			if endsWithSemicolon(line) {
-				stmts = append(stmts, buf.String())
+				if err := store(buf.String()); err != nil {
+					return sqlAnnotations{}, err
+				}
 				buf.Reset()
 				verboseInfo("StateMachine: store simple Down query")
 			}
 		case gooseStatementEndUp:
-			stmts = append(stmts, buf.String())
+			if err := store(buf.String()); err != nil {
+				return sqlAnnotations{}, err
+			}
 			buf.Reset()
 			verboseInfo("StateMachine: store Up statement")
 			stateMachine.Set(gooseUp)
 		case gooseStatementEndDown:
-			stmts = append(stmts, buf.String())
+			if err := store(buf.String()); err != nil {
+				return sqlAnnotations{}, err
+			}
 			buf.Reset()
 			verboseInfo("StateMachine: store Down statement")
 			stateMachine.Set(gooseDown)
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		return nil, false, errors.Wrap(err, "failed to scan migration")
+		return sqlAnnotations{}, errors.Wrap(err, "failed to scan migration")
 	}
 	// EOF
 
 	switch stateMachine.Get() {
 	case start:
-		return nil, false, errors.New("failed to parse migration: must start with '-- +goose Up' annotation, see https://github.com/pressly/goose#sql-migrations")
+		return sqlAnnotations{}, errors.New("failed to parse migration: must start with '-- +goose Up' annotation, see https://github.com/pressly/goose#sql-migrations")
 	case gooseStatementBeginUp, gooseStatementBeginDown:
-		return nil, false, errors.New("failed to parse migration: missing '-- +goose StatementEnd' annotation")
+		return sqlAnnotations{}, errors.New("failed to parse migration: missing '-- +goose StatementEnd' annotation")
+	}
+	if copyData {
+		return sqlAnnotations{}, errors.Errorf("failed to parse migration: missing %q end-of-data marker after COPY FROM stdin", copyEndOfData)
 	}
 
 	if bufferRemaining := strings.TrimSpace(buf.String()); len(bufferRemaining) > 0 {
-		return nil, false, errors.Errorf("failed to parse migration: state %q, direction: %v: unexpected unfinished SQL query: %q: missing semicolon?", stateMachine, direction, bufferRemaining)
+		return sqlAnnotations{}, errors.Errorf("failed to parse migration: state %v, direction: %v: unexpected unfinished SQL query: %q: missing semicolon?", stateMachine, direction, bufferRemaining)
 	}
 
-	return stmts, useTx, nil
+	return a, nil
 }
 
 // Checks the line to see if the line has a statement-ending semicolon
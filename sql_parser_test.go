@@ -50,6 +50,7 @@ func TestSplitStatements(t *testing.T) {
 		{sql: functxt, up: 2, down: 2},
 		{sql: mysqlChangeDelimiter, up: 4, down: 0},
 		{sql: copyFromStdin, up: 1, down: 0},
+		{sql: copyFromStdinData, up: 3, down: 1},
 		{sql: plpgsqlSyntax, up: 2, down: 2},
 		{sql: plpgsqlSyntaxMixedStatements, up: 2, down: 2},
 	}
@@ -94,12 +95,12 @@ func TestUseTransactions(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		_, useTx, err := parseSQLMigration(f, true)
+		_, a, err := parseSQLMigration(f, true)
 		if err != nil {
 			t.Error(err)
 		}
-		if useTx != test.useTransactions {
-			t.Errorf("Failed transaction check. got %v, want %v", useTx, test.useTransactions)
+		if a.useTx != test.useTransactions {
+			t.Errorf("Failed transaction check. got %v, want %v", a.useTx, test.useTransactions)
 		}
 		f.Close()
 	}
@@ -112,6 +113,7 @@ func TestParsingErrors(t *testing.T) {
 		noUpDownAnnotations,
 		multiUpDown,
 		downFirst,
+		copyFromStdinNoEndOfData,
 	}
 	for i, sql := range tt {
 		_, _, err := parseSQLMigration(strings.NewReader(sql), true)
@@ -297,6 +299,28 @@ COPY public.django_content_type (id, app_label, model) FROM stdin;
 -- +goose StatementEnd
 `
 
+var copyFromStdinData = `
+-- +goose Up
+CREATE TABLE users (id int, name text, bio text);
+
+COPY users (id, name, bio) FROM stdin;
+1	alice	-- not a comment
+
+2	bob	\N
+\.
+
+SELECT 1;
+
+-- +goose Down
+DROP TABLE users;
+`
+
+var copyFromStdinNoEndOfData = `
+-- +goose Up
+COPY users (id, name) FROM stdin;
+1	alice
+`
+
 var plpgsqlSyntax = `
 -- +goose Up
 -- +goose StatementBegin
@@ -342,3 +366,52 @@ FOR EACH ROW EXECUTE PROCEDURE  update_updated_at_column();
 DROP TRIGGER update_properties_updated_at;
 DROP FUNCTION update_updated_at_column();
 `
+
+func TestParseSQLStatementsChunksCopyData(t *testing.T) {
+	t.Parallel()
+
+	var sql strings.Builder
+	sql.WriteString("-- +goose Up\nCOPY users (id, name) FROM stdin;\n")
+	row := strings.Repeat("x", 1024)
+	for i := 0; i < 3*copyChunkSize/len(row); i++ {
+		sql.WriteString("1\t" + row + "\n")
+	}
+	sql.WriteString("\\.\n")
+
+	var stmts []string
+	_, err := parseSQLStatements(strings.NewReader(sql.String()), true, func(stmt string) error {
+		stmts = append(stmts, stmt)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) < 3 {
+		t.Fatalf("expected COPY data to be split in chunks, got %v statements", len(stmts))
+	}
+	for i, stmt := range stmts {
+		if len(stmt) > copyChunkSize+2*len(row) {
+			t.Errorf("stmts[%v] is too large: %v bytes", i, len(stmt))
+		}
+		if !isCopyFromStdin(stmt) || !strings.HasSuffix(stmt, "\\.\n") {
+			t.Errorf("stmts[%v] is not a complete COPY FROM stdin statement", i)
+		}
+	}
+}
+
+func TestParseSQLStatementsHandleError(t *testing.T) {
+	t.Parallel()
+
+	stop := errors.New("stop")
+	count := 0
+	_, err := parseSQLStatements(strings.NewReader(multilineSQL), true, func(stmt string) error {
+		count++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("expected handle error, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected parsing to stop after the first statement, got %v statements", count)
+	}
+}
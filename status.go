@@ -2,53 +2,309 @@ package goose
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
-// Status prints the status of all migrations.
-func Status(db *sql.DB, dir string) error {
-	// collect all migrations
-	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+const (
+	yellowColor = "\033[33m"
+	redColor    = "\033[31m"
+)
+
+var statusColor = false
+
+// SetStatusColor colors the rows of the status table: pending migrations in
+// yellow, drifted ones in red, gated ones in gray.
+func SetStatusColor(enabled bool) {
+	statusColor = enabled
+}
+
+// statusRow is the status of a migration, as printed by Status.
+type statusRow struct {
+	Version    int64             `json:"version"`
+	Migration  string            `json:"migration"`
+	State      string            `json:"state"` // applied, pending or gated
+	AppliedAt  *time.Time        `json:"applied_at,omitempty"`
+	DurationMs *int64            `json:"duration_ms,omitempty"`
+	Checksum   string            `json:"checksum,omitempty"` // ok, drift or unknown, for applied migrations
+	Build      string            `json:"build,omitempty"`
+	RunID      string            `json:"run_id,omitempty"`
+	Meta       map[string]string `json:"meta,omitempty"`
+}
+
+// Status prints the status of all migrations, as an aligned table of their
+// state, applied-at time, duration, checksum state and name.
+func Status(db *sql.DB, dir string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return statusLocked(db, dir)
+	})
+}
+
+// statusLocked is Status for the callers holding optionsMu.
+func statusLocked(db *sql.DB, dir string) error {
+	var b strings.Builder
+	if err := status(&b, db, dir, "table"); err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		log.Println(line)
+	}
+	return nil
+}
+
+// StatusFormat writes the status of all migrations to w, in format: table
+// like Status, wide adding their version, build and run ID, json or yaml.
+func StatusFormat(w io.Writer, db *sql.DB, dir, format string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return status(w, db, dir, format)
+	})
+}
+
+func status(w io.Writer, db *sql.DB, dir, format string) error {
+	switch format {
+	case "table", "wide", "json", "yaml":
+	default:
+		return errors.Errorf("unknown status format %q, expected table, wide, json or yaml", format)
+	}
+
+	rows, err := migrationStatuses(db, dir)
 	if err != nil {
-		return errors.Wrap(err, "failed to collect migrations")
+		return err
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case "yaml":
+		return writeStatusYAML(w, rows)
+	}
+	return writeStatusTable(w, rows, format == "wide")
+}
+
+// migrationStatuses returns the status of the migrations of dir, from the
+// latest record of each in the version table.
+func migrationStatuses(db *sql.DB, dir string) ([]statusRow, error) {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to collect migrations")
 	}
 
 	// must ensure that the version table exists if we're running on a pristine DB
 	if _, err := EnsureDBVersion(db); err != nil {
-		return errors.Wrap(err, "failed to ensure DB version")
+		return nil, errors.Wrap(err, "failed to ensure DB version")
 	}
 
-	log.Println("    Applied At                  Migration")
-	log.Println("    =======================================")
-	for _, migration := range migrations {
-		if err := printMigrationStatus(db, migration.Version, filepath.Base(migration.Source)); err != nil {
-			return errors.Wrap(err, "failed to print status")
+	type record struct {
+		applied           bool
+		tstamp            time.Time
+		build, sum, durMs sql.NullString
+		runID             sql.NullString
+	}
+	latestFirst := "id DESC"
+	switch GetDialect().(type) {
+	case *ClickHouseDialect, *BigQueryDialect, *TrinoDialect, *SpannerDialect:
+		latestFirst = "tstamp DESC"
+	case *RedshiftDialect:
+		// IDENTITY values are not in insertion order on Redshift.
+		latestFirst = "tstamp DESC, id DESC"
+	}
+	q := fmt.Sprintf("SELECT version_id, tstamp, is_applied, %s, %s, %s, %s FROM %s ORDER BY %s",
+		versionColumn(db, "build"), versionColumn(db, "checksum"), versionColumn(db, "duration_ms"), versionColumn(db, "run_id"), TableName(), latestFirst)
+	dbRows, err := db.Query(q)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query the version table")
+	}
+	defer dbRows.Close()
+	latest := map[int64]record{}
+	for dbRows.Next() {
+		var (
+			version int64
+			rec     record
+		)
+		if err := dbRows.Scan(&version, &rec.tstamp, &rec.applied, &rec.build, &rec.sum, &rec.durMs, &rec.runID); err != nil {
+			return nil, errors.Wrap(err, "failed to scan row")
 		}
+		if _, ok := latest[version]; !ok {
+			latest[version] = rec
+		}
+	}
+	if err := dbRows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to get next row")
 	}
 
-	return nil
+	rows := make([]statusRow, 0, len(migrations))
+	for _, m := range migrations {
+		row := statusRow{Version: m.Version, Migration: filepath.Base(m.Source), State: "pending", Meta: m.Meta}
+		rec, ok := latest[m.Version]
+		if !ok || !rec.applied {
+			if m.Gated() {
+				row.State = "gated"
+			}
+			rows = append(rows, row)
+			continue
+		}
+
+		tstamp := rec.tstamp
+		row.State, row.AppliedAt, row.Build, row.RunID = "applied", &tstamp, rec.build.String, rec.runID.String
+		if ms, err := strconv.ParseInt(rec.durMs.String, 10, 64); err == nil {
+			row.DurationMs = &ms
+		}
+		row.Checksum = "unknown"
+		if rec.sum.String != "" {
+			checksum, err := m.Checksum()
+			if err != nil {
+				return nil, err
+			}
+			switch checksum {
+			case "":
+			case rec.sum.String:
+				row.Checksum = "ok"
+			default:
+				row.Checksum = "drift"
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func writeStatusTable(w io.Writer, rows []statusRow, wide bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"STATE", "APPLIED AT", "DURATION", "CHECKSUM", "MIGRATION"}
+	if wide {
+		header = append([]string{"VERSION"}, append(header, "BUILD", "RUN", "META")...)
+	}
+	writeStatusLine(tw, grayColor, header)
+
+	var applied, pending, drifted int
+	for _, row := range rows {
+		appliedAt, duration, checksum := "-", "-", "-"
+		if row.AppliedAt != nil {
+			appliedAt = row.AppliedAt.Format(time.ANSIC)
+		}
+		if row.DurationMs != nil {
+			duration = (time.Duration(*row.DurationMs) * time.Millisecond).String()
+		}
+		if row.Checksum != "" {
+			checksum = row.Checksum
+		}
+		migration := row.Migration
+		if len(row.Meta) > 0 && !wide {
+			migration += " (" + formatMeta(row.Meta) + ")"
+		}
+
+		color := resetColor
+		switch {
+		case row.Checksum == "drift":
+			color = redColor
+			drifted++
+		case row.State == "pending":
+			color = yellowColor
+			pending++
+		case row.State == "gated":
+			color = grayColor
+		}
+		if row.State == "applied" {
+			applied++
+		}
+
+		cells := []string{row.State, appliedAt, duration, checksum, migration}
+		if wide {
+			build := row.Build
+			if build == "" {
+				build = "-"
+			}
+			run := row.RunID
+			if run == "" {
+				run = "-"
+			}
+			cells = append([]string{strconv.FormatInt(row.Version, 10)}, append(cells, build, run)...)
+			if len(row.Meta) > 0 {
+				cells = append(cells, formatMeta(row.Meta))
+			}
+		}
+		writeStatusLine(tw, color, cells)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d applied, %d pending, %d drifted.\n", applied, pending, drifted)
+	return err
 }
 
-func printMigrationStatus(db *sql.DB, version int64, script string) error {
+// writeStatusLine writes a row of the status table. Colors have the same
+// length, so that they don't break the alignment of the columns.
+func writeStatusLine(w io.Writer, color string, cells []string) {
+	line := "    " + strings.Join(cells, "\t")
+	if statusColor {
+		line = color + line + resetColor
+	}
+	fmt.Fprintln(w, line)
+}
+
+func writeStatusYAML(w io.Writer, rows []statusRow) error {
+	if len(rows) == 0 {
+		_, err := fmt.Fprintln(w, "[]")
+		return err
+	}
+	var b strings.Builder
+	for _, row := range rows {
+		fmt.Fprintf(&b, "- version: %d\n", row.Version)
+		fmt.Fprintf(&b, "  migration: %s\n", strconv.Quote(row.Migration))
+		fmt.Fprintf(&b, "  state: %s\n", row.State)
+		if row.AppliedAt != nil {
+			fmt.Fprintf(&b, "  applied_at: %s\n", row.AppliedAt.Format(time.RFC3339Nano))
+		}
+		if row.DurationMs != nil {
+			fmt.Fprintf(&b, "  duration_ms: %d\n", *row.DurationMs)
+		}
+		if row.Checksum != "" {
+			fmt.Fprintf(&b, "  checksum: %s\n", row.Checksum)
+		}
+		if row.Build != "" {
+			fmt.Fprintf(&b, "  build: %s\n", strconv.Quote(row.Build))
+		}
+		if row.RunID != "" {
+			fmt.Fprintf(&b, "  run_id: %s\n", strconv.Quote(row.RunID))
+		}
+		if len(row.Meta) > 0 {
+			b.WriteString("  meta:\n")
+			keys := make([]string, 0, len(row.Meta))
+			for k := range row.Meta {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(&b, "    %s: %s\n", strconv.Quote(k), strconv.Quote(row.Meta[k]))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// migrationStatus retrieves the latest record of a migration in the version
+// table. It is not applied if the migration was never applied.
+func migrationStatus(db QueryExecer, version int64) (MigrationRecord, error) {
 	q := GetDialect().migrationSQL()
 
-	var row MigrationRecord
+	row := MigrationRecord{VersionID: version}
 
 	err := db.QueryRow(q, version).Scan(&row.TStamp, &row.IsApplied)
 	if err != nil && err != sql.ErrNoRows {
-		return errors.Wrap(err, "failed to query the latest migration")
-	}
-
-	var appliedAt string
-	if row.IsApplied {
-		appliedAt = row.TStamp.Format(time.ANSIC)
-	} else {
-		appliedAt = "Pending"
+		return row, errors.Wrap(err, "failed to query the latest migration")
 	}
 
-	log.Printf("    %-24s -- %v\n", appliedAt, script)
-	return nil
+	return row, nil
 }
@@ -0,0 +1,146 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Diff is the difference between the migrations of a directory and
+// the migrations applied to a database.
+type Diff struct {
+	Pending    []*Migration // migrations to apply, in order, except the gated ones
+	OutOfOrder []*Migration // unapplied migrations older than the current version, which up skips
+	Drifted    []*Migration // applied migrations whose source changed since they were applied
+	Missing    []int64      // applied versions missing from the directory
+}
+
+// GetDiff compares the migrations of dir with the migrations applied
+// to db. Like up, it only counts the unapplied migrations older than the
+// current version as pending with SetAllowMissing, or once their gate is
+// enabled. Drift is only detected for migrations applied by a version of
+// goose recording checksums in the version table.
+func GetDiff(db *sql.DB, dir string) (*Diff, error) {
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to collect migrations")
+	}
+	current, err := EnsureDBVersion(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to ensure DB version")
+	}
+
+	diff := &Diff{}
+	column := versionColumn(db, "checksum")
+	for _, m := range migrations {
+		applied, recorded, err := appliedChecksum(db, column, m.Version)
+		if err != nil {
+			return nil, err
+		}
+		if !applied {
+			switch {
+			case m.Gated():
+			case m.Version > current || allowMissing || m.Gate() != "":
+				diff.Pending = append(diff.Pending, m)
+			default:
+				diff.OutOfOrder = append(diff.OutOfOrder, m)
+			}
+			continue
+		}
+		if recorded == "" {
+			continue
+		}
+		checksum, err := m.Checksum()
+		if err != nil {
+			return nil, err
+		}
+		if checksum != "" && checksum != recorded {
+			diff.Drifted = append(diff.Drifted, m)
+		}
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range applied {
+		if _, err := migrations.Current(v); err != nil {
+			diff.Missing = append(diff.Missing, v)
+		}
+	}
+
+	return diff, nil
+}
+
+// appliedChecksum reports whether the latest record of a migration in the
+// version table is applied, and the checksum it was recorded with, from
+// column, see versionColumn.
+func appliedChecksum(db *sql.DB, column string, version int64) (applied bool, checksum string, err error) {
+	q := fmt.Sprintf("SELECT is_applied, %s FROM %s WHERE version_id = %s ORDER BY tstamp DESC", column, TableName(), GetDialect().placeholder(1))
+	var c sql.NullString
+	err = db.QueryRow(q, version).Scan(&applied, &c)
+	if err == sql.ErrNoRows {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", errors.Wrap(err, "failed to query the latest migration")
+	}
+	return applied, c.String, nil
+}
+
+// appliedVersions returns the versions applied to db, highest first,
+// excluding the initial version 0.
+func appliedVersions(db *sql.DB) ([]int64, error) {
+	applied := true
+	records, err := NewStore(db).ListApplied(context.Background(), ListFilter{IsApplied: &applied})
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int64, len(records))
+	for i, rec := range records {
+		versions[i] = rec.VersionID
+	}
+	return versions, nil
+}
+
+// StatusDiff prints the difference between the migrations of dir and
+// the migrations applied to db, in the style of a plan:
+//
+//	+ 00042_add_index.sql will be applied
+//	! 00040_add_column.sql is older than the current version: it will be skipped
+//	~ 00013_add_users.sql checksum drift, changed since it was applied
+//	- nothing to revert
+func StatusDiff(db *sql.DB, dir string) error {
+	diff, err := GetDiff(db, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range diff.Pending {
+		log.Printf("+ %s will be applied\n", filepath.Base(m.Source))
+	}
+	for _, m := range diff.OutOfOrder {
+		log.Printf("! %s is older than the current version: it will be skipped\n", filepath.Base(m.Source))
+	}
+	for _, m := range diff.Drifted {
+		log.Printf("~ %s checksum drift, changed since it was applied\n", filepath.Base(m.Source))
+	}
+	for _, v := range diff.Missing {
+		log.Printf("- %d is applied, but missing from %s: it can't be reverted\n", v, dir)
+	}
+	if len(diff.Pending) == 0 {
+		log.Println("+ nothing to apply")
+	}
+	if len(diff.Drifted) == 0 {
+		log.Println("~ no drift")
+	}
+	if len(diff.Missing) == 0 {
+		log.Println("- nothing to revert")
+	}
+
+	log.Printf("%d to apply, %d out of order, %d drifted, %d missing.\n", len(diff.Pending), len(diff.OutOfOrder), len(diff.Drifted), len(diff.Missing))
+	return nil
+}
@@ -0,0 +1,45 @@
+package goose
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetDiff(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := UpTo(db, dir, 2); err != nil {
+		t.Fatal(err)
+	}
+	// Edited after it was applied.
+	if err := ioutil.WriteFile(filepath.Join(dir, "00001_create_a.sql"), []byte("-- +goose Up\nCREATE TABLE a (id bigint);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Applied from another branch.
+	if err := insertVersion(db, 9, true, "abc", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := GetDiff(db, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateVersions(t, "Pending", diff.Pending, nil)
+	validateVersions(t, "OutOfOrder", diff.OutOfOrder, []int64{3})
+	validateVersions(t, "Drifted", diff.Drifted, []int64{1})
+	if len(diff.Missing) != 1 || diff.Missing[0] != 9 {
+		t.Errorf("unexpected missing versions, got %v, want [9]", diff.Missing)
+	}
+
+	SetAllowMissing(true)
+	defer SetAllowMissing(false)
+	if diff, err = GetDiff(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	validateVersions(t, "Pending", diff.Pending, []int64{3})
+	validateVersions(t, "OutOfOrder", diff.OutOfOrder, nil)
+}
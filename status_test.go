@@ -0,0 +1,74 @@
+package goose
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatusFormat(t *testing.T) {
+	defer SetLogger(log)
+	SetLogger(&bufferLogger{})
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if err := UpTo(db, dir, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "00002_create_b.sql"), []byte("-- +goose Up\nCREATE TABLE b (id bigint);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := StatusFormat(&b, db, dir, "table"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected a header, 3 migrations and a summary, got %q", b.String())
+	}
+	column := strings.Index(lines[0], "MIGRATION")
+	for _, line := range lines[1:4] {
+		if len(line) <= column || line[column-1] != ' ' || line[column] != '0' {
+			t.Errorf("expected the migration names to be aligned, got %q", b.String())
+			break
+		}
+	}
+	if !strings.Contains(lines[1], "applied") || !strings.Contains(lines[1], " ok ") {
+		t.Errorf("unexpected status of 00001, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "drift") || !strings.Contains(lines[3], "pending") {
+		t.Errorf("expected 00002 to drift and 00003 to be pending, got %q", b.String())
+	}
+	if lines[4] != "2 applied, 1 pending, 1 drifted." {
+		t.Errorf("unexpected summary %q", lines[4])
+	}
+
+	b.Reset()
+	if err := StatusFormat(&b, db, dir, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var rows []statusRow
+	if err := json.Unmarshal([]byte(b.String()), &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0].AppliedAt == nil || rows[0].DurationMs == nil || rows[2].State != "pending" {
+		t.Errorf("unexpected json status %s", b.String())
+	}
+
+	b.Reset()
+	if err := StatusFormat(&b, db, dir, "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "- version: 2\n  migration: \"00002_create_b.sql\"\n  state: applied\n") {
+		t.Errorf("unexpected yaml status %s", b.String())
+	}
+
+	if err := StatusFormat(&b, db, dir, "xml"); err == nil {
+		t.Error("expected error on unknown format")
+	}
+}
@@ -0,0 +1,139 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Store queries the version table of a database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore returns a Store of the version table of db.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// ListFilter filters the migrations listed by Store.ListApplied.
+type ListFilter struct {
+	FromVersion int64 // lowest version listed, included
+	ToVersion   int64 // highest version listed, included, or 0 for no bound
+	IsApplied   *bool // state of the migrations listed, or nil for both
+	Limit       int   // maximum number of migrations listed, or 0 for no limit
+	Offset      int   // number of migrations skipped
+}
+
+// ListApplied lists the migrations recorded in the version table, as their
+// latest record, applied or rolled back, highest version first. The
+// filter is applied by the database, so that very long version tables,
+// like the ones of per-tenant schemas, aren't read entirely. The initial
+// version 0 is never listed.
+func (s *Store) ListApplied(ctx context.Context, filter ListFilter) ([]MigrationRecord, error) {
+	q, args := listAppliedQuery(filter)
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query version table")
+	}
+	defer rows.Close()
+
+	var records []MigrationRecord
+	for rows.Next() {
+		var rec MigrationRecord
+		if err := rows.Scan(&rec.VersionID, &rec.IsApplied, &rec.TStamp); err != nil {
+			return nil, errors.Wrap(err, "failed to scan row")
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to get next row")
+	}
+	return records, nil
+}
+
+// listAppliedQuery returns the query of ListApplied and its arguments.
+func listAppliedQuery(f ListFilter) (string, []interface{}) {
+	d := GetDialect()
+	var (
+		conds = []string{"version_id > 0"}
+		args  []interface{}
+	)
+	arg := func(cond string, v interface{}) {
+		args = append(args, v)
+		conds = append(conds, cond+" "+d.placeholder(len(args)))
+	}
+	if f.FromVersion > 0 {
+		arg("version_id >=", f.FromVersion)
+	}
+	if f.ToVersion > 0 {
+		arg("version_id <=", f.ToVersion)
+	}
+
+	var q string
+	switch d.(type) {
+	case *ClickHouseDialect, *BigQueryDialect, *TrinoDialect, *SpannerDialect:
+		// ClickHouse, BigQuery, Trino and Spanner version tables have no
+		// id: the latest record is the one with the latest timestamp.
+		latest := "argMax(is_applied, tstamp)"
+		switch d.(type) {
+		case *BigQueryDialect:
+			latest = "ARRAY_AGG(is_applied ORDER BY tstamp DESC LIMIT 1)[OFFSET(0)]"
+		case *TrinoDialect:
+			latest = "max_by(is_applied, tstamp)"
+		case *SpannerDialect:
+			latest = "ANY_VALUE(is_applied HAVING MAX tstamp)"
+		}
+		q = fmt.Sprintf("SELECT version_id, %s AS applied, max(tstamp) FROM %s WHERE %s GROUP BY version_id", latest, TableName(), strings.Join(conds, " AND "))
+		if f.IsApplied != nil {
+			// Trino doesn't resolve the aliases of the select list in
+			// HAVING.
+			args = append(args, *f.IsApplied)
+			q += fmt.Sprintf(" HAVING %s = %s", latest, d.placeholder(len(args)))
+		}
+	default:
+		latest := fmt.Sprintf("SELECT MAX(id) FROM %s GROUP BY version_id", TableName())
+		if _, ok := d.(*RedshiftDialect); ok {
+			// Redshift IDENTITY values are not always in the order of
+			// insertion, like in dbVersionQuery.
+			latest = fmt.Sprintf("SELECT id FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY version_id ORDER BY tstamp DESC, id DESC) AS n FROM %s) latest WHERE n = 1", TableName())
+		}
+		conds = append([]string{"id IN (" + latest + ")"}, conds...)
+		if f.IsApplied != nil {
+			arg("is_applied =", *f.IsApplied)
+		}
+		q = fmt.Sprintf("SELECT version_id, is_applied, tstamp FROM %s WHERE %s", TableName(), strings.Join(conds, " AND "))
+	}
+	q += " ORDER BY version_id DESC"
+
+	return q + limitClause(f.Limit, f.Offset), args
+}
+
+// limitClause returns the clause of the current dialect limiting the rows
+// of an ordered query to limit rows, all if 0, after the first offset ones,
+// or "" if neither is set.
+func limitClause(limit, offset int) string {
+	if limit <= 0 && offset <= 0 {
+		return ""
+	}
+	rows := int64(limit)
+	if rows <= 0 {
+		rows = maxVersion
+	}
+	switch GetDialect().(type) {
+	case *SqlServerDialect:
+		return fmt.Sprintf(" OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", offset, rows)
+	case *FirebirdDialect:
+		last := maxVersion
+		if limit > 0 {
+			last = int64(offset + limit)
+		}
+		return fmt.Sprintf(" ROWS %d TO %d", offset+1, last)
+	case *TrinoDialect:
+		return fmt.Sprintf(" OFFSET %d LIMIT %d", offset, rows)
+	}
+	return fmt.Sprintf(" LIMIT %d OFFSET %d", rows, offset)
+}
@@ -0,0 +1,63 @@
+package goose
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStoreListApplied(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+	if _, err := EnsureDBVersion(db); err != nil {
+		t.Fatal(err)
+	}
+	for v := int64(1); v <= 5; v++ {
+		if err := insertVersion(db, v, true, "", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 4 is rolled back, 2 rolled back then applied again.
+	for _, rec := range []MigrationRecord{{VersionID: 4}, {VersionID: 2}, {VersionID: 2, IsApplied: true}} {
+		if err := insertVersion(db, rec.VersionID, rec.IsApplied, "", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	applied, rolledBack := true, false
+	tests := []struct {
+		filter ListFilter
+		want   []int64
+	}{
+		{ListFilter{}, []int64{5, 4, 3, 2, 1}},
+		{ListFilter{IsApplied: &applied}, []int64{5, 3, 2, 1}},
+		{ListFilter{IsApplied: &rolledBack}, []int64{4}},
+		{ListFilter{FromVersion: 2, ToVersion: 4}, []int64{4, 3, 2}},
+		{ListFilter{Limit: 2}, []int64{5, 4}},
+		{ListFilter{Limit: 2, Offset: 2}, []int64{3, 2}},
+		{ListFilter{Offset: 4}, []int64{1}},
+	}
+	store := NewStore(db)
+	for _, test := range tests {
+		records, err := store.ListApplied(context.Background(), test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int64
+		for _, rec := range records {
+			got = append(got, rec.VersionID)
+			if rec.IsApplied == (rec.VersionID == 4) {
+				t.Errorf("%+v: unexpected state of version %d", test.filter, rec.VersionID)
+			}
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%+v: got versions %v, want %v", test.filter, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%+v: got versions %v, want %v", test.filter, got, test.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Tag is a release marker: the name of a product version, like v2.3.0,
+// for a migration version.
+type Tag struct {
+	Name      string    `json:"name"`
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func tagTableName() string {
+	return TableName() + "_tag"
+}
+
+// TagVersion tags version with name. Tags can't be moved: tagging with the
+// name of an existing tag fails.
+func TagVersion(db *sql.DB, name string, version int64) error {
+	if name == "" {
+		return errors.New("tag name must not be empty")
+	}
+	if err := ensureTagTable(db); err != nil {
+		return err
+	}
+	if tag, err := GetTag(db, name); err == nil {
+		return errors.Errorf("tag %s already tags version %d", name, tag.Version)
+	}
+
+	d := GetDialect()
+	q := fmt.Sprintf("INSERT INTO %s (name, version_id, created_at) VALUES (%s, %s, %s)", tagTableName(), d.placeholder(1), d.placeholder(2), d.placeholder(3))
+	if _, err := db.Exec(q, name, version, time.Now().Unix()); err != nil {
+		return errors.Wrap(err, "failed to record tag")
+	}
+	return nil
+}
+
+// GetTag retrieves a tag.
+func GetTag(db *sql.DB, name string) (*Tag, error) {
+	if !tagTableExists(db) {
+		return nil, errors.Errorf("no tag %q", name)
+	}
+	q := fmt.Sprintf("SELECT version_id, created_at FROM %s WHERE name = %s", tagTableName(), GetDialect().placeholder(1))
+	tag := &Tag{Name: name}
+	var createdAt int64
+	err := db.QueryRow(q, name).Scan(&tag.Version, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.Errorf("no tag %q", name)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve tag")
+	}
+	tag.CreatedAt = time.Unix(createdAt, 0)
+	return tag, nil
+}
+
+// ListTags returns the tags, by version.
+func ListTags(db *sql.DB) ([]*Tag, error) {
+	if !tagTableExists(db) {
+		return nil, nil
+	}
+	rows, err := db.Query(fmt.Sprintf("SELECT name, version_id, created_at FROM %s ORDER BY version_id, created_at", tagTableName()))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query tag table")
+	}
+	defer rows.Close()
+
+	var tags []*Tag
+	for rows.Next() {
+		var (
+			tag       Tag
+			createdAt int64
+		)
+		if err := rows.Scan(&tag.Name, &tag.Version, &createdAt); err != nil {
+			return nil, errors.Wrap(err, "failed to scan row")
+		}
+		tag.CreatedAt = time.Unix(createdAt, 0)
+		tags = append(tags, &tag)
+	}
+	return tags, rows.Err()
+}
+
+// UpToTag migrates up to the version tagged with name, in the tag table of
+// the version table of opts.
+func UpToTag(db *sql.DB, dir, name string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return upToTagLocked(db, dir, name)
+	})
+}
+
+// upToTagLocked is UpToTag for the callers holding optionsMu.
+func upToTagLocked(db *sql.DB, dir, name string) error {
+	tag, err := GetTag(db, name)
+	if err != nil {
+		return err
+	}
+	return upToLocked(db, dir, tag.Version)
+}
+
+// DownToTag rolls back migrations to the version tagged with name, which
+// stays applied.
+func DownToTag(db *sql.DB, dir, name string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return downToTagLocked(db, dir, name)
+	})
+}
+
+// downToTagLocked is DownToTag for the callers holding optionsMu.
+func downToTagLocked(db *sql.DB, dir, name string) error {
+	tag, err := GetTag(db, name)
+	if err != nil {
+		return err
+	}
+	return downToLocked(db, dir, tag.Version)
+}
+
+// tagTableExists reports whether the tag table exists, reads not creating
+// it.
+func tagTableExists(db *sql.DB) bool {
+	_, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %s WHERE 1 = 0", tagTableName()))
+	return err == nil
+}
+
+// ensureTagTable creates the tag table if it doesn't exist.
+func ensureTagTable(db *sql.DB) error {
+	if tagTableExists(db) {
+		return nil
+	}
+	q := fmt.Sprintf(`CREATE TABLE %s (
+                name VARCHAR(255) NOT NULL PRIMARY KEY,
+                version_id BIGINT NOT NULL,
+                created_at BIGINT NOT NULL
+            )`, tagTableName())
+	if _, err := db.Exec(q); err != nil {
+		return errors.Wrap(err, "failed to create tag table")
+	}
+	return nil
+}
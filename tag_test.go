@@ -0,0 +1,69 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if tags, err := ListTags(db); err != nil || len(tags) != 0 {
+		t.Errorf("expected no tags, got %v (%v)", tags, err)
+	}
+	if _, err := GetTag(db, "v1.0.0"); err == nil {
+		t.Error("expected an error for a missing tag")
+	}
+	if tagTableExists(db) {
+		t.Error("expected the reads not to create the tag table")
+	}
+
+	if err := TagVersion(db, "v1.0.0", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := TagVersion(db, "v2.0.0", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := TagVersion(db, "v1.0.0", 2); err == nil {
+		t.Errorf("expected an error moving a tag")
+	}
+
+	if err := UpToTag(db, dir, "v2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := GetDBVersion(db); err != nil || current != 3 {
+		t.Errorf("unexpected version after up-to-tag, got %d (%v), want 3", current, err)
+	}
+	if err := DownToTag(db, dir, "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := GetDBVersion(db); err != nil || current != 1 {
+		t.Errorf("unexpected version after down-to-tag, got %d (%v), want 1", current, err)
+	}
+	if err := UpToTag(db, dir, "v3.0.0"); err == nil {
+		t.Errorf("expected an error for a missing tag")
+	}
+
+	tags, err := ListTags(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0].Name != "v1.0.0" || tags[1].Version != 3 {
+		t.Errorf("unexpected tags %+v", tags)
+	}
+
+	defer SetTableName(TableName())
+	SetTableName("other_version")
+	if err := TagVersion(db, "other", 2); err != nil {
+		t.Fatal(err)
+	}
+	SetTableName("goose_db_version")
+	if err := DownToTag(db, dir, "other", WithTableName("other_version")); err != nil {
+		t.Fatal(err)
+	}
+	if err := DownToTag(db, dir, "other"); err == nil {
+		t.Error("expected the tag to be resolved in the tag table of the options")
+	}
+}
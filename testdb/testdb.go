@@ -0,0 +1,384 @@
+// +build go1.14
+
+// Package testdb creates databases migrated with goose for tests, a uniquely
+// named database per test, so that tests can run in parallel:
+//
+//	func TestUsers(t *testing.T) {
+//		t.Parallel()
+//		db := testdb.New(t, os.Getenv("TEST_DATABASE_URL"), "../migrations")
+//		// ...
+//	}
+//
+// On Postgres, the migrations are applied once to a template database,
+// cloned for each test with CREATE DATABASE ... TEMPLATE, and cached for the
+// next runs as long as the migrations are unchanged.
+package testdb
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/loderunner/goose"
+	"github.com/pkg/errors"
+)
+
+var (
+	// mu serializes the migrations, since the settings of goose are global,
+	// and the clones of the templates, which Postgres rejects while the
+	// template is in use.
+	mu        sync.Mutex
+	templates = map[string]string{} // template database by DSN and directory
+	pooling   = true
+	caching   = true
+)
+
+const templatePrefix = "goose_template_"
+
+// SetTemplateCache sets whether the templates are kept for the next runs,
+// named after a hash of the migrations, so that a run with unchanged
+// migrations clones them without migrating at all. Enabled by default;
+// disabled, the templates are dropped by Close.
+func SetTemplateCache(enabled bool) {
+	caching = enabled
+}
+
+// SetTemplates sets whether the migrations are applied once to a template
+// database cloned for each test, on Postgres. Enabled by default.
+func SetTemplates(enabled bool) {
+	pooling = enabled
+}
+
+// New creates a database named uniquely for t on the server of dsn,
+// migrated up with the migrations of dir, and dropped when t completes.
+//
+// dsn is a postgres:// or postgresql:// URL, a mysql:// URL followed by a
+// DSN of github.com/go-sql-driver/mysql, or sqlite3:// for a file in a
+// temporary directory. The drivers are imported by the tests.
+func New(t testing.TB, dsn, dir string) *sql.DB {
+	t.Helper()
+	db, err := create(t, dsn, dir)
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	return db
+}
+
+func create(t testing.TB, dsn, dir string) (*sql.DB, error) {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return createPostgres(t, dsn, dir)
+	case strings.HasPrefix(dsn, "mysql://"):
+		return createMySQL(t, strings.TrimPrefix(dsn, "mysql://"), dir)
+	case strings.HasPrefix(dsn, "sqlite3://"):
+		tmp, err := ioutil.TempDir("", "goose-testdb")
+		if err != nil {
+			return nil, err
+		}
+		t.Cleanup(func() { os.RemoveAll(tmp) })
+		db, err := open(t, "sqlite3", filepath.Join(tmp, "test.db"))
+		if err != nil {
+			return nil, err
+		}
+		return db, migrate(t, "sqlite3", db, dir)
+	default:
+		return nil, errors.Errorf("unsupported DSN %q, expected a postgres://, mysql:// or sqlite3:// URL", dsn)
+	}
+}
+
+func createPostgres(t testing.TB, dsn, dir string) (*sql.DB, error) {
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+
+	name := uniqueName("goose_test_")
+	if !pooling {
+		if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+			return nil, errors.Wrap(err, "failed to create database")
+		}
+		dropOnCleanup(t, "postgres", dsn, name)
+		db, err := open(t, "postgres", withDatabase(dsn, name))
+		if err != nil {
+			return nil, err
+		}
+		return db, migrate(t, "postgres", db, dir)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	tmpl, err := template(t, admin, dsn, dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := admin.Exec(fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", name, tmpl)); err != nil {
+		return nil, errors.Wrapf(err, "failed to clone template %s", tmpl)
+	}
+	dropOnCleanup(t, "postgres", dsn, name)
+	return open(t, "postgres", withDatabase(dsn, name))
+}
+
+// template returns the template database of the migrations of dir on the
+// server of dsn, creating and migrating it on first use.
+func template(t testing.TB, admin *sql.DB, dsn, dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	key := dsn + "\x00" + abs
+	if name, ok := templates[key]; ok {
+		return name, nil
+	}
+
+	name := uniqueName(templatePrefix)
+	if caching {
+		hash, err := migrationsHash(dir)
+		if err != nil {
+			return "", err
+		}
+		name = templatePrefix + hash
+		var exists bool
+		if err := admin.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists); err != nil {
+			return "", errors.Wrap(err, "failed to look up template database")
+		}
+		if exists {
+			templates[key] = name
+			return name, nil
+		}
+	}
+
+	// The template is migrated under a temporary name, then renamed, so
+	// that a failed or concurrent run never leaves a half migrated template
+	// behind its name.
+	tmp := uniqueName(name + "_")
+	if _, err := admin.Exec("CREATE DATABASE " + tmp); err != nil {
+		return "", errors.Wrap(err, "failed to create template database")
+	}
+	db, err := sql.Open("postgres", withDatabase(dsn, tmp))
+	if err != nil {
+		return "", err
+	}
+	// Postgres only clones templates without connections.
+	err = migrateLocked(t, "postgres", db, dir)
+	db.Close()
+	if err == nil {
+		_, err = admin.Exec(fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", tmp, name))
+		if err != nil && caching {
+			// Another run just created the same template.
+			var exists bool
+			if admin.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists) == nil && exists {
+				err = nil
+			}
+			admin.Exec("DROP DATABASE IF EXISTS " + tmp)
+		}
+	}
+	if err != nil {
+		admin.Exec("DROP DATABASE IF EXISTS " + tmp)
+		return "", err
+	}
+	templates[key] = name
+	return name, nil
+}
+
+// migrationsHash returns a hash of the names and contents of the migrations
+// of dir, naming the template cached for them.
+func migrationsHash(dir string) (string, error) {
+	migrations, err := goose.CollectMigrations(dir, 0, goose.MaxVersion)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, m := range migrations {
+		fmt.Fprintf(h, "%d %s\n", m.Version, filepath.Base(m.Source))
+		// Go migrations registered from another directory hash by name.
+		if data, err := ioutil.ReadFile(m.Source); err == nil {
+			h.Write(data)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// Close drops the template databases created for this run, typically from
+// TestMain once the tests ran:
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		testdb.Close()
+//		os.Exit(code)
+//	}
+//
+// The templates cached for the next runs are kept, see DropTemplates.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if caching {
+		return nil
+	}
+	for key, name := range templates {
+		dsn := strings.SplitN(key, "\x00", 2)[0]
+		if err := dropDatabase(dsn, name); err != nil {
+			return err
+		}
+		delete(templates, key)
+	}
+	return nil
+}
+
+// DropTemplates drops the template databases cached on the server of dsn,
+// those of past versions of the migrations accumulating over time.
+func DropTemplates(dsn string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	rows, err := admin.Query("SELECT datname FROM pg_database WHERE datname LIKE $1", templatePrefix+"%")
+	if err != nil {
+		return errors.Wrap(err, "failed to list template databases")
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	for _, name := range names {
+		if err := dropDatabase(dsn, name); err != nil {
+			return err
+		}
+	}
+	for key := range templates {
+		if strings.HasPrefix(key, dsn+"\x00") {
+			delete(templates, key)
+		}
+	}
+	return nil
+}
+
+func dropDatabase(dsn, name string) error {
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name); err != nil {
+		return errors.Wrapf(err, "failed to drop template %s", name)
+	}
+	return nil
+}
+
+func createMySQL(t testing.TB, dsn, dir string) (*sql.DB, error) {
+	admin, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+
+	name := uniqueName("goose_test_")
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		return nil, errors.Wrap(err, "failed to create database")
+	}
+	dropOnCleanup(t, "mysql", dsn, name)
+
+	// The database of a DSN of github.com/go-sql-driver/mysql follows the
+	// last slash, before the parameters.
+	base, params := dsn, ""
+	if i := strings.Index(dsn, "?"); i >= 0 {
+		base, params = dsn[:i], dsn[i:]
+	}
+	if i := strings.LastIndex(base, "/"); i >= 0 {
+		base = base[:i]
+	}
+	db, err := open(t, "mysql", base+"/"+name+params)
+	if err != nil {
+		return nil, err
+	}
+	return db, migrate(t, "mysql", db, dir)
+}
+
+// open opens a database closed when t completes.
+func open(t testing.TB, driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, nil
+}
+
+// dropOnCleanup drops the database name of the server of dsn when t
+// completes. Registered before the database is opened, it runs once the
+// database is closed.
+func dropOnCleanup(t testing.TB, driver, dsn, name string) {
+	t.Cleanup(func() {
+		admin, err := sql.Open(driver, dsn)
+		if err != nil {
+			t.Logf("testdb: failed to drop %s: %v", name, err)
+			return
+		}
+		defer admin.Close()
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name); err != nil {
+			t.Logf("testdb: failed to drop %s: %v", name, err)
+		}
+	})
+}
+
+func migrate(t testing.TB, dialect string, db *sql.DB, dir string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return migrateLocked(t, dialect, db, dir)
+}
+
+func migrateLocked(t testing.TB, dialect string, db *sql.DB, dir string) error {
+	if err := goose.SetDialect(dialect); err != nil {
+		return err
+	}
+	return goose.Up(db, dir, goose.WithLogger(testLogger{t}))
+}
+
+// withDatabase returns the URL dsn, connecting to the database name.
+func withDatabase(dsn, name string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return dsn
+	}
+	u.Path = "/" + name
+	return u.String()
+}
+
+func uniqueName(prefix string) string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return prefix + hex.EncodeToString(b)
+}
+
+// testLogger logs the output of goose to the log of a test.
+type testLogger struct {
+	t testing.TB
+}
+
+func (l testLogger) Fatal(v ...interface{})                 { l.t.Fatal(v...) }
+func (l testLogger) Fatalf(format string, v ...interface{}) { l.t.Fatalf(format, v...) }
+func (l testLogger) Print(v ...interface{})                 { l.t.Log(v...) }
+func (l testLogger) Println(v ...interface{})               { l.t.Log(v...) }
+func (l testLogger) Printf(format string, v ...interface{}) {
+	l.t.Logf(strings.TrimSuffix(format, "\n"), v...)
+}
@@ -0,0 +1,53 @@
+package goose
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var tidbBatchSize int
+
+// tidbBatchable matches the data statements TiDB can split into batches:
+// single-table UPDATE, DELETE and INSERT ... SELECT.
+var tidbBatchable = regexp.MustCompile(`(?is)^\s*(?:UPDATE|DELETE|(?:INSERT|REPLACE)\s.*\bSELECT)\b`)
+
+// SetTiDBBatchSize splits the UPDATE, DELETE and INSERT ... SELECT
+// statements of the migrations running without a transaction into batches
+// of size rows on TiDB, with its non-transactional DML statements, so that
+// large data migrations don't exceed the transaction size limit of TiDB.
+// The statements must touch a single table. A size of 0, the default,
+// disables batching.
+func SetTiDBBatchSize(size int) {
+	tidbBatchSize = size
+}
+
+// tidbBatch returns query as a TiDB non-transactional DML statement, if
+// batching is enabled and query is a batchable data statement.
+func tidbBatch(query string) string {
+	if _, ok := GetDialect().(*TiDBDialect); !ok || tidbBatchSize <= 0 {
+		return query
+	}
+	stmt := stripSQLComments(query)
+	if !tidbBatchable.MatchString(stmt) {
+		return query
+	}
+	verboseInfo("Batching statement by %d rows", tidbBatchSize)
+	return fmt.Sprintf("BATCH LIMIT %d %s", tidbBatchSize, strings.TrimSpace(stmt))
+}
+
+// tidbSchemaChangedRetries is the number of times a migration transaction
+// is retried after failing on a concurrent schema change.
+const tidbSchemaChangedRetries = 3
+
+// tidbSchemaChanged reports whether err is the error of a TiDB transaction
+// using a table whose schema was changed by a concurrent DDL statement,
+// which happens without metadata locks: before TiDB 6.3, or with
+// tidb_enable_metadata_lock disabled.
+func tidbSchemaChanged(err error) bool {
+	if _, ok := GetDialect().(*TiDBDialect); !ok || err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "Error 8028") || strings.Contains(msg, "Information schema is changed")
+}
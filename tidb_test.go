@@ -0,0 +1,63 @@
+package goose
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTiDBBatch(t *testing.T) {
+	defer SetDialect("postgres")
+	defer SetTiDBBatchSize(0)
+	if err := SetDialect("tidb"); err != nil {
+		t.Fatal(err)
+	}
+
+	query := "-- Archive old orders.\nUPDATE orders SET status = 'archived' WHERE created_at < '2020-01-01';\n"
+	if got := tidbBatch(query); got != query {
+		t.Errorf("expected no batching by default, got %q", got)
+	}
+
+	SetTiDBBatchSize(1000)
+	tt := []struct {
+		query string
+		want  string
+	}{
+		{query, "BATCH LIMIT 1000 UPDATE orders SET status = 'archived' WHERE created_at < '2020-01-01';"},
+		{"DELETE FROM sessions WHERE expired;", "BATCH LIMIT 1000 DELETE FROM sessions WHERE expired;"},
+		{"INSERT INTO archive SELECT * FROM orders;", "BATCH LIMIT 1000 INSERT INTO archive SELECT * FROM orders;"},
+		{"INSERT INTO orders VALUES (1);", "INSERT INTO orders VALUES (1);"},
+		{"ALTER TABLE orders ADD COLUMN status text;", "ALTER TABLE orders ADD COLUMN status text;"},
+	}
+	for _, tc := range tt {
+		if got := tidbBatch(tc.query); got != tc.want {
+			t.Errorf("%q: expected %q, got %q", tc.query, tc.want, got)
+		}
+	}
+
+	if err := SetDialect("mysql"); err != nil {
+		t.Fatal(err)
+	}
+	if got := tidbBatch(query); got != query {
+		t.Errorf("expected no batching on MySQL, got %q", got)
+	}
+}
+
+func TestTiDBSchemaChanged(t *testing.T) {
+	defer SetDialect("postgres")
+	err := errors.New("Error 8028: Information schema is changed during the execution of the statement")
+	if err := SetDialect("tidb"); err != nil {
+		t.Fatal(err)
+	}
+	if !tidbSchemaChanged(err) {
+		t.Error("expected a schema change error")
+	}
+	if tidbSchemaChanged(errors.New("Error 1062: Duplicate entry")) || tidbSchemaChanged(nil) {
+		t.Error("expected other errors not to be schema changes")
+	}
+	if err := SetDialect("mysql"); err != nil {
+		t.Fatal(err)
+	}
+	if tidbSchemaChanged(err) {
+		t.Error("expected no schema change error on MySQL")
+	}
+}
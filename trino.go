@@ -0,0 +1,13 @@
+package goose
+
+import "strings"
+
+// trinoStatement returns query without its trailing semicolon on Trino,
+// which runs a single statement per query, and rejects the semicolons
+// ending them.
+func trinoStatement(query string) string {
+	if _, ok := GetDialect().(*TrinoDialect); !ok {
+		return query
+	}
+	return strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+}
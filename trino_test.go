@@ -0,0 +1,44 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrinoDialect(t *testing.T) {
+	defer SetDialect("postgres")
+	defer SetTableName(TableName())
+	if err := SetDialect("trino"); err != nil {
+		t.Fatal(err)
+	}
+	SetTableName("iceberg.analytics.goose_db_version")
+
+	if transactional() {
+		t.Error("expected Trino migrations to run without a transaction")
+	}
+	if q := GetDialect().insertVersionSQL(); !strings.Contains(q, "current_timestamp(6)") {
+		t.Errorf("expected the timestamp to be inserted, got %q", q)
+	}
+	applied := true
+	q, args := listAppliedQuery(ListFilter{IsApplied: &applied, Limit: 10, Offset: 20})
+	if !strings.Contains(q, "FROM iceberg.analytics.goose_db_version") || !strings.Contains(q, "HAVING max_by(is_applied, tstamp) = ?") || len(args) != 1 {
+		t.Errorf("expected the latest record by timestamp, got %q %v", q, args)
+	}
+	if !strings.HasSuffix(q, " OFFSET 20 LIMIT 10") {
+		t.Errorf("expected OFFSET before LIMIT, got %q", q)
+	}
+
+	if s := trinoStatement("CREATE TABLE events (id bigint);\n"); s != "CREATE TABLE events (id bigint)" {
+		t.Errorf("expected the trailing semicolon to be trimmed, got %q", s)
+	}
+	if _, err := genCreateIndex([]string{"events", "id"}); err == nil {
+		t.Error("expected create-index to be unsupported")
+	}
+
+	if err := SetDialect("postgres"); err != nil {
+		t.Fatal(err)
+	}
+	if s := trinoStatement("SELECT 1;"); s != "SELECT 1;" {
+		t.Errorf("expected the statement to be kept on Postgres, got %q", s)
+	}
+}
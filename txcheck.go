@@ -0,0 +1,92 @@
+package goose
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// pgNoTxStatement matches the Postgres statements that can't run in a
+	// transaction block.
+	pgNoTxStatement = regexp.MustCompile(`(?is)^\s*(?:(?:CREATE(?:\s+UNIQUE)?|DROP)\s+INDEX\s+CONCURRENTLY|REINDEX\s.*\sCONCURRENTLY|VACUUM|(?:CREATE|DROP)\s+(?:DATABASE|TABLESPACE)|ALTER\s+SYSTEM)\b`)
+	// mysqlImplicitCommit matches the MySQL DDL statements implicitly
+	// committing the current transaction.
+	mysqlImplicitCommit = regexp.MustCompile(`(?is)^\s*(?:CREATE|ALTER|DROP|RENAME|TRUNCATE)\s`)
+	mysqlTemporary      = regexp.MustCompile(`(?is)^\s*(?:CREATE|DROP)\s+TEMPORARY\s`)
+	// mssqlNoTxStatement matches the SQL Server statements that can't run
+	// in a transaction.
+	mssqlNoTxStatement = regexp.MustCompile(`(?is)^\s*(?:(?:CREATE|ALTER|DROP)\s+DATABASE|BACKUP|RESTORE)\b`)
+	// sqliteNoTxStatement matches the SQLite statements that can't run in
+	// a transaction.
+	sqliteNoTxStatement = regexp.MustCompile(`(?is)^\s*VACUUM\b`)
+	// firebirdCreateTable and firebirdDML match the Firebird statements
+	// creating a table, and writing to one: Firebird creates tables when
+	// their transaction commits, they can't be written before.
+	firebirdCreateTable = regexp.MustCompile(`(?is)^\s*(?:RECREATE|CREATE(?:\s+OR\s+ALTER)?)\s+TABLE\s+([\w$."]+)`)
+	firebirdDML         = regexp.MustCompile(`(?is)^\s*(?:INSERT\s+INTO|UPDATE|DELETE\s+FROM|MERGE\s+INTO|UPDATE\s+OR\s+INSERT\s+INTO)\s+([\w$."]+)`)
+)
+
+// txCheck detects the statements of a SQL migration that break its
+// transaction, as they are parsed.
+type txCheck struct {
+	count    int
+	noTx     string // first statement that can't run in a transaction
+	implicit string // first statement implicitly committing the transaction
+	created  map[string]bool
+}
+
+func (c *txCheck) add(stmt string) {
+	c.count++
+	stmt = stripSQLComments(stmt)
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		if c.noTx == "" && pgNoTxStatement.MatchString(stmt) {
+			c.noTx = stmt
+		}
+	case *RedshiftDialect:
+		if c.noTx == "" && redshiftNoTxStatement.MatchString(stmt) {
+			c.noTx = stmt
+		}
+	case *MySQLDialect, *MariaDBDialect, *TiDBDialect:
+		if c.implicit == "" && mysqlImplicitCommit.MatchString(stmt) && !mysqlTemporary.MatchString(stmt) {
+			c.implicit = stmt
+		}
+	case *SqlServerDialect:
+		if c.noTx == "" && mssqlNoTxStatement.MatchString(stmt) {
+			c.noTx = stmt
+		}
+	case *Sqlite3Dialect, *LibSQLDialect:
+		if c.noTx == "" && sqliteNoTxStatement.MatchString(stmt) {
+			c.noTx = stmt
+		}
+	case *FirebirdDialect:
+		if m := firebirdCreateTable.FindStringSubmatch(stmt); m != nil {
+			if c.created == nil {
+				c.created = map[string]bool{}
+			}
+			c.created[strings.ToUpper(m[1])] = true
+		} else if m := firebirdDML.FindStringSubmatch(stmt); m != nil && c.noTx == "" && c.created[strings.ToUpper(m[1])] {
+			c.noTx = stmt
+		}
+	}
+}
+
+// err returns an error if m runs in a transaction, and has a statement
+// that can't run in one, or a statement implicitly committing it along
+// with other statements, which would be left half-applied if a later
+// statement failed.
+func (c *txCheck) err(m *Migration, a sqlAnnotations) error {
+	if !a.useTx {
+		return nil
+	}
+	if c.noTx != "" {
+		return errors.Errorf("ERROR %v: %q can't run in a transaction: add '-- +goose NO TRANSACTION' to the migration", filepath.Base(m.Source), summarizeStatement(c.noTx))
+	}
+	if c.implicit != "" && c.count > 1 {
+		return errors.Errorf("ERROR %v: %q implicitly commits the transaction of the migration, which can't be rolled back if a statement fails: add '-- +goose NO TRANSACTION' to the migration, or split it", filepath.Base(m.Source), summarizeStatement(c.implicit))
+	}
+	return nil
+}
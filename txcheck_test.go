@@ -0,0 +1,52 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTxCheck(t *testing.T) {
+	defer SetDialect("postgres")
+	m := &Migration{Source: "00001_test.sql"}
+	tx, noTx := sqlAnnotations{useTx: true}, sqlAnnotations{}
+
+	tests := []struct {
+		dialect    string
+		statements []string
+		a          sqlAnnotations
+		want       string
+	}{
+		{"postgres", []string{"CREATE INDEX CONCURRENTLY a_idx ON a (id);"}, tx, "can't run in a transaction"},
+		{"postgres", []string{"-- unique\ncreate unique index concurrently a_idx on a (id);"}, tx, "can't run in a transaction"},
+		{"postgres", []string{"CREATE INDEX CONCURRENTLY a_idx ON a (id);"}, noTx, ""},
+		{"postgres", []string{"CREATE TABLE a (id int);", "CREATE INDEX a_idx ON a (id);"}, tx, ""},
+		{"mysql", []string{"CREATE TABLE a (id int);"}, tx, ""},
+		{"mysql", []string{"INSERT INTO a VALUES (1);", "ALTER TABLE a ADD COLUMN b int;"}, tx, "implicitly commits"},
+		{"mysql", []string{"CREATE TEMPORARY TABLE t (id int);", "INSERT INTO t VALUES (1);"}, tx, ""},
+		{"mysql", []string{"INSERT INTO a VALUES (1);", "ALTER TABLE a ADD COLUMN b int;"}, noTx, ""},
+		{"sqlite3", []string{"VACUUM;"}, tx, "can't run in a transaction"},
+		{"redshift", []string{"ALTER TABLE events ALTER COLUMN name TYPE varchar(512);"}, tx, "can't run in a transaction"},
+		{"redshift", []string{"ALTER TABLE events APPEND FROM staging_events;"}, tx, "can't run in a transaction"},
+		{"redshift", []string{"CREATE EXTERNAL TABLE spectrum.events (id int) LOCATION 's3://bucket/events/';"}, tx, "can't run in a transaction"},
+		{"redshift", []string{"ALTER TABLE events ADD COLUMN name varchar(256);"}, tx, ""},
+		{"firebird", []string{"CREATE TABLE plans (id int);", "insert into PLANS values (1);"}, tx, "can't run in a transaction"},
+		{"firebird", []string{"CREATE TABLE plans (id int);", "INSERT INTO plans VALUES (1);"}, noTx, ""},
+		{"firebird", []string{"CREATE TABLE plans (id int);", "INSERT INTO users VALUES (1);"}, tx, ""},
+	}
+	for _, test := range tests {
+		if err := SetDialect(test.dialect); err != nil {
+			t.Fatal(err)
+		}
+		var check txCheck
+		for _, stmt := range test.statements {
+			check.add(stmt)
+		}
+		err := check.err(m, test.a)
+		if test.want == "" && err != nil {
+			t.Errorf("%s %q: unexpected error %v", test.dialect, test.statements, err)
+		}
+		if test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)) {
+			t.Errorf("%s %q: expected an error containing %q, got %v", test.dialect, test.statements, test.want, err)
+		}
+	}
+}
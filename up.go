@@ -2,14 +2,53 @@ package goose
 
 import (
 	"database/sql"
+
+	"github.com/pkg/errors"
 )
 
 // UpTo migrates up to a specific version.
-func UpTo(db *sql.DB, dir string, version int64) error {
-	migrations, err := CollectMigrations(dir, minVersion, version)
+func UpTo(db *sql.DB, dir string, version int64, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return upToLocked(db, dir, version)
+	})
+}
+
+// upToLocked is UpTo for the callers holding optionsMu.
+func upToLocked(db *sql.DB, dir string, version int64) error {
+	return invoke("up-to", db, func() error {
+		return upTo(db, dir, version)
+	})
+}
+
+func upTo(db *sql.DB, dir string, version int64) error {
+	migrations, err := collectMigrations(dir, minVersion, version, options{})
 	if err != nil {
 		return err
 	}
+	migrations = ungated(migrations)
+
+	if noVersioning {
+		for _, m := range migrations {
+			if err := m.Up(db); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := upBaseline(db, migrations, version); err != nil {
+		return err
+	}
+
+	// Gated migrations skipped until their gate was enabled are missing
+	// ones: apply them even if missing migrations aren't allowed.
+	missing := migrations
+	if !allowMissing {
+		missing = openedGates(migrations)
+	}
+	if err := upMissing(db, missing); err != nil {
+		return err
+	}
 
 	for {
 		current, err := GetDBVersion(db)
@@ -33,16 +72,55 @@ func UpTo(db *sql.DB, dir string, version int64) error {
 }
 
 // Up applies all available migrations.
-func Up(db *sql.DB, dir string) error {
-	return UpTo(db, dir, maxVersion)
+func Up(db *sql.DB, dir string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return upLocked(db, dir)
+	})
+}
+
+// upLocked is Up for the callers holding optionsMu.
+func upLocked(db *sql.DB, dir string) error {
+	return invoke("up", db, func() error {
+		return upTo(db, dir, maxVersion)
+	})
 }
 
 // UpByOne migrates up by a single version.
-func UpByOne(db *sql.DB, dir string) error {
-	migrations, err := CollectMigrations(dir, minVersion, maxVersion)
+func UpByOne(db *sql.DB, dir string, opts ...OptionsFunc) error {
+	return withOptions(opts, func() error {
+		return upByOneLocked(db, dir)
+	})
+}
+
+// upByOneLocked is UpByOne for the callers holding optionsMu.
+func upByOneLocked(db *sql.DB, dir string) error {
+	return invoke("up-by-one", db, func() error {
+		return upByOne(db, dir)
+	})
+}
+
+func upByOne(db *sql.DB, dir string) error {
+	if noVersioning {
+		return errors.New("up-by-one requires the version table, it can't run without versioning")
+	}
+
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
 	if err != nil {
 		return err
 	}
+	migrations = ungated(migrations)
+
+	missing := migrations
+	if !allowMissing {
+		missing = openedGates(migrations)
+	}
+	missing, err = missingMigrations(db, missing)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return missing[0].Up(db)
+	}
 
 	currentVersion, err := GetDBVersion(db)
 	if err != nil {
@@ -63,3 +141,41 @@ func UpByOne(db *sql.DB, dir string) error {
 
 	return nil
 }
+
+// missingMigrations returns the pending migrations older than the current
+// version, in order.
+func missingMigrations(db *sql.DB, migrations Migrations) (Migrations, error) {
+	if len(migrations) == 0 {
+		return nil, nil
+	}
+	current, err := GetDBVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := dbMigrationsStatus(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get status of migrations")
+	}
+
+	var missing Migrations
+	for _, m := range migrations {
+		if m.Version < current && !statuses[m.Version] {
+			missing = append(missing, m)
+		}
+	}
+	return missing, nil
+}
+
+// upMissing applies the missing migrations, in order.
+func upMissing(db *sql.DB, migrations Migrations) error {
+	missing, err := missingMigrations(db, migrations)
+	if err != nil {
+		return err
+	}
+	for _, m := range missing {
+		if err := m.Up(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
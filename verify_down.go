@@ -0,0 +1,85 @@
+package goose
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// VerifyDown checks that the down migrations revert their up migrations,
+// on scratch, an empty database. It applies the migrations of dir up to
+// from, then for each migration after it: applies it, rolls it back and
+// applies it again, comparing the tables and columns of scratch after each
+// step. It returns an error for the first migration whose down doesn't
+// restore the schema from before its up, or whose up doesn't produce the
+// same schema again. Indexes and constraints are not compared.
+//
+// It supports Postgres, MySQL and SQLite.
+func VerifyDown(scratch *sql.DB, dir string, from int64) error {
+	if schemaColumnsQuery() == "" {
+		return errors.New("verifying down migrations is not supported by this dialect")
+	}
+
+	tables, err := readSchema(scratch)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the schema of the scratch database")
+	}
+	if len(tables) > 0 {
+		return errors.New("the scratch database must be empty")
+	}
+	migrations, err := collectMigrations(dir, minVersion, maxVersion, options{})
+	if err != nil {
+		return err
+	}
+	// Bypass invoke, like ScaffoldSchemaDiff: the scratch database is
+	// thrown away.
+	if err := upTo(scratch, dir, from); err != nil {
+		return errors.Wrap(err, "failed to apply migrations to the scratch database")
+	}
+
+	verified := 0
+	for _, m := range migrations.Filter(from, maxVersion) {
+		before, err := readSchema(scratch)
+		if err != nil {
+			return errors.Wrap(err, "failed to read the schema of the scratch database")
+		}
+		if err := m.Up(scratch); err != nil {
+			return err
+		}
+		after, err := readSchema(scratch)
+		if err != nil {
+			return errors.Wrap(err, "failed to read the schema of the scratch database")
+		}
+		if err := m.Down(scratch); err != nil {
+			return err
+		}
+		if err := compareSchemas(scratch, before); err != nil {
+			return errors.Wrapf(err, "%v: down doesn't revert up", filepath.Base(m.Source))
+		}
+		if err := m.Up(scratch); err != nil {
+			return errors.Wrapf(err, "%v: up fails after down", filepath.Base(m.Source))
+		}
+		if err := compareSchemas(scratch, after); err != nil {
+			return errors.Wrapf(err, "%v: up after down doesn't produce the same schema", filepath.Base(m.Source))
+		}
+		verified++
+	}
+
+	log.Printf("goose: verified the down migrations of %d migrations\n", verified)
+	return nil
+}
+
+// compareSchemas returns an error if the schema of db differs from want,
+// with the DDL restoring want.
+func compareSchemas(db *sql.DB, want map[string]*schemaTable) error {
+	got, err := readSchema(db)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the schema of the scratch database")
+	}
+	if diff, _ := diffSchemas(got, want); len(diff) > 0 {
+		return errors.Errorf("the schema differs by: %s", strings.Join(diff, " "))
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package goose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyDown(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	scratch, cleanup := openTestDB(t)
+	defer cleanup()
+	if err := VerifyDown(scratch, dir, 0); err != nil {
+		t.Errorf("unexpected error verifying down migrations reverting their up: %v", err)
+	}
+	if err := VerifyDown(scratch, dir, 0); err == nil {
+		t.Errorf("expected an error verifying on a database that isn't empty")
+	}
+
+	dir, cleanupDir = writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\n-- +goose Down\nDROP TABLE a;\n",
+		"00002_create_b.sql": "-- +goose Up\nCREATE TABLE b (id int);\n-- +goose Down\nSELECT 1;\n",
+	})
+	defer cleanupDir()
+	scratch, cleanup = openTestDB(t)
+	defer cleanup()
+	err := VerifyDown(scratch, dir, 1)
+	if err == nil || !strings.Contains(err.Error(), "00002_create_b.sql: down doesn't revert up") {
+		t.Errorf("expected an error for the down migration not reverting its up, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+// Package version parses, compares, formats and increments the versions of
+// goose migrations, the numeric prefixes of their file names, in both
+// schemes of goose: sequential versions, like 00042_add_users.sql, and
+// timestamps, like 20240102150405_add_users.sql, written by goose create
+// and renumbered to sequential versions by goose fix. External generators
+// use it to name migrations consistently with goose:
+//
+//	v := version.Next(versions, version.Timestamp, time.Now())
+//	name := version.Format(v, version.Timestamp) + "_add_users.sql"
+package version
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Scheme is a versioning scheme of migrations.
+type Scheme int
+
+const (
+	// Sequential versions are numbered 1, 2, 3..., formatted with at least
+	// SequentialWidth digits.
+	Sequential Scheme = iota
+	// Timestamp versions are the times migrations were created at, in
+	// TimestampFormat.
+	Timestamp
+)
+
+const (
+	// TimestampFormat is the time layout of timestamp versions.
+	TimestampFormat = "20060102150405"
+	// SequentialWidth is the minimum number of digits of sequential
+	// versions, padded with zeros.
+	SequentialWidth = 5
+)
+
+// Parse returns the version of a migration file name, its numeric prefix
+// before the first underscore. Versions are greater than zero.
+func Parse(filename string) (int64, error) {
+	base := filepath.Base(filename)
+	idx := strings.Index(base, "_")
+	if idx < 0 {
+		return 0, errors.New("no separator found")
+	}
+	n, err := strconv.ParseInt(base[:idx], 10, 64)
+	if err == nil && n <= 0 {
+		return 0, errors.New("migration IDs must be greater than zero")
+	}
+	return n, err
+}
+
+// Time returns the time of a timestamp version, and false if v is not one.
+func Time(v int64) (time.Time, bool) {
+	t, err := time.Parse(TimestampFormat, strconv.FormatInt(v, 10))
+	if err != nil || !t.After(time.Unix(0, 0)) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// SchemeOf returns the scheme of v: Timestamp if it is a valid timestamp
+// after 1970, Sequential otherwise.
+func SchemeOf(v int64) Scheme {
+	if _, ok := Time(v); ok {
+		return Timestamp
+	}
+	return Sequential
+}
+
+// FromTime returns the timestamp version of t, to the second.
+func FromTime(t time.Time) int64 {
+	v, _ := strconv.ParseInt(t.Format(TimestampFormat), 10, 64)
+	return v
+}
+
+// Format formats v in the scheme s, as in the names of migration files.
+func Format(v int64, s Scheme) string {
+	if s == Sequential {
+		return fmt.Sprintf("%0*d", SequentialWidth, v)
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// Compare returns -1 if a is older than b, 1 if it is newer, and 0 if they
+// are equal. Sequential versions are older than timestamps, like goose
+// orders them.
+func Compare(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Next returns the version following the versions of the scheme s: the
+// last sequential version plus one, or the timestamp of now, after the
+// last timestamp. Versions of the other scheme are ignored.
+func Next(versions []int64, s Scheme, now time.Time) int64 {
+	var last int64
+	for _, v := range versions {
+		if SchemeOf(v) == s && v > last {
+			last = v
+		}
+	}
+	if s == Sequential {
+		return last + 1
+	}
+	next := FromTime(now)
+	if t, ok := Time(last); ok && next <= last {
+		next = FromTime(t.Add(time.Second))
+	}
+	return next
+}
@@ -0,0 +1,59 @@
+package version
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		filename string
+		version  int64
+		ok       bool
+	}{
+		{"00042_add_users.sql", 42, true},
+		{"migrations/20240102150405_add_users.go", 20240102150405, true},
+		{"00000_zero.sql", 0, false},
+		{"add_users.sql", 0, false},
+		{"nounderscore.sql", 0, false},
+	}
+	for _, test := range tests {
+		v, err := Parse(test.filename)
+		if (err == nil) != test.ok || v != test.version {
+			t.Errorf("Parse(%q) = %d, %v", test.filename, v, err)
+		}
+	}
+}
+
+func TestScheme(t *testing.T) {
+	if SchemeOf(42) != Sequential || SchemeOf(20240102150405) != Timestamp || SchemeOf(20241302150405) != Sequential {
+		t.Error("unexpected schemes")
+	}
+	if got := Format(42, Sequential); got != "00042" {
+		t.Errorf("expected 00042, got %s", got)
+	}
+	if got := Format(20240102150405, Timestamp); got != "20240102150405" {
+		t.Errorf("expected 20240102150405, got %s", got)
+	}
+	if Compare(42, 20240102150405) != -1 || Compare(3, 3) != 0 || Compare(4, 3) != 1 {
+		t.Error("unexpected comparisons")
+	}
+}
+
+func TestNext(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	versions := []int64{1, 2, 20240102150400}
+	if got := Next(versions, Sequential, now); got != 3 {
+		t.Errorf("expected 3, got %d", got)
+	}
+	if got := Next(nil, Sequential, now); got != 1 {
+		t.Errorf("expected 1, got %d", got)
+	}
+	if got := Next(versions, Timestamp, now); got != 20240102150405 {
+		t.Errorf("expected the timestamp of now, got %d", got)
+	}
+	// A later timestamp, created on a machine with a clock ahead.
+	if got := Next(append(versions, 20240102150459), Timestamp, now); got != 20240102150500 {
+		t.Errorf("expected the second after the last timestamp, got %d", got)
+	}
+}
@@ -0,0 +1,103 @@
+package goose
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// versionCacheKey identifies the version table of a database.
+type versionCacheKey struct {
+	db     *sql.DB
+	table  string
+	ensure bool // read by EnsureDBVersion, creating the table
+}
+
+type cachedVersion struct {
+	version int64
+	read    time.Time
+}
+
+var versionCache struct {
+	sync.Mutex
+	ttl      time.Duration
+	running  int // commands holding the lock, reading the version table directly
+	cleared  int // number of times the cache was cleared
+	versions map[versionCacheKey]cachedVersion
+}
+
+// SetVersionCache sets how long the current version read by GetDBVersion,
+// Pending and VersionHandler is cached, 0 to disable the cache, by default,
+// so that status tooling polling every few seconds doesn't hammer the
+// version table. The cache is cleared when the process acquires the lock
+// and finishes a command, and when it applies or rolls back a migration;
+// commands modifying the database always read the version table.
+func SetVersionCache(ttl time.Duration) {
+	versionCache.Lock()
+	defer versionCache.Unlock()
+	versionCache.ttl = ttl
+	clearVersionCache()
+}
+
+// InvalidateVersionCache clears the versions cached with SetVersionCache,
+// after the database was migrated by another process.
+func InvalidateVersionCache() {
+	versionCache.Lock()
+	defer versionCache.Unlock()
+	clearVersionCache()
+}
+
+// cachedDBVersion returns the version of db cached less than the TTL ago,
+// or reads it, with EnsureDBVersion if ensure is true, and caches it.
+func cachedDBVersion(db *sql.DB, ensure bool) (int64, error) {
+	read := queryDBVersion
+	if ensure {
+		read = EnsureDBVersion
+	}
+	key := versionCacheKey{db: db, table: TableName(), ensure: ensure}
+	versionCache.Lock()
+	ttl, running, cleared := versionCache.ttl, versionCache.running > 0, versionCache.cleared
+	if c, ok := versionCache.versions[key]; ok && !running && time.Since(c.read) < ttl {
+		versionCache.Unlock()
+		return c.version, nil
+	}
+	versionCache.Unlock()
+	if ttl <= 0 || running {
+		return read(db)
+	}
+
+	started := time.Now()
+	version, err := read(db)
+	if err != nil {
+		return version, err
+	}
+	versionCache.Lock()
+	defer versionCache.Unlock()
+	// Don't cache a version read while the cache was cleared.
+	if versionCache.cleared == cleared {
+		if versionCache.versions == nil {
+			versionCache.versions = map[versionCacheKey]cachedVersion{}
+		}
+		versionCache.versions[key] = cachedVersion{version: version, read: started}
+	}
+	return version, nil
+}
+
+// clearVersionCache clears the cache, with the lock of versionCache held.
+func clearVersionCache() {
+	versionCache.versions = nil
+	versionCache.cleared++
+}
+
+// versionCacheRunning marks the start or the end of a command holding the
+// lock, clearing the cache.
+func versionCacheRunning(running bool) {
+	versionCache.Lock()
+	defer versionCache.Unlock()
+	if running {
+		versionCache.running++
+	} else {
+		versionCache.running--
+	}
+	clearVersionCache()
+}
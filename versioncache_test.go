@@ -0,0 +1,52 @@
+package goose
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVersionCache(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, testMigrations)
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	SetVersionCache(time.Hour)
+	defer SetVersionCache(0)
+	expect := func(want int64) {
+		t.Helper()
+		if v, err := GetDBVersion(db); err != nil || v != want {
+			t.Errorf("expected version %d, got %d (%v)", want, v, err)
+		}
+	}
+
+	if err := UpTo(db, dir, 1); err != nil {
+		t.Fatal(err)
+	}
+	expect(1)
+	// Applied by another process.
+	if err := insertVersion(db, 2, true, "", 0); err != nil {
+		t.Fatal(err)
+	}
+	expect(1)
+	if count, _, err := Pending(db, Migrations{newGoMigration(1), newGoMigration(2)}); err != nil || count != 0 {
+		t.Errorf("expected the version read by Pending to be current, got %d pending (%v)", count, err)
+	}
+	InvalidateVersionCache()
+	expect(2)
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	expect(3)
+	if err := Down(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	expect(2)
+
+	SetVersionCache(0)
+	if err := insertVersion(db, 3, true, "", 0); err != nil {
+		t.Fatal(err)
+	}
+	expect(3)
+}
@@ -0,0 +1,175 @@
+package goose
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// versionTableUpgrade is an upgrade of the format of the version table, for
+// the tables created by older versions of goose.
+type versionTableUpgrade struct {
+	name    string
+	applied func(db *sql.DB) (bool, error)
+	apply   func(db *sql.DB) error
+}
+
+// versionTableUpgrades are the upgrades of the version table since its
+// first format, in order. New upgrades are appended: tables created since
+// then are created in the format of the last one.
+var versionTableUpgrades = []versionTableUpgrade{
+	addVersionColumn("build"),
+	addVersionColumn("checksum"),
+	addVersionColumn("duration_ms"),
+	{name: "index on version_id and is_applied", applied: versionIndexExists, apply: createVersionIndex},
+	addVersionColumn("run_id"),
+}
+
+// upgradedTables are the version tables upgraded by the process.
+var upgradedTables struct {
+	sync.Mutex
+	tables map[versionCacheKey]bool
+}
+
+// upgradeVersionTable applies the missing upgrades to a version table
+// created by an older version of goose, once per process. It runs in the
+// invocations of the commands modifying the database, under their lock, so
+// that the read-only commands don't run DDL. A table that doesn't exist yet
+// is created in the last format. An upgrade failing because another process
+// applied it in the meantime is ignored.
+func upgradeVersionTable(db *sql.DB) error {
+	key := versionCacheKey{db: db, table: TableName()}
+	upgradedTables.Lock()
+	defer upgradedTables.Unlock()
+	if upgradedTables.tables[key] {
+		return nil
+	}
+	ensureDialect(db)
+	if exists, _ := versionColumnExists(db, "version_id"); !exists {
+		return nil
+	}
+
+	for _, u := range versionTableUpgrades {
+		applied, err := u.applied(db)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check the %s of the version table", u.name)
+		}
+		if applied {
+			continue
+		}
+		verboseInfo("Adding %s to version table", u.name)
+		if applyErr := u.apply(db); applyErr != nil {
+			if applied, err := u.applied(db); err != nil || !applied {
+				return errors.Wrapf(applyErr, "failed to add %s to version table", u.name)
+			}
+		}
+	}
+
+	if upgradedTables.tables == nil {
+		upgradedTables.tables = map[versionCacheKey]bool{}
+	}
+	upgradedTables.tables[key] = true
+	return nil
+}
+
+// addVersionColumn is the upgrade adding a column to the version table.
+func addVersionColumn(column string) versionTableUpgrade {
+	return versionTableUpgrade{
+		name: column + " column",
+		applied: func(db *sql.DB) (bool, error) {
+			return versionColumnExists(db, column)
+		},
+		apply: func(db *sql.DB) error {
+			_, err := db.Exec(GetDialect().addVersionColumnSQL(column))
+			return err
+		},
+	}
+}
+
+// versionColumnExists reports whether the version table has column.
+func versionColumnExists(db *sql.DB, column string) (bool, error) {
+	_, err := db.Exec(fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", column, TableName()))
+	return err == nil, nil
+}
+
+// versionColumn returns column to select it from the version table, or NULL
+// if the table, not upgraded yet by a command modifying the database, lacks
+// it.
+func versionColumn(db *sql.DB, column string) string {
+	if exists, _ := versionColumnExists(db, column); !exists {
+		return "NULL"
+	}
+	return column
+}
+
+// versionIndexName returns the name of the index of the version table on
+// version_id and is_applied, and the schema of the table.
+func versionIndexName() (schema, index string) {
+	schema, table := splitTableName(TableName())
+	return schema, table + "_version_idx"
+}
+
+// versionIndexSQL returns the statement creating the index of the version
+// table on version_id and is_applied, or an empty string if the dialect
+// has no indexes.
+func versionIndexSQL() string {
+	schema, index := versionIndexName()
+	switch GetDialect().(type) {
+	case *PostgresDialect, *MySQLDialect, *MariaDBDialect, *TiDBDialect, *SqlServerDialect:
+		return fmt.Sprintf("CREATE INDEX %s ON %s (version_id, is_applied)", index, TableName())
+	case *Sqlite3Dialect, *LibSQLDialect:
+		if schema != "" {
+			index = schema + "." + index
+		}
+		_, table := splitTableName(TableName())
+		return fmt.Sprintf("CREATE INDEX %s ON %s (version_id, is_applied)", index, table)
+	default:
+		return ""
+	}
+}
+
+// versionIndexExists reports whether the index of the version table on
+// version_id and is_applied exists, or is not supported by the dialect.
+func versionIndexExists(db *sql.DB) (bool, error) {
+	schema, index := versionIndexName()
+	_, table := splitTableName(TableName())
+	var q string
+	switch GetDialect().(type) {
+	case *PostgresDialect:
+		name := index
+		if schema != "" {
+			name = schema + "." + index
+		}
+		q = fmt.Sprintf("SELECT COUNT(*) FROM (SELECT to_regclass(%s) AS i) t WHERE i IS NOT NULL", stringLiteral(name))
+	case *MySQLDialect, *MariaDBDialect, *TiDBDialect:
+		database := "DATABASE()"
+		if schema != "" {
+			database = stringLiteral(schema)
+		}
+		q = fmt.Sprintf("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = %s AND table_name = %s AND index_name = %s", database, stringLiteral(table), stringLiteral(index))
+	case *SqlServerDialect:
+		q = fmt.Sprintf("SELECT COUNT(*) FROM sys.indexes WHERE name = %s AND object_id = OBJECT_ID(%s)", stringLiteral(index), stringLiteral(TableName()))
+	case *Sqlite3Dialect, *LibSQLDialect:
+		master := "sqlite_master"
+		if schema != "" {
+			master = schema + ".sqlite_master"
+		}
+		q = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE type = 'index' AND name = %s", master, stringLiteral(index))
+	default:
+		return true, nil
+	}
+	var count int
+	if err := db.QueryRow(q).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// createVersionIndex creates the index of the version table on version_id
+// and is_applied.
+func createVersionIndex(db *sql.DB) error {
+	_, err := db.Exec(versionIndexSQL())
+	return err
+}
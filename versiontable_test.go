@@ -0,0 +1,89 @@
+package goose
+
+import (
+	"testing"
+)
+
+func TestUpgradeVersionTable(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	// The format of the first versions of goose.
+	for _, q := range []string{
+		"CREATE TABLE goose_db_version (id INTEGER PRIMARY KEY AUTOINCREMENT, version_id INTEGER NOT NULL, is_applied INTEGER NOT NULL, tstamp TIMESTAMP DEFAULT (datetime('now')))",
+		"INSERT INTO goose_db_version (version_id, is_applied) VALUES (0, 1), (1, 1)",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v, err := EnsureDBVersion(db); err != nil || v != 1 {
+		t.Fatalf("expected version 1, got %d (%v)", v, err)
+	}
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql": "-- +goose Up\nCREATE TABLE a (id int);\n",
+		"00002_create_b.sql": "-- +goose Up\nCREATE TABLE b (id int);\n",
+	})
+	defer cleanupDir()
+
+	// Read-only commands don't upgrade the table.
+	if err := Status(db, dir, WithLogger(&bufferLogger{})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT build FROM goose_db_version"); err == nil {
+		t.Error("expected status not to add the columns")
+	}
+
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT build, checksum, duration_ms, run_id FROM goose_db_version"); err != nil {
+		t.Errorf("expected the columns to be added: %v", err)
+	}
+	if ok, err := versionIndexExists(db); err != nil || !ok {
+		t.Errorf("expected the index to be created, got %v (%v)", ok, err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 2 {
+		t.Errorf("expected version 2, got %d (%v)", v, err)
+	}
+
+	// Upgrades are applied once per process.
+	if _, err := db.Exec("DROP INDEX goose_db_version_version_idx"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := versionIndexExists(db); ok {
+		t.Error("expected the upgrades not to be checked again")
+	}
+}
+
+func TestVersionTableIndex(t *testing.T) {
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if _, err := EnsureDBVersion(db); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := versionIndexExists(db); err != nil || !ok {
+		t.Errorf("expected new version tables to have the index, got %v (%v)", ok, err)
+	}
+
+	defer SetDialect("sqlite3")
+	defer SetTableName(TableName())
+	SetTableName("ops.goose_db_version")
+	for dialect, want := range map[string]string{
+		"postgres":   "CREATE INDEX goose_db_version_version_idx ON ops.goose_db_version (version_id, is_applied)",
+		"sqlite3":    "CREATE INDEX ops.goose_db_version_version_idx ON goose_db_version (version_id, is_applied)",
+		"libsql":     "CREATE INDEX ops.goose_db_version_version_idx ON goose_db_version (version_id, is_applied)",
+		"clickhouse": "",
+	} {
+		if err := SetDialect(dialect); err != nil {
+			t.Fatal(err)
+		}
+		if got := versionIndexSQL(); got != want {
+			t.Errorf("%s: expected %q, got %q", dialect, want, got)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WASMRuntime runs the .wasm migrations, modules compiled from any language
+// to WebAssembly, as a portable alternative to Go plugins. goose embeds no
+// runtime nor host ABI: it is implemented with a WebAssembly runtime like
+// wazero, whose Call instantiates the module with host exposed to it as it
+// defines, and calls its exported function fn, "up" or "down".
+type WASMRuntime interface {
+	Call(ctx context.Context, module []byte, fn string, host WASMHost) error
+}
+
+// WASMHost is the host API of the .wasm migrations, running SQL statements
+// in the transaction of the migration.
+type WASMHost interface {
+	// Exec executes a statement, returning the number of rows affected.
+	Exec(query string, args ...interface{}) (int64, error)
+	// Query runs a query, returning its rows as a JSON array of objects.
+	Query(query string, args ...interface{}) ([]byte, error)
+}
+
+var wasmRuntime WASMRuntime
+
+// SetWASMRuntime sets the runtime of the .wasm migrations, which aren't
+// collected without one.
+func SetWASMRuntime(r WASMRuntime) {
+	wasmRuntime = r
+}
+
+// wasmHost is the WASMHost of a migration, on its transaction or its
+// connection.
+type wasmHost struct {
+	ctx context.Context
+	qe  QueryExecer
+}
+
+func (h wasmHost) Exec(query string, args ...interface{}) (int64, error) {
+	res, err := h.qe.ExecContext(h.ctx, query, args...)
+	if err != nil {
+		return 0, withStatement(err, query)
+	}
+	return res.RowsAffected()
+}
+
+func (h wasmHost) Query(query string, args ...interface{}) ([]byte, error) {
+	rows, err := h.qe.QueryContext(h.ctx, query, args...)
+	if err != nil {
+		return nil, withStatement(err, query)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	records := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, errors.Wrap(err, "failed to scan row")
+		}
+		record := make(map[string]interface{}, len(columns))
+		for i, c := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			record[c] = values[i]
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to get next row")
+	}
+	return json.Marshal(records)
+}
+
+// runWASMMigration calls the up or down function of the .wasm migration m,
+// in a transaction recording its version.
+func runWASMMigration(db *sql.DB, m *Migration, direction bool) error {
+	if wasmRuntime == nil {
+		return errors.Errorf("ERROR %v: failed to run WASM migration: no WASM runtime, see SetWASMRuntime", filepath.Base(m.Source))
+	}
+	module, err := ioutil.ReadFile(m.Source)
+	if err != nil {
+		return errors.Wrapf(err, "ERROR %v: failed to read WASM migration", filepath.Base(m.Source))
+	}
+	checksum, err := m.Checksum()
+	if err != nil {
+		return err
+	}
+	fn := "down"
+	if direction {
+		fn = "up"
+	}
+	started := time.Now()
+
+	run := func(qe QueryExecer) error {
+		if err := wasmRuntime.Call(runCtx, module, fn, wasmHost{ctx: runCtx, qe: qe}); err != nil {
+			return failedMigration(transactional(), errors.Wrapf(err, "ERROR %v: failed to run WASM migration function %s", filepath.Base(m.Source), fn))
+		}
+		if err := resetSession(qe); err != nil {
+			return err
+		}
+		if noVersioning {
+			return nil
+		}
+		if direction {
+			return errors.Wrap(insertVersion(qe, m.Version, direction, checksum, time.Since(started)), "ERROR failed to execute transaction")
+		}
+		return errors.Wrap(deleteVersion(qe, m.Version), "ERROR failed to execute transaction")
+	}
+
+	if !transactional() {
+		return withConn(db, func(conn connExecer) error {
+			if err := setupSession(conn); err != nil {
+				return err
+			}
+			return run(conn)
+		})
+	}
+	tx, release, err := beginTx(db)
+	if err != nil {
+		return errors.Wrap(err, "ERROR failed to begin transaction")
+	}
+	defer release()
+	if err := run(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "ERROR failed to commit transaction")
+}
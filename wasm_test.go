@@ -0,0 +1,64 @@
+package goose
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeWASMRuntime runs the modules of the tests, made of the statements of
+// their up and down functions separated by a newline.
+type fakeWASMRuntime struct {
+	rows []string
+}
+
+func (r *fakeWASMRuntime) Call(ctx context.Context, module []byte, fn string, host WASMHost) error {
+	statements := strings.SplitN(string(module), "\n", 2)
+	query := statements[0]
+	if fn == "down" {
+		query = statements[1]
+	}
+	if _, err := host.Exec(query); err != nil {
+		return err
+	}
+	if fn == "up" {
+		rows, err := host.Query("SELECT id, name FROM w")
+		if err != nil {
+			return err
+		}
+		r.rows = append(r.rows, string(rows))
+	}
+	return nil
+}
+
+func TestWASMMigration(t *testing.T) {
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_w.wasm": "CREATE TABLE w (id int, name text); INSERT INTO w VALUES (1, 'one')\nDROP TABLE w",
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	if migrations, err := CollectMigrations(dir, minVersion, maxVersion); err != nil || len(migrations) != 0 {
+		t.Fatalf("expected WASM migrations not to be collected without a runtime, got %v (%v)", migrations, err)
+	}
+
+	r := &fakeWASMRuntime{}
+	SetWASMRuntime(r)
+	defer SetWASMRuntime(nil)
+	if err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := GetDBVersion(db); err != nil || v != 1 {
+		t.Errorf("expected version 1, got %d (%v)", v, err)
+	}
+	if len(r.rows) != 1 || r.rows[0] != `[{"id":1,"name":"one"}]` {
+		t.Errorf("expected the rows of the query as JSON, got %v", r.rows)
+	}
+	if err := Down(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT * FROM w"); err == nil {
+		t.Error("expected the down function to drop the table")
+	}
+}
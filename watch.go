@@ -0,0 +1,91 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// Watcher applies the new migrations of a directory to a database as soon
+// as they are saved, for local development.
+type Watcher struct {
+	db       *sql.DB
+	dir      string
+	interval time.Duration
+	debounce time.Duration
+}
+
+// NewWatcher creates a Watcher of the migrations of dir, applied to db.
+func NewWatcher(db *sql.DB, dir string) *Watcher {
+	return &Watcher{db: db, dir: dir, interval: 500 * time.Millisecond, debounce: time.Second}
+}
+
+// SetInterval sets the interval at which the directory is polled for
+// changes, 500 milliseconds by default.
+func (w *Watcher) SetInterval(d time.Duration) {
+	w.interval = d
+}
+
+// SetDebounce sets how long the directory must be unchanged before the
+// migrations are applied, so that an editor saving several files, or a
+// file in several writes, triggers a single run. 1 second by default.
+func (w *Watcher) SetDebounce(d time.Duration) {
+	w.debounce = d
+}
+
+// Watch applies the pending migrations, then the new ones whenever the
+// directory changes, until ctx is done. Failed runs are logged, and retried
+// on the next change.
+func (w *Watcher) Watch(ctx context.Context) error {
+	log.Printf("goose: watching %s\n", w.dir)
+	w.apply()
+
+	last := w.snapshot()
+	var changed time.Time
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		snap := w.snapshot()
+		if snap != last {
+			last, changed = snap, time.Now()
+			continue
+		}
+		if !changed.IsZero() && time.Since(changed) >= w.debounce {
+			changed = time.Time{}
+			w.apply()
+		}
+	}
+}
+
+func (w *Watcher) apply() {
+	if err := Up(w.db, w.dir); err != nil {
+		log.Printf("goose: watch: failed to apply migrations: %v\n", err)
+		return
+	}
+	log.Printf("goose: watch: up to date, waiting for changes\n")
+}
+
+// snapshot returns a fingerprint of the names, sizes and modification times
+// of the files of the directory.
+func (w *Watcher) snapshot() string {
+	files, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, f := range files {
+		if !f.IsDir() {
+			fmt.Fprintf(&b, "%s %d %d\n", f.Name(), f.Size(), f.ModTime().UnixNano())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package goose
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// watchLogger sends the messages of the watcher to a channel.
+type watchLogger struct {
+	bufferLogger
+	messages chan string
+}
+
+func (l *watchLogger) Printf(format string, v ...interface{}) {
+	l.messages <- fmt.Sprintf(format, v...)
+}
+
+func TestWatcher(t *testing.T) {
+	defer SetLogger(log)
+	logger := &watchLogger{messages: make(chan string, 100)}
+	SetLogger(logger)
+	dir, cleanupDir := writeTestMigrations(t, map[string]string{
+		"00001_create_a.sql": testMigrations["00001_create_a.sql"],
+	})
+	defer cleanupDir()
+	db, cleanup := openTestDB(t)
+	defer cleanup()
+
+	w := NewWatcher(db, dir)
+	w.SetInterval(10 * time.Millisecond)
+	w.SetDebounce(50 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Watch(ctx) }()
+
+	// waitRun waits for the watcher to be done applying the migrations.
+	waitRun := func() string {
+		t.Helper()
+		for {
+			select {
+			case msg := <-logger.messages:
+				if strings.HasPrefix(msg, "goose: watch: ") {
+					return msg
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the watcher")
+			}
+		}
+	}
+	if msg := waitRun(); !strings.Contains(msg, "up to date") {
+		t.Fatalf("unexpected message %q", msg)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "00002_create_b.sql"), []byte("-- +goose Up\nCREATE TABLE b (id int;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitRun(); !strings.Contains(msg, "failed to apply migrations") {
+		t.Fatalf("expected the invalid migration to fail, got %q", msg)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "00002_create_b.sql"), []byte(testMigrations["00002_create_b.sql"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitRun(); !strings.Contains(msg, "up to date") {
+		t.Fatalf("unexpected message %q", msg)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if version, err := GetDBVersion(db); err != nil || version != 2 {
+		t.Errorf("expected version 2, got %d (%v)", version, err)
+	}
+}